@@ -2,27 +2,54 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"io"
 	"net"
 	"net/http"
 
+	"note_service/app/internal/attachment"
+	attachmentdb "note_service/app/internal/attachment/db"
 	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/collab"
 	"note_service/app/internal/config"
 	"note_service/app/internal/note"
 	"note_service/app/internal/note/db"
+	"note_service/app/internal/note/graph"
+	"note_service/app/internal/note/rpc"
+	"note_service/app/internal/notebook"
+	notebookdb "note_service/app/internal/notebook/db"
+	"note_service/app/internal/outbox"
+	outboxdb "note_service/app/internal/outbox/db"
+	"note_service/app/internal/reminder"
+	reminderdb "note_service/app/internal/reminder/db"
+	"note_service/app/internal/webhook"
+	webhookdb "note_service/app/internal/webhook/db"
+	"note_service/app/pkg/blob"
+	"note_service/app/pkg/cors"
+	"note_service/app/pkg/envelope"
 	"note_service/app/pkg/handlers/metric"
 	"note_service/app/pkg/logging"
+	"note_service/app/pkg/openapi"
 	"note_service/app/pkg/postgres"
+	"note_service/app/pkg/ratelimit"
+	"note_service/app/pkg/tlsconfig"
+	"note_service/app/pkg/validator"
 
 	"note_service/app/pkg/shutdown"
 	"os"
 	"path"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -44,29 +71,367 @@ func main() {
 	if err != nil {
 		logger.Fatalf("Error creating PostgreSQL client: %v", err)
 	}
+	if cfg.Encryption.Enabled {
+		logger.Println("note encryption initializing")
+		masterKeys := cfg.Encryption.MasterKeys
+		if cfg.Encryption.MasterKeyFile != "" {
+			if masterKeys, err = envelope.ReadKeyFile(cfg.Encryption.MasterKeyFile); err != nil {
+				logger.Fatalf("Error reading master key file: %v", err)
+			}
+		}
+		keyring, err := envelope.NewKeyring(masterKeys)
+		if err != nil {
+			logger.Fatalf("Error loading master keys: %v", err)
+		}
+		postgresClient.EnableEncryption(keyring)
+	}
 
 	noteStorage := db.NewStorage(postgresClient, logger)
+
+	validationRules := map[string]validator.Rule{
+		"text":  validator.Rule(cfg.Validation.Text),
+		"title": validator.Rule(cfg.Validation.Title),
+	}
+	noteValidator := validator.New(validationRules)
+	noteStream := note.NewBroker(cfg.Stream.BufferSize, cfg.Stream.QueueSize)
+	noteRenderer, err := note.NewRenderer(cfg.Render.CacheSize, cfg.Render.ImageProxy)
 	if err != nil {
-		panic(err)
+		logger.Fatalf("Error creating note renderer: %v", err)
 	}
+	// late gets the events for publishers that need the note service
+	// themselves and are made after it.
+	var late note.Publishers
+	publishers := note.Publishers{noteStream, noteRenderer, &late}
+	var closers []io.Closer
+	var webhookService webhook.Service
+	if cfg.Webhooks.Enabled {
+		logger.Println("webhooks initializing")
+		if cfg.Webhooks.Lease <= cfg.Webhooks.Timeout {
+			logger.Fatalf("webhooks.lease (%s) must exceed webhooks.timeout (%s)", cfg.Webhooks.Lease,
+				cfg.Webhooks.Timeout)
+		}
+		webhookStorage := webhookdb.NewStorage(postgresClient, logger)
+		admins := make([]uuid.UUID, 0, len(cfg.Webhooks.Admins))
+		for _, a := range cfg.Webhooks.Admins {
+			id, err := uuid.Parse(a)
+			if err != nil {
+				logger.Fatalf("Invalid webhook admin id %q: %v", a, err)
+			}
+			admins = append(admins, id)
+		}
+		webhookValidator := validator.New(map[string]validator.Rule{
+			"url": {MinRunes: 1, MaxBytes: 2048},
+		})
+		webhookService, err = webhook.NewService(webhookStorage, webhookValidator, admins, cfg.Webhooks.AllowPrivate, logger)
+		if err != nil {
+			panic(err)
+		}
+		publishers = append(publishers, webhook.NewDispatcher(webhookStorage, logger))
 
-	noteService, err := note.NewService(noteStorage, logger)
+		worker := webhook.NewWorker(webhookStorage, webhook.NewHTTPClient(cfg.Webhooks.Timeout, cfg.Webhooks.AllowPrivate), webhook.WorkerConfig{
+			PollInterval:         cfg.Webhooks.PollInterval,
+			BatchSize:            cfg.Webhooks.BatchSize,
+			Lease:                cfg.Webhooks.Lease,
+			MaxAttempts:          cfg.Webhooks.MaxAttempts,
+			BaseBackoff:          cfg.Webhooks.BaseBackoff,
+			MaxBackoff:           cfg.Webhooks.MaxBackoff,
+			DisableAfterFailures: cfg.Webhooks.DisableAfterFailures,
+		}, logger)
+		worker.Start()
+		closers = append(closers, worker)
+	}
+
+	if cfg.Outbox.Enabled {
+		logger.Println("outbox relay initializing")
+		if cfg.Outbox.Lease <= cfg.Outbox.Timeout {
+			logger.Fatalf("outbox.lease (%s) must exceed outbox.timeout (%s)", cfg.Outbox.Lease, cfg.Outbox.Timeout)
+		}
+		sink, err := outboxSink(cfg)
+		if err != nil {
+			logger.Fatalf("Error creating outbox sink: %v", err)
+		}
+		relay := outbox.NewRelay(outboxdb.NewStorage(postgresClient, logger), sink, outbox.RelayConfig{
+			PollInterval: cfg.Outbox.PollInterval,
+			BatchSize:    cfg.Outbox.BatchSize,
+			Lease:        cfg.Outbox.Lease,
+			Retention:    cfg.Outbox.Retention,
+		}, logger)
+		relay.Start()
+		closers = append(closers, relay)
+		if c, ok := sink.(io.Closer); ok {
+			closers = append(closers, c)
+		}
+	}
+
+	var reminderService reminder.Service
+	if cfg.Reminders.Enabled {
+		logger.Println("reminders initializing")
+		notifier, err := reminderNotifier(cfg, logger)
+		if err != nil {
+			logger.Fatalf("Error creating reminder notifier: %v", err)
+		}
+		if cfg.Reminders.Lease <= cfg.Reminders.Timeout {
+			logger.Fatalf("reminders.lease (%s) must exceed reminders.timeout (%s)", cfg.Reminders.Lease,
+				cfg.Reminders.Timeout)
+		}
+		reminderStorage := reminderdb.NewStorage(postgresClient, logger)
+		reminderValidator := validator.New(map[string]validator.Rule{
+			"rrule": {MinRunes: 1, MaxBytes: 512},
+		})
+		reminderService, err = reminder.NewService(reminderStorage, noteStorage, reminderValidator, logger)
+		if err != nil {
+			panic(err)
+		}
+		worker := reminder.NewWorker(reminderStorage, notifier, reminder.WorkerConfig{
+			PollInterval: cfg.Reminders.PollInterval,
+			BatchSize:    cfg.Reminders.BatchSize,
+			Lease:        cfg.Reminders.Lease,
+			MaxAttempts:  cfg.Reminders.MaxAttempts,
+			RetryDelay:   cfg.Reminders.RetryDelay,
+		}, logger)
+		worker.Start()
+		closers = append(closers, worker)
+	}
+
+	var attachmentService attachment.Service
+	if cfg.Attachments.Enabled {
+		logger.Println("attachments initializing")
+		blobStore, err := blob.NewLocalStore(cfg.Attachments.Root)
+		if err != nil {
+			logger.Fatalf("Error creating blob store: %v", err)
+		}
+		attachmentService, err = attachment.NewService(attachmentdb.NewStorage(postgresClient, logger), noteStorage,
+			blobStore, attachment.Limits{
+				MaxFileBytes: cfg.Attachments.MaxFileBytes,
+				QuotaBytes:   cfg.Attachments.QuotaBytes,
+				AllowedTypes: cfg.Attachments.AllowedTypes,
+			}, logger)
+		if err != nil {
+			panic(err)
+		}
+		publishers = append(publishers, attachmentService)
+
+		sweeper := attachment.NewSweeper(attachmentService, cfg.Attachments.SweepInterval, logger)
+		sweeper.Start()
+		closers = append(closers, sweeper)
+	}
+
+	noteService, err := note.NewService(noteStorage, noteValidator, note.Limits{
+		MaxCiphertextBytes: cfg.Validation.Encrypted.MaxCiphertextBytes,
+		MaxRecipients:      cfg.Validation.Encrypted.MaxRecipients,
+		MaxWrappedKeyBytes: cfg.Validation.Encrypted.MaxWrappedKeyBytes,
+	}, publishers, logger)
+	if err != nil {
+		panic(err)
+	}
+	scheduler := note.NewScheduler(noteStorage, publishers, note.SchedulerConfig{
+		PollInterval: cfg.Schedule.PollInterval,
+		BatchSize:    cfg.Schedule.BatchSize,
+	}, logger)
+	scheduler.Start()
+	closers = append(closers, scheduler)
+	reaper := note.NewReaper(noteStorage, publishers, note.ReaperConfig{
+		PollInterval: cfg.Reaper.PollInterval,
+		BatchSize:    cfg.Reaper.BatchSize,
+	}, logger)
+	reaper.Start()
+	closers = append(closers, reaper)
+	if cfg.Encryption.Enabled {
+		rotator := note.NewRotator(noteStorage, note.RotatorConfig{
+			PollInterval:  cfg.Encryption.PollInterval,
+			BatchSize:     cfg.Encryption.BatchSize,
+			DataKeyMaxAge: cfg.Encryption.DataKeyMaxAge,
+		}, logger)
+		rotator.Start()
+		closers = append(closers, rotator)
+	}
+	notebookValidator := validator.New(map[string]validator.Rule{
+		"name": validator.Rule(cfg.Validation.Title),
+	})
+	notebookService, err := notebook.NewService(notebookdb.NewStorage(postgresClient, logger), notebookValidator,
+		noteService, publishers, logger)
 	if err != nil {
 		panic(err)
 	}
 	userClient := user_client.NewClient(cfg.UserService.URL, "/me", logger)
+
+	var rateLimiter *ratelimit.Limiter
+	if cfg.RateLimit.Enabled {
+		logger.Println("rate limiter initializing")
+		groups := make(map[string]ratelimit.Group, len(cfg.RateLimit.Groups))
+		for name, g := range cfg.RateLimit.Groups {
+			groups[name] = ratelimit.Group{
+				Anonymous:     ratelimit.Rule(g.Anonymous),
+				Authenticated: ratelimit.Rule(g.Authenticated),
+			}
+		}
+		store := ratelimit.NewMemoryStore(cfg.RateLimit.CleanupInterval)
+		rateLimiter = ratelimit.NewLimiter(store, groups, cfg.RateLimit.TrustForwardedFor, logger)
+		closers = append(closers, store)
+	}
+
 	notesHandler := note.Handler{
-		Logger:      logger,
-		NoteService: noteService,
-		UserClient:  userClient,
+		Logger:       logger,
+		NoteService:  noteService,
+		UserClient:   userClient,
+		RateLimiter:  rateLimiter,
+		MaxBodyBytes: cfg.Validation.MaxBodyBytes,
+		Stream:       noteStream,
+		Heartbeat:    cfg.Stream.Heartbeat,
+		Renderer:     noteRenderer,
+	}
+	var corsPolicy *cors.CORS
+	if cfg.CORS.Enabled {
+		logger.Println("cors initializing")
+		corsPolicy, err = cors.New(cors.Options{
+			AllowedOrigins:   cfg.CORS.AllowedOrigins,
+			AllowedMethods:   cfg.CORS.AllowedMethods,
+			AllowedHeaders:   cfg.CORS.AllowedHeaders,
+			ExposedHeaders:   cfg.CORS.ExposedHeaders,
+			AllowCredentials: cfg.CORS.AllowCredentials,
+			MaxAge:           cfg.CORS.MaxAge,
+		})
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+
+	apiHandlers := []apiHandler{&notesHandler}
+
+	notebookHandler := notebook.Handler{
+		Logger:          logger,
+		NotebookService: notebookService,
+		UserClient:      userClient,
+		RateLimiter:     rateLimiter,
+		MaxBodyBytes:    cfg.Validation.MaxBodyBytes,
+	}
+	apiHandlers = append(apiHandlers, &notebookHandler)
+
+	if attachmentService != nil {
+		attachmentHandler := attachment.Handler{
+			Logger:            logger,
+			AttachmentService: attachmentService,
+			UserClient:        userClient,
+			RateLimiter:       rateLimiter,
+			TransferTimeout:   cfg.Attachments.TransferTimeout,
+		}
+		apiHandlers = append(apiHandlers, &attachmentHandler)
+	}
+
+	if reminderService != nil {
+		reminderHandler := reminder.Handler{
+			Logger:          logger,
+			ReminderService: reminderService,
+			UserClient:      userClient,
+			RateLimiter:     rateLimiter,
+			MaxBodyBytes:    cfg.Validation.MaxBodyBytes,
+		}
+		apiHandlers = append(apiHandlers, &reminderHandler)
+	}
+
+	if webhookService != nil {
+		webhookHandler := webhook.Handler{
+			Logger:         logger,
+			WebhookService: webhookService,
+			UserClient:     userClient,
+			RateLimiter:    rateLimiter,
+			MaxBodyBytes:   cfg.Validation.MaxBodyBytes,
+		}
+		apiHandlers = append(apiHandlers, &webhookHandler)
+	}
+
+	if cfg.Collab.Enabled {
+		logger.Println("collab hub initializing")
+		hub := collab.NewHub(noteService, noteStorage, collab.Config{
+			SnapshotInterval: cfg.Collab.SnapshotInterval,
+			IdleTimeout:      cfg.Collab.IdleTimeout,
+			HistorySize:      cfg.Collab.HistorySize,
+			Heartbeat:        cfg.Collab.Heartbeat,
+			ReadTimeout:      cfg.Collab.ReadTimeout,
+			SendQueue:        cfg.Collab.SendQueue,
+			MaxRunes:         cfg.Validation.Text.MaxRunes,
+			MaxBytes:         cfg.Validation.Text.MaxBytes,
+			MaxMessageBytes:  int(cfg.Validation.MaxBodyBytes),
+		}, logger)
+		hub.Start()
+		late = append(late, hub)
+		closers = append(closers, hub)
+		collabHandler := collab.Handler{
+			Logger:      logger,
+			Hub:         hub,
+			UserClient:  userClient,
+			RateLimiter: rateLimiter,
+			CORS:        corsPolicy,
+		}
+		apiHandlers = append(apiHandlers, &collabHandler)
+	}
+
+	if cfg.GraphQL.Enabled {
+		logger.Println("graphql handler initializing")
+		graphHandler, err := graph.NewHandler(noteService, userClient, rateLimiter, graph.Limits{
+			MaxDepth:      cfg.GraphQL.MaxDepth,
+			MaxComplexity: cfg.GraphQL.MaxComplexity,
+			DefaultPage:   cfg.GraphQL.DefaultPage,
+		}, cfg.Validation.MaxBodyBytes, logger)
+		if err != nil {
+			logger.Fatalf("Error building graphql schema: %v", err)
+		}
+		apiHandlers = append(apiHandlers, graphHandler)
+	}
+
+	logger.Println("openapi document initializing")
+	apiDoc, err := registerAPI(router, validationRules, apiHandlers...)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	openapiHandler, err := openapi.NewHandler(apiDoc)
+	if err != nil {
+		logger.Fatalf("Error building openapi document: %v", err)
+	}
+	openapiHandler.Register(router)
+
+	var handler http.Handler = router
+	if corsPolicy != nil {
+		router.GlobalOPTIONS = http.HandlerFunc(corsPolicy.Preflight)
+		handler = corsPolicy.Handler(router)
+	}
+
+	var grpcServer *grpc.Server
+	if cfg.GRPC.Enabled {
+		logger.Println("grpc server initializing")
+		grpcServer = rpc.NewGRPCServer(noteService, userClient, logger)
 	}
-	notesHandler.Register(router)
 
 	logger.Println("start application")
-	start(router, logger, cfg)
+	start(handler, grpcServer, logger, cfg, closers...)
+}
+
+// apiHandler serves routes that are described in the openapi document.
+type apiHandler interface {
+	Register(router openapi.Router)
+	Describe(b *openapi.Builder)
+}
+
+// registerAPI registers handlers on router and returns the openapi document,
+// verified against the routes that were actually registered.
+func registerAPI(router *httprouter.Router, rules map[string]validator.Rule,
+	handlers ...apiHandler) (openapi.Document, error) {
+	b := openapi.NewBuilder("note_service", "1.0.0")
+	b.Rules(rules)
+	recorder := openapi.NewRecorder(router)
+	for _, h := range handlers {
+		h.Register(recorder)
+		h.Describe(b)
+	}
+	if err := b.Verify(recorder.Registered(), router); err != nil {
+		return openapi.Document{}, err
+	}
+	return b.Document(), nil
 }
 
-func start(router http.Handler, logger logging.Logger, cfg *config.Config) {
+// start serves until a shutdown signal arrives, then closes the server
+// followed by the background workers in extra.
+func start(router http.Handler, grpcServer *grpc.Server, logger logging.Logger, cfg *config.Config, extra ...io.Closer) {
 	var server *http.Server
 	var listener net.Listener
 
@@ -94,14 +459,51 @@ func start(router http.Handler, logger logging.Logger, cfg *config.Config) {
 		}
 	}
 
+	clientUsers, err := tlsconfig.ParseUsers(cfg.Listen.TLS.ClientUsers)
+	if err != nil {
+		logger.Fatal(err)
+	}
 	server = &http.Server{
-		Handler:      router,
+		Handler:      tlsconfig.Middleware(router, clientUsers),
 		WriteTimeout: 15 * time.Second,
 		ReadTimeout:  15 * time.Second,
 	}
 
+	closers := []io.Closer{server}
+	if cfg.Listen.TLS.Enabled {
+		logger.Info("configure tls listener")
+		tlsCfg := cfg.Listen.TLS
+		reloader, err := tlsconfig.NewReloader(tlsCfg.CertFile, tlsCfg.KeyFile, tlsCfg.ClientCAFile,
+			tlsCfg.ReloadInterval, logger)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		server.TLSConfig, err = tlsconfig.New(tlsconfig.Options{
+			MinVersion:   tlsCfg.MinVersion,
+			CipherSuites: tlsCfg.CipherSuites,
+			ClientCAFile: tlsCfg.ClientCAFile,
+			ClientAuth:   tlsCfg.ClientAuth,
+		}, reloader)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		listener = tls.NewListener(listener, server.TLSConfig)
+		closers = append(closers, reloader)
+	}
+
+	if grpcServer != nil {
+		closers = append(closers, grpcCloser{grpcServer})
+		if cfg.GRPC.Multiplex {
+			logger.Info("multiplex grpc with http")
+			server.Handler = grpcMux(grpcServer, server.Handler, !cfg.Listen.TLS.Enabled)
+		} else {
+			go startGRPC(grpcServer, server.TLSConfig, logger, cfg)
+		}
+	}
+
+	closers = append(closers, extra...)
 	go shutdown.Graceful([]os.Signal{syscall.SIGABRT, syscall.SIGQUIT, syscall.SIGHUP, os.Interrupt, syscall.SIGTERM},
-		server)
+		closers...)
 
 	logger.Println("application initialized and started")
 
@@ -114,3 +516,84 @@ func start(router http.Handler, logger logging.Logger, cfg *config.Config) {
 		}
 	}
 }
+
+// outboxSink builds the sink named in the config. Brokers have no config of
+// their own, wire an outbox.Broker through outbox.NewBrokerSink instead.
+func outboxSink(cfg *config.Config) (outbox.Sink, error) {
+	switch cfg.Outbox.Sink {
+	case "stdout":
+		return outbox.NewWriterSink(os.Stdout), nil
+	case "file":
+		return outbox.NewFileSink(cfg.Outbox.FilePath)
+	case "http":
+		if cfg.Outbox.URL == "" {
+			return nil, errors.New("outbox.url is required for the http sink")
+		}
+		return outbox.NewHTTPSink(cfg.Outbox.URL, &http.Client{Timeout: cfg.Outbox.Timeout}), nil
+	default:
+		return nil, fmt.Errorf("unknown outbox sink %q", cfg.Outbox.Sink)
+	}
+}
+
+// reminderNotifier builds the notifier named in the config.
+func reminderNotifier(cfg *config.Config, logger logging.Logger) (reminder.Notifier, error) {
+	switch cfg.Reminders.Notifier {
+	case "log":
+		return reminder.NewLogNotifier(logger), nil
+	case "webhook":
+		if cfg.Reminders.URL == "" {
+			return nil, errors.New("reminders.url is required for the webhook notifier")
+		}
+		return reminder.NewWebhookNotifier(cfg.Reminders.URL, cfg.Reminders.Secret,
+			&http.Client{Timeout: cfg.Reminders.Timeout}), nil
+	case "stub":
+		return &reminder.StubNotifier{}, nil
+	default:
+		return nil, fmt.Errorf("unknown reminder notifier %q", cfg.Reminders.Notifier)
+	}
+}
+
+func startGRPC(grpcServer *grpc.Server, tlsConfig *tls.Config, logger logging.Logger, cfg *config.Config) {
+	logger.Infof("bind grpc server to host: %s and port: %s", cfg.GRPC.BindIP, cfg.GRPC.Port)
+	listener, err := net.Listen("tcp", fmt.Sprintf("%s:%s", cfg.GRPC.BindIP, cfg.GRPC.Port))
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if tlsConfig != nil {
+		listener = tls.NewListener(listener, tlsconfig.WithNextProtos(tlsConfig, "h2"))
+	}
+	if err := grpcServer.Serve(listener); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		logger.Fatal(err)
+	}
+}
+
+// grpcMux sends HTTP/2 requests with a gRPC content type to the gRPC server
+// and everything else to the HTTP handler. Without TLS, HTTP/2 is only
+// available through h2c. The server's read and write timeouts are meant for
+// HTTP requests, they are lifted for gRPC calls so streams like ListStream
+// stay open as long as the call does.
+func grpcMux(grpcServer *grpc.Server, h http.Handler, cleartext bool) http.Handler {
+	mux := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			rc := http.NewResponseController(w)
+			rc.SetReadDeadline(time.Time{})
+			rc.SetWriteDeadline(time.Time{})
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+		h.ServeHTTP(w, r)
+	})
+	if cleartext {
+		return h2c.NewHandler(mux, &http2.Server{})
+	}
+	return mux
+}
+
+type grpcCloser struct {
+	server *grpc.Server
+}
+
+func (c grpcCloser) Close() error {
+	c.server.GracefulStop()
+	return nil
+}
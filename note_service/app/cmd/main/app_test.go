@@ -0,0 +1,40 @@
+package main
+
+import (
+	"testing"
+
+	"note_service/app/internal/attachment"
+	"note_service/app/internal/collab"
+	"note_service/app/internal/note"
+	"note_service/app/internal/note/graph"
+	"note_service/app/internal/notebook"
+	"note_service/app/internal/reminder"
+	"note_service/app/internal/webhook"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/validator"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+// TestAPIDocumentMatchesRoutes registers every API handler the way main
+// does and checks the document against the router.
+func TestAPIDocumentMatchesRoutes(t *testing.T) {
+	graphHandler, err := graph.NewHandler(nil, nil, nil, graph.Limits{}, 0, logging.Logger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules := map[string]validator.Rule{
+		"text":  {MinRunes: 1, MaxRunes: 128, MaxBytes: 512},
+		"title": {MinRunes: 1, MaxRunes: 200, MaxBytes: 800},
+	}
+	doc, err := registerAPI(httprouter.New(), rules, &note.Handler{}, &notebook.Handler{}, &attachment.Handler{},
+		&reminder.Handler{}, &webhook.Handler{}, &collab.Handler{}, graphHandler)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	text := doc.Components.Schemas["CreateNoteDTO"].Properties["text"]
+	if text.MinLength == nil || *text.MinLength != 1 || text.MaxLength == nil || *text.MaxLength != 128 {
+		t.Errorf("CreateNoteDTO.text lengths = %v..%v, want 1..128", text.MinLength, text.MaxLength)
+	}
+}
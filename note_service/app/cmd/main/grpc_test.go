@@ -0,0 +1,239 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/tlsconfig"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// TestGRPCOverTLS calls a gRPC service through the mutual TLS config the
+// server uses, on the port shared with HTTP and on a port of its own.
+func TestGRPCOverTLS(t *testing.T) {
+	pki := newTestPKI(t)
+	serverTLS, err := tlsconfig.New(tlsconfig.Options{ClientCAFile: pki.caFile}, pki.reloader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, health.NewServer())
+	t.Cleanup(grpcServer.Stop)
+
+	t.Run("multiplexed", func(t *testing.T) {
+		listener := listen(t)
+		server := &http.Server{Handler: grpcMux(grpcServer, http.NotFoundHandler(), false), TLSConfig: serverTLS}
+		go server.Serve(tls.NewListener(listener, server.TLSConfig))
+		t.Cleanup(func() { server.Close() })
+		checkHealth(t, listener.Addr().String(), pki.clientTLS)
+	})
+	t.Run("separate port", func(t *testing.T) {
+		listener := listen(t)
+		go grpcServer.Serve(tls.NewListener(listener, tlsconfig.WithNextProtos(serverTLS, "h2")))
+		checkHealth(t, listener.Addr().String(), pki.clientTLS)
+	})
+}
+
+// TestGRPCStreamOutlivesHTTPTimeouts keeps a multiplexed gRPC stream open
+// past the read and write timeouts of the HTTP server it shares.
+func TestGRPCStreamOutlivesHTTPTimeouts(t *testing.T) {
+	pki := newTestPKI(t)
+	serverTLS, err := tlsconfig.New(tlsconfig.Options{ClientCAFile: pki.caFile}, pki.reloader(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	healthServer := health.NewServer()
+	grpcServer := grpc.NewServer()
+	healthpb.RegisterHealthServer(grpcServer, healthServer)
+	t.Cleanup(grpcServer.Stop)
+
+	tests := []struct {
+		name  string
+		tls   *tls.Config
+		creds credentials.TransportCredentials
+	}{
+		{"tls", serverTLS, credentials.NewTLS(pki.clientTLS)},
+		{"h2c", nil, insecure.NewCredentials()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			listener := listen(t)
+			server := &http.Server{
+				Handler:      grpcMux(grpcServer, http.NotFoundHandler(), tt.tls == nil),
+				TLSConfig:    tt.tls,
+				ReadTimeout:  100 * time.Millisecond,
+				WriteTimeout: 100 * time.Millisecond,
+			}
+			if tt.tls != nil {
+				listener = tls.NewListener(listener, tt.tls)
+			}
+			go server.Serve(listener)
+			t.Cleanup(func() { server.Close() })
+
+			conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(tt.creds))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			service := tt.name
+			stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{Service: service})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := stream.Recv(); err != nil {
+				t.Fatalf("Recv() error = %v", err)
+			}
+			time.Sleep(300 * time.Millisecond)
+			healthServer.SetServingStatus(service, healthpb.HealthCheckResponse_SERVING)
+			resp, err := stream.Recv()
+			if err != nil {
+				t.Fatalf("Recv() after the timeouts error = %v", err)
+			}
+			if resp.Status != healthpb.HealthCheckResponse_SERVING {
+				t.Errorf("Recv() status = %v, want SERVING", resp.Status)
+			}
+		})
+	}
+}
+
+func listen(t *testing.T) net.Listener {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener
+}
+
+func checkHealth(t *testing.T, addr string, clientTLS *tls.Config) {
+	t.Helper()
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(credentials.NewTLS(clientTLS)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatalf("Check() error = %v", err)
+	}
+	if resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatalf("Check() status = %v, want SERVING", resp.Status)
+	}
+}
+
+type testPKI struct {
+	caFile, certFile, keyFile string
+	clientTLS                 *tls.Config
+}
+
+func (p testPKI) reloader(t *testing.T) *tlsconfig.Reloader {
+	t.Helper()
+	r, err := tlsconfig.NewReloader(p.certFile, p.keyFile, p.caFile, time.Hour, logging.Logger{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	return r
+}
+
+// newTestPKI writes a CA and a server certificate for 127.0.0.1 signed by
+// it, and returns a client config with a certificate from the same CA.
+func newTestPKI(t *testing.T) testPKI {
+	t.Helper()
+	dir := t.TempDir()
+	caKey, caCert := newCert(t, nil, nil, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test ca"},
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	})
+	serverKey, serverCert := newCert(t, caKey, caCert, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "server"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	})
+	clientKey, clientCert := newCert(t, caKey, caCert, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "client"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+
+	p := testPKI{
+		caFile:   filepath.Join(dir, "ca.pem"),
+		certFile: filepath.Join(dir, "server.pem"),
+		keyFile:  filepath.Join(dir, "server-key.pem"),
+	}
+	writePEM(t, p.caFile, "CERTIFICATE", caCert.Raw)
+	writePEM(t, p.certFile, "CERTIFICATE", serverCert.Raw)
+	writePEM(t, p.keyFile, "EC PRIVATE KEY", marshalKey(t, serverKey))
+
+	roots := x509.NewCertPool()
+	roots.AddCert(caCert)
+	p.clientTLS = &tls.Config{
+		RootCAs:      roots,
+		Certificates: []tls.Certificate{{Certificate: [][]byte{clientCert.Raw}, PrivateKey: clientKey}},
+	}
+	return p
+}
+
+func newCert(t *testing.T, parentKey *ecdsa.PrivateKey, parent, template *x509.Certificate) (*ecdsa.PrivateKey,
+	*x509.Certificate) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, cert
+}
+
+func marshalKey(t *testing.T, key *ecdsa.PrivateKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return der
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
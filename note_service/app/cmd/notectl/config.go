@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+type cliConfig struct {
+	NoteServiceURL string `yaml:"note_service_url"`
+	UserServiceURL string `yaml:"user_service_url"`
+	Token          string `yaml:"token,omitempty"`
+	Username       string `yaml:"username,omitempty"`
+}
+
+func configPath() (string, error) {
+	if p := os.Getenv("NOTECTL_CONFIG"); p != "" {
+		return p, nil
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "notectl", "config.yml"), nil
+}
+
+func loadConfig() (*cliConfig, error) {
+	cfg := &cliConfig{
+		NoteServiceURL: "http://localhost:8001/",
+		UserServiceURL: "http://localhost:8000/",
+	}
+	p, err := configPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(p)
+	if os.IsNotExist(err) {
+		return cfg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config %s. error: %w", p, err)
+	}
+	if err := yaml.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse config %s. error: %w", p, err)
+	}
+	return cfg, nil
+}
+
+// save writes the config readable by the owner only, it holds the token.
+func (c *cliConfig) save() error {
+	p, err := configPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(p), 0700); err != nil {
+		return err
+	}
+	data, err := yaml.Marshal(c)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(p, data, 0600)
+}
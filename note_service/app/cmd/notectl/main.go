@@ -0,0 +1,316 @@
+// Command notectl works with note_service from the terminal.
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+
+	"note_service/app/internal/client/user_client"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/noteclient"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/term"
+)
+
+const usage = `usage: notectl [-o table|json|yaml] <command> [arguments]
+
+commands:
+  login [-u username]        obtain a token from the user service and store it
+  logout                     forget the stored token
+  list [-limit n]            list notes visible to you
+  get <id>                   show a note
+  create [-public] [-f file] create a note, text from -f, stdin or $EDITOR
+  edit <id>                  edit the text of a note in $EDITOR
+  rm <id>                    delete a note
+  publish <id>               make a note public
+  unpublish <id>             make a note private
+`
+
+type app struct {
+	cfg    *cliConfig
+	client *noteclient.Client
+	format string
+	stdin  io.Reader
+	stdout io.Writer
+}
+
+func main() {
+	if err := run(os.Args[1:]); err != nil {
+		fmt.Fprintln(os.Stderr, "notectl:", err)
+		os.Exit(1)
+	}
+}
+
+func run(args []string) error {
+	global := flag.NewFlagSet("notectl", flag.ContinueOnError)
+	global.Usage = func() { fmt.Fprint(os.Stderr, usage) }
+	format := global.String("o", "table", "output format: table, json or yaml")
+	if err := global.Parse(args); err != nil {
+		return err
+	}
+	if global.NArg() == 0 {
+		global.Usage()
+		return errors.New("no command given")
+	}
+
+	cfg, err := loadConfig()
+	if err != nil {
+		return err
+	}
+	a := &app{
+		cfg:    cfg,
+		client: noteclient.NewClient(cfg.NoteServiceURL, noteclient.WithToken(cfg.Token)),
+		format: *format,
+		stdin:  os.Stdin,
+		stdout: os.Stdout,
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	cmd, cmdArgs := global.Arg(0), global.Args()[1:]
+	switch cmd {
+	case "login":
+		return a.login(ctx, cmdArgs)
+	case "logout":
+		a.cfg.Token = ""
+		return a.cfg.save()
+	case "list", "ls":
+		return a.list(ctx, cmdArgs)
+	case "get":
+		return a.get(ctx, cmdArgs)
+	case "create":
+		return a.create(ctx, cmdArgs)
+	case "edit":
+		return a.edit(ctx, cmdArgs)
+	case "rm":
+		return a.remove(ctx, cmdArgs)
+	case "publish":
+		return a.setPublic(ctx, cmdArgs, true)
+	case "unpublish":
+		return a.setPublic(ctx, cmdArgs, false)
+	}
+	global.Usage()
+	return fmt.Errorf("unknown command %q", cmd)
+}
+
+func (a *app) login(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	username := fs.String("u", a.cfg.Username, "username")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	in := bufio.NewReader(a.stdin)
+	if *username == "" {
+		fmt.Fprint(os.Stderr, "username: ")
+		line, _ := in.ReadString('\n')
+		*username = strings.TrimSpace(line)
+	}
+	password, err := readPassword(a.stdin, in)
+	if err != nil {
+		return fmt.Errorf("failed to read password: %w", err)
+	}
+
+	users := user_client.NewClient(a.cfg.UserServiceURL, "/me", quietLogger())
+	token, err := users.Login(ctx, *username, password)
+	if err != nil {
+		return fmt.Errorf("login failed: %w", err)
+	}
+	a.cfg.Username = *username
+	a.cfg.Token = token.AccessToken
+	if err := a.cfg.save(); err != nil {
+		return fmt.Errorf("failed to store token: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "logged in as %s\n", *username)
+	return nil
+}
+
+// readPassword reads the password without echo when stdin is a terminal,
+// and a line of in otherwise, so it can still be piped in.
+func readPassword(stdin io.Reader, in *bufio.Reader) (string, error) {
+	fmt.Fprint(os.Stderr, "password: ")
+	if f, ok := stdin.(*os.File); ok && term.IsTerminal(int(f.Fd())) {
+		password, err := term.ReadPassword(int(f.Fd()))
+		fmt.Fprintln(os.Stderr)
+		return string(password), err
+	}
+	password, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+	return strings.TrimRight(password, "\r\n"), nil
+}
+
+func (a *app) list(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	limit := fs.Int("limit", 0, "maximum number of notes, 0 lists all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var notes []noteclient.Note
+	it := a.client.Iterate(ctx, 100)
+	for it.Next() {
+		notes = append(notes, it.Note())
+		if *limit > 0 && len(notes) == *limit {
+			break
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	return printNotes(a.stdout, a.format, notes, false)
+}
+
+func (a *app) get(ctx context.Context, args []string) error {
+	id, err := noteID(args)
+	if err != nil {
+		return err
+	}
+	n, err := a.client.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	return printNotes(a.stdout, a.format, []noteclient.Note{*n}, true)
+}
+
+func (a *app) create(ctx context.Context, args []string) error {
+	fs := flag.NewFlagSet("create", flag.ContinueOnError)
+	public := fs.Bool("public", false, "make the note public")
+	file := fs.String("f", "", "read the text from a file, - for stdin")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	var text string
+	var err error
+	switch {
+	case *file == "-" || (*file == "" && stdinIsPiped()):
+		text, err = readAll(a.stdin)
+	case *file != "":
+		var data []byte
+		data, err = os.ReadFile(*file)
+		text = string(data)
+	default:
+		text, err = editText("")
+	}
+	if err != nil {
+		return err
+	}
+	text = strings.TrimRight(text, "\n")
+	if strings.TrimSpace(text) == "" {
+		return errors.New("empty note, nothing created")
+	}
+
+	id, err := a.client.Create(ctx, noteclient.CreateNoteRequest{Text: text, Public: *public})
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(a.stdout, id)
+	return nil
+}
+
+func (a *app) edit(ctx context.Context, args []string) error {
+	id, err := noteID(args)
+	if err != nil {
+		return err
+	}
+	n, err := a.client.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	text, err := editText(n.Text)
+	if err != nil {
+		return err
+	}
+	text = strings.TrimRight(text, "\n")
+	if text == n.Text {
+		fmt.Fprintln(os.Stderr, "no changes")
+		return nil
+	}
+	return a.client.Update(ctx, id, noteclient.UpdateNoteRequest{Text: &text})
+}
+
+func (a *app) remove(ctx context.Context, args []string) error {
+	id, err := noteID(args)
+	if err != nil {
+		return err
+	}
+	return a.client.Delete(ctx, id)
+}
+
+func (a *app) setPublic(ctx context.Context, args []string, public bool) error {
+	id, err := noteID(args)
+	if err != nil {
+		return err
+	}
+	return a.client.Update(ctx, id, noteclient.UpdateNoteRequest{Public: &public})
+}
+
+func noteID(args []string) (uuid.UUID, error) {
+	if len(args) != 1 {
+		return uuid.Nil, errors.New("exactly one note id is required")
+	}
+	id, err := uuid.Parse(args[0])
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid note id %q", args[0])
+	}
+	return id, nil
+}
+
+func stdinIsPiped() bool {
+	info, err := os.Stdin.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice == 0
+}
+
+func readAll(r io.Reader) (string, error) {
+	data, err := io.ReadAll(r)
+	return string(data), err
+}
+
+// editText opens $EDITOR (vi by default) on a temporary file holding text
+// and returns what was saved.
+func editText(text string) (string, error) {
+	editor := os.Getenv("EDITOR")
+	if editor == "" {
+		editor = "vi"
+	}
+	f, err := os.CreateTemp("", "notectl-*.txt")
+	if err != nil {
+		return "", err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(text); err != nil {
+		f.Close()
+		return "", err
+	}
+	f.Close()
+
+	parts := strings.Fields(editor)
+	cmd := exec.Command(parts[0], append(parts[1:], f.Name())...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("editor failed: %w", err)
+	}
+	data, err := os.ReadFile(f.Name())
+	return string(data), err
+}
+
+// quietLogger satisfies the service clients without writing log files.
+func quietLogger() logging.Logger {
+	l := logrus.New()
+	l.SetOutput(os.Stderr)
+	l.SetLevel(logrus.WarnLevel)
+	return logging.Logger{Entry: logrus.NewEntry(l)}
+}
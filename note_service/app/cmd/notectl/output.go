@@ -0,0 +1,78 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"note_service/app/pkg/noteclient"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+type outputNote struct {
+	ID         string `json:"id" yaml:"id"`
+	UserID     string `json:"user_id" yaml:"user_id"`
+	CreateTime string `json:"create_time" yaml:"create_time"`
+	Public     bool   `json:"public" yaml:"public"`
+	Text       string `json:"text" yaml:"text"`
+	Encrypted  bool   `json:"encrypted,omitempty" yaml:"encrypted,omitempty"`
+}
+
+func toOutput(n noteclient.Note) outputNote {
+	return outputNote{
+		ID:         n.ID.String(),
+		UserID:     n.UserID.String(),
+		CreateTime: n.CreateTime.Format(time.RFC3339),
+		Public:     n.Public,
+		Text:       n.Text,
+		Encrypted:  n.Encrypted,
+	}
+}
+
+func printNotes(w io.Writer, format string, notes []noteclient.Note, single bool) error {
+	out := make([]outputNote, 0, len(notes))
+	for _, n := range notes {
+		out = append(out, toOutput(n))
+	}
+	var v interface{} = out
+	if single && len(out) == 1 {
+		v = out[0]
+	}
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "yaml":
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	case "table", "":
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(tw, "ID\tOWNER\tCREATED\tPUBLIC\tTEXT")
+		for _, n := range out {
+			text := preview(n.Text)
+			if n.Encrypted {
+				text = "(encrypted)"
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%t\t%s\n", n.ID, n.UserID, n.CreateTime, n.Public, text)
+		}
+		return tw.Flush()
+	}
+	return fmt.Errorf("unknown output format %q, use table, json or yaml", format)
+}
+
+func preview(text string) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if r := []rune(text); len(r) > 48 {
+		return string(r[:47]) + "…"
+	}
+	return text
+}
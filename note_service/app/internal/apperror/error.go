@@ -1,24 +1,49 @@
 package apperror
 
 import (
-	"encoding/json"
-	"fmt"
+	"errors"
+	"net/http"
+)
+
+const (
+	CodeBadRequest       = "bad_request"
+	CodeValidation       = "validation_failed"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeUpstream         = "upstream_error"
+	CodeInternal         = "internal_error"
+	internalErrorMessage = "internal server error"
 )
 
 var (
-	ErrNotFound  = NewAppError("not found", "")
-	ErrForbidden = NewAppError("forbidden", "")
+	ErrNotFound     = NewAppError(http.StatusNotFound, CodeNotFound, "not found", "")
+	ErrForbidden    = NewAppError(http.StatusForbidden, CodeForbidden, "forbidden", "")
+	ErrUnauthorized = NewAppError(http.StatusUnauthorized, CodeUnauthorized, "authentication required", "")
 )
 
+// FieldError describes a problem with a single field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
 type AppError struct {
-	Err              error  `json:"-"`
-	Message          string `json:"message,omitempty"`
-	DeveloperMessage string `json:"developer_message,omitempty"`
+	Err              error
+	Status           int
+	Code             string
+	Message          string
+	DeveloperMessage string
+	Details          []FieldError
 }
 
-func NewAppError(message, developerMessage string) *AppError {
+func NewAppError(status int, code, message, developerMessage string) *AppError {
 	return &AppError{
-		Err:              fmt.Errorf(message),
+		Err:              errors.New(message),
+		Status:           status,
+		Code:             code,
 		Message:          message,
 		DeveloperMessage: developerMessage,
 	}
@@ -30,26 +55,41 @@ func (e *AppError) Error() string {
 
 func (e *AppError) Unwrap() error { return e.Err }
 
-func (e *AppError) Marshal() []byte {
-	bytes, err := json.Marshal(e)
-	if err != nil {
-		return nil
-	}
-	return bytes
+// Is matches copies made by WithDetails against the error they were made
+// from, so errors.Is(err, ErrNotFound) holds for a detailed ErrNotFound.
+func (e *AppError) Is(target error) bool {
+	t, ok := target.(*AppError)
+	return ok && t.Err == e.Err
+}
+
+// WithDetails returns a copy of the error carrying field-level details.
+func (e *AppError) WithDetails(details ...FieldError) *AppError {
+	c := *e
+	c.Details = append(append([]FieldError{}, e.Details...), details...)
+	return &c
 }
 
 func BadRequestError(message string) *AppError {
-	return NewAppError(message, "some thing wrong with user data")
+	return NewAppError(http.StatusBadRequest, CodeBadRequest, message, "some thing wrong with user data")
+}
+
+func ValidationError(details ...FieldError) *AppError {
+	return NewAppError(http.StatusUnprocessableEntity, CodeValidation, "request validation failed", "").
+		WithDetails(details...)
 }
 
 func NotFoundError(message string) *AppError {
-	return NewAppError(message, "")
+	return NewAppError(http.StatusNotFound, CodeNotFound, message, "")
+}
+
+func TooManyRequestsError(developerMessage string) *AppError {
+	return NewAppError(http.StatusTooManyRequests, CodeTooManyRequests, "too many requests", developerMessage)
 }
 
 func systemError(developerMessage string) *AppError {
-	return NewAppError("system error", developerMessage)
+	return NewAppError(http.StatusInternalServerError, CodeInternal, internalErrorMessage, developerMessage)
 }
 
 func APIError(message, developerMessage string) *AppError {
-	return NewAppError(message, developerMessage)
+	return NewAppError(http.StatusBadGateway, CodeUpstream, message, developerMessage)
 }
@@ -0,0 +1,23 @@
+package apperror
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestWithDetailsIs(t *testing.T) {
+	detailed := ErrNotFound.WithDetails(FieldError{Field: "id", Code: "missing", Message: "no such note"})
+	if !errors.Is(detailed, ErrNotFound) {
+		t.Error("errors.Is(detailed ErrNotFound, ErrNotFound) = false")
+	}
+	if !errors.Is(fmt.Errorf("wrapped: %w", detailed), ErrNotFound) {
+		t.Error("errors.Is(wrapped detailed ErrNotFound, ErrNotFound) = false")
+	}
+	if errors.Is(detailed, ErrForbidden) {
+		t.Error("errors.Is(detailed ErrNotFound, ErrForbidden) = true")
+	}
+	if errors.Is(NotFoundError("not found"), ErrNotFound) {
+		t.Error("errors.Is(NotFoundError, ErrNotFound) = true, only copies should match")
+	}
+}
@@ -1,7 +1,6 @@
 package apperror
 
 import (
-	"errors"
 	"net/http"
 )
 
@@ -9,24 +8,8 @@ type appHandler func(http.ResponseWriter, *http.Request) error
 
 func Middleware(h appHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var appErr *AppError
-		err := h(w, r)
-		if err != nil {
-			if errors.As(err, &appErr) {
-				if errors.Is(err, ErrNotFound) {
-					w.WriteHeader(http.StatusNotFound)
-					return
-				} else if errors.Is(err, ErrForbidden) {
-					w.WriteHeader(http.StatusForbidden)
-					return
-				}
-				err := err.(*AppError)
-				w.WriteHeader(http.StatusBadRequest)
-				w.Write(err.Marshal())
-				return
-			}
-			w.WriteHeader(418)
-			w.Write(systemError(err.Error()).Marshal())
+		if err := h(w, r); err != nil {
+			Write(w, r, err)
 		}
 	}
 }
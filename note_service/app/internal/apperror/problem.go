@@ -0,0 +1,66 @@
+package apperror
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"note_service/app/pkg/logging"
+)
+
+const ProblemContentType = "application/problem+json"
+
+// Problem is an RFC 7807 problem details object.
+type Problem struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Code     string       `json:"code"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+func (e *AppError) Problem(instance string) Problem {
+	status := e.Status
+	if status == 0 {
+		status = http.StatusBadRequest
+	}
+	return Problem{
+		Type:     "about:blank",
+		Title:    http.StatusText(status),
+		Status:   status,
+		Detail:   e.Message,
+		Instance: instance,
+		Code:     e.Code,
+		Errors:   e.Details,
+	}
+}
+
+// Write renders err as problem+json. Errors that are not an AppError are
+// reported as a generic internal error, their text only goes to the log.
+func Write(w http.ResponseWriter, r *http.Request, err error) {
+	logger := logging.GetLogger()
+
+	var appErr *AppError
+	if !errors.As(err, &appErr) {
+		logger.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+		appErr = systemError(err.Error())
+	} else if appErr.DeveloperMessage != "" {
+		logger.Warnf("%s %s: %s: %s", r.Method, r.URL.Path, appErr.Message, appErr.DeveloperMessage)
+	}
+
+	problem := appErr.Problem(r.URL.Path)
+	body, mErr := json.Marshal(problem)
+	if mErr != nil {
+		logger.Errorf("failed to marshal problem: %v", mErr)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", ProblemContentType)
+	if problem.Status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", "Bearer")
+	}
+	w.WriteHeader(problem.Status)
+	w.Write(body)
+}
@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"note_service/app/internal/attachment"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/postgres"
+
+	"github.com/google/uuid"
+)
+
+var _ attachment.Storage = &db{}
+
+type db struct {
+	client *postgres.Client
+	logger logging.Logger
+}
+
+func NewStorage(client *postgres.Client, logger logging.Logger) attachment.Storage {
+	return &db{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (s *db) Create(ctx context.Context, a *attachment.Attachment, quota int64, commit func() error) error {
+	return s.client.CreateAttachment(ctx, a, quota, commit)
+}
+
+func (s *db) Get(ctx context.Context, attachmentUUID uuid.UUID) (*attachment.Attachment, error) {
+	return s.client.GetAttachment(ctx, attachmentUUID)
+}
+
+func (s *db) GetByNote(ctx context.Context, noteUUID uuid.UUID) (*attachment.Attachments, error) {
+	return s.client.GetNoteAttachments(ctx, noteUUID)
+}
+
+func (s *db) Delete(ctx context.Context, attachmentUUID uuid.UUID, drop func(string)) error {
+	return s.client.DeleteAttachment(ctx, attachmentUUID, drop)
+}
+
+func (s *db) DeleteByNote(ctx context.Context, noteUUID uuid.UUID, drop func(string)) (int64, error) {
+	return s.client.DeleteNoteAttachments(ctx, noteUUID, drop)
+}
+
+func (s *db) Sweep(ctx context.Context, drop func(string)) (int64, error) {
+	return s.client.SweepAttachments(ctx, drop)
+}
+
+func (s *db) Release(ctx context.Context, sha256 string, drop func(string)) error {
+	return s.client.ReleaseAttachmentBlob(ctx, sha256, drop)
+}
+
+func (s *db) Usage(ctx context.Context, userUUID uuid.UUID) (int64, error) {
+	return s.client.AttachmentUsage(ctx, userUUID)
+}
@@ -0,0 +1,224 @@
+package attachment
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/handlers"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/openapi"
+	"note_service/app/pkg/ratelimit"
+	"note_service/app/pkg/user"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	attachmentsURL = "/notes/:uuid/attachments"
+	attachmentURL  = "/notes/:uuid/attachments/:attachment"
+
+	readGroup  = "notes_read"
+	writeGroup = "notes_write"
+)
+
+type Handler struct {
+	Logger            logging.Logger
+	AttachmentService Service
+	UserClient        user_client.UserClient
+	RateLimiter       *ratelimit.Limiter
+	// TransferTimeout replaces the server read and write timeouts for
+	// uploads and downloads, which may take longer than other requests.
+	TransferTimeout time.Duration
+}
+
+func (h *Handler) Register(router openapi.Router) {
+	for _, route := range h.Routes() {
+		router.HandlerFunc(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (h *Handler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{ // POST /notes/{uuid}/attachments
+			Method: http.MethodPost,
+			Path:   attachmentsURL,
+			Handler: user.Authentication(h.UserClient,
+				h.RateLimiter.Limit(writeGroup, user.Authorization(apperror.Middleware(h.Upload)))),
+			Operation: uploadOperation,
+		},
+		{ // GET /notes/{uuid}/attachments
+			Method:    http.MethodGet,
+			Path:      attachmentsURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.List))),
+			Operation: listOperation,
+		},
+		{ // GET /notes/{uuid}/attachments/{attachment}
+			Method:    http.MethodGet,
+			Path:      attachmentURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.Download))),
+			Operation: downloadOperation,
+		},
+		{ // DELETE /notes/{uuid}/attachments/{attachment}
+			Method: http.MethodDelete,
+			Path:   attachmentURL,
+			Handler: user.Authentication(h.UserClient,
+				h.RateLimiter.Limit(writeGroup, user.Authorization(apperror.Middleware(h.Delete)))),
+			Operation: deleteOperation,
+		},
+	}
+}
+
+// Upload reads a multipart body part by part and streams the first "file"
+// part to the service, nothing is buffered in memory or on disk beforehand.
+func (h *Handler) Upload(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("UPLOAD ATTACHMENT")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	h.extendDeadlines(w)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return apperror.BadRequestError("request body must be multipart/form-data")
+	}
+	defer r.Body.Close()
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return apperror.ValidationError(apperror.FieldError{Field: "file", Code: "required", Message: "is required"})
+		}
+		if err != nil {
+			return apperror.BadRequestError("malformed multipart body")
+		}
+		if part.FormName() != "file" {
+			part.Close()
+			continue
+		}
+
+		a, err := h.AttachmentService.Upload(r.Context(), noteUUID, userUUID, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+		attachmentBytes, err := json.Marshal(a)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Location", fmt.Sprintf("/notes/%s/attachments/%s", noteUUID, a.AttachmentUUID))
+		w.WriteHeader(http.StatusCreated)
+		w.Write(attachmentBytes)
+		return nil
+	}
+}
+
+func (h *Handler) List(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET ATTACHMENTS")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	attachments, err := h.AttachmentService.List(r.Context(), noteUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	attachmentsBytes, err := json.Marshal(attachments)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(attachmentsBytes)
+
+	return nil
+}
+
+// Download serves the attachment through http.ServeContent, which answers
+// Range, If-Range and conditional requests. The content hash is the ETag.
+func (h *Handler) Download(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("DOWNLOAD ATTACHMENT")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	attachmentUUID, err := handlers.PathUUID(r, "attachment")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	a, b, err := h.AttachmentService.Open(r.Context(), noteUUID, attachmentUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	defer b.Close()
+	h.extendDeadlines(w)
+
+	disposition := "attachment"
+	if inline(*a.ContentType) {
+		disposition = "inline"
+	}
+	w.Header().Set("Content-Type", *a.ContentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": *a.Filename}))
+	w.Header().Set("ETag", `"`+*a.SHA256+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Content-Security-Policy", "sandbox")
+	http.ServeContent(w, r, *a.Filename, *a.CreateTime, b)
+
+	return nil
+}
+
+func (h *Handler) Delete(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("DELETE ATTACHMENT")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	attachmentUUID, err := handlers.PathUUID(r, "attachment")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	if err := h.AttachmentService.Delete(r.Context(), noteUUID, attachmentUUID, userUUID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) extendDeadlines(w http.ResponseWriter) {
+	if h.TransferTimeout <= 0 {
+		return
+	}
+	rc := http.NewResponseController(w)
+	deadline := time.Now().Add(h.TransferTimeout)
+	rc.SetReadDeadline(deadline)
+	rc.SetWriteDeadline(deadline)
+}
+
+// inline reports whether browsers may display a type in place, anything
+// that could run script is downloaded instead.
+func inline(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	switch mediaType {
+	case "image/png", "image/jpeg", "image/gif", "image/webp", "application/pdf", "text/plain":
+		return true
+	}
+	return false
+}
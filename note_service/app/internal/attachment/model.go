@@ -0,0 +1,24 @@
+package attachment
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Attachment is a file on a note. The content lives in the blob store under
+// its SHA-256, so identical uploads share one blob.
+type Attachment struct {
+	AttachmentUUID *uuid.UUID `json:"id,omitempty"`
+	NoteUUID       *uuid.UUID `json:"note_id,omitempty"`
+	UserUUID       *uuid.UUID `json:"user_id,omitempty"`
+	Filename       *string    `json:"filename,omitempty"`
+	ContentType    *string    `json:"content_type,omitempty"`
+	Size           *int64     `json:"size,omitempty"`
+	SHA256         *string    `json:"sha256,omitempty"`
+	CreateTime     *time.Time `json:"create_time,omitempty"`
+}
+
+type Attachments struct {
+	Attachments []Attachment `json:"attachments"`
+}
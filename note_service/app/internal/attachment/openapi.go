@@ -0,0 +1,88 @@
+package attachment
+
+import (
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/openapi"
+)
+
+func (h *Handler) Describe(b *openapi.Builder) {
+	b.Schema(Attachment{})
+	b.Schema(Attachments{})
+	b.Schema(apperror.Problem{})
+	b.AddRoutes(h.Routes())
+}
+
+var (
+	uploadOperation = openapi.Operation{
+		OperationID: "uploadAttachment",
+		Summary:     "Attach a file to one of the caller's notes",
+		Tags:        []string{"attachments"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: map[string]openapi.MediaType{
+			"multipart/form-data": {Schema: &openapi.Schema{
+				Type:       "object",
+				Required:   []string{"file"},
+				Properties: map[string]*openapi.Schema{"file": {Type: "string", Format: "binary"}},
+			}},
+		}},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "Created", Content: openapi.JSON(openapi.Ref("Attachment"))},
+			"400": openapi.Problem("Malformed multipart body or note id"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The note belongs to someone else"),
+			"404": openapi.Problem("Note not found"),
+			"413": openapi.Problem("File too large or quota exceeded"),
+			"415": openapi.Problem("File type not accepted"),
+			"422": openapi.Problem("No file part"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	listOperation = openapi.Operation{
+		OperationID: "listAttachments",
+		Summary:     "List the attachments of a note",
+		Tags:        []string{"attachments"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Attachments", Content: openapi.JSON(openapi.Ref("Attachments"))},
+			"400": openapi.Problem("Malformed note id"),
+			"403": openapi.Problem("The note is not accessible to the caller"),
+			"404": openapi.Problem("Note not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	downloadOperation = openapi.Operation{
+		OperationID: "downloadAttachment",
+		Summary:     "Download an attachment, Range requests are supported",
+		Tags:        []string{"attachments"},
+		Security:    openapi.BearerAuth(),
+		Parameters: []openapi.Parameter{
+			{Name: "Range", In: "header", Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The file", Content: map[string]openapi.MediaType{
+				"application/octet-stream": {Schema: &openapi.Schema{Type: "string", Format: "binary"}}}},
+			"206": {Description: "Part of the file", Content: map[string]openapi.MediaType{
+				"application/octet-stream": {Schema: &openapi.Schema{Type: "string", Format: "binary"}}}},
+			"304": {Description: "Not modified"},
+			"400": openapi.Problem("Malformed note or attachment id"),
+			"403": openapi.Problem("The note is not accessible to the caller"),
+			"404": openapi.Problem("Note or attachment not found"),
+			"416": {Description: "Range not satisfiable"},
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	deleteOperation = openapi.Operation{
+		OperationID: "deleteAttachment",
+		Summary:     "Delete an attachment",
+		Tags:        []string{"attachments"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Deleted"},
+			"400": openapi.Problem("Malformed note or attachment id"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The attachment belongs to someone else"),
+			"404": openapi.Problem("Note or attachment not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+)
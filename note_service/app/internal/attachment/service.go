@@ -0,0 +1,301 @@
+package attachment
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/blob"
+	"note_service/app/pkg/logging"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+var _ Service = &service{}
+
+type Limits struct {
+	// MaxFileBytes caps a single upload, QuotaBytes the total per user.
+	MaxFileBytes int64
+	QuotaBytes   int64
+	// AllowedTypes lists the sniffed MIME types accepted, empty allows all.
+	AllowedTypes []string
+}
+
+type service struct {
+	storage Storage
+	notes   note.Storage
+	blobs   blob.Store
+	limits  Limits
+	logger  logging.Logger
+}
+
+func NewService(storage Storage, notes note.Storage, blobs blob.Store, limits Limits,
+	logger logging.Logger) (Service, error) {
+	return &service{storage: storage, notes: notes, blobs: blobs, limits: limits, logger: logger}, nil
+}
+
+// Service also subscribes to note events to drop the attachments of deleted
+// notes.
+type Service interface {
+	note.EventPublisher
+	Upload(ctx context.Context, noteUUID, userUUID uuid.UUID, filename string, content io.Reader) (*Attachment, error)
+	List(ctx context.Context, noteUUID, userUUID uuid.UUID) (*Attachments, error)
+	Open(ctx context.Context, noteUUID, attachmentUUID, userUUID uuid.UUID) (*Attachment, blob.Blob, error)
+	Delete(ctx context.Context, noteUUID, attachmentUUID, userUUID uuid.UUID) error
+	// Sweep removes the attachments of notes that no longer exist.
+	Sweep(ctx context.Context) (int64, error)
+}
+
+// Upload streams content into the blob store while hashing it, the client's
+// content type is ignored in favour of sniffing the first bytes.
+func (s *service) Upload(ctx context.Context, noteUUID, userUUID uuid.UUID, filename string,
+	content io.Reader) (*Attachment, error) {
+	n, err := s.note(ctx, noteUUID, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if *n.UserUUID != userUUID {
+		return nil, apperror.ErrForbidden
+	}
+
+	limit, quotaBound := s.limits.MaxFileBytes, false
+	if s.limits.QuotaBytes > 0 {
+		used, err := s.storage.Usage(ctx, userUUID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get attachment usage. error: %w", err)
+		}
+		if remaining := s.limits.QuotaBytes - used; limit <= 0 || remaining < limit {
+			limit, quotaBound = remaining, true
+		}
+		if limit <= 0 {
+			return nil, quotaError()
+		}
+	}
+
+	staged, err := s.blobs.Stage(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to stage attachment. error: %w", err)
+	}
+	committed := false
+	defer func() {
+		if !committed {
+			staged.Abort()
+		}
+	}()
+
+	src := bufio.NewReaderSize(content, 512)
+	head, _ := src.Peek(512)
+	contentType := http.DetectContentType(head)
+	if !s.allowed(contentType) {
+		return nil, apperror.NewAppError(http.StatusUnsupportedMediaType, "unsupported_type",
+			fmt.Sprintf("files of type %s are not accepted", contentType), "")
+	}
+
+	var reader io.Reader = src
+	if limit > 0 {
+		reader = io.LimitReader(src, limit+1)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(staged, hash), reader)
+	if err != nil {
+		return nil, apperror.BadRequestError("failed to read upload")
+	}
+	if limit > 0 && size > limit {
+		if quotaBound {
+			return nil, quotaError()
+		}
+		return nil, apperror.NewAppError(http.StatusRequestEntityTooLarge, "file_too_large",
+			fmt.Sprintf("files may be at most %d bytes", s.limits.MaxFileBytes), "")
+	}
+
+	sum := hex.EncodeToString(hash.Sum(nil))
+	id := uuid.New()
+	now := time.Now().UTC()
+	name := cleanFilename(filename)
+	a := Attachment{
+		AttachmentUUID: &id,
+		NoteUUID:       &noteUUID,
+		UserUUID:       &userUUID,
+		Filename:       &name,
+		ContentType:    &contentType,
+		Size:           &size,
+		SHA256:         &sum,
+		CreateTime:     &now,
+	}
+	err = s.storage.Create(ctx, &a, s.limits.QuotaBytes, func() error {
+		committed = true
+		return staged.Commit(sum)
+	})
+	if err != nil {
+		if committed {
+			s.release(ctx, sum)
+		}
+		if errors.Is(err, ErrQuotaExceeded) {
+			return nil, quotaError()
+		}
+		return nil, fmt.Errorf("failed to create attachment. error: %w", err)
+	}
+	return &a, nil
+}
+
+func (s *service) List(ctx context.Context, noteUUID, userUUID uuid.UUID) (*Attachments, error) {
+	if _, err := s.note(ctx, noteUUID, userUUID); err != nil {
+		return nil, err
+	}
+	attachments, err := s.storage.GetByNote(ctx, noteUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attachments. error: %w", err)
+	}
+	return attachments, nil
+}
+
+func (s *service) Open(ctx context.Context, noteUUID, attachmentUUID, userUUID uuid.UUID) (*Attachment, blob.Blob, error) {
+	a, err := s.attachment(ctx, noteUUID, attachmentUUID, userUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+	b, err := s.blobs.Open(ctx, *a.SHA256)
+	if err != nil {
+		if errors.Is(err, blob.ErrNotFound) {
+			return nil, nil, apperror.ErrNotFound
+		}
+		return nil, nil, fmt.Errorf("failed to open attachment. error: %w", err)
+	}
+	return a, b, nil
+}
+
+func (s *service) Delete(ctx context.Context, noteUUID, attachmentUUID, userUUID uuid.UUID) error {
+	a, err := s.attachment(ctx, noteUUID, attachmentUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if *a.UserUUID != userUUID {
+		return apperror.ErrForbidden
+	}
+	if err := s.storage.Delete(ctx, attachmentUUID, s.drop(ctx)); err != nil {
+		return fmt.Errorf("failed to delete attachment. error: %w", err)
+	}
+	return nil
+}
+
+// Sweep removes the attachments of notes deleted without Publish seeing it.
+func (s *service) Sweep(ctx context.Context) (int64, error) {
+	swept, err := s.storage.Sweep(ctx, s.drop(ctx))
+	if err != nil {
+		return 0, fmt.Errorf("failed to sweep attachments. error: %w", err)
+	}
+	return swept, nil
+}
+
+// Publish removes the attachments of deleted notes.
+func (s *service) Publish(ctx context.Context, events ...note.Event) {
+	for _, event := range events {
+		if event.Type != note.EventDeleted || event.Note.NoteUUID == nil {
+			continue
+		}
+		if _, err := s.storage.DeleteByNote(ctx, *event.Note.NoteUUID, s.drop(ctx)); err != nil {
+			s.logger.Errorf("failed to delete attachments of note %s: %v", event.Note.NoteUUID, err)
+		}
+	}
+}
+
+// note applies the visibility of GetNoteByID. Burn-after-read notes only
+// show their attachments to the owner, reading the note is what burns it
+// and the attachments go with it.
+func (s *service) note(ctx context.Context, noteUUID, userUUID uuid.UUID) (*note.Note, error) {
+	n, err := s.notes.GetByID(ctx, noteUUID, userUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrForbidden) || errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to find note by uuid. error: %w", err)
+	}
+	if n.BurnAfterRead != nil && *n.BurnAfterRead && *n.UserUUID != userUUID {
+		return nil, apperror.ErrNotFound
+	}
+	return n, nil
+}
+
+func (s *service) attachment(ctx context.Context, noteUUID, attachmentUUID, userUUID uuid.UUID) (*Attachment, error) {
+	if _, err := s.note(ctx, noteUUID, userUUID); err != nil {
+		return nil, err
+	}
+	a, err := s.storage.Get(ctx, attachmentUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get attachment. error: %w", err)
+	}
+	if *a.NoteUUID != noteUUID {
+		return nil, apperror.ErrNotFound
+	}
+	return a, nil
+}
+
+// release drops a blob that was committed for an attachment which then
+// could not be created, unless another attachment uses it.
+func (s *service) release(ctx context.Context, key string) {
+	if err := s.storage.Release(ctx, key, s.drop(ctx)); err != nil {
+		s.logger.Errorf("failed to release blob %s: %v", key, err)
+	}
+}
+
+// drop deletes blobs nothing refers to anymore. A blob that fails to go is
+// only logged, it takes space but is never served.
+func (s *service) drop(ctx context.Context) func(key string) {
+	return func(key string) {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			s.logger.Errorf("failed to delete blob %s: %v", key, err)
+		}
+	}
+}
+
+func (s *service) allowed(contentType string) bool {
+	if len(s.limits.AllowedTypes) == 0 {
+		return true
+	}
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	for _, t := range s.limits.AllowedTypes {
+		if t == mediaType || (strings.HasSuffix(t, "/*") && strings.HasPrefix(mediaType, strings.TrimSuffix(t, "*"))) {
+			return true
+		}
+	}
+	return false
+}
+
+func quotaError() error {
+	return apperror.NewAppError(http.StatusRequestEntityTooLarge, "quota_exceeded",
+		"attachment storage quota exceeded", "")
+}
+
+// cleanFilename keeps the base name of what the client sent, without
+// control characters and at most 255 bytes long.
+func cleanFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) || r == '"' {
+			return -1
+		}
+		return r
+	}, name)
+	for len(name) > 255 {
+		_, size := utf8.DecodeLastRuneInString(name)
+		name = name[:len(name)-size]
+	}
+	if name == "" || name == "." || name == "/" {
+		return "attachment"
+	}
+	return name
+}
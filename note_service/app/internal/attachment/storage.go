@@ -0,0 +1,34 @@
+package attachment
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+)
+
+// ErrQuotaExceeded is returned by Storage.Create when the attachment would
+// take its owner over the quota.
+var ErrQuotaExceeded = errors.New("attachment quota exceeded")
+
+// Storage serializes the rows referring to a blob with storing and dropping
+// it: the callbacks run while the blob's hash is locked.
+type Storage interface {
+	// Create runs commit to store the blob and then stores a, unless the
+	// attachments of its owner would add up to more than quota bytes. A
+	// quota of zero or less is unlimited.
+	Create(ctx context.Context, a *Attachment, quota int64, commit func() error) error
+	Get(ctx context.Context, attachmentUUID uuid.UUID) (*Attachment, error)
+	GetByNote(ctx context.Context, noteUUID uuid.UUID) (*Attachments, error)
+	// Delete removes an attachment and calls drop when nothing else refers
+	// to its blob.
+	Delete(ctx context.Context, attachmentUUID uuid.UUID, drop func(sha256 string)) error
+	// DeleteByNote removes the attachments of a note and calls drop for the
+	// blobs nothing refers to anymore.
+	DeleteByNote(ctx context.Context, noteUUID uuid.UUID, drop func(sha256 string)) (int64, error)
+	// Sweep does what DeleteByNote does for every note that no longer exists.
+	Sweep(ctx context.Context, drop func(sha256 string)) (int64, error)
+	// Release calls drop when no attachment refers to the blob.
+	Release(ctx context.Context, sha256 string, drop func(sha256 string)) error
+	Usage(ctx context.Context, userUUID uuid.UUID) (int64, error)
+}
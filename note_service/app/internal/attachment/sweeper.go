@@ -0,0 +1,62 @@
+package attachment
+
+import (
+	"context"
+	"note_service/app/pkg/logging"
+	"sync"
+	"time"
+)
+
+// Sweeper periodically removes attachments whose note is gone. Deleted notes
+// normally take their attachments with them through Publish, the sweep
+// catches those whose event was lost, e.g. to a restart.
+type Sweeper struct {
+	service  Service
+	interval time.Duration
+	logger   logging.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewSweeper(service Service, interval time.Duration, logger logging.Logger) *Sweeper {
+	return &Sweeper{service: service, interval: interval, logger: logger}
+}
+
+func (s *Sweeper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.sweep(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Sweeper) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Sweeper) sweep(ctx context.Context) {
+	swept, err := s.service.Sweep(ctx)
+	if err != nil {
+		s.logger.Errorf("failed to sweep attachments: %v", err)
+		return
+	}
+	if swept > 0 {
+		s.logger.Infof("swept %d attachments of deleted notes", swept)
+	}
+}
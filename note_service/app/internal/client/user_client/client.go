@@ -5,10 +5,14 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"note_service/app/internal/apperror"
 	"note_service/app/pkg/logging"
 	"note_service/app/pkg/rest"
+	"strings"
 	"time"
+
+	"github.com/google/uuid"
 )
 
 var _ UserClient = &client{}
@@ -32,8 +36,15 @@ func NewClient(baseURL string, resource string, logger logging.Logger) UserClien
 	return &c
 }
 
+const (
+	usersResource = "/users"
+	loginResource = "/login"
+)
+
 type UserClient interface {
 	GetUserByToken(ctx context.Context, token Token) (User, error)
+	GetUsersByIDs(ctx context.Context, token Token, ids []uuid.UUID) ([]User, error)
+	Login(ctx context.Context, username, password string) (Token, error)
 }
 
 func (c *client) GetUserByToken(ctx context.Context, t Token) (u User, err error) {
@@ -84,3 +95,88 @@ func (c *client) GetUserByToken(ctx context.Context, t Token) (u User, err error
 	}
 	return u, apperror.APIError(response.Error.Message, response.Error.DeveloperMessage)
 }
+
+func (c *client) GetUsersByIDs(ctx context.Context, t Token, ids []uuid.UUID) (users []User, err error) {
+	bearerToken := fmt.Sprintf("%s %s", t.TokenType, t.AccessToken)
+	c.base.Logger.Debug("add ids to filter options")
+	values := make([]string, 0, len(ids))
+	for _, id := range ids {
+		values = append(values, id.String())
+	}
+	filters := []rest.FilterOptions{
+		{
+			Field:  "ids",
+			Values: values,
+		},
+	}
+
+	c.base.Logger.Debug("build url with resource and filter")
+	uri, err := c.base.BuildURL(usersResource, filters)
+	if err != nil {
+		return users, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+	c.base.Logger.Tracef("url: %s", uri)
+
+	c.base.Logger.Debug("create new request")
+	req, err := http.NewRequest(http.MethodGet, uri, nil)
+	if err != nil {
+		return users, fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+	req.Header.Set("Authorization", bearerToken)
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return users, fmt.Errorf("failed to send request due to error: %w", err)
+	}
+
+	if response.IsOk {
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(&users); err != nil {
+			return users, fmt.Errorf("failed to decode body due to error %w", err)
+		}
+		return users, nil
+	} else if response.StatusCode() == 401 {
+		return users, fmt.Errorf("Unauthorized")
+	}
+	return users, apperror.APIError(response.Error.Message, response.Error.DeveloperMessage)
+}
+
+func (c *client) Login(ctx context.Context, username, password string) (t Token, err error) {
+	c.base.Logger.Debug("build url with resource")
+	uri, err := c.base.BuildURL(loginResource, nil)
+	if err != nil {
+		return t, fmt.Errorf("failed to build URL. error: %v", err)
+	}
+
+	c.base.Logger.Debug("create new request")
+	form := url.Values{"username": {username}, "password": {password}}
+	req, err := http.NewRequest(http.MethodPost, uri, strings.NewReader(form.Encode()))
+	if err != nil {
+		return t, fmt.Errorf("failed to create new request due to error: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	c.base.Logger.Debug("send request")
+	reqCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	req = req.WithContext(reqCtx)
+	response, err := c.base.SendRequest(req)
+	if err != nil {
+		return t, fmt.Errorf("failed to send request due to error: %w", err)
+	}
+
+	if response.IsOk {
+		defer response.Body().Close()
+		if err = json.NewDecoder(response.Body()).Decode(&t); err != nil {
+			return t, fmt.Errorf("failed to decode body due to error %w", err)
+		}
+		return t, nil
+	} else if response.StatusCode() == 400 || response.StatusCode() == 401 {
+		return t, fmt.Errorf("Unauthorized")
+	}
+	return t, apperror.APIError(response.Error.Message, response.Error.DeveloperMessage)
+}
@@ -0,0 +1,148 @@
+package collab
+
+import (
+	"encoding/json"
+	"note_service/app/pkg/logging"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+const writeTimeout = 10 * time.Second
+
+// Client is one connection to a document. Messages to it are queued, a
+// client that doesn't keep up with its queue is dropped instead of holding
+// up the others.
+type Client struct {
+	id       uuid.UUID
+	userUUID uuid.UUID
+	doc      *document
+	cfg      Config
+	logger   logging.Logger
+
+	// live and cursor are guarded by doc.mu, a client becomes live once its
+	// connection is upgraded.
+	live   bool
+	cursor Cursor
+
+	queue    chan interface{}
+	done     chan struct{}
+	kickOnce sync.Once
+}
+
+func newClient(doc *document, userUUID uuid.UUID, cfg Config, logger logging.Logger) *Client {
+	return &Client{
+		id:       uuid.New(),
+		userUUID: userUUID,
+		doc:      doc,
+		cfg:      cfg,
+		logger:   logger,
+		queue:    make(chan interface{}, cfg.SendQueue),
+		done:     make(chan struct{}),
+	}
+}
+
+func (c *Client) send(msg interface{}) {
+	select {
+	case <-c.done:
+	case c.queue <- msg:
+	default:
+		c.logger.Warnf("collab client %s of note %s fell behind, dropping it", c.id, c.doc.noteUUID)
+		c.kick()
+	}
+}
+
+func (c *Client) kick() {
+	c.kickOnce.Do(func() { close(c.done) })
+}
+
+// Leave takes the client out of its document. It is called once Serve
+// returned, or instead of it when the upgrade failed.
+func (c *Client) Leave() {
+	c.kick()
+	c.doc.detach(c)
+}
+
+// Serve runs the session over ws until either side ends it. session and
+// revision are those of an earlier connection the client wants to resume.
+func (c *Client) Serve(ws *websocket.Conn, session string, revision int) {
+	ws.MaxPayloadBytes = c.cfg.MaxMessageBytes
+	written := make(chan struct{})
+	go func() {
+		defer close(written)
+		c.write(ws)
+	}()
+	if c.doc.attach(c, session, revision) {
+		c.read(ws)
+	}
+	c.kick()
+	<-written
+}
+
+func (c *Client) read(ws *websocket.Conn) {
+	for {
+		if err := ws.SetReadDeadline(time.Now().Add(c.cfg.ReadTimeout)); err != nil {
+			return
+		}
+		var data []byte
+		if err := websocket.Message.Receive(ws, &data); err != nil {
+			return
+		}
+		var m inbound
+		if err := json.Unmarshal(data, &m); err != nil {
+			c.send(errorMessage{Type: TypeError, Code: ErrCodeInvalidMessage, Message: err.Error()})
+			continue
+		}
+		switch m.Type {
+		case TypeOp:
+			c.doc.apply(c, m)
+		case TypeCursor:
+			c.doc.moveCursor(c, m)
+		case TypePong:
+		default:
+			c.send(errorMessage{Type: TypeError, Code: ErrCodeInvalidMessage,
+				Message: "unknown message type " + m.Type})
+		}
+	}
+}
+
+// write sends queued messages and pings, closing ws once the client is
+// kicked so read returns as well.
+func (c *Client) write(ws *websocket.Conn) {
+	defer ws.Close()
+	heartbeat := time.NewTicker(c.cfg.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		var msg interface{}
+		select {
+		case <-c.done:
+			// Flush what is left, e.g. the error ending the session.
+			for {
+				select {
+				case msg := <-c.queue:
+					if err := writeJSON(ws, msg); err != nil {
+						return
+					}
+				default:
+					return
+				}
+			}
+		case msg = <-c.queue:
+		case <-heartbeat.C:
+			msg = pingMessage{Type: TypePing}
+		}
+		if err := writeJSON(ws, msg); err != nil {
+			c.kick()
+			return
+		}
+	}
+}
+
+func writeJSON(ws *websocket.Conn, msg interface{}) error {
+	if err := ws.SetWriteDeadline(time.Now().Add(writeTimeout)); err != nil {
+		return err
+	}
+	return websocket.JSON.Send(ws, msg)
+}
@@ -0,0 +1,331 @@
+package collab
+
+import (
+	"fmt"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/ot"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// entry is an applied op, the op that made revision r is history[r-base-1].
+type entry struct {
+	op       ot.Op
+	clientID uuid.UUID
+	id       string
+}
+
+// document is the text of a note while a session is open on it. The server
+// holds the only authoritative copy: ops are transformed against what was
+// applied since their revision, applied and then sent to everyone else.
+type document struct {
+	noteUUID uuid.UUID
+	owner    uuid.UUID
+	// session changes whenever the document is loaded, revisions of an
+	// older session can't be caught up with.
+	session string
+	cfg     Config
+
+	mu       sync.Mutex
+	text     []rune
+	revision int
+	base     int
+	history  []entry
+	clients  map[*Client]struct{}
+	// saved is the text of the note at savedRevision. merged is a text the
+	// note got from outside the session that is already part of text.
+	saved         string
+	savedRevision int
+	merged        string
+	idleSince     time.Time
+	closed        bool
+}
+
+func newDocument(n *note.Note, cfg Config) *document {
+	text := ""
+	if n.Text != nil {
+		text = *n.Text
+	}
+	return &document{
+		noteUUID:  *n.NoteUUID,
+		owner:     *n.UserUUID,
+		session:   uuid.NewString(),
+		cfg:       cfg,
+		text:      []rune(text),
+		clients:   make(map[*Client]struct{}),
+		saved:     text,
+		idleSince: time.Now(),
+	}
+}
+
+func (d *document) add(c *Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.clients[c] = struct{}{}
+}
+
+// attach starts the session of c. A client coming back with a session and
+// revision that are still known only gets the ops it missed.
+func (d *document) attach(c *Client, session string, revision int) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed {
+		return false
+	}
+	c.live = true
+	c.cursor = Cursor{ClientID: c.id, UserUUID: c.userUUID}
+	if session == d.session && revision >= d.base && revision <= d.revision {
+		msg := catchUpMessage{Type: TypeCatchUp, Session: d.session, ClientID: c.id, Revision: d.revision,
+			Ops: []opMessage{}}
+		for i, e := range d.history[revision-d.base:] {
+			msg.Ops = append(msg.Ops, opMessage{Revision: revision + i + 1, ClientID: e.clientID, ID: e.id, Op: e.op})
+		}
+		c.send(msg)
+	} else {
+		c.send(d.snapshot(c))
+	}
+	d.broadcastPresence()
+	return true
+}
+
+func (d *document) detach(c *Client) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, ok := d.clients[c]; !ok {
+		return
+	}
+	delete(d.clients, c)
+	if c.live {
+		d.broadcastPresence()
+	}
+	if len(d.clients) == 0 {
+		d.idleSince = time.Now()
+	}
+}
+
+func (d *document) apply(c *Client, m inbound) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed || !c.live {
+		return
+	}
+	if m.ID != "" {
+		for i, e := range d.history {
+			if e.id == m.ID {
+				c.send(ackMessage{Type: TypeAck, Revision: d.base + i + 1, ID: m.ID})
+				return
+			}
+		}
+	}
+	if m.Op == nil {
+		c.send(errorMessage{Type: TypeError, Code: ErrCodeInvalidMessage, Message: "op is missing"})
+		return
+	}
+	if m.Revision < d.base || m.Revision > d.revision {
+		d.resync(c, ErrCodeResync, fmt.Sprintf("revision %d is not available", m.Revision))
+		return
+	}
+
+	op := *m.Op
+	// No revision was ever longer than a note may be.
+	if d.cfg.MaxRunes > 0 && op.BaseLen > d.cfg.MaxRunes {
+		d.resync(c, ErrCodeInvalidOp, ot.ErrBaseLength.Error())
+		return
+	}
+	for _, e := range d.history[m.Revision-d.base:] {
+		var err error
+		if op, _, err = ot.Transform(op, e.op); err != nil {
+			d.resync(c, ErrCodeInvalidOp, err.Error())
+			return
+		}
+	}
+	text, err := op.Apply(d.text)
+	if err != nil {
+		d.resync(c, ErrCodeInvalidOp, err.Error())
+		return
+	}
+	if msg := d.tooLong(text); msg != "" {
+		d.resync(c, ErrCodeTooLong, msg)
+		return
+	}
+	revision := d.commit(op, text, c.id, m.ID)
+	for other := range d.clients {
+		if other != c && other.live {
+			other.send(opMessage{Type: TypeOp, Revision: revision, ClientID: c.id, ID: m.ID, Op: op})
+		}
+	}
+	c.send(ackMessage{Type: TypeAck, Revision: revision, ID: m.ID})
+}
+
+// tooLong keeps the text within what the note service would save.
+func (d *document) tooLong(text []rune) string {
+	if d.cfg.MaxRunes > 0 && len(text) > d.cfg.MaxRunes {
+		return fmt.Sprintf("text is limited to %d characters", d.cfg.MaxRunes)
+	}
+	if d.cfg.MaxBytes > 0 && len(string(text)) > d.cfg.MaxBytes {
+		return fmt.Sprintf("text is limited to %d bytes", d.cfg.MaxBytes)
+	}
+	return ""
+}
+
+// commit makes text the next revision, op having turned the current text
+// into it, and moves every cursor along.
+func (d *document) commit(op ot.Op, text []rune, clientID uuid.UUID, id string) int {
+	d.text = text
+	d.revision++
+	d.history = append(d.history, entry{op: op, clientID: clientID, id: id})
+	if len(d.history) > d.cfg.HistorySize {
+		d.history = d.history[1:]
+		d.base++
+	}
+	for c := range d.clients {
+		c.cursor.Position = op.TransformIndex(c.cursor.Position)
+		c.cursor.Anchor = op.TransformIndex(c.cursor.Anchor)
+	}
+	return d.revision
+}
+
+func (d *document) moveCursor(c *Client, m inbound) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed || !c.live || m.Revision < d.base || m.Revision > d.revision {
+		return
+	}
+	position, anchor := m.Position, m.Position
+	if m.Anchor != nil {
+		anchor = *m.Anchor
+	}
+	for _, e := range d.history[m.Revision-d.base:] {
+		position, anchor = e.op.TransformIndex(position), e.op.TransformIndex(anchor)
+	}
+	c.cursor.Position, c.cursor.Anchor = clamp(position, len(d.text)), clamp(anchor, len(d.text))
+	for other := range d.clients {
+		if other != c && other.live {
+			other.send(cursorMessage{Type: TypeCursor, Revision: d.revision, Cursor: c.cursor})
+		}
+	}
+}
+
+func clamp(i, max int) int {
+	if i < 0 {
+		return 0
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
+
+// resync tells c its op was dropped and starts it over from the current
+// text.
+func (d *document) resync(c *Client, code, message string) {
+	c.send(errorMessage{Type: TypeError, Code: code, Message: message})
+	c.send(d.snapshot(c))
+}
+
+func (d *document) snapshot(c *Client) snapshotMessage {
+	return snapshotMessage{Type: TypeSnapshot, Session: d.session, ClientID: c.id, Revision: d.revision,
+		Text: string(d.text)}
+}
+
+func (d *document) broadcastPresence() {
+	msg := presenceMessage{Type: TypePresence, Revision: d.revision, Clients: []Cursor{}}
+	for c := range d.clients {
+		if c.live {
+			msg.Clients = append(msg.Clients, c.cursor)
+		}
+	}
+	sort.Slice(msg.Clients, func(i, j int) bool {
+		return msg.Clients[i].ClientID.String() < msg.Clients[j].ClientID.String()
+	})
+	for c := range d.clients {
+		if c.live {
+			c.send(msg)
+		}
+	}
+}
+
+// unsaved returns the text to save when it changed since the last save.
+func (d *document) unsaved() (string, int, bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed || d.revision == d.savedRevision {
+		return "", 0, false
+	}
+	return string(d.text), d.revision, true
+}
+
+func (d *document) markSaved(text string, revision int) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.saved, d.savedRevision, d.merged = text, revision, ""
+}
+
+// merge brings a change made to the note outside the session in as an op
+// of its own. It is taken as an edit of the last saved text, so it can only
+// be merged while the history still reaches back to that save.
+func (d *document) merge(current string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.closed || current == d.saved || current == d.merged {
+		return nil
+	}
+	if d.savedRevision < d.base {
+		d.merged = current
+		return fmt.Errorf("history no longer reaches revision %d", d.savedRevision)
+	}
+	op := ot.Diff(d.saved, current)
+	for _, e := range d.history[d.savedRevision-d.base:] {
+		var err error
+		if op, _, err = ot.Transform(op, e.op); err != nil {
+			return err
+		}
+	}
+	text, err := op.Apply(d.text)
+	if err != nil {
+		return err
+	}
+	d.merged = current
+	if op.IsNoop() {
+		return nil
+	}
+	revision := d.commit(op, text, uuid.Nil, "")
+	for c := range d.clients {
+		if c.live {
+			c.send(opMessage{Type: TypeOp, Revision: revision, ClientID: uuid.Nil, Op: op})
+		}
+	}
+	return nil
+}
+
+func (d *document) idle(timeout time.Duration) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.clients) == 0 && time.Since(d.idleSince) >= timeout
+}
+
+// close ends the sessions of all clients, telling them why.
+func (d *document) close(code, message string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.closed = true
+	for c := range d.clients {
+		c.send(errorMessage{Type: TypeError, Code: code, Message: message})
+		c.kick()
+	}
+}
+
+// closeOthers ends the sessions of everyone but the owner.
+func (d *document) closeOthers(code, message string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for c := range d.clients {
+		if c.userUUID != d.owner {
+			c.send(errorMessage{Type: TypeError, Code: code, Message: message})
+			c.kick()
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package collab
+
+import (
+	"net/http"
+	"net/url"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/handlers"
+	"note_service/app/pkg/cors"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/openapi"
+	"note_service/app/pkg/ratelimit"
+	"note_service/app/pkg/user"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"golang.org/x/net/websocket"
+)
+
+const (
+	collabURL = "/notes/:uuid/collab"
+
+	// sessions share the rate limits of notes
+	writeGroup = "notes_write"
+)
+
+type Handler struct {
+	Logger      logging.Logger
+	Hub         *Hub
+	UserClient  user_client.UserClient
+	RateLimiter *ratelimit.Limiter
+	// CORS lists the origins besides the service's own that browsers may
+	// open sessions from, nil allows none.
+	CORS *cors.CORS
+}
+
+func (h *Handler) Register(router openapi.Router) {
+	for _, route := range h.Routes() {
+		router.HandlerFunc(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (h *Handler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{ // GET /notes/{uuid}/collab
+			Method:    http.MethodGet,
+			Path:      collabURL,
+			Handler:   h.protect(writeGroup, h.Collab),
+			Operation: collabOperation,
+		},
+	}
+}
+
+// protect requires an authenticated caller, editing is never anonymous.
+func (h *Handler) protect(group string, fn func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return user.Authentication(h.UserClient,
+		h.RateLimiter.Limit(group, user.Authorization(apperror.Middleware(fn))))
+}
+
+func (h *Handler) Collab(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("COLLAB NOTE")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+
+	session := r.URL.Query().Get("session")
+	revision := 0
+	if s := r.URL.Query().Get("revision"); s != "" {
+		if revision, err = strconv.Atoi(s); err != nil || revision < 0 {
+			return apperror.BadRequestError("invalid revision")
+		}
+	}
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") {
+		return apperror.BadRequestError("websocket upgrade required")
+	}
+	if !h.originAllowed(r) {
+		return apperror.ErrForbidden
+	}
+
+	c, err := h.Hub.Join(r.Context(), noteUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	defer c.Leave()
+
+	server := websocket.Server{
+		Handshake: func(_ *websocket.Config, r *http.Request) error {
+			if !h.originAllowed(r) {
+				return apperror.ErrForbidden
+			}
+			return nil
+		},
+		Handler: func(ws *websocket.Conn) {
+			c.Serve(ws, session, revision)
+		},
+	}
+	server.ServeHTTP(w, r)
+	return nil
+}
+
+// originAllowed lets browsers open sessions from the service's own origin
+// and from the origins CORS allows. Clients that aren't browsers send no
+// Origin.
+func (h *Handler) originAllowed(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if h.CORS != nil && h.CORS.OriginAllowed(origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, r.Host)
+}
@@ -0,0 +1,222 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Config struct {
+	SnapshotInterval time.Duration
+	IdleTimeout      time.Duration
+	HistorySize      int
+	Heartbeat        time.Duration
+	ReadTimeout      time.Duration
+	SendQueue        int
+	// MaxRunes and MaxBytes are the limits the note service puts on text.
+	MaxRunes        int
+	MaxBytes        int
+	MaxMessageBytes int
+}
+
+// Hub keeps the documents that are being edited. Edits live in memory and
+// are saved to the note every SnapshotInterval through the note service, so
+// they are validated and emit note.updated like any other update. A change
+// made to the note outside the session is merged in when saving.
+//
+// Only the owner of a note edits it, from as many clients as they like.
+// The Hub is a note.EventPublisher: deleting a note ends its sessions and
+// making it private ends those of anyone but the owner.
+//
+// Documents are held by a single instance, clients of one note have to be
+// routed to the same instance.
+type Hub struct {
+	notes   note.Service
+	storage note.Storage
+	cfg     Config
+	logger  logging.Logger
+
+	mu   sync.Mutex
+	docs map[uuid.UUID]*document
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewHub(notes note.Service, storage note.Storage, cfg Config, logger logging.Logger) *Hub {
+	return &Hub{
+		notes:   notes,
+		storage: storage,
+		cfg:     cfg,
+		logger:  logger,
+		docs:    make(map[uuid.UUID]*document),
+	}
+}
+
+var _ note.EventPublisher = &Hub{}
+
+// Join checks that userUUID may edit the note and adds a client to its
+// document. The client has to Serve or Leave.
+func (h *Hub) Join(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Client, error) {
+	n, err := h.storage.GetByID(ctx, noteUUID, userUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrForbidden) || errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to find note by uuid. error: %w", err)
+	}
+	// Edits are saved as the owner, nobody else may make them.
+	if *n.UserUUID != userUUID {
+		return nil, apperror.ErrForbidden
+	}
+	// Ops can't be applied to ciphertext.
+	if n.IsEncrypted() {
+		return nil, apperror.BadRequestError("encrypted notes can't be edited together")
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	d, ok := h.docs[noteUUID]
+	if !ok {
+		d = newDocument(n, h.cfg)
+		h.docs[noteUUID] = d
+	}
+	c := newClient(d, userUUID, h.cfg, h.logger)
+	d.add(c)
+	return c, nil
+}
+
+// Publish ends the sessions a deleted or unpublished note must not keep.
+func (h *Hub) Publish(ctx context.Context, events ...note.Event) {
+	for _, event := range events {
+		if event.Note.NoteUUID == nil {
+			continue
+		}
+		h.mu.Lock()
+		d, ok := h.docs[*event.Note.NoteUUID]
+		h.mu.Unlock()
+		if !ok {
+			continue
+		}
+		switch event.Type {
+		case note.EventDeleted:
+			h.unload(d, ErrCodeDeleted, "note was deleted")
+		case note.EventUnpublished:
+			d.closeOthers(ErrCodeForbidden, "note was made private")
+		}
+	}
+}
+
+func (h *Hub) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	h.cancel = cancel
+	h.wg.Add(1)
+	go func() {
+		defer h.wg.Done()
+		ticker := time.NewTicker(h.cfg.SnapshotInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				h.flush(ctx)
+			}
+		}
+	}()
+}
+
+// Close saves every document and ends all sessions.
+func (h *Hub) Close() error {
+	if h.cancel != nil {
+		h.cancel()
+	}
+	h.wg.Wait()
+	for _, d := range h.loaded() {
+		h.save(context.Background(), d)
+		d.close(ErrCodeShutdown, "server is shutting down")
+	}
+	return nil
+}
+
+func (h *Hub) loaded() []*document {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	docs := make([]*document, 0, len(h.docs))
+	for _, d := range h.docs {
+		docs = append(docs, d)
+	}
+	return docs
+}
+
+// flush saves every document and unloads those nobody edited for
+// IdleTimeout.
+func (h *Hub) flush(ctx context.Context) {
+	for _, d := range h.loaded() {
+		if !h.save(ctx, d) {
+			continue
+		}
+		h.mu.Lock()
+		if d.idle(h.cfg.IdleTimeout) {
+			delete(h.docs, d.noteUUID)
+			d.close(ErrCodeShutdown, "document was unloaded")
+		}
+		h.mu.Unlock()
+	}
+}
+
+// save writes the text of d to the note, reporting whether d is still
+// loaded.
+func (h *Hub) save(ctx context.Context, d *document) bool {
+	current, err := h.storage.GetByID(ctx, d.noteUUID, d.owner)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			h.unload(d, ErrCodeDeleted, "note was deleted")
+			return false
+		}
+		h.logger.Errorf("failed to load note %s for collab: %v", d.noteUUID, err)
+		return true
+	}
+	if current.Text != nil {
+		if err := d.merge(*current.Text); err != nil {
+			h.logger.Warnf("failed to merge outside change of note %s, it will be overwritten: %v",
+				d.noteUUID, err)
+		}
+	}
+
+	text, revision, ok := d.unsaved()
+	// Notes can't have blank text, a cleared document is saved once
+	// something is typed again.
+	if !ok || strings.TrimSpace(text) == "" {
+		return true
+	}
+	err = h.notes.Update(ctx, note.UpdateNoteDTO{NoteUUID: &d.noteUUID, Text: &text}, d.owner)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			h.unload(d, ErrCodeDeleted, "note was deleted")
+			return false
+		}
+		h.logger.Warnf("failed to save collab snapshot of note %s at revision %d: %v", d.noteUUID,
+			revision, err)
+		return true
+	}
+	d.markSaved(text, revision)
+	return true
+}
+
+func (h *Hub) unload(d *document, code, message string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.docs[d.noteUUID] == d {
+		delete(h.docs, d.noteUUID)
+	}
+	d.close(code, message)
+}
@@ -0,0 +1,111 @@
+package collab
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/cors"
+	"note_service/app/pkg/logging"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStorage struct {
+	note.Storage
+	n note.Note
+}
+
+func (s *fakeStorage) GetByID(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*note.Note, error) {
+	if noteUUID != *s.n.NoteUUID {
+		return nil, apperror.ErrNotFound
+	}
+	n := s.n
+	return &n, nil
+}
+
+func newHub(t *testing.T, public bool) (*Hub, note.Note) {
+	t.Helper()
+	noteUUID, owner := uuid.New(), uuid.New()
+	text := "shared"
+	n := note.Note{NoteUUID: &noteUUID, UserUUID: &owner, Text: &text, Public: &public}
+	logger := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+	return NewHub(nil, &fakeStorage{n: n}, Config{SendQueue: 8}, logger), n
+}
+
+func kicked(c *Client) bool {
+	select {
+	case <-c.done:
+		return true
+	default:
+		return false
+	}
+}
+
+func TestJoinRequiresOwner(t *testing.T) {
+	h, n := newHub(t, true)
+	if _, err := h.Join(context.Background(), *n.NoteUUID, uuid.New()); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Join() by other error = %v, want %v", err, apperror.ErrForbidden)
+	}
+	c, err := h.Join(context.Background(), *n.NoteUUID, *n.UserUUID)
+	if err != nil {
+		t.Fatalf("Join() by owner error = %v", err)
+	}
+	c.Leave()
+}
+
+func TestPublishEndsSessions(t *testing.T) {
+	h, n := newHub(t, true)
+	owner, err := h.Join(context.Background(), *n.NoteUUID, *n.UserUUID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Someone who joined some other way than Join.
+	other := newClient(owner.doc, uuid.New(), h.cfg, h.logger)
+	owner.doc.add(other)
+
+	h.Publish(context.Background(), note.NewEvent(note.EventUnpublished, n))
+	if !kicked(other) || kicked(owner) {
+		t.Errorf("after unpublishing kicked other, owner = %v, %v, want true, false", kicked(other), kicked(owner))
+	}
+
+	h.Publish(context.Background(), note.NewEvent(note.EventDeleted, n))
+	if !kicked(owner) {
+		t.Error("owner still connected after the note was deleted")
+	}
+	if len(h.loaded()) != 0 {
+		t.Error("document of the deleted note is still loaded")
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	c, err := cors.New(cors.Options{AllowedOrigins: []string{"https://app.example.com"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		origin string
+		cors   *cors.CORS
+		want   bool
+	}{
+		{"", nil, true},
+		{"http://notes.test", nil, true},
+		{"https://app.example.com", c, true},
+		{"https://app.example.com", nil, false},
+		{"https://evil.test", c, false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest("GET", "http://notes.test/notes/x/collab", nil)
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		h := Handler{CORS: tt.cors}
+		if got := h.originAllowed(r); got != tt.want {
+			t.Errorf("originAllowed(%q, cors %v) = %v, want %v", tt.origin, tt.cors != nil, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package collab
+
+import (
+	"note_service/app/pkg/ot"
+
+	"github.com/google/uuid"
+)
+
+// Messages clients send.
+const (
+	// TypeOp carries an edit made on top of Revision. Clients transform
+	// their pending op with it as the first argument of ot.Transform.
+	TypeOp     = "op"
+	TypeCursor = "cursor"
+	TypePong   = "pong"
+)
+
+// Messages the server sends.
+const (
+	// TypeSnapshot starts a session, the client drops what it had.
+	TypeSnapshot = "snapshot"
+	// TypeCatchUp resumes a session with the ops missed since the
+	// revision the client reconnected with.
+	TypeCatchUp  = "catchup"
+	TypeAck      = "ack"
+	TypePresence = "presence"
+	TypeError    = "error"
+	TypePing     = "ping"
+)
+
+// Error codes, every error but invalid_message is followed by a snapshot or
+// the end of the session.
+const (
+	ErrCodeInvalidMessage = "invalid_message"
+	ErrCodeInvalidOp      = "invalid_op"
+	ErrCodeTooLong        = "too_long"
+	ErrCodeResync         = "resync"
+	ErrCodeDeleted        = "deleted"
+	ErrCodeForbidden      = "forbidden"
+	ErrCodeShutdown       = "shutdown"
+)
+
+type inbound struct {
+	Type     string `json:"type"`
+	Revision int    `json:"revision"`
+	// ID names an op so a client that lost the ack can send it again.
+	ID       string `json:"id,omitempty"`
+	Op       *ot.Op `json:"op,omitempty"`
+	Position int    `json:"position"`
+	Anchor   *int   `json:"anchor,omitempty"`
+}
+
+type snapshotMessage struct {
+	Type     string    `json:"type"`
+	Session  string    `json:"session"`
+	ClientID uuid.UUID `json:"client_id"`
+	Revision int       `json:"revision"`
+	Text     string    `json:"text"`
+}
+
+type catchUpMessage struct {
+	Type     string      `json:"type"`
+	Session  string      `json:"session"`
+	ClientID uuid.UUID   `json:"client_id"`
+	Revision int         `json:"revision"`
+	Ops      []opMessage `json:"ops"`
+}
+
+// opMessage is an op other clients made, ClientID is uuid.Nil for changes
+// made to the note outside the session.
+type opMessage struct {
+	Type     string    `json:"type,omitempty"`
+	Revision int       `json:"revision"`
+	ClientID uuid.UUID `json:"client_id"`
+	ID       string    `json:"id,omitempty"`
+	Op       ot.Op     `json:"op"`
+}
+
+type ackMessage struct {
+	Type     string `json:"type"`
+	Revision int    `json:"revision"`
+	ID       string `json:"id,omitempty"`
+}
+
+// Cursor is the caret of a client, Anchor the other end of its selection.
+type Cursor struct {
+	ClientID uuid.UUID `json:"client_id"`
+	UserUUID uuid.UUID `json:"user_id"`
+	Position int       `json:"position"`
+	Anchor   int       `json:"anchor"`
+}
+
+type cursorMessage struct {
+	Type     string `json:"type"`
+	Revision int    `json:"revision"`
+	Cursor
+}
+
+type presenceMessage struct {
+	Type     string   `json:"type"`
+	Revision int      `json:"revision"`
+	Clients  []Cursor `json:"clients"`
+}
+
+type errorMessage struct {
+	Type    string `json:"type"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+type pingMessage struct {
+	Type string `json:"type"`
+}
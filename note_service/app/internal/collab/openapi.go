@@ -0,0 +1,44 @@
+package collab
+
+import (
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/openapi"
+)
+
+func (h *Handler) Describe(b *openapi.Builder) {
+	b.Schema(apperror.Problem{})
+	b.AddRoutes(h.Routes())
+}
+
+var (
+	collabOperation = openapi.Operation{
+		OperationID: "collabNote",
+		Summary:     "Edit the text of an own note from several clients at once over a WebSocket",
+		Description: "Messages are JSON objects with a type. The server starts with a snapshot (session, " +
+			"client_id, revision, text), or with a catchup carrying the ops missed when session and " +
+			"revision of an earlier connection were given. Clients send op messages with the revision " +
+			"they were made on, an id and the op in ot.js form ([retain, \"insert\", -delete], counting " +
+			"code points), and get an ack with the new revision while others get the op. Ops of others " +
+			"are transformed against pending ones, passing the pending op first as in ot.js. Cursor " +
+			"messages (position, anchor) are relayed, presence lists everyone connected. An error with " +
+			"code invalid_op, too_long or resync is followed by a new snapshot, deleted, forbidden and " +
+			"shutdown end the session. Answer ping with pong. The text is saved to the note every few seconds " +
+			"and changes made to the note meanwhile are merged in as ops with the nil client_id.",
+		Tags:     []string{"notes"},
+		Security: openapi.BearerAuth(),
+		Parameters: []openapi.Parameter{
+			{Name: "session", In: "query", Description: "Session of the connection to resume",
+				Schema: &openapi.Schema{Type: "string"}},
+			{Name: "revision", In: "query", Description: "Last revision the resumed connection knew",
+				Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: map[string]openapi.Response{
+			"101": {Description: "Switched to the WebSocket protocol"},
+			"400": openapi.Problem("Malformed note id or revision, not a WebSocket upgrade, or an encrypted note"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The note belongs to someone else, or the Origin is not allowed"),
+			"404": openapi.Problem("Note not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+)
@@ -6,6 +6,7 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/ilyakaznacheev/cleanenv"
 )
@@ -16,7 +17,32 @@ type Config struct {
 		Type   string `yaml:"type" env-default:"port"`
 		BindIP string `yaml:"bind_ip" env-default:"localhost"`
 		Port   string `yaml:"port" env-default:"8080"`
+		TLS    struct {
+			Enabled        bool          `yaml:"enabled" env-default:"false"`
+			CertFile       string        `yaml:"cert_file"`
+			KeyFile        string        `yaml:"key_file"`
+			MinVersion     string        `yaml:"min_version" env-default:"1.2"`
+			CipherSuites   []string      `yaml:"cipher_suites"`
+			ClientCAFile   string        `yaml:"client_ca_file"`
+			ClientAuth     string        `yaml:"client_auth"`
+			ReloadInterval time.Duration `yaml:"reload_interval" env-default:"30s"`
+			// ClientUsers maps URIs and common names of verified client
+			// certificates to the user ids those callers act as.
+			ClientUsers map[string]string `yaml:"client_users"`
+		} `yaml:"tls"`
 	}
+	GRPC struct {
+		Enabled   bool   `yaml:"enabled" env-default:"false"`
+		Multiplex bool   `yaml:"multiplex" env-default:"false"`
+		BindIP    string `yaml:"bind_ip" env-default:"localhost"`
+		Port      string `yaml:"port" env-default:"9090"`
+	} `yaml:"grpc"`
+	GraphQL struct {
+		Enabled       bool `yaml:"enabled" env-default:"false"`
+		MaxDepth      int  `yaml:"max_depth" env-default:"6"`
+		MaxComplexity int  `yaml:"max_complexity" env-default:"500"`
+		DefaultPage   int  `yaml:"default_page" env-default:"20"`
+	} `yaml:"graphql"`
 	UserService struct {
 		URL string `yaml:"url" env-required:"true"`
 	} `yaml:"userservice" env-required:"true"`
@@ -27,6 +53,155 @@ type Config struct {
 		Password string `yaml:"password"`
 		Database string `yaml:"database" env-required:"true"`
 	} `yaml:"postgresql" env-required:"true"`
+	RateLimit struct {
+		Enabled bool `yaml:"enabled" env-default:"false"`
+		// TrustForwardedFor keys anonymous callers on the last X-Forwarded-For
+		// entry, only for a single proxy in front that appends to it.
+		TrustForwardedFor bool                      `yaml:"trust_forwarded_for" env-default:"false"`
+		CleanupInterval   time.Duration             `yaml:"cleanup_interval" env-default:"1m"`
+		Groups            map[string]RateLimitGroup `yaml:"groups"`
+	} `yaml:"ratelimit"`
+	CORS struct {
+		Enabled          bool          `yaml:"enabled" env-default:"false"`
+		AllowedOrigins   []string      `yaml:"allowed_origins"`
+		AllowedMethods   []string      `yaml:"allowed_methods"`
+		AllowedHeaders   []string      `yaml:"allowed_headers"`
+		ExposedHeaders   []string      `yaml:"exposed_headers"`
+		AllowCredentials bool          `yaml:"allow_credentials" env-default:"false"`
+		MaxAge           time.Duration `yaml:"max_age" env-default:"10m"`
+	} `yaml:"cors"`
+	Validation struct {
+		MaxBodyBytes int64 `yaml:"max_body_bytes" env-default:"65536"`
+		Text         struct {
+			MinRunes int `yaml:"min_runes" env-default:"1"`
+			MaxRunes int `yaml:"max_runes" env-default:"128"`
+			MaxBytes int `yaml:"max_bytes" env-default:"512"`
+		} `yaml:"text"`
+		Title struct {
+			MinRunes int `yaml:"min_runes" env-default:"1"`
+			MaxRunes int `yaml:"max_runes" env-default:"200"`
+			MaxBytes int `yaml:"max_bytes" env-default:"800"`
+		} `yaml:"title"`
+		// Encrypted limits what encrypted notes store in place of text.
+		Encrypted struct {
+			MaxCiphertextBytes int `yaml:"max_ciphertext_bytes" env-default:"4096"`
+			MaxRecipients      int `yaml:"max_recipients" env-default:"32"`
+			MaxWrappedKeyBytes int `yaml:"max_wrapped_key_bytes" env-default:"1024"`
+		} `yaml:"encrypted"`
+	} `yaml:"validation"`
+	Render struct {
+		CacheSize int `yaml:"cache_size" env-default:"1024"`
+		// ImageProxy, when set, is the URL rendered images are loaded through.
+		// Without it remote images are rendered as links.
+		ImageProxy string `yaml:"image_proxy"`
+	} `yaml:"render"`
+	Attachments struct {
+		Enabled         bool          `yaml:"enabled" env-default:"false"`
+		Root            string        `yaml:"root" env-default:"data/blobs"`
+		MaxFileBytes    int64         `yaml:"max_file_bytes" env-default:"10485760"`
+		QuotaBytes      int64         `yaml:"quota_bytes" env-default:"104857600"`
+		AllowedTypes    []string      `yaml:"allowed_types"`
+		TransferTimeout time.Duration `yaml:"transfer_timeout" env-default:"10m"`
+		// SweepInterval is how often attachments of deleted notes are looked
+		// for, should their delete event have been lost.
+		SweepInterval time.Duration `yaml:"sweep_interval" env-default:"1h"`
+	} `yaml:"attachments"`
+	Stream struct {
+		BufferSize int           `yaml:"buffer_size" env-default:"1000"`
+		QueueSize  int           `yaml:"queue_size" env-default:"64"`
+		Heartbeat  time.Duration `yaml:"heartbeat" env-default:"15s"`
+	} `yaml:"stream"`
+	Webhooks struct {
+		Enabled bool `yaml:"enabled" env-default:"false"`
+		// Admins are user ids allowed to register endpoints for every note.
+		Admins []string `yaml:"admins"`
+		// AllowPrivate lets endpoints point at loopback and private
+		// addresses, for local development.
+		AllowPrivate bool          `yaml:"allow_private" env-default:"false"`
+		PollInterval time.Duration `yaml:"poll_interval" env-default:"2s"`
+		BatchSize    int           `yaml:"batch_size" env-default:"50"`
+		// Lease is renewed before each delivery and must exceed Timeout.
+		Lease                time.Duration `yaml:"lease" env-default:"1m"`
+		Timeout              time.Duration `yaml:"timeout" env-default:"10s"`
+		MaxAttempts          int           `yaml:"max_attempts" env-default:"8"`
+		BaseBackoff          time.Duration `yaml:"base_backoff" env-default:"10s"`
+		MaxBackoff           time.Duration `yaml:"max_backoff" env-default:"1h"`
+		DisableAfterFailures int           `yaml:"disable_after_failures" env-default:"20"`
+	} `yaml:"webhooks"`
+	Outbox struct {
+		// Enabled starts the relay, events are written to the outbox either way.
+		Enabled      bool          `yaml:"enabled" env-default:"false"`
+		Sink         string        `yaml:"sink" env-default:"stdout"`
+		FilePath     string        `yaml:"file_path" env-default:"logs/outbox.jsonl"`
+		URL          string        `yaml:"url"`
+		Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+		PollInterval time.Duration `yaml:"poll_interval" env-default:"1s"`
+		BatchSize    int           `yaml:"batch_size" env-default:"100"`
+		Lease        time.Duration `yaml:"lease" env-default:"1m"`
+		Retention    time.Duration `yaml:"retention" env-default:"168h"`
+	} `yaml:"outbox"`
+	Reminders struct {
+		Enabled bool `yaml:"enabled" env-default:"false"`
+		// Notifier is log, webhook or stub.
+		Notifier string `yaml:"notifier" env-default:"log"`
+		URL      string `yaml:"url"`
+		// Secret signs webhook notifications like webhook deliveries.
+		Secret       string        `yaml:"secret"`
+		Timeout      time.Duration `yaml:"timeout" env-default:"10s"`
+		PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"`
+		BatchSize    int           `yaml:"batch_size" env-default:"50"`
+		// Lease is renewed before each notification and must exceed Timeout.
+		Lease       time.Duration `yaml:"lease" env-default:"1m"`
+		MaxAttempts int           `yaml:"max_attempts" env-default:"5"`
+		RetryDelay  time.Duration `yaml:"retry_delay" env-default:"30s"`
+	} `yaml:"reminders"`
+	Schedule struct {
+		// PollInterval bounds how late publish_at and unpublish_at apply.
+		PollInterval time.Duration `yaml:"poll_interval" env-default:"5s"`
+		BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	} `yaml:"schedule"`
+	Reaper struct {
+		// PollInterval bounds how long expired notes stay in the database,
+		// reads leave them out right away.
+		PollInterval time.Duration `yaml:"poll_interval" env-default:"1m"`
+		BatchSize    int           `yaml:"batch_size" env-default:"100"`
+	} `yaml:"reaper"`
+	Collab struct {
+		Enabled bool `yaml:"enabled" env-default:"false"`
+		// SnapshotInterval bounds how much editing a crash loses.
+		SnapshotInterval time.Duration `yaml:"snapshot_interval" env-default:"5s"`
+		IdleTimeout      time.Duration `yaml:"idle_timeout" env-default:"1m"`
+		// HistorySize is how many ops a reconnecting client can catch up on.
+		HistorySize int           `yaml:"history_size" env-default:"1000"`
+		Heartbeat   time.Duration `yaml:"heartbeat" env-default:"30s"`
+		ReadTimeout time.Duration `yaml:"read_timeout" env-default:"75s"`
+		SendQueue   int           `yaml:"send_queue" env-default:"256"`
+	} `yaml:"collab"`
+	Encryption struct {
+		Enabled bool `yaml:"enabled" env-default:"false"`
+		// MasterKeys are base64 encoded 32 byte keys, the first one wraps
+		// new data keys and the others only unwrap older ones until the
+		// rotation rewrapped them. MasterKeyFile holds them one per line
+		// instead.
+		MasterKeys    []string `yaml:"master_keys"`
+		MasterKeyFile string   `yaml:"master_key_file"`
+		// DataKeyMaxAge is how long a data key encrypts new text, 0 keeps
+		// data keys forever.
+		DataKeyMaxAge time.Duration `yaml:"data_key_max_age" env-default:"2160h"`
+		PollInterval  time.Duration `yaml:"poll_interval" env-default:"10m"`
+		BatchSize     int           `yaml:"batch_size" env-default:"100"`
+	} `yaml:"encryption"`
+}
+
+type RateLimitGroup struct {
+	Anonymous     RateLimitRule `yaml:"anonymous"`
+	Authenticated RateLimitRule `yaml:"authenticated"`
+}
+
+type RateLimitRule struct {
+	Requests int           `yaml:"requests"`
+	Period   time.Duration `yaml:"period"`
+	Burst    int           `yaml:"burst"`
 }
 
 var instance *Config
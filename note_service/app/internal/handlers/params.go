@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"note_service/app/internal/apperror"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+)
+
+// PathUUID parses the :name segment of the matched route.
+func PathUUID(r *http.Request, name string) (uuid.UUID, error) {
+	params := httprouter.ParamsFromContext(r.Context())
+	id, err := uuid.Parse(params.ByName(name))
+	if err != nil {
+		return uuid.Nil, apperror.BadRequestError(fmt.Sprintf("invalid %s type", name))
+	}
+	return id, nil
+}
@@ -5,6 +5,7 @@ import (
 	"note_service/app/internal/note"
 	"note_service/app/pkg/logging"
 	"note_service/app/pkg/postgres"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -23,13 +24,13 @@ func NewStorage(client *postgres.Client, logger logging.Logger) note.Storage {
 	}
 }
 
-func (s *db) GetNotes(ctx context.Context, userUUID uuid.UUID) (*note.Notes, error) {
-	note, err := s.client.GetNotes(ctx, userUUID)
+func (s *db) GetNotes(ctx context.Context, userUUID uuid.UUID, opts note.ListOptions) (*note.Notes, error) {
+	note, err := s.client.GetNotes(ctx, userUUID, opts)
 	return note, err
 }
 
-func (s *db) Create(ctx context.Context, note note.Note) error {
-	err := s.client.CreateNote(ctx, &note)
+func (s *db) Create(ctx context.Context, note *note.Note) error {
+	err := s.client.CreateNote(ctx, note)
 	return err
 }
 
@@ -38,8 +39,8 @@ func (s *db) GetByID(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID
 	return note, err
 }
 
-func (s *db) Update(ctx context.Context, note note.Note, userUUID uuid.UUID) error {
-	err := s.client.UpdateNote(ctx, &note, userUUID)
+func (s *db) Update(ctx context.Context, note *note.Note, userUUID uuid.UUID) error {
+	err := s.client.UpdateNote(ctx, note, userUUID)
 	return err
 }
 
@@ -47,3 +48,35 @@ func (s *db) Delete(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID)
 	err := s.client.DeleteNote(ctx, noteUUID, userUUID)
 	return err
 }
+
+func (s *db) GetLinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*note.Links, error) {
+	return s.client.GetNoteLinks(ctx, noteUUID, userUUID)
+}
+
+func (s *db) GetBacklinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*note.Notes, error) {
+	return s.client.GetNoteBacklinks(ctx, noteUUID, userUUID)
+}
+
+func (s *db) GetGraph(ctx context.Context, userUUID uuid.UUID) (*note.Graph, error) {
+	return s.client.GetNoteGraph(ctx, userUUID)
+}
+
+func (s *db) Schedule(ctx context.Context, note *note.Note, userUUID uuid.UUID) error {
+	return s.client.ScheduleNote(ctx, note, userUUID)
+}
+
+func (s *db) ApplySchedules(ctx context.Context, now time.Time, limit int) ([]note.Transition, error) {
+	return s.client.ApplyNoteSchedules(ctx, now, limit)
+}
+
+func (s *db) Burn(ctx context.Context, noteUUID uuid.UUID, readerUUID uuid.UUID) (*note.Note, error) {
+	return s.client.BurnNote(ctx, noteUUID, readerUUID)
+}
+
+func (s *db) DeleteExpired(ctx context.Context, now time.Time, limit int) ([]note.Note, error) {
+	return s.client.DeleteExpiredNotes(ctx, now, limit)
+}
+
+func (s *db) RotateKeys(ctx context.Context, now time.Time, maxAge time.Duration, limit int) (int, error) {
+	return s.client.RotateNoteKeys(ctx, now, maxAge, limit)
+}
@@ -0,0 +1,20 @@
+package db_test
+
+import (
+	"testing"
+
+	"note_service/app/internal/note/db"
+	"note_service/app/internal/note/storagetest"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/postgres/postgrestest"
+
+	"github.com/google/uuid"
+)
+
+func TestStorageContract(t *testing.T) {
+	client := postgrestest.NewClient(t)
+	storagetest.Run(t, storagetest.Harness{
+		Storage: db.NewStorage(client, logging.Logger{}),
+		NewUser: func(t *testing.T) uuid.UUID { return postgrestest.NewUser(t, client) },
+	})
+}
@@ -0,0 +1,53 @@
+package note
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	EventCreated     = "note.created"
+	EventUpdated     = "note.updated"
+	EventDeleted     = "note.deleted"
+	EventPublished   = "note.published"
+	EventUnpublished = "note.unpublished"
+	// EventScheduled is emitted when the publish_at or unpublish_at of a
+	// note is set or cleared, the change itself emits the events above.
+	EventScheduled = "note.scheduled"
+)
+
+var EventTypes = []string{EventCreated, EventUpdated, EventDeleted, EventPublished, EventUnpublished,
+	EventScheduled}
+
+// Event describes a change of a note. It is emitted by Service after the
+// change was stored, so every transport triggers the same events.
+type Event struct {
+	ID         uuid.UUID `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt time.Time `json:"occurred_at"`
+	Note       Note      `json:"note"`
+}
+
+func NewEvent(eventType string, n Note) Event {
+	return Event{
+		ID:         uuid.New(),
+		Type:       eventType,
+		OccurredAt: time.Now().UTC(),
+		Note:       n,
+	}
+}
+
+type EventPublisher interface {
+	Publish(ctx context.Context, events ...Event)
+}
+
+// Publishers fans events out to every publisher in order.
+type Publishers []EventPublisher
+
+func (p Publishers) Publish(ctx context.Context, events ...Event) {
+	for _, publisher := range p {
+		publisher.Publish(ctx, events...)
+	}
+}
@@ -0,0 +1,43 @@
+package graph
+
+import (
+	"errors"
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/logging"
+)
+
+var _ interface {
+	error
+	Extensions() map[string]interface{}
+} = &graphError{}
+
+// graphError carries the apperror code and field details into the
+// "extensions" member of a GraphQL error.
+type graphError struct {
+	message    string
+	extensions map[string]interface{}
+}
+
+func (e *graphError) Error() string {
+	return e.message
+}
+
+func (e *graphError) Extensions() map[string]interface{} {
+	return e.extensions
+}
+
+func toGraphError(err error) error {
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) || appErr.Status >= 500 {
+		logging.GetLogger().Errorf("graphql resolver failed: %v", err)
+		return &graphError{
+			message:    "internal server error",
+			extensions: map[string]interface{}{"code": apperror.CodeInternal},
+		}
+	}
+	ext := map[string]interface{}{"code": appErr.Code, "status": appErr.Status}
+	if len(appErr.Details) > 0 {
+		ext["errors"] = appErr.Details
+	}
+	return &graphError{message: appErr.Message, extensions: ext}
+}
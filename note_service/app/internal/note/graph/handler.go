@@ -0,0 +1,134 @@
+package graph
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/openapi"
+	"note_service/app/pkg/ratelimit"
+	"note_service/app/pkg/user"
+	"note_service/app/pkg/validator"
+	"strings"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+)
+
+const (
+	graphqlURL = "/graphql"
+
+	readGroup = "notes_read"
+)
+
+type Handler struct {
+	Logger       logging.Logger
+	UserClient   user_client.UserClient
+	RateLimiter  *ratelimit.Limiter
+	Limits       Limits
+	MaxBodyBytes int64
+	schema       graphql.Schema
+}
+
+func NewHandler(noteService note.Service, userClient user_client.UserClient, rateLimiter *ratelimit.Limiter,
+	limits Limits, maxBodyBytes int64, logger logging.Logger) (*Handler, error) {
+	schema, err := newSchema(noteService)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		Logger:       logger,
+		UserClient:   userClient,
+		RateLimiter:  rateLimiter,
+		Limits:       limits,
+		MaxBodyBytes: maxBodyBytes,
+		schema:       schema,
+	}, nil
+}
+
+func (h *Handler) Register(router openapi.Router) {
+	for _, route := range h.Routes() {
+		router.HandlerFunc(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (h *Handler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{ // GET /graphql
+			Method:    http.MethodGet,
+			Path:      graphqlURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.Query))),
+			Operation: queryOperation("graphqlGet", false),
+		},
+		{ // POST /graphql
+			Method:    http.MethodPost,
+			Path:      graphqlURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.Query))),
+			Operation: queryOperation("graphqlPost", true),
+		},
+	}
+}
+
+type QueryRequest struct {
+	Query         string                 `json:"query" validate:"required"`
+	OperationName string                 `json:"operationName,omitempty"`
+	Variables     map[string]interface{} `json:"variables,omitempty"`
+}
+
+func (h *Handler) Query(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GRAPHQL QUERY")
+
+	var req QueryRequest
+	if r.Method == http.MethodGet {
+		q := r.URL.Query()
+		req.Query = q.Get("query")
+		req.OperationName = q.Get("operationName")
+		if vars := q.Get("variables"); vars != "" {
+			if err := json.Unmarshal([]byte(vars), &req.Variables); err != nil {
+				return apperror.BadRequestError("variables must be a JSON object")
+			}
+		}
+	} else {
+		defer r.Body.Close()
+		if err := validator.DecodeJSON(w, r, &req, h.MaxBodyBytes); err != nil {
+			return err
+		}
+	}
+	if req.Query == "" {
+		return apperror.ValidationError(apperror.FieldError{Field: "query", Code: "required", Message: "is required"})
+	}
+
+	var result *graphql.Result
+	if err := checkLimits(req.Query, req.OperationName, req.Variables, h.Limits); err != nil {
+		result = &graphql.Result{Errors: []gqlerrors.FormattedError{{
+			Message:    err.Error(),
+			Extensions: map[string]interface{}{"code": "query_too_complex"},
+		}}}
+	} else {
+		token := user_client.Token{TokenType: "Bearer"}
+		if auth, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer "); ok {
+			token.AccessToken = auth
+		}
+		ctx := context.WithValue(r.Context(), loaderKey{}, newUserLoader(r.Context(), h.UserClient, token))
+		result = graphql.Do(graphql.Params{
+			Schema:         h.schema,
+			RequestString:  req.Query,
+			VariableValues: req.Variables,
+			OperationName:  req.OperationName,
+			Context:        ctx,
+		})
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+
+	return nil
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"fmt"
+
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/parser"
+)
+
+type Limits struct {
+	MaxDepth      int
+	MaxComplexity int
+	DefaultPage   int
+}
+
+// checkLimits parses the query and rejects it before execution when its
+// selection depth or estimated complexity is too high. Every field costs 1,
+// list fields multiply the cost of their selection by the requested page size.
+func checkLimits(query, operationName string, variables map[string]interface{}, limits Limits) error {
+	doc, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		// let graphql.Do report syntax errors in the usual format
+		return nil
+	}
+
+	fragments := make(map[string]*ast.FragmentDefinition)
+	var operations []*ast.OperationDefinition
+	for _, def := range doc.Definitions {
+		switch d := def.(type) {
+		case *ast.FragmentDefinition:
+			fragments[d.Name.Value] = d
+		case *ast.OperationDefinition:
+			if operationName == "" || (d.Name != nil && d.Name.Value == operationName) {
+				operations = append(operations, d)
+			}
+		}
+	}
+
+	w := walker{fragments: fragments, variables: variables, limits: limits}
+	for _, op := range operations {
+		depth, cost := w.selectionSet(op.SelectionSet, 1, map[string]bool{})
+		if limits.MaxDepth > 0 && depth > limits.MaxDepth {
+			return fmt.Errorf("query depth %d exceeds the limit of %d", depth, limits.MaxDepth)
+		}
+		if limits.MaxComplexity > 0 && cost > limits.MaxComplexity {
+			return fmt.Errorf("query complexity %d exceeds the limit of %d", cost, limits.MaxComplexity)
+		}
+	}
+	return nil
+}
+
+// listFields are the fields of the schema that return a page of results.
+var listFields = map[string]bool{"notes": true}
+
+type walker struct {
+	fragments map[string]*ast.FragmentDefinition
+	variables map[string]interface{}
+	limits    Limits
+}
+
+func (w walker) selectionSet(set *ast.SelectionSet, level int, visiting map[string]bool) (depth, cost int) {
+	if set == nil {
+		return level - 1, 0
+	}
+	depth = level
+	for _, sel := range set.Selections {
+		var d, c int
+		switch s := sel.(type) {
+		case *ast.Field:
+			d, c = w.selectionSet(s.SelectionSet, level+1, visiting)
+			c = w.multiplier(s)*c + 1
+		case *ast.InlineFragment:
+			d, c = w.selectionSet(s.SelectionSet, level, visiting)
+		case *ast.FragmentSpread:
+			name := s.Name.Value
+			frag, ok := w.fragments[name]
+			if !ok || visiting[name] {
+				continue
+			}
+			visiting[name] = true
+			d, c = w.selectionSet(frag.SelectionSet, level, visiting)
+			delete(visiting, name)
+		}
+		if d > depth {
+			depth = d
+		}
+		cost += c
+	}
+	return depth, cost
+}
+
+// multiplier returns the page size a list field asks for.
+func (w walker) multiplier(f *ast.Field) int {
+	if f.SelectionSet == nil {
+		return 1
+	}
+	for _, arg := range f.Arguments {
+		if arg.Name.Value != "first" {
+			continue
+		}
+		switch v := arg.Value.(type) {
+		case *ast.IntValue:
+			var n int
+			fmt.Sscan(v.Value, &n)
+			return max(n, 1)
+		case *ast.Variable:
+			if n, ok := w.variables[v.Name.Value].(float64); ok {
+				return max(int(n), 1)
+			}
+		}
+	}
+	if listFields[f.Name.Value] {
+		return max(w.limits.DefaultPage, 1)
+	}
+	return 1
+}
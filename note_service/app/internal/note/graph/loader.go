@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"context"
+	"note_service/app/internal/client/user_client"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// maxUsersPerRequest is the most ids the user service takes in one /users
+// request.
+const maxUsersPerRequest = 100
+
+// userLoader batches owner lookups of a single GraphQL request. Load only
+// records the id and returns a thunk; the first thunk that runs fetches every
+// id recorded so far in one user_client call.
+type userLoader struct {
+	ctx    context.Context
+	client user_client.UserClient
+	token  user_client.Token
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]struct{}
+	cache   map[uuid.UUID]*user_client.User
+	err     error
+}
+
+func newUserLoader(ctx context.Context, client user_client.UserClient, token user_client.Token) *userLoader {
+	return &userLoader{
+		ctx:     ctx,
+		client:  client,
+		token:   token,
+		pending: make(map[uuid.UUID]struct{}),
+		cache:   make(map[uuid.UUID]*user_client.User),
+	}
+}
+
+func (l *userLoader) Load(id uuid.UUID) func() (interface{}, error) {
+	l.mu.Lock()
+	if _, ok := l.cache[id]; !ok {
+		l.pending[id] = struct{}{}
+	}
+	l.mu.Unlock()
+
+	return func() (interface{}, error) {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		if _, ok := l.pending[id]; ok {
+			l.dispatch()
+		}
+		if l.err != nil {
+			return nil, l.err
+		}
+		if u := l.cache[id]; u != nil {
+			return u, nil
+		}
+		return nil, nil
+	}
+}
+
+// dispatch must be called with mu held.
+func (l *userLoader) dispatch() {
+	ids := make([]uuid.UUID, 0, len(l.pending))
+	for id := range l.pending {
+		ids = append(ids, id)
+	}
+	l.pending = make(map[uuid.UUID]struct{})
+
+	for len(ids) > 0 {
+		batch := ids[:min(len(ids), maxUsersPerRequest)]
+		ids = ids[len(batch):]
+		users, err := l.client.GetUsersByIDs(l.ctx, l.token, batch)
+		if err != nil {
+			l.err = err
+			return
+		}
+		for _, id := range batch {
+			l.cache[id] = nil
+		}
+		for i := range users {
+			l.cache[users[i].UUID] = &users[i]
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	"note_service/app/internal/client/user_client"
+
+	"github.com/google/uuid"
+)
+
+type fakeUserClient struct {
+	user_client.UserClient
+	calls [][]uuid.UUID
+}
+
+func (c *fakeUserClient) GetUsersByIDs(_ context.Context, _ user_client.Token, ids []uuid.UUID) ([]user_client.User,
+	error) {
+	c.calls = append(c.calls, ids)
+	users := make([]user_client.User, len(ids))
+	for i, id := range ids {
+		users[i] = user_client.User{UUID: id}
+	}
+	return users, nil
+}
+
+func TestUserLoaderChunksRequests(t *testing.T) {
+	client := &fakeUserClient{}
+	l := newUserLoader(context.Background(), client, user_client.Token{})
+	ids := make([]uuid.UUID, 250)
+	thunks := make([]func() (interface{}, error), len(ids))
+	for i := range ids {
+		ids[i] = uuid.New()
+		thunks[i] = l.Load(ids[i])
+	}
+	for i, thunk := range thunks {
+		u, err := thunk()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := u.(*user_client.User).UUID; got != ids[i] {
+			t.Fatalf("Load(%s) = %s", ids[i], got)
+		}
+	}
+
+	if len(client.calls) != 3 {
+		t.Fatalf("GetUsersByIDs called %d times, want 3", len(client.calls))
+	}
+	for _, call := range client.calls {
+		if len(call) > maxUsersPerRequest {
+			t.Errorf("GetUsersByIDs got %d ids, want at most %d", len(call), maxUsersPerRequest)
+		}
+	}
+}
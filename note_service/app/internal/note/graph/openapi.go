@@ -0,0 +1,34 @@
+package graph
+
+import (
+	"note_service/app/pkg/openapi"
+)
+
+func (h *Handler) Describe(b *openapi.Builder) {
+	b.Schema(QueryRequest{})
+	b.AddRoutes(h.Routes())
+}
+
+func queryOperation(id string, post bool) openapi.Operation {
+	op := openapi.Operation{
+		OperationID: id,
+		Summary:     "Execute a GraphQL query or mutation over notes",
+		Tags:        []string{"graphql"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "GraphQL result, errors are reported in the errors member",
+				Content: openapi.JSON(&openapi.Schema{Type: "object"})},
+		},
+	}
+	if post {
+		op.RequestBody = &openapi.RequestBody{Required: true,
+			Content: openapi.JSON(&openapi.Schema{Ref: "#/components/schemas/QueryRequest"})}
+	} else {
+		op.Parameters = []openapi.Parameter{
+			{Name: "query", In: "query", Required: true, Schema: &openapi.Schema{Type: "string"}},
+			{Name: "operationName", In: "query", Schema: &openapi.Schema{Type: "string"}},
+			{Name: "variables", In: "query", Description: "JSON encoded variables", Schema: &openapi.Schema{Type: "string"}},
+		}
+	}
+	return op
+}
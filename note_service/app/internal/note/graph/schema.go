@@ -0,0 +1,361 @@
+package graph
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/rest"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/graphql-go/graphql"
+)
+
+const maxPageSize = 100
+
+type loaderKey struct{}
+
+var userType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*user_client.User).UUID.String(), nil
+		}},
+		"username": &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*user_client.User).Username, nil
+		}},
+	},
+})
+
+var noteType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "Note",
+	Fields: graphql.Fields{
+		"id": &graphql.Field{Type: graphql.NewNonNull(graphql.ID), Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).NoteUUID.String(), nil
+		}},
+		"text": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).Text, nil
+		}},
+		"public": &graphql.Field{Type: graphql.Boolean, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).Public, nil
+		}},
+		"title": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).Title, nil
+		}},
+		"pinned": &graphql.Field{Type: graphql.Boolean, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).Pinned, nil
+		}},
+		"archived": &graphql.Field{Type: graphql.Boolean, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).Archived, nil
+		}},
+		"format": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).Format, nil
+		}},
+		"notebookId": &graphql.Field{Type: graphql.ID, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			n := p.Source.(*note.Note)
+			if n.NotebookUUID == nil {
+				return nil, nil
+			}
+			return n.NotebookUUID.String(), nil
+		}},
+		"createTime": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).CreateTime, nil
+		}},
+		"publishAt": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).PublishAt, nil
+		}},
+		"unpublishAt": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).UnpublishAt, nil
+		}},
+		"expiresAt": &graphql.Field{Type: graphql.DateTime, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).ExpiresAt, nil
+		}},
+		"burnAfterRead": &graphql.Field{Type: graphql.Boolean, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).BurnAfterRead, nil
+		}},
+		"encrypted": &graphql.Field{Type: graphql.Boolean, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			return p.Source.(*note.Note).Encrypted, nil
+		}},
+		// ciphertext is base64 like in the REST API, recipients are only
+		// served there.
+		"ciphertext": &graphql.Field{Type: graphql.String, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			n := p.Source.(*note.Note)
+			if n.Ciphertext == nil {
+				return nil, nil
+			}
+			return base64.StdEncoding.EncodeToString(n.Ciphertext), nil
+		}},
+		"owner": &graphql.Field{Type: userType, Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+			n := p.Source.(*note.Note)
+			if n.UserUUID == nil {
+				return nil, nil
+			}
+			return p.Context.Value(loaderKey{}).(*userLoader).Load(*n.UserUUID), nil
+		}},
+	},
+})
+
+var noteConnectionType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "NoteConnection",
+	Fields: graphql.Fields{
+		"nodes":      &graphql.Field{Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(noteType)))},
+		"totalCount": &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"hasMore":    &graphql.Field{Type: graphql.NewNonNull(graphql.Boolean)},
+	},
+})
+
+var noteFilterType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "NoteFilter",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"public":       &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"ownerId":      &graphql.InputObjectFieldConfig{Type: graphql.ID},
+		"mine":         &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+		"textContains": &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"createdAfter": &graphql.InputObjectFieldConfig{Type: graphql.DateTime},
+		"archived":     &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+	},
+})
+
+var createNoteInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "CreateNoteInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"text":   &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.String)},
+		"public": &graphql.InputObjectFieldConfig{Type: graphql.NewNonNull(graphql.Boolean)},
+	},
+})
+
+var updateNoteInputType = graphql.NewInputObject(graphql.InputObjectConfig{
+	Name: "UpdateNoteInput",
+	Fields: graphql.InputObjectConfigFieldMap{
+		"text":   &graphql.InputObjectFieldConfig{Type: graphql.String},
+		"public": &graphql.InputObjectFieldConfig{Type: graphql.Boolean},
+	},
+})
+
+type resolver struct {
+	service note.Service
+}
+
+func newSchema(service note.Service) (graphql.Schema, error) {
+	r := resolver{service: service}
+	idArg := graphql.FieldConfigArgument{"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)}}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"note": &graphql.Field{Type: noteType, Args: idArg, Resolve: r.note},
+			"notes": &graphql.Field{
+				Type: graphql.NewNonNull(noteConnectionType),
+				Args: graphql.FieldConfigArgument{
+					"filter": &graphql.ArgumentConfig{Type: noteFilterType},
+					"first":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 20},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: r.notes,
+			},
+		},
+	})
+
+	mutation := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Mutation",
+		Fields: graphql.Fields{
+			"createNote": &graphql.Field{
+				Type: noteType,
+				Args: graphql.FieldConfigArgument{
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(createNoteInputType)},
+				},
+				Resolve: r.createNote,
+			},
+			"updateNote": &graphql.Field{
+				Type: noteType,
+				Args: graphql.FieldConfigArgument{
+					"id":    &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.ID)},
+					"input": &graphql.ArgumentConfig{Type: graphql.NewNonNull(updateNoteInputType)},
+				},
+				Resolve: r.updateNote,
+			},
+			"deleteNote": &graphql.Field{Type: graphql.Boolean, Args: idArg, Resolve: r.deleteNote},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query, Mutation: mutation})
+}
+
+func (r resolver) note(p graphql.ResolveParams) (interface{}, error) {
+	noteUUID, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	n, err := r.service.GetOne(p.Context, noteUUID, userFromContext(p.Context))
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, nil
+		}
+		return nil, toGraphError(err)
+	}
+	return n, nil
+}
+
+type connection struct {
+	Nodes      []*note.Note `json:"nodes"`
+	TotalCount int          `json:"totalCount"`
+	HasMore    bool         `json:"hasMore"`
+}
+
+func (r resolver) notes(p graphql.ResolveParams) (interface{}, error) {
+	userUUID := userFromContext(p.Context)
+	first, _ := p.Args["first"].(int)
+	offset, _ := p.Args["offset"].(int)
+	if first < 0 || offset < 0 {
+		return nil, toGraphError(apperror.BadRequestError("first and offset must not be negative"))
+	}
+
+	opts, err := listOptions(p.Args["filter"], userUUID)
+	if err != nil {
+		return nil, err
+	}
+	// A limit of 0 lists everything, first: 0 still only wants the count.
+	opts.Limit, opts.Offset = max(min(first, maxPageSize), 1), offset
+	notes, err := r.service.GetMany(p.Context, userUUID, opts)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		return nil, toGraphError(err)
+	}
+
+	conn := connection{Nodes: []*note.Note{}}
+	if notes != nil {
+		for i := 0; i < len(notes.Notes) && i < first; i++ {
+			conn.Nodes = append(conn.Nodes, &notes.Notes[i])
+		}
+		conn.TotalCount = notes.Total
+		conn.HasMore = offset+len(conn.Nodes) < notes.Total
+	}
+	return conn, nil
+}
+
+// listOptions turns the filter argument into filters the storage runs in
+// its query.
+func listOptions(arg interface{}, userUUID uuid.UUID) (note.ListOptions, error) {
+	filter, _ := arg.(map[string]interface{})
+	var opts note.ListOptions
+	eq := func(field, value string) {
+		opts.Filters = append(opts.Filters, rest.FilterOptions{Field: field, Operator: rest.OperatorEq,
+			Values: []string{value}})
+	}
+
+	opts.IncludeArchived, _ = filter["archived"].(bool)
+	if public, ok := filter["public"].(bool); ok {
+		eq("public", strconv.FormatBool(public))
+	}
+	if raw, ok := filter["ownerId"]; ok && raw != nil {
+		ownerUUID, err := parseID(raw)
+		if err != nil {
+			return opts, err
+		}
+		eq("user_id", ownerUUID.String())
+	}
+	if mine, ok := filter["mine"].(bool); ok && mine {
+		eq("user_id", userUUID.String())
+	}
+	if text, ok := filter["textContains"].(string); ok && text != "" {
+		opts.Filters = append(opts.Filters, rest.FilterOptions{Field: "text", Operator: rest.OperatorLike,
+			Values: []string{text}})
+	}
+	if after, ok := filter["createdAfter"].(time.Time); ok {
+		opts.Filters = append(opts.Filters, rest.FilterOptions{Field: "create_time", Operator: rest.OperatorGt,
+			Values: []string{after.UTC().Format(time.RFC3339Nano)}})
+	}
+	return opts, nil
+}
+
+func (r resolver) createNote(p graphql.ResolveParams) (interface{}, error) {
+	userUUID, err := authorized(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	input := p.Args["input"].(map[string]interface{})
+	text, _ := input["text"].(string)
+	public, _ := input["public"].(bool)
+
+	id, err := r.service.Create(p.Context, note.CreateNoteDTO{UserUUID: &userUUID, Text: &text, Public: &public})
+	if err != nil {
+		return nil, toGraphError(err)
+	}
+	noteUUID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, toGraphError(err)
+	}
+	n, err := r.service.GetOne(p.Context, noteUUID, userUUID)
+	if err != nil {
+		return nil, toGraphError(err)
+	}
+	return n, nil
+}
+
+func (r resolver) updateNote(p graphql.ResolveParams) (interface{}, error) {
+	userUUID, err := authorized(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	noteUUID, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	input := p.Args["input"].(map[string]interface{})
+	dto := note.UpdateNoteDTO{NoteUUID: &noteUUID}
+	if text, ok := input["text"].(string); ok {
+		dto.Text = &text
+	}
+	if public, ok := input["public"].(bool); ok {
+		dto.Public = &public
+	}
+
+	if err := r.service.Update(p.Context, dto, userUUID); err != nil {
+		return nil, toGraphError(err)
+	}
+	n, err := r.service.GetOne(p.Context, noteUUID, userUUID)
+	if err != nil {
+		return nil, toGraphError(err)
+	}
+	return n, nil
+}
+
+func (r resolver) deleteNote(p graphql.ResolveParams) (interface{}, error) {
+	userUUID, err := authorized(p.Context)
+	if err != nil {
+		return nil, err
+	}
+	noteUUID, err := parseID(p.Args["id"])
+	if err != nil {
+		return nil, err
+	}
+	if err := r.service.Delete(p.Context, noteUUID, userUUID); err != nil {
+		return nil, toGraphError(err)
+	}
+	return true, nil
+}
+
+func userFromContext(ctx context.Context) uuid.UUID {
+	userUUID, _ := ctx.Value("userUUID").(uuid.UUID)
+	return userUUID
+}
+
+func authorized(ctx context.Context) (uuid.UUID, error) {
+	userUUID := userFromContext(ctx)
+	if userUUID == uuid.Nil {
+		return userUUID, toGraphError(apperror.ErrUnauthorized)
+	}
+	return userUUID, nil
+}
+
+func parseID(v interface{}) (uuid.UUID, error) {
+	s, _ := v.(string)
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return id, toGraphError(apperror.BadRequestError("invalid uuid type"))
+	}
+	return id, nil
+}
@@ -4,52 +4,126 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"net/url"
 	"note_service/app/internal/apperror"
 	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/handlers"
 	"note_service/app/pkg/logging"
+	"note_service/app/pkg/openapi"
+	"note_service/app/pkg/ratelimit"
+	"note_service/app/pkg/rest"
 	"note_service/app/pkg/user"
+	"note_service/app/pkg/validator"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/julienschmidt/httprouter"
 )
 
 const (
-	notesURL = "/notes"
-	noteURL  = "/notes/:uuid"
+	notesURL     = "/notes"
+	noteURL      = "/notes/:uuid"
+	streamURL    = "/notes/stream"
+	graphURL     = "/notes/graph"
+	linksURL     = "/notes/:uuid/links"
+	backlinksURL = "/notes/:uuid/backlinks"
+	scheduleURL  = "/notes/:uuid/schedule"
+
+	readGroup  = "notes_read"
+	writeGroup = "notes_write"
 )
 
 type Handler struct {
-	Logger      logging.Logger
-	NoteService Service
-	UserClient  user_client.UserClient
-}
-
-func (h *Handler) Register(router *httprouter.Router) {
-	router.HandlerFunc( // GET /notes
-		http.MethodGet,
-		notesURL,
-		user.Authentication(h.UserClient, apperror.Middleware(h.GetNotes)),
-	)
-	router.HandlerFunc( // GET /note/{uuid}
-		http.MethodGet,
-		noteURL,
-		user.Authentication(h.UserClient, apperror.Middleware(h.GetNote)),
-	)
-	router.HandlerFunc( // POST /notes
-		http.MethodPost,
-		notesURL,
-		user.Authentication(h.UserClient, user.Authorization(apperror.Middleware(h.CreateNote))),
-	)
-	router.HandlerFunc( // PATCH /note/{uuid}
-		http.MethodPatch,
-		noteURL,
-		user.Authentication(h.UserClient, user.Authorization(apperror.Middleware(h.UpdateNote))),
-	)
-	router.HandlerFunc( // DELETE /note/{uuid}
-		http.MethodDelete,
-		noteURL,
-		user.Authentication(h.UserClient, user.Authorization(apperror.Middleware(h.DeleteNote))),
-	)
+	Logger       logging.Logger
+	NoteService  Service
+	UserClient   user_client.UserClient
+	RateLimiter  *ratelimit.Limiter
+	MaxBodyBytes int64
+	Stream       *Broker
+	Heartbeat    time.Duration
+	Renderer     *Renderer
+}
+
+func (h *Handler) Register(router openapi.Router) {
+	for _, route := range h.Routes() {
+		if route.Path == streamURL || route.Path == graphURL {
+			// httprouter can't hold /notes/stream or /notes/graph next to
+			// /notes/:uuid, GetNote hands the request over instead.
+			continue
+		}
+		router.HandlerFunc(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (h *Handler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{ // GET /notes
+			Method:    http.MethodGet,
+			Path:      notesURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.GetNotes))),
+			Operation: getNotesOperation,
+		},
+		{ // GET /note/{uuid}
+			Method:    http.MethodGet,
+			Path:      noteURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.GetNote))),
+			Operation: getNoteOperation,
+		},
+		{ // GET /notes/stream
+			Method:    http.MethodGet,
+			Path:      streamURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.StreamNotes))),
+			Operation: streamNotesOperation,
+		},
+		{ // GET /notes/graph
+			Method:    http.MethodGet,
+			Path:      graphURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.GetGraph))),
+			Operation: getGraphOperation,
+		},
+		{ // GET /notes/{uuid}/links
+			Method:    http.MethodGet,
+			Path:      linksURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.GetLinks))),
+			Operation: getLinksOperation,
+		},
+		{ // GET /notes/{uuid}/backlinks
+			Method:    http.MethodGet,
+			Path:      backlinksURL,
+			Handler:   user.Authentication(h.UserClient, h.RateLimiter.Limit(readGroup, apperror.Middleware(h.GetBacklinks))),
+			Operation: getBacklinksOperation,
+		},
+		{ // POST /notes
+			Method: http.MethodPost,
+			Path:   notesURL,
+			Handler: user.Authentication(h.UserClient,
+				h.RateLimiter.Limit(writeGroup, user.Authorization(apperror.Middleware(h.CreateNote)))),
+			Operation: createNoteOperation,
+		},
+		{ // PATCH /note/{uuid}
+			Method: http.MethodPatch,
+			Path:   noteURL,
+			Handler: user.Authentication(h.UserClient,
+				h.RateLimiter.Limit(writeGroup, user.Authorization(apperror.Middleware(h.UpdateNote)))),
+			Operation: updateNoteOperation,
+		},
+		{ // DELETE /note/{uuid}
+			Method: http.MethodDelete,
+			Path:   noteURL,
+			Handler: user.Authentication(h.UserClient,
+				h.RateLimiter.Limit(writeGroup, user.Authorization(apperror.Middleware(h.DeleteNote)))),
+			Operation: deleteNoteOperation,
+		},
+		{ // PUT /notes/{uuid}/schedule
+			Method: http.MethodPut,
+			Path:   scheduleURL,
+			Handler: user.Authentication(h.UserClient,
+				h.RateLimiter.Limit(writeGroup, user.Authorization(apperror.Middleware(h.ScheduleNote)))),
+			Operation: scheduleNoteOperation,
+		},
+	}
 }
 
 func (h *Handler) GetNotes(w http.ResponseWriter, r *http.Request) error {
@@ -58,10 +132,25 @@ func (h *Handler) GetNotes(w http.ResponseWriter, r *http.Request) error {
 
 	userUUID := r.Context().Value("userUUID").(uuid.UUID)
 
-	note, err := h.NoteService.GetMany(r.Context(), userUUID)
+	limit, offset, err := pageParams(r)
 	if err != nil {
 		return err
 	}
+	opts, err := ParseListOptions(r.URL.Query())
+	if err != nil {
+		return err
+	}
+
+	opts.Limit, opts.Offset = limit, offset
+
+	note, err := h.NoteService.GetMany(r.Context(), userUUID, opts)
+	if err != nil {
+		return err
+	}
+	w.Header().Set("X-Total-Count", strconv.Itoa(note.Total))
+	if note.Notes == nil {
+		note.Notes = []Note{}
+	}
 	noteBytes, err := json.Marshal(note)
 	if err != nil {
 		return err
@@ -83,16 +172,55 @@ func (h *Handler) GetNote(w http.ResponseWriter, r *http.Request) error {
 	if strNoteUUID == "" {
 		return apperror.BadRequestError("uuid query parameter is required and must be a comma separated integers")
 	}
+	switch strNoteUUID {
+	case "stream":
+		return h.StreamNotes(w, r)
+	case "graph":
+		return h.GetGraph(w, r)
+	}
 	userUUID := r.Context().Value("userUUID").(uuid.UUID)
 
 	noteUUID, err := uuid.Parse(strNoteUUID)
 	if err != nil {
 		return apperror.BadRequestError("invalid uuid type")
 	}
+	representation, err := noteRepresentation(r)
+	if err != nil {
+		return err
+	}
 	note, err := h.NoteService.GetOne(r.Context(), noteUUID, userUUID)
 	if err != nil {
 		return err
 	}
+	w.Header().Set("Vary", "Accept")
+	// Encrypted notes can't be rendered, they are answered with JSON whatever
+	// was asked for, so a burn-after-read note isn't lost to a 406.
+	if note.IsEncrypted() {
+		representation = jsonType
+	}
+
+	switch representation {
+	case htmlType:
+		out, err := h.Renderer.HTML(*note)
+		if err != nil {
+			return err
+		}
+		w.Header().Set("Content-Type", htmlType+"; charset=utf-8")
+		w.Header().Set("Content-Security-Policy", h.Renderer.ContentSecurityPolicy())
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(out))
+		return nil
+	case markdownType, plainType:
+		w.Header().Set("Content-Type", representation+"; charset=utf-8")
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(http.StatusOK)
+		if note.Text != nil {
+			w.Write([]byte(*note.Text))
+		}
+		return nil
+	}
+
 	noteBytes, err := json.Marshal(note)
 	if err != nil {
 		return err
@@ -115,8 +243,8 @@ func (h *Handler) CreateNote(w http.ResponseWriter, r *http.Request) error {
 	h.Logger.Debug("decode create note dto")
 	var dto CreateNoteDTO
 	defer r.Body.Close()
-	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-		return apperror.BadRequestError("invalid data")
+	if err := validator.DecodeJSON(w, r, &dto, h.MaxBodyBytes); err != nil {
+		return err
 	}
 
 	dto.UserUUID = &userUUID
@@ -148,8 +276,8 @@ func (h *Handler) UpdateNote(w http.ResponseWriter, r *http.Request) error {
 	h.Logger.Debug("decode update note dto")
 	var dto UpdateNoteDTO
 	defer r.Body.Close()
-	if err := json.NewDecoder(r.Body).Decode(&dto); err != nil {
-		return apperror.BadRequestError("invalid data")
+	if err := validator.DecodeJSON(w, r, &dto, h.MaxBodyBytes); err != nil {
+		return err
 	}
 
 	noteUUID, err := uuid.Parse(strNoteUUID)
@@ -192,3 +320,301 @@ func (h *Handler) DeleteNote(w http.ResponseWriter, r *http.Request) error {
 
 	return nil
 }
+
+// ScheduleNote replaces when a note gets published or unpublished.
+func (h *Handler) ScheduleNote(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("SCHEDULE NOTE")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+
+	h.Logger.Debug("decode schedule dto")
+	var dto ScheduleDTO
+	defer r.Body.Close()
+	if err := validator.DecodeJSON(w, r, &dto, h.MaxBodyBytes); err != nil {
+		return err
+	}
+	dto.NoteUUID = &noteUUID
+
+	note, err := h.NoteService.Schedule(r.Context(), dto, userUUID)
+	if err != nil {
+		return err
+	}
+	noteBytes, err := json.Marshal(note)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(noteBytes)
+
+	return nil
+}
+
+func (h *Handler) GetLinks(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET NOTE LINKS")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	links, err := h.NoteService.GetLinks(r.Context(), noteUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	linksBytes, err := json.Marshal(links)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(linksBytes)
+
+	return nil
+}
+
+func (h *Handler) GetBacklinks(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET NOTE BACKLINKS")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	notes, err := h.NoteService.GetBacklinks(r.Context(), noteUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	notesBytes, err := json.Marshal(notes)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(notesBytes)
+
+	return nil
+}
+
+// GetGraph exports the caller's notes and their links as nodes and edges.
+func (h *Handler) GetGraph(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET NOTE GRAPH")
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	graph, err := h.NoteService.GetGraph(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	graphBytes, err := json.Marshal(graph)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(graphBytes)
+
+	return nil
+}
+
+// StreamNotes sends note events visible to the caller as Server-Sent Events
+// until the client goes away. A Last-Event-ID header resumes after that
+// event, if it is no longer buffered a "reset" event tells the client to
+// reload its notes first.
+func (h *Handler) StreamNotes(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("STREAM NOTES")
+	if h.Stream == nil {
+		return apperror.ErrNotFound
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetWriteDeadline(time.Time{}); err != nil {
+		return err
+	}
+
+	lastEventID := r.Header.Get("Last-Event-ID")
+	if lastEventID == "" {
+		lastEventID = r.URL.Query().Get("last_event_id")
+	}
+	sub, replay, complete := h.Stream.Subscribe(userUUID, lastEventID)
+	defer h.Stream.Unsubscribe(sub)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if !complete {
+		fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+	}
+	for _, se := range replay {
+		if err := writeStreamEvent(w, se, userUUID); err != nil {
+			return nil
+		}
+	}
+	if err := rc.Flush(); err != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(h.Heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return nil
+		case se, ok := <-sub.Events:
+			if !ok {
+				return nil
+			}
+			if err := writeStreamEvent(w, se, userUUID); err != nil {
+				return nil
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(w, ": heartbeat\n\n"); err != nil {
+				return nil
+			}
+		}
+		if err := rc.Flush(); err != nil {
+			return nil
+		}
+	}
+}
+
+func writeStreamEvent(w http.ResponseWriter, se streamEvent, userUUID uuid.UUID) error {
+	data, err := json.Marshal(forSubscriber(se.Event, userUUID))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", se.ID, se.Event.Type, data)
+	return err
+}
+
+const (
+	jsonType     = "application/json"
+	htmlType     = "text/html"
+	markdownType = "text/markdown"
+	plainType    = "text/plain"
+)
+
+// noteRepresentation picks what GetNote answers with. ?render=html wins,
+// otherwise the Accept header decides and JSON is the default.
+func noteRepresentation(r *http.Request) (string, error) {
+	switch r.URL.Query().Get("render") {
+	case "":
+	case "html":
+		return htmlType, nil
+	default:
+		return "", apperror.ValidationError(apperror.FieldError{Field: "render", Code: "invalid",
+			Message: "must be html"})
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return jsonType, nil
+	}
+	if t := negotiate(accept, jsonType, htmlType, markdownType, plainType); t != "" {
+		return t, nil
+	}
+	return "", apperror.NewAppError(http.StatusNotAcceptable, "not_acceptable",
+		"note is available as application/json, text/html, text/markdown or text/plain", "")
+}
+
+// negotiate returns the offer with the highest quality in the Accept header,
+// earlier offers win ties. Empty means none is acceptable.
+func negotiate(accept string, offers ...string) string {
+	best, bestQ := "", 0.0
+	for _, offer := range offers {
+		q, specificity := 0.0, -1
+		for _, part := range strings.Split(accept, ",") {
+			mediaRange, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+			mediaRange = strings.ToLower(strings.TrimSpace(mediaRange))
+			s := -1
+			switch {
+			case mediaRange == offer:
+				s = 2
+			case mediaRange == "*/*":
+				s = 0
+			case strings.HasSuffix(mediaRange, "/*") && strings.HasPrefix(offer, strings.TrimSuffix(mediaRange, "*")):
+				s = 1
+			}
+			if s <= specificity {
+				continue
+			}
+			specificity, q = s, 1.0
+			for _, param := range strings.Split(params, ";") {
+				if v, ok := strings.CutPrefix(strings.TrimSpace(param), "q="); ok {
+					if f, err := strconv.ParseFloat(v, 64); err == nil {
+						q = f
+					}
+				}
+			}
+		}
+		if q > bestQ {
+			best, bestQ = offer, q
+		}
+	}
+	return best
+}
+
+// pageParams reads the optional limit and offset query parameters of GET /notes,
+// a zero limit means no limit.
+func pageParams(r *http.Request) (limit, offset int, err error) {
+	q := r.URL.Query()
+	var details []apperror.FieldError
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit < 1 {
+			details = append(details, apperror.FieldError{Field: "limit", Code: "invalid", Message: "must be a positive integer"})
+		}
+	}
+	if v := q.Get("offset"); v != "" {
+		if offset, err = strconv.Atoi(v); err != nil || offset < 0 {
+			details = append(details, apperror.FieldError{Field: "offset", Code: "invalid", Message: "must be a non-negative integer"})
+		}
+	}
+	if len(details) > 0 {
+		return 0, 0, apperror.ValidationError(details...)
+	}
+	return limit, offset, nil
+}
+
+// ParseListOptions reads the archived, filter and sort parameters of a note
+// listing.
+func ParseListOptions(q url.Values) (ListOptions, error) {
+	var opts ListOptions
+	var err error
+	if v := q.Get("archived"); v != "" {
+		if opts.IncludeArchived, err = strconv.ParseBool(v); err != nil {
+			return opts, apperror.ValidationError(apperror.FieldError{Field: "archived", Code: "invalid", Message: "must be a boolean"})
+		}
+	}
+	if opts.Filters, err = rest.ParseFilters(q, FilterFields); err != nil {
+		return opts, filterError(err)
+	}
+	if opts.Sort, err = rest.ParseSort(q.Get("sort"), SortFields); err != nil {
+		return opts, filterError(err)
+	}
+	return opts, nil
+}
+
+// filterError turns filter and sort parse errors into a 422.
+func filterError(err error) error {
+	var details []apperror.FieldError
+	switch e := err.(type) {
+	case rest.FilterErrors:
+		for _, fe := range e {
+			details = append(details, apperror.FieldError{Field: fe.Field, Code: "invalid", Message: fe.Message})
+		}
+	case rest.FilterError:
+		details = append(details, apperror.FieldError{Field: e.Field, Code: "invalid", Message: e.Message})
+	default:
+		return err
+	}
+	return apperror.ValidationError(details...)
+}
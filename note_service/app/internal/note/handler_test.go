@@ -0,0 +1,67 @@
+package note
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/logging"
+	"strings"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/julienschmidt/httprouter"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeService struct {
+	Service
+	updated *UpdateNoteDTO
+}
+
+func (s *fakeService) Update(ctx context.Context, dto UpdateNoteDTO, userUUID uuid.UUID) error {
+	s.updated = &dto
+	return nil
+}
+
+func TestUpdateNoteBody(t *testing.T) {
+	tests := []struct {
+		body      string
+		wantField string
+		wantCode  string
+	}{
+		{`{"text": "a"}`, "", ""},
+		{`{"text": "a", "bogus": 1}`, "bogus", "unknown_field"},
+		{`{"pinned": "yes"}`, "pinned", "invalid_type"},
+		{`{"title": 1}`, "title", "invalid_type"},
+	}
+	for _, tt := range tests {
+		service := &fakeService{}
+		h := &Handler{Logger: logging.Logger{Entry: logrus.NewEntry(logrus.New())}, NoteService: service}
+		noteUUID, userUUID := uuid.New(), uuid.New()
+		r := httptest.NewRequest("PATCH", "/notes/"+noteUUID.String(), strings.NewReader(tt.body))
+		ctx := context.WithValue(r.Context(), httprouter.ParamsKey,
+			httprouter.Params{{Key: "uuid", Value: noteUUID.String()}})
+		ctx = context.WithValue(ctx, "userUUID", userUUID)
+
+		err := h.UpdateNote(httptest.NewRecorder(), r.WithContext(ctx))
+		if tt.wantCode == "" {
+			if err != nil || service.updated == nil {
+				t.Errorf("UpdateNote(%s) error = %v, updated %v", tt.body, err, service.updated != nil)
+			}
+			continue
+		}
+		var appErr *apperror.AppError
+		if !errors.As(err, &appErr) || len(appErr.Details) != 1 {
+			t.Errorf("UpdateNote(%s) error = %v, want one field error", tt.body, err)
+			continue
+		}
+		if d := appErr.Details[0]; d.Field != tt.wantField || d.Code != tt.wantCode {
+			t.Errorf("UpdateNote(%s) field error = %s %s, want %s %s", tt.body, d.Field, d.Code,
+				tt.wantField, tt.wantCode)
+		}
+		if service.updated != nil {
+			t.Errorf("UpdateNote(%s) reached the service", tt.body)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package note
+
+import (
+	"regexp"
+
+	"github.com/google/uuid"
+)
+
+var linkPattern = regexp.MustCompile(`\[\[([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\]\]`)
+
+// LinkTargets returns the notes text references as [[uuid]], each once and
+// in order of appearance. References to source itself are dropped.
+func LinkTargets(source uuid.UUID, text string) []uuid.UUID {
+	var targets []uuid.UUID
+	seen := map[uuid.UUID]bool{source: true}
+	for _, m := range linkPattern.FindAllStringSubmatch(text, -1) {
+		id, err := uuid.Parse(m[1])
+		if err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		targets = append(targets, id)
+	}
+	return targets
+}
+
+// Link is a reference from one note to another. Broken links point at notes
+// that don't exist (anymore), Note is only set for links that aren't.
+type Link struct {
+	NoteUUID uuid.UUID `json:"id"`
+	Broken   bool      `json:"broken"`
+	Note     *Note     `json:"note,omitempty"`
+}
+
+type Links struct {
+	Links []Link `json:"links"`
+}
+
+type GraphNode struct {
+	NoteUUID uuid.UUID `json:"id"`
+	Title    *string   `json:"title,omitempty"`
+	Public   bool      `json:"public"`
+	Archived bool      `json:"archived"`
+	// External nodes are visible notes of other users the caller links to.
+	External bool `json:"external,omitempty"`
+}
+
+// GraphEdge links Source to Target, Target has no node if the edge is broken.
+type GraphEdge struct {
+	Source uuid.UUID `json:"source"`
+	Target uuid.UUID `json:"target"`
+	Broken bool      `json:"broken"`
+}
+
+// Graph holds the caller's notes and the links between them.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
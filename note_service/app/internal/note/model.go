@@ -1,8 +1,13 @@
 package note
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"time"
 
+	"note_service/app/pkg/rest"
+
 	"github.com/google/uuid"
 )
 
@@ -12,17 +17,138 @@ type Note struct {
 	CreateTime *time.Time `json:"create_time,omitempty"`
 	Text       *string    `json:"text,omitempty"`
 	Public     *bool      `json:"public,omitempty"`
+	Format     *string    `json:"format,omitempty"`
+	Title      *string    `json:"title,omitempty"`
+	// TitleSet marks Title as given in an update, a nil Title then removes
+	// the title.
+	TitleSet bool  `json:"-" bson:"-"`
+	Pinned   *bool `json:"pinned,omitempty"`
+	Archived *bool `json:"archived,omitempty"`
+	// NotebookUUID is nil for notes at the root.
+	NotebookUUID *uuid.UUID `json:"notebook_id,omitempty"`
+	// InheritPublic notes take Public from their notebook.
+	InheritPublic *bool `json:"inherit_public,omitempty"`
+	// PublishAt and UnpublishAt schedule a change of Public. The Scheduler
+	// applies them once they pass and clears them again.
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+	// ExpiresAt is when the note disappears, the Reaper deletes it later.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// BurnAfterRead notes are deleted by the first read of someone other
+	// than their owner and are left out of listings for everyone else.
+	BurnAfterRead *bool `json:"burn_after_read,omitempty"`
+	// Encrypted notes are encrypted by the client: they have Ciphertext in
+	// place of Text and the server can't read, search or render them.
+	Encrypted  *bool       `json:"encrypted,omitempty"`
+	Ciphertext []byte      `json:"ciphertext,omitempty"`
+	Recipients []Recipient `json:"recipients,omitempty"`
+}
+
+// IsEncrypted reports whether the note is encrypted by the client.
+func (n Note) IsEncrypted() bool {
+	return n.Encrypted != nil && *n.Encrypted
+}
+
+// VisibleTo reports whether userUUID may read n: its owner and recipients
+// always, everyone else while it is public and its unpublish_at hasn't
+// passed yet.
+func (n Note) VisibleTo(userUUID uuid.UUID, now time.Time) bool {
+	if userUUID != uuid.Nil && n.UserUUID != nil && *n.UserUUID == userUUID {
+		return true
+	}
+	if n.Public != nil && *n.Public && (n.UnpublishAt == nil || n.UnpublishAt.After(now)) {
+		return true
+	}
+	for _, r := range n.Recipients {
+		if userUUID != uuid.Nil && r.UserUUID != nil && *r.UserUUID == userUUID {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted is n without its content, for events about notes whose content
+// must not outlive them.
+func (n Note) Redacted() Note {
+	n.Text, n.Title, n.Ciphertext, n.Recipients = nil, nil, nil, nil
+	return n
 }
 
+// WithoutPrivateText is n as events stored outside the notes table carry
+// it, in the outbox and as webhook deliveries. The text of a private note
+// is left out there, it may only be stored encrypted.
+func (n Note) WithoutPrivateText() Note {
+	if n.Public == nil || !*n.Public {
+		n.Text = nil
+	}
+	return n
+}
+
+// Recipient is a user who can decrypt an encrypted note. WrappedKey is the
+// key of the note encrypted by the client for that user's key KeyID with
+// Algorithm, the server stores all of it as is.
+type Recipient struct {
+	UserUUID   *uuid.UUID `json:"user_id"`
+	Algorithm  string     `json:"algorithm"`
+	KeyID      string     `json:"key_id,omitempty"`
+	WrappedKey []byte     `json:"wrapped_key"`
+}
+
+const (
+	FormatPlain    = "plain"
+	FormatMarkdown = "markdown"
+)
+
 type Notes struct {
 	Notes []Note `json:"notes" bson:"notes,omitempty"`
+	// Total counts the notes before ListOptions.Limit and Offset.
+	Total int `json:"-" bson:"-"`
 }
 
+// ListOptions narrows down GetMany. Archived notes are left out unless
+// IncludeArchived is set. Filters and Sort only name fields of FilterFields
+// and SortFields, storages still must not trust them as column names.
+type ListOptions struct {
+	IncludeArchived bool
+	Filters         []rest.FilterOptions
+	Sort            []rest.SortOptions
+	// Limit and Offset page the notes, a Limit of 0 returns all of them.
+	Limit  int
+	Offset int
+}
+
+// FilterFields are the query parameters GET /notes filters by.
+var FilterFields = rest.FilterSchema{
+	"user_id": {Type: rest.TypeUUID, Operators: []string{rest.OperatorEq, rest.OperatorNeq, rest.OperatorIn}},
+	"public":  {Type: rest.TypeBool, Operators: []string{rest.OperatorEq, rest.OperatorNeq}},
+	"create_time": {Type: rest.TypeTime,
+		Operators: []string{rest.OperatorEq, rest.OperatorGt, rest.OperatorLt, rest.OperatorBetween}},
+	"text":        {Type: rest.TypeString, Operators: []string{rest.OperatorLike}},
+	"notebook_id": {Type: rest.TypeUUID, Operators: []string{rest.OperatorEq, rest.OperatorNeq, rest.OperatorIn}},
+}
+
+// SortFields are the fields GET /notes sorts by, pinned notes stay first.
+var SortFields = []string{"create_time"}
+
 func CreateNote(dto CreateNoteDTO) Note {
 	return Note{
 		UserUUID: dto.UserUUID,
 		Text:     dto.Text,
 		Public:   dto.Public,
+		Format:   dto.Format,
+		Title:    dto.Title,
+		Pinned:   dto.Pinned,
+
+		NotebookUUID: dto.NotebookUUID,
+		PublishAt:    dto.PublishAt,
+		UnpublishAt:  dto.UnpublishAt,
+
+		ExpiresAt:     dto.ExpiresAt,
+		BurnAfterRead: dto.BurnAfterRead,
+
+		Encrypted:  dto.Encrypted,
+		Ciphertext: dto.Ciphertext,
+		Recipients: dto.Recipients,
 	}
 }
 
@@ -31,17 +157,147 @@ func UpdatedNote(dto UpdateNoteDTO) Note {
 		NoteUUID: dto.NoteUUID,
 		Text:     dto.Text,
 		Public:   dto.Public,
+		Format:   dto.Format,
+		Title:    dto.Title,
+		TitleSet: dto.TitleSet,
+		Pinned:   dto.Pinned,
+		Archived: dto.Archived,
+
+		NotebookUUID:  dto.NotebookUUID,
+		InheritPublic: dto.InheritPublic,
+
+		ExpiresAt:     dto.ExpiresAt,
+		BurnAfterRead: dto.BurnAfterRead,
+
+		Ciphertext: dto.Ciphertext,
+		Recipients: dto.Recipients,
 	}
 }
 
 type CreateNoteDTO struct {
-	UserUUID *uuid.UUID `json:"id"`
-	Text     *string    `json:"text"`
-	Public   *bool      `json:"public"`
+	UserUUID *uuid.UUID `json:"id" validate:"-"`
+	// Text is required unless the note is encrypted.
+	Text *string `json:"text" validate:"notblank,rule=text"`
+	// Public is required unless the note goes into a notebook, leaving it
+	// out there makes the note inherit the notebook's visibility.
+	Public *bool `json:"public"`
+	// Format defaults to plain.
+	Format       *string    `json:"format,omitempty" validate:"oneof=plain|markdown"`
+	Title        *string    `json:"title,omitempty" validate:"notblank,rule=title"`
+	Pinned       *bool      `json:"pinned,omitempty"`
+	NotebookUUID *uuid.UUID `json:"notebook_id,omitempty"`
+	PublishAt    *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt  *time.Time `json:"unpublish_at,omitempty"`
+	ExpiresAt    *time.Time `json:"expires_at,omitempty"`
+	// BurnAfterRead defaults to false.
+	BurnAfterRead *bool `json:"burn_after_read,omitempty"`
+	// Encrypted can't be changed later, encrypted notes need Ciphertext and
+	// Recipients including the owner instead of Text.
+	Encrypted  *bool       `json:"encrypted,omitempty"`
+	Ciphertext []byte      `json:"ciphertext,omitempty"`
+	Recipients []Recipient `json:"recipients,omitempty"`
 }
 
 type UpdateNoteDTO struct {
-	NoteUUID *uuid.UUID `json:"id"`
-	Text     *string    `json:"text,omitempty"`
-	Public   *bool      `json:"public,omitempty"`
+	NoteUUID *uuid.UUID `json:"id" validate:"-"`
+	Text     *string    `json:"text,omitempty" validate:"notblank,rule=text"`
+	// Public clears the pending schedule if it conflicts with it, see
+	// ScheduleConflicts.
+	Public *bool   `json:"public,omitempty"`
+	Format *string `json:"format,omitempty" validate:"oneof=plain|markdown"`
+	Title  *string `json:"title,omitempty" validate:"notblank,rule=title"`
+	// TitleSet is set when the request has a title, "title": null removes
+	// it.
+	TitleSet bool  `json:"-"`
+	Pinned   *bool `json:"pinned,omitempty"`
+	Archived *bool `json:"archived,omitempty"`
+	// NotebookUUID moves the note, the nil UUID moves it to the root.
+	NotebookUUID *uuid.UUID `json:"notebook_id,omitempty"`
+	// InheritPublic set to true makes the note follow its notebook again,
+	// setting Public overrides the notebook.
+	InheritPublic *bool `json:"inherit_public,omitempty"`
+	// ExpiresAt can be moved but not removed.
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	BurnAfterRead *bool      `json:"burn_after_read,omitempty"`
+	// Ciphertext and Recipients replace those of an encrypted note.
+	Ciphertext []byte      `json:"ciphertext,omitempty"`
+	Recipients []Recipient `json:"recipients,omitempty"`
+}
+
+// UnmarshalJSON sets TitleSet, "title": null and a missing title both leave
+// Title nil.
+func (dto *UpdateNoteDTO) UnmarshalJSON(data []byte) error {
+	type plain UpdateNoteDTO
+	var fields struct {
+		plain
+		Title json.RawMessage `json:"title"`
+	}
+	// The caller's decoder options don't reach this far, unknown fields are
+	// rejected here like validator.DecodeJSON does for other bodies.
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fields); err != nil {
+		return err
+	}
+	*dto = UpdateNoteDTO(fields.plain)
+	dto.TitleSet = fields.Title != nil
+	if dto.TitleSet && string(fields.Title) != "null" {
+		if err := json.Unmarshal(fields.Title, &dto.Title); err != nil {
+			var typeErr *json.UnmarshalTypeError
+			if errors.As(err, &typeErr) {
+				typeErr.Field = "title"
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// ScheduleDTO replaces the schedule of a note, leaving a time out clears it.
+type ScheduleDTO struct {
+	NoteUUID    *uuid.UUID `json:"id" validate:"-"`
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+}
+
+// Transition is a note whose schedule was applied, WasPublic is the
+// visibility it had before.
+type Transition struct {
+	Note      Note
+	WasPublic bool
+}
+
+// DuePublic applies the schedule times up to now to the visibility public,
+// the later one wins when both passed.
+func DuePublic(public bool, publishAt, unpublishAt *time.Time, now time.Time) bool {
+	publishDue := publishAt != nil && !publishAt.After(now)
+	unpublishDue := unpublishAt != nil && !unpublishAt.After(now)
+	switch {
+	case publishDue && unpublishDue:
+		return publishAt.After(*unpublishAt)
+	case publishDue:
+		return true
+	case unpublishDue:
+		return false
+	}
+	return public
+}
+
+// ScheduleConflicts reports whether a schedule contradicts setting the
+// visibility to public by hand: its next time would publish a public note
+// or unpublish a private one, or a time passed that the Scheduler hasn't
+// applied yet and would apply over the change.
+func ScheduleConflicts(public bool, publishAt, unpublishAt *time.Time, now time.Time) bool {
+	if publishAt != nil && !publishAt.After(now) || unpublishAt != nil && !unpublishAt.After(now) {
+		return true
+	}
+	publishFirst := publishAt != nil && (unpublishAt == nil || publishAt.Before(*unpublishAt))
+	return publishFirst && public || !publishFirst && unpublishAt != nil && !public
+}
+
+// IsEmpty reports whether the update would not change anything.
+func (dto UpdateNoteDTO) IsEmpty() bool {
+	return dto.Text == nil && dto.Public == nil && dto.Format == nil && dto.Title == nil && !dto.TitleSet &&
+		dto.Pinned == nil && dto.Archived == nil && dto.NotebookUUID == nil && dto.InheritPublic == nil &&
+		dto.ExpiresAt == nil && dto.BurnAfterRead == nil && dto.Ciphertext == nil && dto.Recipients == nil
 }
@@ -0,0 +1,66 @@
+package note
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestUpdateNoteDTOTitleSet(t *testing.T) {
+	tests := []struct {
+		body      string
+		wantSet   bool
+		wantTitle string
+	}{
+		{`{"pinned": true}`, false, ""},
+		{`{"title": null}`, true, ""},
+		{`{"title": "new"}`, true, "new"},
+	}
+	for _, tt := range tests {
+		var dto UpdateNoteDTO
+		if err := json.Unmarshal([]byte(tt.body), &dto); err != nil {
+			t.Fatalf("Unmarshal(%s) error = %v", tt.body, err)
+		}
+		title := ""
+		if dto.Title != nil {
+			title = *dto.Title
+		}
+		if dto.TitleSet != tt.wantSet || title != tt.wantTitle {
+			t.Errorf("Unmarshal(%s) = TitleSet %v, Title %q, want %v, %q", tt.body, dto.TitleSet, title,
+				tt.wantSet, tt.wantTitle)
+		}
+		if dto.IsEmpty() {
+			t.Errorf("Unmarshal(%s).IsEmpty() = true", tt.body)
+		}
+	}
+	var dto UpdateNoteDTO
+	if err := json.Unmarshal([]byte(`{"pinned": true}`), &dto); err != nil || dto.Pinned == nil || !*dto.Pinned {
+		t.Errorf("Unmarshal() dropped the other fields: %+v, %v", dto, err)
+	}
+}
+
+func TestScheduleConflicts(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	past, soon, later := now.Add(-time.Minute), now.Add(time.Hour), now.Add(2*time.Hour)
+	tests := []struct {
+		name                   string
+		public                 bool
+		publishAt, unpublishAt *time.Time
+		want                   bool
+	}{
+		{"no schedule", true, nil, nil, false},
+		{"publish a public note", true, &soon, nil, true},
+		{"publish a private note", false, &soon, nil, false},
+		{"unpublish a public note", true, nil, &soon, false},
+		{"unpublish a private note", false, nil, &soon, true},
+		{"publish first on a private note", false, &soon, &later, false},
+		{"publish first on a public note", true, &soon, &later, true},
+		{"unpublish first on a private note", false, &later, &soon, true},
+		{"passed time not applied yet", true, nil, &past, true},
+	}
+	for _, tt := range tests {
+		if got := ScheduleConflicts(tt.public, tt.publishAt, tt.unpublishAt, now); got != tt.want {
+			t.Errorf("%s: ScheduleConflicts() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
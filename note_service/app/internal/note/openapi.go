@@ -0,0 +1,225 @@
+package note
+
+import (
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/openapi"
+)
+
+// Operations only reference component schemas by name, Describe registers
+// the Go types behind them.
+func (h *Handler) Describe(b *openapi.Builder) {
+	b.Schema(Note{})
+	b.Schema(Notes{})
+	b.Schema(CreateNoteDTO{})
+	b.Schema(UpdateNoteDTO{})
+	b.Schema(ScheduleDTO{})
+	b.Schema(Links{})
+	b.Schema(Graph{})
+	b.Schema(apperror.Problem{})
+	b.AddRoutes(h.Routes())
+}
+
+var rateLimitHeaders = map[string]openapi.Header{
+	"RateLimit-Limit":     {Schema: &openapi.Schema{Type: "integer"}},
+	"RateLimit-Remaining": {Schema: &openapi.Schema{Type: "integer"}},
+	"RateLimit-Reset":     {Schema: &openapi.Schema{Type: "integer"}},
+}
+
+var pageHeaders = map[string]openapi.Header{
+	"X-Total-Count":       {Description: "Number of notes before paging", Schema: &openapi.Schema{Type: "integer"}},
+	"RateLimit-Limit":     {Schema: &openapi.Schema{Type: "integer"}},
+	"RateLimit-Remaining": {Schema: &openapi.Schema{Type: "integer"}},
+	"RateLimit-Reset":     {Schema: &openapi.Schema{Type: "integer"}},
+}
+
+func tooManyRequests() openapi.Response {
+	resp := openapi.Problem("Rate limit exceeded")
+	resp.Headers = map[string]openapi.Header{
+		"Retry-After": {Schema: &openapi.Schema{Type: "integer"}},
+	}
+	return resp
+}
+
+var (
+	getNotesOperation = openapi.Operation{
+		OperationID: "listNotes",
+		Summary:     "List public notes and the caller's private notes",
+		Description: "Burn-after-read notes are only listed for their owner.",
+		Tags:        []string{"notes"},
+		Security:    openapi.BearerAuth(),
+		Parameters: []openapi.Parameter{
+			{Name: "limit", In: "query", Description: "Maximum number of notes to return",
+				Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "offset", In: "query", Description: "Number of notes to skip",
+				Schema: &openapi.Schema{Type: "integer"}},
+			{Name: "archived", In: "query", Description: "Include archived notes",
+				Schema: &openapi.Schema{Type: "boolean"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Notes visible to the caller, pinned notes first", Headers: pageHeaders,
+				Content: openapi.JSON(openapi.Ref("Notes"))},
+			"404": openapi.Problem("No visible notes"),
+			"422": openapi.Problem("Invalid paging, archived, filter or sort parameter"),
+			"429": tooManyRequests(),
+		},
+	}
+	getNoteOperation = openapi.Operation{
+		OperationID: "getNote",
+		Summary:     "Get a note by id",
+		Description: "Reading a burn-after-read note of another user deletes it, later reads get 404. " +
+			"Expired notes are not found either. Encrypted notes are always answered with JSON.",
+		Tags:     []string{"notes"},
+		Security: openapi.BearerAuth(),
+		Parameters: []openapi.Parameter{
+			{Name: "render", In: "query", Description: "html returns the note rendered to sanitized HTML",
+				Schema: &openapi.Schema{Type: "string", Enum: []interface{}{"html"}}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The note, as JSON or in the representation asked for by render or Accept",
+				Headers: rateLimitHeaders, Content: map[string]openapi.MediaType{
+					"application/json": {Schema: openapi.Ref("Note")},
+					"text/html":        {Schema: &openapi.Schema{Type: "string"}},
+					"text/markdown":    {Schema: &openapi.Schema{Type: "string"}},
+					"text/plain":       {Schema: &openapi.Schema{Type: "string"}},
+				}},
+			"400": openapi.Problem("Malformed note id"),
+			"403": openapi.Problem("The note is not accessible to the caller"),
+			"404": openapi.Problem("Note not found, expired or burnt"),
+			"406": openapi.Problem("None of the accepted representations is available"),
+			"422": openapi.Problem("Invalid render parameter"),
+			"429": tooManyRequests(),
+		},
+	}
+	streamNotesOperation = openapi.Operation{
+		OperationID: "streamNotes",
+		Summary:     "Receive changes of visible notes as Server-Sent Events",
+		Description: "Events are named after the change (note.created, note.updated, note.published, " +
+			"note.unpublished, note.scheduled, note.deleted) and carry the event as JSON. Send " +
+			"Last-Event-ID to resume, a reset event means the missed events are gone and notes should " +
+			"be reloaded.",
+		Tags:     []string{"notes"},
+		Security: openapi.BearerAuth(),
+		Parameters: []openapi.Parameter{
+			{Name: "Last-Event-ID", In: "header", Description: "Id of the last event received",
+				Schema: &openapi.Schema{Type: "string"}},
+			{Name: "last_event_id", In: "query", Description: "Same as Last-Event-ID for clients that can't set headers",
+				Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Event stream", Headers: rateLimitHeaders,
+				Content: map[string]openapi.MediaType{"text/event-stream": {Schema: &openapi.Schema{Type: "string"}}}},
+			"429": tooManyRequests(),
+		},
+	}
+	getLinksOperation = openapi.Operation{
+		OperationID: "listNoteLinks",
+		Summary:     "List the notes a note links to with [[id]]",
+		Description: "Links to notes the caller can't see are left out, links to notes that don't exist " +
+			"are reported as broken.",
+		Tags:     []string{"notes"},
+		Security: openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Links", Headers: rateLimitHeaders, Content: openapi.JSON(openapi.Ref("Links"))},
+			"400": openapi.Problem("Malformed note id"),
+			"403": openapi.Problem("The note is not accessible to the caller"),
+			"404": openapi.Problem("Note not found"),
+			"429": tooManyRequests(),
+		},
+	}
+	getBacklinksOperation = openapi.Operation{
+		OperationID: "listNoteBacklinks",
+		Summary:     "List the notes visible to the caller that link to a note",
+		Tags:        []string{"notes"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Linking notes, newest first", Headers: rateLimitHeaders,
+				Content: openapi.JSON(openapi.Ref("Notes"))},
+			"400": openapi.Problem("Malformed note id"),
+			"403": openapi.Problem("The note is not accessible to the caller"),
+			"404": openapi.Problem("Note not found"),
+			"429": tooManyRequests(),
+		},
+	}
+	getGraphOperation = openapi.Operation{
+		OperationID: "getNoteGraph",
+		Summary:     "Export the caller's notes and their links as a graph",
+		Description: "Every note of the caller is a node. Linked notes of other users that the caller can " +
+			"see are external nodes, broken edges point at notes that don't exist.",
+		Tags:     []string{"notes"},
+		Security: openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Nodes and edges", Headers: rateLimitHeaders, Content: openapi.JSON(openapi.Ref("Graph"))},
+			"401": openapi.Problem("Authentication required"),
+			"429": tooManyRequests(),
+		},
+	}
+	createNoteOperation = openapi.Operation{
+		OperationID: "createNote",
+		Summary:     "Create a note",
+		Description: "An encrypted note is encrypted by the client: it has a base64 ciphertext instead of " +
+			"text and a recipient with a wrapped key for each user able to decrypt it, the owner included. " +
+			"The server stores both as they come, so encrypted notes aren't searched, linked or rendered.",
+		Tags:        []string{"notes"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("CreateNoteDTO"))},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "Created", Headers: map[string]openapi.Header{
+				"Location": {Description: "URL of the new note", Schema: &openapi.Schema{Type: "string"}},
+			}},
+			"400": openapi.Problem("Malformed request body"),
+			"401": openapi.Problem("Authentication required"),
+			"422": openapi.Problem("Validation failed"),
+			"429": tooManyRequests(),
+		},
+	}
+	updateNoteOperation = openapi.Operation{
+		OperationID: "updateNote",
+		Summary:     "Partially update a note",
+		Tags:        []string{"notes"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("UpdateNoteDTO"))},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Updated"},
+			"400": openapi.Problem("Malformed request body or note id"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The note is not accessible to the caller"),
+			"404": openapi.Problem("Note not found"),
+			"422": openapi.Problem("Validation failed"),
+			"429": tooManyRequests(),
+		},
+	}
+	scheduleNoteOperation = openapi.Operation{
+		OperationID: "scheduleNote",
+		Summary:     "Schedule when a note gets published or unpublished",
+		Description: "Replaces publish_at and unpublish_at, a time left out clears it. Times must lie in the " +
+			"future and the first one must change the visibility of the note. Passed times are applied " +
+			"within seconds, emitting note.updated and note.published or note.unpublished, and the note " +
+			"stops inheriting its visibility from its notebook.",
+		Tags:        []string{"notes"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("ScheduleDTO"))},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The scheduled note", Headers: rateLimitHeaders, Content: openapi.JSON(openapi.Ref("Note"))},
+			"400": openapi.Problem("Malformed request body or note id"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The note belongs to another user"),
+			"404": openapi.Problem("Note not found"),
+			"422": openapi.Problem("Invalid schedule"),
+			"429": tooManyRequests(),
+		},
+	}
+	deleteNoteOperation = openapi.Operation{
+		OperationID: "deleteNote",
+		Summary:     "Delete a note",
+		Tags:        []string{"notes"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Deleted"},
+			"400": openapi.Problem("Malformed note id"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The note is not accessible to the caller"),
+			"404": openapi.Problem("Note not found"),
+			"429": tooManyRequests(),
+		},
+	}
+)
@@ -0,0 +1,79 @@
+package note
+
+import (
+	"context"
+	"note_service/app/pkg/logging"
+	"sync"
+	"time"
+)
+
+type ReaperConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// Reaper deletes expired notes. Reads already treat them as gone, so
+// PollInterval only bounds how long they stay in the database.
+type Reaper struct {
+	storage   Storage
+	publisher EventPublisher
+	cfg       ReaperConfig
+	logger    logging.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewReaper(storage Storage, publisher EventPublisher, cfg ReaperConfig, logger logging.Logger) *Reaper {
+	return &Reaper{storage: storage, publisher: publisher, cfg: cfg, logger: logger}
+}
+
+func (r *Reaper) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.poll(ctx)
+			}
+		}
+	}()
+}
+
+func (r *Reaper) Close() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	return nil
+}
+
+func (r *Reaper) poll(ctx context.Context) {
+	for {
+		expired, err := r.storage.DeleteExpired(ctx, time.Now(), r.cfg.BatchSize)
+		if err != nil {
+			r.logger.Errorf("failed to delete expired notes: %v", err)
+			return
+		}
+		if len(expired) > 0 {
+			r.logger.Infof("deleted %d expired notes", len(expired))
+			if r.publisher != nil {
+				events := make([]Event, 0, len(expired))
+				for _, n := range expired {
+					events = append(events, NewEvent(EventDeleted, n.Redacted()))
+				}
+				r.publisher.Publish(ctx, events...)
+			}
+		}
+		if len(expired) < r.cfg.BatchSize {
+			return
+		}
+	}
+}
@@ -0,0 +1,218 @@
+package note
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+)
+
+var _ EventPublisher = &Renderer{}
+
+// Renderer turns notes into sanitized HTML. Goldmark drops raw HTML and
+// dangerous link destinations while rendering, the bluemonday allowlist then
+// runs over the output as well so a renderer bug can't introduce markup the
+// policy doesn't know.
+//
+// Results are cached per note together with a digest of the content, events
+// evict entries early but a stale entry is never served either way.
+type Renderer struct {
+	md     goldmark.Markdown
+	policy *bluemonday.Policy
+	csp    string
+
+	mu      sync.Mutex
+	size    int
+	entries map[uuid.UUID]*list.Element
+	order   *list.List
+}
+
+type renderEntry struct {
+	noteUUID uuid.UUID
+	digest   [sha256.Size]byte
+	html     string
+}
+
+// NewRenderer keeps up to cacheSize rendered notes. A non-empty imageProxy
+// makes every image load through it, with the original address in its url
+// query parameter. Without one, remote images are rendered as links to them
+// so reading a note doesn't call third parties from the reader's browser.
+func NewRenderer(cacheSize int, imageProxy string) (*Renderer, error) {
+	policy := bluemonday.UGCPolicy()
+	policy.AllowURLSchemes("http", "https", "mailto")
+	policy.RequireParseableURLs(true)
+	// outbound links get rel="nofollow noreferrer noopener" target="_blank"
+	policy.RequireNoFollowOnLinks(true)
+	policy.RequireNoReferrerOnLinks(true)
+	policy.AddTargetBlankToFullyQualifiedLinks(true)
+	imgSrc := "'self'"
+	var parserOptions []parser.Option
+	if imageProxy == "" {
+		parserOptions = append(parserOptions, parser.WithASTTransformers(util.Prioritized(remoteImages{}, 100)))
+	} else {
+		proxy, err := url.Parse(imageProxy)
+		if err != nil {
+			return nil, err
+		}
+		if !proxy.IsAbs() || proxy.Host == "" {
+			return nil, fmt.Errorf("image proxy %q is not an absolute URL", imageProxy)
+		}
+		imgSrc += " " + proxy.Scheme + "://" + proxy.Host
+		policy.RewriteSrc(func(u *url.URL) {
+			if !u.IsAbs() {
+				return
+			}
+			q := proxy.Query()
+			q.Set("url", u.String())
+			*u = *proxy
+			u.RawQuery = q.Encode()
+		})
+	}
+
+	return &Renderer{
+		md:      goldmark.New(goldmark.WithExtensions(extension.GFM), goldmark.WithParserOptions(parserOptions...)),
+		policy:  policy,
+		csp:     "default-src 'none'; img-src " + imgSrc + "; style-src 'unsafe-inline'",
+		size:    cacheSize,
+		entries: make(map[uuid.UUID]*list.Element),
+		order:   list.New(),
+	}, nil
+}
+
+// HTML returns the sanitized HTML of n. Plain notes are escaped and keep
+// their line breaks. Burn-after-read notes are never cached.
+func (r *Renderer) HTML(n Note) (string, error) {
+	text, format := "", FormatPlain
+	if n.Text != nil {
+		text = *n.Text
+	}
+	if n.Format != nil {
+		format = *n.Format
+	}
+	digest := sha256.Sum256([]byte(format + "\x00" + text))
+	cache := n.NoteUUID != nil && (n.BurnAfterRead == nil || !*n.BurnAfterRead)
+
+	if cache {
+		if out, ok := r.cached(*n.NoteUUID, digest); ok {
+			return out, nil
+		}
+	}
+
+	var out string
+	if format == FormatMarkdown {
+		var buf bytes.Buffer
+		if err := r.md.Convert([]byte(text), &buf); err != nil {
+			return "", err
+		}
+		out = r.policy.Sanitize(buf.String())
+	} else {
+		out = "<p>" + strings.ReplaceAll(html.EscapeString(text), "\n", "<br>\n") + "</p>\n"
+	}
+
+	if cache {
+		r.store(*n.NoteUUID, digest, out)
+	}
+	return out, nil
+}
+
+// ContentSecurityPolicy is the policy HTML returned by the renderer is served
+// with, it only lets images load from the service and the image proxy.
+func (r *Renderer) ContentSecurityPolicy() string {
+	return r.csp
+}
+
+func (r *Renderer) Publish(ctx context.Context, events ...Event) {
+	for _, event := range events {
+		if event.Note.NoteUUID != nil && event.Type != EventCreated {
+			r.evict(*event.Note.NoteUUID)
+		}
+	}
+}
+
+func (r *Renderer) cached(noteUUID uuid.UUID, digest [sha256.Size]byte) (string, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	el, ok := r.entries[noteUUID]
+	if !ok {
+		return "", false
+	}
+	entry := el.Value.(*renderEntry)
+	if entry.digest != digest {
+		return "", false
+	}
+	r.order.MoveToFront(el)
+	return entry.html, true
+}
+
+func (r *Renderer) store(noteUUID uuid.UUID, digest [sha256.Size]byte, out string) {
+	if r.size <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if el, ok := r.entries[noteUUID]; ok {
+		el.Value = &renderEntry{noteUUID: noteUUID, digest: digest, html: out}
+		r.order.MoveToFront(el)
+		return
+	}
+	r.entries[noteUUID] = r.order.PushFront(&renderEntry{noteUUID: noteUUID, digest: digest, html: out})
+	for r.order.Len() > r.size {
+		oldest := r.order.Back()
+		r.order.Remove(oldest)
+		delete(r.entries, oldest.Value.(*renderEntry).noteUUID)
+	}
+}
+
+func (r *Renderer) evict(noteUUID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if el, ok := r.entries[noteUUID]; ok {
+		r.order.Remove(el)
+		delete(r.entries, noteUUID)
+	}
+}
+
+// remoteImages turns images with an absolute address into links to them.
+type remoteImages struct{}
+
+func (remoteImages) Transform(doc *ast.Document, _ text.Reader, _ parser.Context) {
+	var images []*ast.Image
+	ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if img, ok := n.(*ast.Image); ok && entering && remoteURL(string(img.Destination)) {
+			images = append(images, img)
+		}
+		return ast.WalkContinue, nil
+	})
+	for _, img := range images {
+		link := ast.NewLink()
+		link.Destination, link.Title = img.Destination, img.Title
+		for c := img.FirstChild(); c != nil; {
+			next := c.NextSibling()
+			link.AppendChild(link, c)
+			c = next
+		}
+		if !link.HasChildren() {
+			link.AppendChild(link, ast.NewString(img.Destination))
+		}
+		img.Parent().ReplaceChild(img.Parent(), img, link)
+	}
+}
+
+func remoteURL(raw string) bool {
+	u, err := url.Parse(raw)
+	return err != nil || u.IsAbs() || u.Host != ""
+}
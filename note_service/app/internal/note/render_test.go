@@ -0,0 +1,67 @@
+package note
+
+import (
+	"strings"
+	"testing"
+)
+
+func renderMarkdown(t *testing.T, r *Renderer, text string) string {
+	t.Helper()
+	format := FormatMarkdown
+	out, err := r.HTML(Note{Text: &text, Format: &format})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func TestRendererBlocksRemoteImages(t *testing.T) {
+	r, err := NewRenderer(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := renderMarkdown(t, r, "![logo](https://tracker.test/a.png) ![](//tracker.test/b.png) ![local](/attachments/c)")
+
+	if strings.Contains(out, `<img src="https://tracker.test`) || strings.Contains(out, `<img src="//tracker.test`) {
+		t.Errorf("remote image rendered: %s", out)
+	}
+	if !strings.Contains(out, `href="https://tracker.test/a.png"`) || !strings.Contains(out, ">logo</a>") {
+		t.Errorf("remote image not turned into a link: %s", out)
+	}
+	if !strings.Contains(out, `<img src="/attachments/c" alt="local">`) {
+		t.Errorf("local image dropped: %s", out)
+	}
+	if csp := r.ContentSecurityPolicy(); !strings.Contains(csp, "img-src 'self';") {
+		t.Errorf("ContentSecurityPolicy() = %q", csp)
+	}
+}
+
+func TestRendererProxiesImages(t *testing.T) {
+	r, err := NewRenderer(0, "https://proxy.test/img")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := renderMarkdown(t, r, "![logo](https://tracker.test/a.png)")
+
+	if !strings.Contains(out, `<img src="https://proxy.test/img?url=https%3A%2F%2Ftracker.test%2Fa.png"`) {
+		t.Errorf("image not proxied: %s", out)
+	}
+	if csp := r.ContentSecurityPolicy(); !strings.Contains(csp, "img-src 'self' https://proxy.test;") {
+		t.Errorf("ContentSecurityPolicy() = %q", csp)
+	}
+}
+
+func TestRendererLinks(t *testing.T) {
+	r, err := NewRenderer(0, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := renderMarkdown(t, r, "[out](https://example.test) [in](/notes/x)")
+
+	if !strings.Contains(out, `<a href="https://example.test" rel="nofollow noreferrer noopener" target="_blank">`) {
+		t.Errorf("outbound link: %s", out)
+	}
+	if !strings.Contains(out, `<a href="/notes/x" rel="nofollow noreferrer">`) {
+		t.Errorf("local link: %s", out)
+	}
+}
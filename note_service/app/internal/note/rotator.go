@@ -0,0 +1,79 @@
+package note
+
+import (
+	"context"
+	"note_service/app/pkg/logging"
+	"sync"
+	"time"
+)
+
+type RotatorConfig struct {
+	PollInterval  time.Duration
+	BatchSize     int
+	DataKeyMaxAge time.Duration
+}
+
+// Rotator keeps note text encrypted at rest with current keys: it rewraps
+// data keys after the master key changed, replaces data keys older than
+// DataKeyMaxAge and re-encrypts the notes that used them. Private notes
+// written before encryption was enabled are encrypted by it as well.
+type Rotator struct {
+	storage Storage
+	cfg     RotatorConfig
+	logger  logging.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRotator(storage Storage, cfg RotatorConfig, logger logging.Logger) *Rotator {
+	return &Rotator{storage: storage, cfg: cfg, logger: logger}
+}
+
+// Start runs a first rotation right away, so enabling encryption or adding
+// a master key takes effect without waiting for PollInterval.
+func (r *Rotator) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.poll(ctx)
+		ticker := time.NewTicker(r.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.poll(ctx)
+			}
+		}
+	}()
+}
+
+func (r *Rotator) Close() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	return nil
+}
+
+func (r *Rotator) poll(ctx context.Context) {
+	total := 0
+	for ctx.Err() == nil {
+		n, err := r.storage.RotateKeys(ctx, time.Now(), r.cfg.DataKeyMaxAge, r.cfg.BatchSize)
+		total += n
+		if err != nil {
+			r.logger.Errorf("failed to rotate note keys: %v", err)
+			break
+		}
+		if n == 0 {
+			break
+		}
+	}
+	if total > 0 {
+		r.logger.Infof("rotated %d note keys and texts", total)
+	}
+}
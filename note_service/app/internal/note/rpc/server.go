@@ -0,0 +1,224 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	notev1 "note_service/app/api/note/v1"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/user"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+var _ notev1.NoteServiceServer = &Server{}
+
+type Server struct {
+	notev1.UnimplementedNoteServiceServer
+	NoteService note.Service
+	Logger      logging.Logger
+}
+
+func NewGRPCServer(noteService note.Service, userClient user_client.UserClient, logger logging.Logger,
+	opts ...grpc.ServerOption) *grpc.Server {
+	opts = append(opts,
+		grpc.ChainUnaryInterceptor(user.UnaryAuthentication(userClient)),
+		grpc.ChainStreamInterceptor(user.StreamAuthentication(userClient)),
+	)
+	s := grpc.NewServer(opts...)
+	notev1.RegisterNoteServiceServer(s, &Server{NoteService: noteService, Logger: logger})
+	return s
+}
+
+func (s *Server) Create(ctx context.Context, req *notev1.CreateNoteRequest) (*notev1.CreateNoteResponse, error) {
+	userUUID, err := authorized(ctx)
+	if err != nil {
+		return nil, err
+	}
+	noteUUID, err := s.NoteService.Create(ctx, note.CreateNoteDTO{
+		UserUUID: &userUUID,
+		Text:     req.Text,
+		Public:   req.Public,
+		Title:    req.Title,
+		Pinned:   req.Pinned,
+	})
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+	return &notev1.CreateNoteResponse{Id: noteUUID}, nil
+}
+
+func (s *Server) GetOne(ctx context.Context, req *notev1.GetNoteRequest) (*notev1.Note, error) {
+	noteUUID, err := parseUUID(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	n, err := s.NoteService.GetOne(ctx, noteUUID, userFromContext(ctx))
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+	return toProto(n), nil
+}
+
+func (s *Server) GetMany(ctx context.Context, req *notev1.GetNotesRequest) (*notev1.GetNotesResponse, error) {
+	notes, err := s.NoteService.GetMany(ctx, userFromContext(ctx), listOptions(req))
+	if err != nil {
+		return nil, s.toStatus(err)
+	}
+	resp := &notev1.GetNotesResponse{Notes: make([]*notev1.Note, 0, len(notes.Notes))}
+	for i := range notes.Notes {
+		resp.Notes = append(resp.Notes, toProto(&notes.Notes[i]))
+	}
+	return resp, nil
+}
+
+func (s *Server) StreamNotes(req *notev1.GetNotesRequest, stream notev1.NoteService_StreamNotesServer) error {
+	ctx := stream.Context()
+	notes, err := s.NoteService.GetMany(ctx, userFromContext(ctx), listOptions(req))
+	if err != nil {
+		return s.toStatus(err)
+	}
+	for i := range notes.Notes {
+		if err := stream.Send(toProto(&notes.Notes[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Server) Update(ctx context.Context, req *notev1.UpdateNoteRequest) (*emptypb.Empty, error) {
+	userUUID, err := authorized(ctx)
+	if err != nil {
+		return nil, err
+	}
+	noteUUID, err := parseUUID(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	dto := note.UpdateNoteDTO{NoteUUID: &noteUUID, Text: req.Text, Public: req.Public, Pinned: req.Pinned,
+		Archived: req.Archived}
+	if req.Title != nil {
+		dto.TitleSet = true
+		if *req.Title != "" {
+			dto.Title = req.Title
+		}
+	}
+	if err := s.NoteService.Update(ctx, dto, userUUID); err != nil {
+		return nil, s.toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (s *Server) Delete(ctx context.Context, req *notev1.DeleteNoteRequest) (*emptypb.Empty, error) {
+	userUUID, err := authorized(ctx)
+	if err != nil {
+		return nil, err
+	}
+	noteUUID, err := parseUUID(req.Id)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.NoteService.Delete(ctx, noteUUID, userUUID); err != nil {
+		return nil, s.toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func listOptions(req *notev1.GetNotesRequest) note.ListOptions {
+	return note.ListOptions{IncludeArchived: req.GetIncludeArchived()}
+}
+
+func userFromContext(ctx context.Context) uuid.UUID {
+	userUUID, _ := ctx.Value("userUUID").(uuid.UUID)
+	return userUUID
+}
+
+func authorized(ctx context.Context) (uuid.UUID, error) {
+	userUUID := userFromContext(ctx)
+	if userUUID == uuid.Nil {
+		return userUUID, status.Error(codes.Unauthenticated, "authentication required")
+	}
+	return userUUID, nil
+}
+
+func parseUUID(s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return id, status.Error(codes.InvalidArgument, "invalid uuid type")
+	}
+	return id, nil
+}
+
+func toProto(n *note.Note) *notev1.Note {
+	pb := &notev1.Note{}
+	if n.NoteUUID != nil {
+		pb.Id = n.NoteUUID.String()
+	}
+	if n.UserUUID != nil {
+		pb.UserId = n.UserUUID.String()
+	}
+	if n.CreateTime != nil {
+		pb.CreateTime = timestamppb.New(*n.CreateTime)
+	}
+	if n.Text != nil {
+		pb.Text = *n.Text
+	}
+	if n.Public != nil {
+		pb.Public = *n.Public
+	}
+	if n.Title != nil {
+		pb.Title = *n.Title
+	}
+	if n.Pinned != nil {
+		pb.Pinned = *n.Pinned
+	}
+	if n.Archived != nil {
+		pb.Archived = *n.Archived
+	}
+	return pb
+}
+
+var statusCodes = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnprocessableEntity:   codes.InvalidArgument,
+	http.StatusRequestEntityTooLarge: codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.AlreadyExists,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusBadGateway:            codes.Unavailable,
+}
+
+// toStatus maps an AppError onto a gRPC status the same way
+// apperror.Write maps it onto an HTTP status.
+func (s *Server) toStatus(err error) error {
+	var appErr *apperror.AppError
+	if !errors.As(err, &appErr) {
+		s.Logger.Errorf("grpc call failed: %v", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+	code, ok := statusCodes[appErr.Status]
+	if !ok {
+		s.Logger.Errorf("grpc call failed: %v: %s", err, appErr.DeveloperMessage)
+		return status.Error(codes.Internal, "internal server error")
+	}
+	msg := appErr.Message
+	if len(appErr.Details) > 0 {
+		fields := make([]string, 0, len(appErr.Details))
+		for _, d := range appErr.Details {
+			fields = append(fields, d.Field+": "+d.Message)
+		}
+		msg += ": " + strings.Join(fields, "; ")
+	}
+	return status.Error(code, msg)
+}
@@ -0,0 +1,68 @@
+package rpc
+
+import (
+	"context"
+	"testing"
+
+	notev1 "note_service/app/api/note/v1"
+	"note_service/app/internal/note"
+
+	"github.com/google/uuid"
+)
+
+type fakeService struct {
+	note.Service
+	opts   note.ListOptions
+	update note.UpdateNoteDTO
+}
+
+func (s *fakeService) GetMany(_ context.Context, _ uuid.UUID, opts note.ListOptions) (*note.Notes, error) {
+	s.opts = opts
+	return &note.Notes{}, nil
+}
+
+func (s *fakeService) Update(_ context.Context, dto note.UpdateNoteDTO, _ uuid.UUID) error {
+	s.update = dto
+	return nil
+}
+
+func TestGetManyPassesOptions(t *testing.T) {
+	svc := &fakeService{}
+	s := &Server{NoteService: svc}
+	if _, err := s.GetMany(context.Background(), &notev1.GetNotesRequest{IncludeArchived: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !svc.opts.IncludeArchived {
+		t.Error("GetMany() dropped include_archived")
+	}
+}
+
+func TestUpdateTitle(t *testing.T) {
+	ctx := context.WithValue(context.Background(), "userUUID", uuid.New())
+	empty, title := "", "title"
+	tests := []struct {
+		name      string
+		title     *string
+		wantSet   bool
+		wantTitle *string
+	}{
+		{"absent", nil, false, nil},
+		{"empty clears", &empty, true, nil},
+		{"set", &title, true, &title},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := &fakeService{}
+			s := &Server{NoteService: svc}
+			req := &notev1.UpdateNoteRequest{Id: uuid.NewString(), Title: tt.title}
+			if _, err := s.Update(ctx, req); err != nil {
+				t.Fatal(err)
+			}
+			got := svc.update
+			if got.TitleSet != tt.wantSet || (got.Title == nil) != (tt.wantTitle == nil) ||
+				(got.Title != nil && *got.Title != *tt.wantTitle) {
+				t.Errorf("Update() dto = TitleSet %v, Title %v", got.TitleSet, got.Title)
+			}
+		})
+	}
+}
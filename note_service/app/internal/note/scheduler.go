@@ -0,0 +1,78 @@
+package note
+
+import (
+	"context"
+	"note_service/app/pkg/logging"
+	"sync"
+	"time"
+)
+
+type SchedulerConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+}
+
+// Scheduler publishes and unpublishes notes once their publish_at or
+// unpublish_at passes, so visibility follows the schedule within
+// PollInterval. The storage records the outbox events together with the
+// change, the scheduler hands the same change to publisher.
+type Scheduler struct {
+	storage   Storage
+	publisher EventPublisher
+	cfg       SchedulerConfig
+	logger    logging.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewScheduler(storage Storage, publisher EventPublisher, cfg SchedulerConfig, logger logging.Logger) *Scheduler {
+	return &Scheduler{storage: storage, publisher: publisher, cfg: cfg, logger: logger}
+}
+
+func (s *Scheduler) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.cancel = cancel
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(s.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				s.poll(ctx)
+			}
+		}
+	}()
+}
+
+func (s *Scheduler) Close() error {
+	if s.cancel != nil {
+		s.cancel()
+	}
+	s.wg.Wait()
+	return nil
+}
+
+func (s *Scheduler) poll(ctx context.Context) {
+	for {
+		transitions, err := s.storage.ApplySchedules(ctx, time.Now(), s.cfg.BatchSize)
+		if err != nil {
+			s.logger.Errorf("failed to apply note schedules: %v", err)
+			return
+		}
+		var events []Event
+		for _, t := range transitions {
+			events = append(events, changeEvents(t.Note, t.WasPublic)...)
+		}
+		if len(events) > 0 && s.publisher != nil {
+			s.publisher.Publish(ctx, events...)
+		}
+		if len(transitions) < s.cfg.BatchSize {
+			return
+		}
+	}
+}
@@ -6,35 +6,106 @@ import (
 	"fmt"
 	"note_service/app/internal/apperror"
 	"note_service/app/pkg/logging"
+	"note_service/app/pkg/validator"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 var _ Service = &service{}
 
+// Limits bound what encrypted notes store in place of text, which the text
+// rule of the validator can't check.
+type Limits struct {
+	MaxCiphertextBytes int
+	MaxRecipients      int
+	MaxWrappedKeyBytes int
+}
+
 type service struct {
-	storage Storage
-	logger  logging.Logger
+	storage   Storage
+	validator *validator.Validator
+	limits    Limits
+	publisher EventPublisher
+	logger    logging.Logger
 }
 
-func NewService(noteStorage Storage, logger logging.Logger) (Service, error) {
+func NewService(noteStorage Storage, validator *validator.Validator, limits Limits, publisher EventPublisher,
+	logger logging.Logger) (Service, error) {
 	return &service{
-		storage: noteStorage,
-		logger:  logger,
+		storage:   noteStorage,
+		validator: validator,
+		limits:    limits,
+		publisher: publisher,
+		logger:    logger,
 	}, nil
 }
 
 type Service interface {
 	Create(ctx context.Context, dto CreateNoteDTO) (string, error)
-	GetMany(ctx context.Context, userUUID uuid.UUID) (*Notes, error)
+	GetMany(ctx context.Context, userUUID uuid.UUID, opts ListOptions) (*Notes, error)
 	GetOne(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Note, error)
 	Update(ctx context.Context, dto UpdateNoteDTO, userUUID uuid.UUID) error
 	Delete(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) error
+	GetLinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Links, error)
+	GetBacklinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Notes, error)
+	GetGraph(ctx context.Context, userUUID uuid.UUID) (*Graph, error)
+	// Schedule replaces the publish_at and unpublish_at of a note owned by
+	// userUUID and returns the note.
+	Schedule(ctx context.Context, dto ScheduleDTO, userUUID uuid.UUID) (*Note, error)
 }
 
 func (s service) Create(ctx context.Context, dto CreateNoteDTO) (noteUUID string, err error) {
+	errs := s.validator.Struct(&dto)
+	if dto.Public == nil && dto.NotebookUUID == nil {
+		errs = append(errs, apperror.FieldError{Field: "public", Code: "required", Message: "is required"})
+	}
+	dto.PublishAt, dto.UnpublishAt = scheduleTime(dto.PublishAt), scheduleTime(dto.UnpublishAt)
+	dto.ExpiresAt = scheduleTime(dto.ExpiresAt)
+	errs = append(errs, checkSchedule(dto.Public, dto.PublishAt, dto.UnpublishAt, time.Now())...)
+	errs = append(errs, checkExpiry(dto.ExpiresAt, time.Now())...)
+	if dto.Encrypted != nil && *dto.Encrypted {
+		if dto.Text != nil {
+			errs = append(errs, apperror.FieldError{Field: "text", Code: "conflict",
+				Message: "can't be set on an encrypted note"})
+		}
+		if dto.Ciphertext == nil {
+			errs = append(errs, apperror.FieldError{Field: "ciphertext", Code: "required", Message: "is required"})
+		}
+		if dto.Recipients == nil {
+			errs = append(errs, apperror.FieldError{Field: "recipients", Code: "required", Message: "is required"})
+		}
+		errs = append(errs, s.checkEncrypted(dto.Ciphertext, dto.Recipients, *dto.UserUUID)...)
+	} else {
+		if dto.Text == nil {
+			errs = append(errs, apperror.FieldError{Field: "text", Code: "required", Message: "is required"})
+		}
+		errs = append(errs, checkNotEncrypted(dto.Ciphertext, dto.Recipients)...)
+	}
+	if len(errs) > 0 {
+		return noteUUID, apperror.ValidationError(errs...)
+	}
 	note := CreateNote(dto)
-	err = s.storage.Create(ctx, note)
+	if note.Format == nil {
+		format := FormatPlain
+		note.Format = &format
+	}
+	if note.Pinned == nil {
+		pinned := false
+		note.Pinned = &pinned
+	}
+	archived := false
+	note.Archived = &archived
+	if note.BurnAfterRead == nil {
+		burn := false
+		note.BurnAfterRead = &burn
+	}
+	if note.Encrypted == nil {
+		encrypted := false
+		note.Encrypted = &encrypted
+	}
+	err = s.storage.Create(ctx, &note)
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
 			return noteUUID, err
@@ -42,9 +113,20 @@ func (s service) Create(ctx context.Context, dto CreateNoteDTO) (noteUUID string
 		return noteUUID, fmt.Errorf("failed to create note. error: %w", err)
 	}
 
-	return noteUUID, nil
+	events := []Event{NewEvent(EventCreated, note)}
+	if note.Public != nil && *note.Public {
+		events = append(events, NewEvent(EventPublished, note))
+	}
+	if note.PublishAt != nil || note.UnpublishAt != nil {
+		events = append(events, NewEvent(EventScheduled, note))
+	}
+	s.emit(ctx, events...)
+
+	return note.NoteUUID.String(), nil
 }
 
+// GetOne is a read of the note, burn-after-read notes of other users are
+// deleted by it.
 func (s service) GetOne(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (n *Note, err error) {
 	n, err = s.storage.GetByID(ctx, noteUUID, userUUID)
 
@@ -54,11 +136,33 @@ func (s service) GetOne(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.U
 		}
 		return n, fmt.Errorf("failed to find note by uuid. error: %w", err)
 	}
+	if n.BurnAfterRead == nil || !*n.BurnAfterRead || *n.UserUUID == userUUID {
+		return n, nil
+	}
+	n, err = s.storage.Burn(ctx, noteUUID, userUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to burn note. error: %w", err)
+	}
+	s.emit(ctx, NewEvent(EventDeleted, n.Redacted()))
 	return n, nil
 }
 
-func (s service) GetMany(ctx context.Context, userUUID uuid.UUID) (n *Notes, err error) {
-	n, err = s.storage.GetNotes(ctx, userUUID)
+// visible checks that a note can be read by userUUID without reading it.
+func (s service) visible(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) error {
+	if _, err := s.storage.GetByID(ctx, noteUUID, userUUID); err != nil {
+		if errors.Is(err, apperror.ErrForbidden) || errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to find note by uuid. error: %w", err)
+	}
+	return nil
+}
+
+func (s service) GetMany(ctx context.Context, userUUID uuid.UUID, opts ListOptions) (n *Notes, err error) {
+	n, err = s.storage.GetNotes(ctx, userUUID, opts)
 
 	if err != nil {
 		return n, err
@@ -67,8 +171,45 @@ func (s service) GetMany(ctx context.Context, userUUID uuid.UUID) (n *Notes, err
 }
 
 func (s service) Update(ctx context.Context, dto UpdateNoteDTO, userUUID uuid.UUID) error {
-	note := UpdatedNote(dto)
-	err := s.storage.Update(ctx, note, userUUID)
+	if dto.IsEmpty() {
+		return apperror.ValidationError(apperror.FieldError{
+			Code: "empty_update", Message: "at least one field must be provided"})
+	}
+	errs := s.validator.Struct(&dto)
+	if dto.Public != nil && dto.InheritPublic != nil && *dto.InheritPublic {
+		errs = append(errs, apperror.FieldError{Field: "inherit_public", Code: "conflict",
+			Message: "can't be set together with public"})
+	}
+	dto.ExpiresAt = scheduleTime(dto.ExpiresAt)
+	errs = append(errs, checkExpiry(dto.ExpiresAt, time.Now())...)
+	if len(errs) > 0 {
+		return apperror.ValidationError(errs...)
+	}
+	prev, err := s.storage.GetByID(ctx, *dto.NoteUUID, userUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrForbidden) || errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to update note. error: %w", err)
+	}
+	if *prev.UserUUID != userUUID {
+		return apperror.ErrForbidden
+	}
+	if prev.IsEncrypted() {
+		if dto.Text != nil {
+			errs = append(errs, apperror.FieldError{Field: "text", Code: "conflict",
+				Message: "can't be set on an encrypted note"})
+		}
+		errs = append(errs, s.checkEncrypted(dto.Ciphertext, dto.Recipients, *prev.UserUUID)...)
+	} else {
+		errs = append(errs, checkNotEncrypted(dto.Ciphertext, dto.Recipients)...)
+	}
+	if len(errs) > 0 {
+		return apperror.ValidationError(errs...)
+	}
+
+	updated := UpdatedNote(dto)
+	err = s.storage.Update(ctx, &updated, userUUID)
 
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -76,11 +217,28 @@ func (s service) Update(ctx context.Context, dto UpdateNoteDTO, userUUID uuid.UU
 		}
 		return fmt.Errorf("failed to update note. error: %w", err)
 	}
+
+	events := changeEvents(updated, prev.Public != nil && *prev.Public)
+	if (prev.PublishAt != nil || prev.UnpublishAt != nil) && updated.PublishAt == nil && updated.UnpublishAt == nil {
+		events = append(events, NewEvent(EventScheduled, updated))
+	}
+	s.emit(ctx, events...)
 	return nil
 }
 
 func (s service) Delete(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) error {
-	err := s.storage.Delete(ctx, noteUUID, userUUID)
+	prev, err := s.storage.GetByID(ctx, noteUUID, userUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrForbidden) || errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete note. error: %w", err)
+	}
+	if *prev.UserUUID != userUUID {
+		return apperror.ErrForbidden
+	}
+
+	err = s.storage.Delete(ctx, noteUUID, userUUID)
 
 	if err != nil {
 		if errors.Is(err, apperror.ErrNotFound) {
@@ -88,5 +246,199 @@ func (s service) Delete(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.U
 		}
 		return fmt.Errorf("failed to delete note. error: %w", err)
 	}
+	s.emit(ctx, NewEvent(EventDeleted, *prev))
 	return err
 }
+
+// GetLinks lists what a note links to, the note itself must be visible to
+// the caller like in GetOne.
+func (s service) GetLinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Links, error) {
+	if err := s.visible(ctx, noteUUID, userUUID); err != nil {
+		return nil, err
+	}
+	links, err := s.storage.GetLinks(ctx, noteUUID, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note links. error: %w", err)
+	}
+	return links, nil
+}
+
+func (s service) GetBacklinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Notes, error) {
+	if err := s.visible(ctx, noteUUID, userUUID); err != nil {
+		return nil, err
+	}
+	notes, err := s.storage.GetBacklinks(ctx, noteUUID, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note backlinks. error: %w", err)
+	}
+	return notes, nil
+}
+
+func (s service) GetGraph(ctx context.Context, userUUID uuid.UUID) (*Graph, error) {
+	if userUUID == uuid.Nil {
+		return nil, apperror.ErrUnauthorized
+	}
+	graph, err := s.storage.GetGraph(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get note graph. error: %w", err)
+	}
+	return graph, nil
+}
+
+func (s service) Schedule(ctx context.Context, dto ScheduleDTO, userUUID uuid.UUID) (*Note, error) {
+	prev, err := s.storage.GetByID(ctx, *dto.NoteUUID, userUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrForbidden) || errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to schedule note. error: %w", err)
+	}
+	if *prev.UserUUID != userUUID {
+		return nil, apperror.ErrForbidden
+	}
+	dto.PublishAt, dto.UnpublishAt = scheduleTime(dto.PublishAt), scheduleTime(dto.UnpublishAt)
+	if errs := checkSchedule(prev.Public, dto.PublishAt, dto.UnpublishAt, time.Now()); len(errs) > 0 {
+		return nil, apperror.ValidationError(errs...)
+	}
+
+	scheduled := Note{NoteUUID: dto.NoteUUID, PublishAt: dto.PublishAt, UnpublishAt: dto.UnpublishAt}
+	if err := s.storage.Schedule(ctx, &scheduled, userUUID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to schedule note. error: %w", err)
+	}
+	s.emit(ctx, NewEvent(EventScheduled, scheduled))
+	return &scheduled, nil
+}
+
+// scheduleTime stores schedule and expiry times in UTC to the second.
+func scheduleTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	at := t.UTC().Truncate(time.Second)
+	return &at
+}
+
+// checkSchedule validates a schedule for a note with visibility public, nil
+// while a new note waits for its notebook. Both times lie in the future and
+// whichever passes first has to change the visibility.
+func checkSchedule(public *bool, publishAt, unpublishAt *time.Time, now time.Time) []apperror.FieldError {
+	var errs []apperror.FieldError
+	if publishAt != nil && !publishAt.After(now) {
+		errs = append(errs, apperror.FieldError{Field: "publish_at", Code: "past", Message: "must be in the future"})
+	}
+	if unpublishAt != nil && !unpublishAt.After(now) {
+		errs = append(errs, apperror.FieldError{Field: "unpublish_at", Code: "past", Message: "must be in the future"})
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	if publishAt != nil && unpublishAt != nil && publishAt.Equal(*unpublishAt) {
+		return []apperror.FieldError{{Field: "unpublish_at", Code: "conflict",
+			Message: "can't be the same as publish_at"}}
+	}
+	if public == nil {
+		return nil
+	}
+	publishFirst := publishAt != nil && (unpublishAt == nil || publishAt.Before(*unpublishAt))
+	switch {
+	case publishFirst && *public:
+		return []apperror.FieldError{{Field: "publish_at", Code: "conflict", Message: "note is already public"}}
+	case !publishFirst && unpublishAt != nil && !*public:
+		return []apperror.FieldError{{Field: "unpublish_at", Code: "conflict", Message: "note is not public"}}
+	}
+	return nil
+}
+
+func checkExpiry(expiresAt *time.Time, now time.Time) []apperror.FieldError {
+	if expiresAt != nil && !expiresAt.After(now) {
+		return []apperror.FieldError{{Field: "expires_at", Code: "past", Message: "must be in the future"}}
+	}
+	return nil
+}
+
+// checkEncrypted validates the ciphertext and recipients of an encrypted note
+// of owner, either may be nil when left unchanged. The owner has to stay a
+// recipient or they would lose access to their own note.
+func (s service) checkEncrypted(ciphertext []byte, recipients []Recipient, owner uuid.UUID) []apperror.FieldError {
+	var errs []apperror.FieldError
+	if ciphertext != nil && len(ciphertext) == 0 {
+		errs = append(errs, apperror.FieldError{Field: "ciphertext", Code: "blank", Message: "must not be empty"})
+	}
+	if len(ciphertext) > s.limits.MaxCiphertextBytes {
+		errs = append(errs, apperror.FieldError{Field: "ciphertext", Code: "too_large",
+			Message: fmt.Sprintf("must be at most %d bytes", s.limits.MaxCiphertextBytes)})
+	}
+	if recipients == nil {
+		return errs
+	}
+	if len(recipients) > s.limits.MaxRecipients {
+		return append(errs, apperror.FieldError{Field: "recipients", Code: "too_many",
+			Message: fmt.Sprintf("must be at most %d", s.limits.MaxRecipients)})
+	}
+	seen := make(map[uuid.UUID]bool, len(recipients))
+	for i, r := range recipients {
+		field := fmt.Sprintf("recipients[%d]", i)
+		switch {
+		case r.UserUUID == nil:
+			errs = append(errs, apperror.FieldError{Field: field + ".user_id", Code: "required", Message: "is required"})
+		case seen[*r.UserUUID]:
+			errs = append(errs, apperror.FieldError{Field: field + ".user_id", Code: "duplicate",
+				Message: "is listed more than once"})
+		default:
+			seen[*r.UserUUID] = true
+		}
+		if strings.TrimSpace(r.Algorithm) == "" {
+			errs = append(errs, apperror.FieldError{Field: field + ".algorithm", Code: "required", Message: "is required"})
+		}
+		if len(r.WrappedKey) == 0 {
+			errs = append(errs, apperror.FieldError{Field: field + ".wrapped_key", Code: "required",
+				Message: "is required"})
+		}
+		if len(r.WrappedKey) > s.limits.MaxWrappedKeyBytes {
+			errs = append(errs, apperror.FieldError{Field: field + ".wrapped_key", Code: "too_large",
+				Message: fmt.Sprintf("must be at most %d bytes", s.limits.MaxWrappedKeyBytes)})
+		}
+	}
+	if !seen[owner] {
+		errs = append(errs, apperror.FieldError{Field: "recipients", Code: "missing_owner",
+			Message: "must include the owner of the note"})
+	}
+	return errs
+}
+
+func checkNotEncrypted(ciphertext []byte, recipients []Recipient) []apperror.FieldError {
+	var errs []apperror.FieldError
+	if ciphertext != nil {
+		errs = append(errs, apperror.FieldError{Field: "ciphertext", Code: "not_encrypted",
+			Message: "can only be set on an encrypted note"})
+	}
+	if recipients != nil {
+		errs = append(errs, apperror.FieldError{Field: "recipients", Code: "not_encrypted",
+			Message: "can only be set on an encrypted note"})
+	}
+	return errs
+}
+
+// changeEvents are the events of an update to n, along with the change of
+// its visibility from wasPublic.
+func changeEvents(n Note, wasPublic bool) []Event {
+	events := []Event{NewEvent(EventUpdated, n)}
+	isPublic := n.Public != nil && *n.Public
+	switch {
+	case !wasPublic && isPublic:
+		events = append(events, NewEvent(EventPublished, n))
+	case wasPublic && !isPublic:
+		events = append(events, NewEvent(EventUnpublished, n))
+	}
+	return events
+}
+
+func (s service) emit(ctx context.Context, events ...Event) {
+	if s.publisher == nil {
+		return
+	}
+	s.publisher.Publish(ctx, events...)
+}
@@ -2,14 +2,41 @@ package note
 
 import (
 	"context"
+	"time"
 
 	"github.com/google/uuid"
 )
 
 type Storage interface {
-	Create(ctx context.Context, note Note) error
+	Create(ctx context.Context, note *Note) error
 	GetByID(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Note, error)
-	GetNotes(ctx context.Context, userUUID uuid.UUID) (*Notes, error)
-	Update(ctx context.Context, note Note, userUUID uuid.UUID) error
+	// GetNotes returns the notes visible to userUUID, pinned notes first.
+	GetNotes(ctx context.Context, userUUID uuid.UUID, opts ListOptions) (*Notes, error)
+	// Update applies the set fields of note and fills in the rest, including
+	// the visibility the note ends up with.
+	Update(ctx context.Context, note *Note, userUUID uuid.UUID) error
 	Delete(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) error
+	// GetLinks returns the links of a note, leaving out existing notes
+	// userUUID can't see.
+	GetLinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Links, error)
+	// GetBacklinks returns the notes visible to userUUID that link to a note.
+	GetBacklinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Notes, error)
+	GetGraph(ctx context.Context, userUUID uuid.UUID) (*Graph, error)
+	// Schedule replaces PublishAt and UnpublishAt of a note owned by
+	// userUUID and fills in the rest of note.
+	Schedule(ctx context.Context, note *Note, userUUID uuid.UUID) error
+	// ApplySchedules applies up to limit schedules that passed by now. Notes
+	// being applied elsewhere are skipped.
+	ApplySchedules(ctx context.Context, now time.Time, limit int) ([]Transition, error)
+	// Burn deletes a burn-after-read note that is public or has readerUUID
+	// as a recipient and returns it. Of concurrent calls only one gets the
+	// note, the others get ErrNotFound.
+	Burn(ctx context.Context, noteUUID uuid.UUID, readerUUID uuid.UUID) (*Note, error)
+	// DeleteExpired deletes up to limit notes that expired by now and
+	// returns them.
+	DeleteExpired(ctx context.Context, now time.Time, limit int) ([]Note, error)
+	// RotateKeys does a batch of maintenance on the keys note text is
+	// encrypted with at rest, replacing data keys older than maxAge. It
+	// returns how many keys and notes it changed, 0 once nothing is left.
+	RotateKeys(ctx context.Context, now time.Time, maxAge time.Duration, limit int) (int, error)
 }
@@ -0,0 +1,329 @@
+// Package storagetest is the contract every note.Storage has to meet. An
+// implementation's tests call Run with a way to get storage and users.
+package storagetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/rest"
+
+	"github.com/google/uuid"
+)
+
+// Harness gives the contract a storage and users its notes can belong to.
+type Harness struct {
+	Storage note.Storage
+	NewUser func(t *testing.T) uuid.UUID
+}
+
+func Run(t *testing.T, h Harness) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, h) })
+	t.Run("Visibility", func(t *testing.T) { testVisibility(t, h) })
+	t.Run("Recipients", func(t *testing.T) { testRecipients(t, h) })
+	t.Run("OwnerOnlyWrites", func(t *testing.T) { testOwnerOnlyWrites(t, h) })
+	t.Run("Unpublished", func(t *testing.T) { testUnpublished(t, h) })
+	t.Run("PublicClearsSchedule", func(t *testing.T) { testPublicClearsSchedule(t, h) })
+	t.Run("UpdateTitle", func(t *testing.T) { testUpdateTitle(t, h) })
+	t.Run("PinnedFirst", func(t *testing.T) { testPinnedFirst(t, h) })
+	t.Run("Archived", func(t *testing.T) { testArchived(t, h) })
+	t.Run("Paging", func(t *testing.T) { testPaging(t, h) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, h) })
+}
+
+// Create stores a note with the given text, owned by userUUID.
+func Create(t *testing.T, s note.Storage, userUUID uuid.UUID, text string, public bool,
+	opts ...func(*note.Note)) note.Note {
+	t.Helper()
+	format := note.FormatPlain
+	n := note.Note{UserUUID: &userUUID, Text: &text, Public: &public, Format: &format,
+		Pinned: ptr(false), Archived: ptr(false), BurnAfterRead: ptr(false), Encrypted: ptr(false)}
+	for _, opt := range opts {
+		opt(&n)
+	}
+	if err := s.Create(context.Background(), &n); err != nil {
+		t.Fatalf("Create() error = %v", err)
+	}
+	return n
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func testCreateAndGet(t *testing.T, h Harness) {
+	owner := h.NewUser(t)
+	created := Create(t, h.Storage, owner, "hello", false, func(n *note.Note) {
+		n.Title, n.Pinned = ptr("greeting"), ptr(true)
+	})
+
+	got, err := h.Storage.GetByID(context.Background(), *created.NoteUUID, owner)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if *got.Text != "hello" || *got.Public || got.Title == nil || *got.Title != "greeting" || !*got.Pinned ||
+		*got.Archived || *got.UserUUID != owner {
+		t.Errorf("GetByID() = %+v", got)
+	}
+}
+
+func testVisibility(t *testing.T, h Harness) {
+	owner, other := h.NewUser(t), h.NewUser(t)
+	private := Create(t, h.Storage, owner, "private", false)
+	public := Create(t, h.Storage, owner, "public", true)
+	ctx := context.Background()
+
+	if _, err := h.Storage.GetByID(ctx, *private.NoteUUID, other); !isHidden(err) {
+		t.Errorf("GetByID(private) by other error = %v, want forbidden or not found", err)
+	}
+	if _, err := h.Storage.GetByID(ctx, *public.NoteUUID, other); err != nil {
+		t.Errorf("GetByID(public) by other error = %v", err)
+	}
+	notes, err := h.Storage.GetNotes(ctx, other, note.ListOptions{})
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("GetNotes() error = %v", err)
+	}
+	if contains(notes, *private.NoteUUID) || !contains(notes, *public.NoteUUID) {
+		t.Errorf("GetNotes() by other = %v, want the public note only", ids(notes))
+	}
+}
+
+func testRecipients(t *testing.T, h Harness) {
+	owner, recipient, other := h.NewUser(t), h.NewUser(t), h.NewUser(t)
+	n := Create(t, h.Storage, owner, "", false, encryptedFor(owner, recipient))
+	ctx := context.Background()
+
+	if _, err := h.Storage.GetByID(ctx, *n.NoteUUID, recipient); err != nil {
+		t.Errorf("GetByID() by recipient error = %v", err)
+	}
+	if _, err := h.Storage.GetByID(ctx, *n.NoteUUID, other); !isHidden(err) {
+		t.Errorf("GetByID() by other error = %v, want forbidden or not found", err)
+	}
+	for _, tt := range []struct {
+		user uuid.UUID
+		want bool
+	}{{recipient, true}, {other, false}} {
+		notes, err := h.Storage.GetNotes(ctx, tt.user, note.ListOptions{Filters: ownerFilter(owner)})
+		if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+			t.Fatalf("GetNotes() error = %v", err)
+		}
+		if contains(notes, *n.NoteUUID) != tt.want {
+			t.Errorf("GetNotes() by %s = %v, want the note listed %v", tt.user, ids(notes), tt.want)
+		}
+	}
+}
+
+func testOwnerOnlyWrites(t *testing.T, h Harness) {
+	owner, recipient := h.NewUser(t), h.NewUser(t)
+	n := Create(t, h.Storage, owner, "", false, encryptedFor(owner, recipient))
+	ctx := context.Background()
+
+	u := note.Note{NoteUUID: n.NoteUUID, Ciphertext: []byte("replaced")}
+	if err := h.Storage.Update(ctx, &u, recipient); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Update() by recipient error = %v, want forbidden", err)
+	}
+	if err := h.Storage.Delete(ctx, *n.NoteUUID, recipient); !errors.Is(err, apperror.ErrForbidden) {
+		t.Errorf("Delete() by recipient error = %v, want forbidden", err)
+	}
+	got, err := h.Storage.GetByID(ctx, *n.NoteUUID, owner)
+	if err != nil {
+		t.Fatalf("GetByID() error = %v", err)
+	}
+	if string(got.Ciphertext) != "ciphertext" {
+		t.Errorf("Ciphertext = %q after refused writes, want %q", got.Ciphertext, "ciphertext")
+	}
+}
+
+func testUnpublished(t *testing.T, h Harness) {
+	owner, other := h.NewUser(t), h.NewUser(t)
+	// The Scheduler hasn't applied the unpublish_at yet.
+	n := Create(t, h.Storage, owner, "was public", true, func(n *note.Note) {
+		n.UnpublishAt = ptr(time.Now().Add(-time.Minute).UTC().Truncate(time.Second))
+	})
+	ctx := context.Background()
+
+	if _, err := h.Storage.GetByID(ctx, *n.NoteUUID, other); !isHidden(err) {
+		t.Errorf("GetByID() by other error = %v, want forbidden or not found", err)
+	}
+	notes, err := h.Storage.GetNotes(ctx, other, note.ListOptions{Filters: ownerFilter(owner)})
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("GetNotes() error = %v", err)
+	}
+	if contains(notes, *n.NoteUUID) {
+		t.Errorf("GetNotes() by other = %v, want the unpublished note left out", ids(notes))
+	}
+	if _, err := h.Storage.GetByID(ctx, *n.NoteUUID, owner); err != nil {
+		t.Errorf("GetByID() by owner error = %v", err)
+	}
+}
+
+func testPublicClearsSchedule(t *testing.T, h Harness) {
+	owner := h.NewUser(t)
+	later := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	ctx := context.Background()
+	update := func(n note.Note, public bool) *note.Note {
+		t.Helper()
+		if err := h.Storage.Update(ctx, &note.Note{NoteUUID: n.NoteUUID, Public: &public}, owner); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		got, err := h.Storage.GetByID(ctx, *n.NoteUUID, owner)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		return got
+	}
+
+	publish := Create(t, h.Storage, owner, "publish later", false, func(n *note.Note) { n.PublishAt = &later })
+	if got := update(publish, true); got.PublishAt != nil {
+		t.Errorf("publish_at after publishing by hand = %v, want it cleared", got.PublishAt)
+	}
+	unpublish := Create(t, h.Storage, owner, "unpublish later", true, func(n *note.Note) { n.UnpublishAt = &later })
+	if got := update(unpublish, true); got.UnpublishAt == nil || !got.UnpublishAt.Equal(later) {
+		t.Errorf("unpublish_at after an unrelated update = %v, want %v", got.UnpublishAt, later)
+	}
+	if got := update(unpublish, false); got.UnpublishAt != nil {
+		t.Errorf("unpublish_at after unpublishing by hand = %v, want it cleared", got.UnpublishAt)
+	}
+}
+
+func testUpdateTitle(t *testing.T, h Harness) {
+	owner := h.NewUser(t)
+	n := Create(t, h.Storage, owner, "text", false, func(n *note.Note) { n.Title = ptr("first") })
+	ctx := context.Background()
+	update := func(u note.Note) *note.Note {
+		t.Helper()
+		u.NoteUUID = n.NoteUUID
+		if err := h.Storage.Update(ctx, &u, owner); err != nil {
+			t.Fatalf("Update() error = %v", err)
+		}
+		got, err := h.Storage.GetByID(ctx, *n.NoteUUID, owner)
+		if err != nil {
+			t.Fatalf("GetByID() error = %v", err)
+		}
+		return got
+	}
+
+	if got := update(note.Note{Pinned: ptr(true)}); got.Title == nil || *got.Title != "first" {
+		t.Errorf("title after unrelated update = %v, want first", got.Title)
+	}
+	if got := update(note.Note{Title: ptr("second"), TitleSet: true}); got.Title == nil || *got.Title != "second" {
+		t.Errorf("title after setting it = %v, want second", got.Title)
+	}
+	if got := update(note.Note{TitleSet: true}); got.Title != nil {
+		t.Errorf("title after clearing it = %q, want none", *got.Title)
+	}
+}
+
+func testPinnedFirst(t *testing.T, h Harness) {
+	owner := h.NewUser(t)
+	Create(t, h.Storage, owner, "first", false)
+	pinned := Create(t, h.Storage, owner, "second", false, func(n *note.Note) { n.Pinned = ptr(true) })
+
+	notes, err := h.Storage.GetNotes(context.Background(), owner, note.ListOptions{
+		Filters: ownerFilter(owner)})
+	if err != nil {
+		t.Fatalf("GetNotes() error = %v", err)
+	}
+	if len(notes.Notes) != 2 || *notes.Notes[0].NoteUUID != *pinned.NoteUUID {
+		t.Errorf("GetNotes() = %v, want the pinned note first", ids(notes))
+	}
+}
+
+func testArchived(t *testing.T, h Harness) {
+	owner := h.NewUser(t)
+	archived := Create(t, h.Storage, owner, "old", false, func(n *note.Note) { n.Archived = ptr(true) })
+	ctx := context.Background()
+
+	notes, err := h.Storage.GetNotes(ctx, owner, note.ListOptions{Filters: ownerFilter(owner)})
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("GetNotes() error = %v", err)
+	}
+	if contains(notes, *archived.NoteUUID) {
+		t.Errorf("GetNotes() lists the archived note")
+	}
+	notes, err = h.Storage.GetNotes(ctx, owner, note.ListOptions{Filters: ownerFilter(owner), IncludeArchived: true})
+	if err != nil {
+		t.Fatalf("GetNotes(IncludeArchived) error = %v", err)
+	}
+	if !contains(notes, *archived.NoteUUID) {
+		t.Errorf("GetNotes(IncludeArchived) = %v, want the archived note", ids(notes))
+	}
+}
+
+func testPaging(t *testing.T, h Harness) {
+	owner := h.NewUser(t)
+	for _, text := range []string{"a", "b", "c", "d", "e"} {
+		Create(t, h.Storage, owner, text, false)
+	}
+	ctx := context.Background()
+	all, err := h.Storage.GetNotes(ctx, owner, note.ListOptions{Filters: ownerFilter(owner)})
+	if err != nil {
+		t.Fatalf("GetNotes() error = %v", err)
+	}
+
+	page, err := h.Storage.GetNotes(ctx, owner, note.ListOptions{Filters: ownerFilter(owner), Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("GetNotes(Limit, Offset) error = %v", err)
+	}
+	if page.Total != 5 || len(page.Notes) != 2 || *page.Notes[0].NoteUUID != *all.Notes[2].NoteUUID {
+		t.Errorf("GetNotes(Limit: 2, Offset: 2) = %v of %d, want %v of 5", ids(page), page.Total,
+			ids(&note.Notes{Notes: all.Notes[2:4]}))
+	}
+}
+
+func testDelete(t *testing.T, h Harness) {
+	owner := h.NewUser(t)
+	n := Create(t, h.Storage, owner, "gone", false)
+	ctx := context.Background()
+	if err := h.Storage.Delete(ctx, *n.NoteUUID, owner); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+	if _, err := h.Storage.GetByID(ctx, *n.NoteUUID, owner); !errors.Is(err, apperror.ErrNotFound) {
+		t.Errorf("GetByID() after Delete error = %v, want not found", err)
+	}
+}
+
+// encryptedFor makes the note end-to-end encrypted for users.
+func encryptedFor(users ...uuid.UUID) func(*note.Note) {
+	return func(n *note.Note) {
+		n.Text, n.Encrypted, n.Ciphertext = nil, ptr(true), []byte("ciphertext")
+		for _, u := range users {
+			n.Recipients = append(n.Recipients, note.Recipient{UserUUID: ptr(u), Algorithm: "x25519",
+				WrappedKey: []byte("wrapped")})
+		}
+	}
+}
+
+func ownerFilter(userUUID uuid.UUID) []rest.FilterOptions {
+	return []rest.FilterOptions{{Field: "user_id", Operator: rest.OperatorEq, Values: []string{userUUID.String()}}}
+}
+
+func isHidden(err error) bool {
+	return errors.Is(err, apperror.ErrForbidden) || errors.Is(err, apperror.ErrNotFound)
+}
+
+func contains(notes *note.Notes, noteUUID uuid.UUID) bool {
+	if notes == nil {
+		return false
+	}
+	for _, n := range notes.Notes {
+		if *n.NoteUUID == noteUUID {
+			return true
+		}
+	}
+	return false
+}
+
+func ids(notes *note.Notes) []uuid.UUID {
+	var out []uuid.UUID
+	if notes != nil {
+		for _, n := range notes.Notes {
+			out = append(out, *n.NoteUUID)
+		}
+	}
+	return out
+}
@@ -0,0 +1,153 @@
+package note
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ EventPublisher = &Broker{}
+
+// Broker fans note events out to stream subscribers and keeps the most recent
+// ones so a reconnecting client can resume from its Last-Event-ID. Stream ids
+// are "<epoch>-<sequence>", the epoch changes on every start so ids handed
+// out by an earlier process are never mistaken for current ones.
+type Broker struct {
+	mu          sync.Mutex
+	epoch       string
+	seq         uint64
+	buffer      []streamEvent
+	next        int
+	full        bool
+	subscribers map[*Subscription]struct{}
+	queueSize   int
+}
+
+type streamEvent struct {
+	ID    string
+	Seq   uint64
+	Event Event
+}
+
+// Subscription receives the events visible to one user. Events is closed
+// when the subscriber falls too far behind, the client is then expected to
+// reconnect and resume through the replay buffer.
+type Subscription struct {
+	userUUID uuid.UUID
+	Events   chan streamEvent
+}
+
+func NewBroker(bufferSize, queueSize int) *Broker {
+	return &Broker{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		buffer:      make([]streamEvent, bufferSize),
+		subscribers: make(map[*Subscription]struct{}),
+		queueSize:   queueSize,
+	}
+}
+
+func (b *Broker) Publish(ctx context.Context, events ...Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, event := range events {
+		b.seq++
+		se := streamEvent{ID: fmt.Sprintf("%s-%d", b.epoch, b.seq), Seq: b.seq, Event: event}
+		if len(b.buffer) > 0 {
+			b.buffer[b.next] = se
+			b.next = (b.next + 1) % len(b.buffer)
+			b.full = b.full || b.next == 0
+		}
+		for sub := range b.subscribers {
+			if !Visible(event, sub.userUUID) {
+				continue
+			}
+			select {
+			case sub.Events <- se:
+			default:
+				delete(b.subscribers, sub)
+				close(sub.Events)
+			}
+		}
+	}
+}
+
+// Subscribe registers a subscriber and returns the buffered events after
+// lastEventID that it may see. complete is false when lastEventID is set but
+// the events after it are no longer buffered, the client then has to reload.
+func (b *Broker) Subscribe(userUUID uuid.UUID, lastEventID string) (sub *Subscription, replay []streamEvent, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{userUUID: userUUID, Events: make(chan streamEvent, b.queueSize)}
+	b.subscribers[sub] = struct{}{}
+	if lastEventID == "" {
+		return sub, nil, true
+	}
+
+	after, ok := b.parseID(lastEventID)
+	if !ok {
+		return sub, nil, false
+	}
+	buffered := b.buffered()
+	if after < b.seq && (len(buffered) == 0 || buffered[0].Seq > after+1) {
+		return sub, nil, false
+	}
+	for _, se := range buffered {
+		if se.Seq > after && Visible(se.Event, userUUID) {
+			replay = append(replay, se)
+		}
+	}
+	return sub, replay, true
+}
+
+func (b *Broker) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[sub]; ok {
+		delete(b.subscribers, sub)
+		close(sub.Events)
+	}
+}
+
+func (b *Broker) parseID(id string) (uint64, bool) {
+	epoch, seq, found := strings.Cut(id, "-")
+	if !found || epoch != b.epoch {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(seq, 10, 64)
+	if err != nil || n > b.seq {
+		return 0, false
+	}
+	return n, true
+}
+
+// buffered returns the buffer oldest first.
+func (b *Broker) buffered() []streamEvent {
+	if !b.full {
+		return b.buffer[:b.next]
+	}
+	return append(append([]streamEvent{}, b.buffer[b.next:]...), b.buffer[:b.next]...)
+}
+
+// Visible applies the GetNoteByID rule to an event, see Note.VisibleTo. An
+// unpublished note was public until this event, so everyone is told, but only
+// the owner gets its content.
+func Visible(event Event, userUUID uuid.UUID) bool {
+	return event.Type == EventUnpublished || event.Note.VisibleTo(userUUID, time.Now())
+}
+
+// forSubscriber strips the content of notes the subscriber may no longer
+// read, or may only read once.
+func forSubscriber(event Event, userUUID uuid.UUID) Event {
+	owner := event.Note.UserUUID != nil && *event.Note.UserUUID == userUUID
+	burn := event.Note.BurnAfterRead != nil && *event.Note.BurnAfterRead
+	if !owner && (event.Type == EventUnpublished || burn) {
+		event.Note = Note{NoteUUID: event.Note.NoteUUID, Public: event.Note.Public}
+	}
+	return event
+}
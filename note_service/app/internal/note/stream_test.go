@@ -0,0 +1,138 @@
+package note
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestVisible(t *testing.T) {
+	owner, recipient, stranger := uuid.New(), uuid.New(), uuid.New()
+	yes, no := true, false
+	past, future := time.Now().Add(-time.Minute), time.Now().Add(time.Hour)
+	private := Note{UserUUID: &owner, Public: &no}
+	public := Note{UserUUID: &owner, Public: &yes}
+	publicUntilFuture := Note{UserUUID: &owner, Public: &yes, UnpublishAt: &future}
+	publicUntilPast := Note{UserUUID: &owner, Public: &yes, UnpublishAt: &past}
+	encrypted := Note{UserUUID: &owner, Public: &no, Recipients: []Recipient{{UserUUID: &recipient}}}
+
+	tests := []struct {
+		name  string
+		event Event
+		user  uuid.UUID
+		want  bool
+	}{
+		{"owner of a private note", NewEvent(EventUpdated, private), owner, true},
+		{"stranger and a private note", NewEvent(EventUpdated, private), stranger, false},
+		{"anonymous and a private note", NewEvent(EventUpdated, private), uuid.Nil, false},
+		{"stranger and a public note", NewEvent(EventUpdated, public), stranger, true},
+		{"before unpublish_at", NewEvent(EventUpdated, publicUntilFuture), stranger, true},
+		{"past unpublish_at", NewEvent(EventUpdated, publicUntilPast), stranger, false},
+		{"owner past unpublish_at", NewEvent(EventUpdated, publicUntilPast), owner, true},
+		{"recipient", NewEvent(EventUpdated, encrypted), recipient, true},
+		{"recipient of a deleted note", NewEvent(EventDeleted, encrypted), recipient, true},
+		{"stranger and an encrypted note", NewEvent(EventUpdated, encrypted), stranger, false},
+		{"unpublished", NewEvent(EventUnpublished, private), stranger, true},
+	}
+	for _, tt := range tests {
+		if got := Visible(tt.event, tt.user); got != tt.want {
+			t.Errorf("Visible(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func publicEvent(owner uuid.UUID) Event {
+	id, public := uuid.New(), true
+	return NewEvent(EventCreated, Note{NoteUUID: &id, UserUUID: &owner, Public: &public})
+}
+
+func seqs(events []streamEvent) []uint64 {
+	var s []uint64
+	for _, se := range events {
+		s = append(s, se.Seq)
+	}
+	return s
+}
+
+func TestBrokerResume(t *testing.T) {
+	owner, other := uuid.New(), uuid.New()
+	b := NewBroker(3, 10)
+	first, _, _ := b.Subscribe(owner, "")
+	for i := 0; i < 5; i++ {
+		b.Publish(context.Background(), publicEvent(owner))
+	}
+	b.Unsubscribe(first)
+	var ids []string
+	for se := range first.Events {
+		ids = append(ids, se.ID)
+	}
+	if len(ids) != 5 {
+		t.Fatalf("subscriber got %d events, want 5", len(ids))
+	}
+
+	tests := []struct {
+		name         string
+		lastEventID  string
+		wantReplay   []uint64
+		wantComplete bool
+	}{
+		{"no id", "", nil, true},
+		{"within the buffer", ids[2], []uint64{4, 5}, true},
+		{"just before the buffer", ids[1], []uint64{3, 4, 5}, true},
+		{"fell out of the buffer", ids[0], nil, false},
+		{"latest", ids[4], nil, true},
+		{"earlier process", "0-1", nil, false},
+		{"ahead of the broker", ids[4][:len(ids[4])-1] + "9", nil, false},
+		{"garbage", "garbage", nil, false},
+	}
+	for _, tt := range tests {
+		sub, replay, complete := b.Subscribe(other, tt.lastEventID)
+		b.Unsubscribe(sub)
+		got := seqs(replay)
+		if complete != tt.wantComplete || len(got) != len(tt.wantReplay) {
+			t.Errorf("Subscribe(%s) = %v, %v, want %v, %v", tt.name, got, complete, tt.wantReplay, tt.wantComplete)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.wantReplay[i] {
+				t.Errorf("Subscribe(%s) replay = %v, want %v", tt.name, got, tt.wantReplay)
+				break
+			}
+		}
+	}
+}
+
+func TestBrokerReplaysOnlyVisibleEvents(t *testing.T) {
+	owner, stranger := uuid.New(), uuid.New()
+	b := NewBroker(10, 10)
+	sub, _, _ := b.Subscribe(owner, "")
+	b.Publish(context.Background(), publicEvent(owner), NewEvent(EventUpdated, Note{UserUUID: &owner}),
+		publicEvent(owner))
+	b.Unsubscribe(sub)
+	first := <-sub.Events
+
+	_, replay, complete := b.Subscribe(stranger, first.ID)
+	if got := seqs(replay); !complete || len(got) != 1 || got[0] != 3 {
+		t.Errorf("Subscribe() replay = %v, %v, want [3], true", got, complete)
+	}
+	_, replay, _ = b.Subscribe(owner, first.ID)
+	if got := seqs(replay); len(got) != 2 {
+		t.Errorf("Subscribe() replay for the owner = %v, want [2 3]", got)
+	}
+}
+
+func TestBrokerDropsSlowSubscribers(t *testing.T) {
+	owner := uuid.New()
+	b := NewBroker(0, 1)
+	sub, _, _ := b.Subscribe(owner, "")
+	b.Publish(context.Background(), publicEvent(owner), publicEvent(owner))
+	if _, ok := <-sub.Events; !ok {
+		t.Fatal("first event not delivered")
+	}
+	if _, ok := <-sub.Events; ok {
+		t.Error("Events not closed after the queue overflowed")
+	}
+	b.Unsubscribe(sub) // already dropped, must not close twice
+}
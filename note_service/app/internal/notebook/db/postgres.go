@@ -0,0 +1,45 @@
+package db
+
+import (
+	"context"
+	"note_service/app/internal/note"
+	"note_service/app/internal/notebook"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/postgres"
+
+	"github.com/google/uuid"
+)
+
+var _ notebook.Storage = &db{}
+
+type db struct {
+	client *postgres.Client
+	logger logging.Logger
+}
+
+func NewStorage(client *postgres.Client, logger logging.Logger) notebook.Storage {
+	return &db{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (s *db) CreateNotebook(ctx context.Context, notebook *notebook.Notebook) error {
+	return s.client.CreateNotebook(ctx, notebook)
+}
+
+func (s *db) GetNotebook(ctx context.Context, notebookUUID uuid.UUID) (*notebook.Notebook, error) {
+	return s.client.GetNotebook(ctx, notebookUUID)
+}
+
+func (s *db) GetNotebooks(ctx context.Context, userUUID uuid.UUID) (*notebook.Notebooks, error) {
+	return s.client.GetNotebooks(ctx, userUUID)
+}
+
+func (s *db) UpdateNotebook(ctx context.Context, notebook *notebook.Notebook) ([]note.Note, error) {
+	return s.client.UpdateNotebook(ctx, notebook)
+}
+
+func (s *db) DeleteNotebook(ctx context.Context, notebookUUID uuid.UUID, mode string) ([]note.Note, error) {
+	return s.client.DeleteNotebook(ctx, notebookUUID, mode)
+}
@@ -0,0 +1,235 @@
+package notebook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/handlers"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/openapi"
+	"note_service/app/pkg/ratelimit"
+	"note_service/app/pkg/user"
+	"note_service/app/pkg/validator"
+
+	"github.com/google/uuid"
+)
+
+const (
+	notebooksURL     = "/notebooks"
+	notebookURL      = "/notebooks/:uuid"
+	notebookNotesURL = "/notebooks/:uuid/notes"
+
+	// notebooks share the rate limits of notes
+	readGroup  = "notes_read"
+	writeGroup = "notes_write"
+)
+
+type Handler struct {
+	Logger          logging.Logger
+	NotebookService Service
+	UserClient      user_client.UserClient
+	RateLimiter     *ratelimit.Limiter
+	MaxBodyBytes    int64
+}
+
+func (h *Handler) Register(router openapi.Router) {
+	for _, route := range h.Routes() {
+		router.HandlerFunc(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (h *Handler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{ // POST /notebooks
+			Method:    http.MethodPost,
+			Path:      notebooksURL,
+			Handler:   h.protect(writeGroup, h.CreateNotebook),
+			Operation: createNotebookOperation,
+		},
+		{ // GET /notebooks
+			Method:    http.MethodGet,
+			Path:      notebooksURL,
+			Handler:   h.protect(readGroup, h.GetNotebooks),
+			Operation: getNotebooksOperation,
+		},
+		{ // GET /notebooks/{uuid}
+			Method: http.MethodGet,
+			Path:   notebookURL,
+			Handler: user.Authentication(h.UserClient,
+				h.RateLimiter.Limit(readGroup, apperror.Middleware(h.GetNotebook))),
+			Operation: getNotebookOperation,
+		},
+		{ // GET /notebooks/{uuid}/notes
+			Method: http.MethodGet,
+			Path:   notebookNotesURL,
+			Handler: user.Authentication(h.UserClient,
+				h.RateLimiter.Limit(readGroup, apperror.Middleware(h.GetNotebookNotes))),
+			Operation: getNotebookNotesOperation,
+		},
+		{ // PATCH /notebooks/{uuid}
+			Method:    http.MethodPatch,
+			Path:      notebookURL,
+			Handler:   h.protect(writeGroup, h.UpdateNotebook),
+			Operation: updateNotebookOperation,
+		},
+		{ // DELETE /notebooks/{uuid}
+			Method:    http.MethodDelete,
+			Path:      notebookURL,
+			Handler:   h.protect(writeGroup, h.DeleteNotebook),
+			Operation: deleteNotebookOperation,
+		},
+	}
+}
+
+// protect requires an authenticated caller.
+func (h *Handler) protect(group string, fn func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return user.Authentication(h.UserClient,
+		h.RateLimiter.Limit(group, user.Authorization(apperror.Middleware(fn))))
+}
+
+func (h *Handler) CreateNotebook(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("CREATE NOTEBOOK")
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+
+	var dto CreateNotebookDTO
+	defer r.Body.Close()
+	if err := validator.DecodeJSON(w, r, &dto, h.MaxBodyBytes); err != nil {
+		return err
+	}
+	dto.UserUUID = &userUUID
+
+	nb, err := h.NotebookService.Create(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	notebookBytes, err := json.Marshal(nb)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", notebooksURL, nb.NotebookUUID))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(notebookBytes)
+
+	return nil
+}
+
+func (h *Handler) GetNotebooks(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET NOTEBOOKS")
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	notebooks, err := h.NotebookService.GetMany(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	notebooksBytes, err := json.Marshal(notebooks)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(notebooksBytes)
+
+	return nil
+}
+
+func (h *Handler) GetNotebook(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET NOTEBOOK")
+	w.Header().Set("Content-Type", "application/json")
+
+	notebookUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	nb, err := h.NotebookService.GetOne(r.Context(), notebookUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	notebookBytes, err := json.Marshal(nb)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(notebookBytes)
+
+	return nil
+}
+
+func (h *Handler) GetNotebookNotes(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET NOTEBOOK NOTES")
+	w.Header().Set("Content-Type", "application/json")
+
+	notebookUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	opts, err := note.ParseListOptions(r.URL.Query())
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	notes, err := h.NotebookService.GetNotes(r.Context(), notebookUUID, userUUID, opts)
+	if err != nil {
+		return err
+	}
+	notesBytes, err := json.Marshal(notes)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(notesBytes)
+
+	return nil
+}
+
+func (h *Handler) UpdateNotebook(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("PARTIALLY UPDATE NOTEBOOK")
+	w.Header().Set("Content-Type", "application/json")
+
+	notebookUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+
+	var dto UpdateNotebookDTO
+	defer r.Body.Close()
+	if err := validator.DecodeJSON(w, r, &dto, h.MaxBodyBytes); err != nil {
+		return err
+	}
+	dto.NotebookUUID = &notebookUUID
+
+	if err := h.NotebookService.Update(r.Context(), dto, userUUID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteNotebook(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("DELETE NOTEBOOK")
+	w.Header().Set("Content-Type", "application/json")
+
+	notebookUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	mode := r.URL.Query().Get("mode")
+	if err := h.NotebookService.Delete(r.Context(), notebookUUID, mode, userUUID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
@@ -0,0 +1,52 @@
+package notebook
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Ways to delete a notebook. Move deletes only the notebook, its notes and
+// child notebooks move to the root. Cascade deletes the notebook, every
+// notebook below it and all of their notes.
+const (
+	DeleteMove    = "move"
+	DeleteCascade = "cascade"
+)
+
+// Notebook groups notes and other notebooks of one user. Notes that inherit
+// visibility are public exactly when their own notebook is. Changing the
+// visibility of a notebook changes it for every notebook below it too.
+type Notebook struct {
+	NotebookUUID *uuid.UUID `json:"id,omitempty"`
+	UserUUID     *uuid.UUID `json:"user_id,omitempty"`
+	// ParentUUID is nil for notebooks at the root.
+	ParentUUID *uuid.UUID `json:"parent_id,omitempty"`
+	Name       *string    `json:"name,omitempty"`
+	Public     *bool      `json:"public,omitempty"`
+	CreateTime *time.Time `json:"create_time,omitempty"`
+}
+
+type Notebooks struct {
+	Notebooks []Notebook `json:"notebooks"`
+}
+
+type CreateNotebookDTO struct {
+	UserUUID   *uuid.UUID `json:"-" validate:"-"`
+	ParentUUID *uuid.UUID `json:"parent_id,omitempty"`
+	Name       *string    `json:"name" validate:"required,notblank,rule=name"`
+	// Public defaults to false.
+	Public *bool `json:"public,omitempty"`
+}
+
+type UpdateNotebookDTO struct {
+	NotebookUUID *uuid.UUID `json:"-" validate:"-"`
+	// ParentUUID moves the notebook, the nil UUID moves it to the root.
+	ParentUUID *uuid.UUID `json:"parent_id,omitempty"`
+	Name       *string    `json:"name,omitempty" validate:"notblank,rule=name"`
+	Public     *bool      `json:"public,omitempty"`
+}
+
+func (dto UpdateNotebookDTO) IsEmpty() bool {
+	return dto.ParentUUID == nil && dto.Name == nil && dto.Public == nil
+}
@@ -0,0 +1,121 @@
+package notebook
+
+import (
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/openapi"
+)
+
+// Describe registers the notebook schemas, Notes comes from the note handler.
+func (h *Handler) Describe(b *openapi.Builder) {
+	b.Schema(Notebook{})
+	b.Schema(Notebooks{})
+	b.Schema(CreateNotebookDTO{})
+	b.Schema(UpdateNotebookDTO{})
+	b.Schema(apperror.Problem{})
+	b.AddRoutes(h.Routes())
+}
+
+var (
+	createNotebookOperation = openapi.Operation{
+		OperationID: "createNotebook",
+		Summary:     "Create a notebook, optionally inside another one",
+		Tags:        []string{"notebooks"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("CreateNotebookDTO"))},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "Created", Content: openapi.JSON(openapi.Ref("Notebook")),
+				Headers: map[string]openapi.Header{
+					"Location": {Description: "URL of the new notebook", Schema: &openapi.Schema{Type: "string"}},
+				}},
+			"400": openapi.Problem("Malformed request body"),
+			"401": openapi.Problem("Authentication required"),
+			"422": openapi.Problem("Validation failed"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	getNotebooksOperation = openapi.Operation{
+		OperationID: "listNotebooks",
+		Summary:     "List the caller's notebooks",
+		Tags:        []string{"notebooks"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Notebooks by name, nesting is given by parent_id",
+				Content: openapi.JSON(openapi.Ref("Notebooks"))},
+			"401": openapi.Problem("Authentication required"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	getNotebookOperation = openapi.Operation{
+		OperationID: "getNotebook",
+		Summary:     "Get a notebook of the caller or a public notebook",
+		Tags:        []string{"notebooks"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The notebook", Content: openapi.JSON(openapi.Ref("Notebook"))},
+			"400": openapi.Problem("Malformed notebook id"),
+			"403": openapi.Problem("The notebook is not accessible to the caller"),
+			"404": openapi.Problem("Notebook not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	getNotebookNotesOperation = openapi.Operation{
+		OperationID: "listNotebookNotes",
+		Summary:     "List the notes of a notebook that are visible to the caller",
+		Description: "Notes of nested notebooks are not included. Takes the archived, filter and sort " +
+			"parameters of listNotes.",
+		Tags:     []string{"notebooks"},
+		Security: openapi.BearerAuth(),
+		Parameters: []openapi.Parameter{
+			{Name: "archived", In: "query", Description: "Include archived notes",
+				Schema: &openapi.Schema{Type: "boolean"}},
+			{Name: "sort", In: "query", Description: "create_time or -create_time, pinned notes stay first",
+				Schema: &openapi.Schema{Type: "string"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Notes, possibly none", Content: openapi.JSON(openapi.Ref("Notes"))},
+			"400": openapi.Problem("Malformed notebook id"),
+			"403": openapi.Problem("The notebook is not accessible to the caller"),
+			"404": openapi.Problem("Notebook not found"),
+			"422": openapi.Problem("Invalid archived, filter or sort parameter"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	updateNotebookOperation = openapi.Operation{
+		OperationID: "updateNotebook",
+		Summary:     "Rename, move or change the visibility of a notebook",
+		Description: "Changing public also changes the notes that inherit the notebook's visibility.",
+		Tags:        []string{"notebooks"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("UpdateNotebookDTO"))},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Updated"},
+			"400": openapi.Problem("Malformed request body or notebook id"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The notebook is not accessible to the caller"),
+			"404": openapi.Problem("Notebook not found"),
+			"422": openapi.Problem("Validation failed"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	deleteNotebookOperation = openapi.Operation{
+		OperationID: "deleteNotebook",
+		Summary:     "Delete a notebook",
+		Tags:        []string{"notebooks"},
+		Security:    openapi.BearerAuth(),
+		Parameters: []openapi.Parameter{
+			{Name: "mode", In: "query",
+				Description: "move (default) moves the notes and notebooks inside to the root, " +
+					"cascade deletes them",
+				Schema: &openapi.Schema{Type: "string", Enum: []interface{}{DeleteMove, DeleteCascade}}},
+		},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Deleted"},
+			"400": openapi.Problem("Malformed notebook id"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The notebook is not accessible to the caller"),
+			"404": openapi.Problem("Notebook not found"),
+			"422": openapi.Problem("Invalid mode"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+)
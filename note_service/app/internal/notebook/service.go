@@ -0,0 +1,228 @@
+package notebook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/rest"
+	"note_service/app/pkg/validator"
+
+	"github.com/google/uuid"
+)
+
+var _ Service = &service{}
+
+type service struct {
+	storage   Storage
+	validator *validator.Validator
+	notes     note.Service
+	publisher note.EventPublisher
+	logger    logging.Logger
+}
+
+// NewService lists notebook contents through notes and announces notes that
+// change along with a notebook through publisher.
+func NewService(storage Storage, validator *validator.Validator, notes note.Service, publisher note.EventPublisher,
+	logger logging.Logger) (Service, error) {
+	return &service{
+		storage:   storage,
+		validator: validator,
+		notes:     notes,
+		publisher: publisher,
+		logger:    logger,
+	}, nil
+}
+
+type Service interface {
+	Create(ctx context.Context, dto CreateNotebookDTO) (*Notebook, error)
+	GetMany(ctx context.Context, userUUID uuid.UUID) (*Notebooks, error)
+	GetOne(ctx context.Context, notebookUUID uuid.UUID, userUUID uuid.UUID) (*Notebook, error)
+	// GetNotes lists the notes of a notebook that are visible to userUUID.
+	GetNotes(ctx context.Context, notebookUUID uuid.UUID, userUUID uuid.UUID, opts note.ListOptions) (*note.Notes, error)
+	Update(ctx context.Context, dto UpdateNotebookDTO, userUUID uuid.UUID) error
+	Delete(ctx context.Context, notebookUUID uuid.UUID, mode string, userUUID uuid.UUID) error
+}
+
+func (s service) Create(ctx context.Context, dto CreateNotebookDTO) (*Notebook, error) {
+	errs := s.validator.Struct(&dto)
+	if dto.ParentUUID != nil {
+		errs = append(errs, s.checkParent(ctx, *dto.ParentUUID, *dto.UserUUID)...)
+	}
+	if len(errs) > 0 {
+		return nil, apperror.ValidationError(errs...)
+	}
+
+	public := dto.Public != nil && *dto.Public
+	nb := Notebook{
+		UserUUID:   dto.UserUUID,
+		ParentUUID: dto.ParentUUID,
+		Name:       dto.Name,
+		Public:     &public,
+	}
+	if err := s.storage.CreateNotebook(ctx, &nb); err != nil {
+		return nil, fmt.Errorf("failed to create notebook. error: %w", err)
+	}
+	return &nb, nil
+}
+
+func (s service) GetMany(ctx context.Context, userUUID uuid.UUID) (*Notebooks, error) {
+	notebooks, err := s.storage.GetNotebooks(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notebooks. error: %w", err)
+	}
+	return notebooks, nil
+}
+
+// GetOne returns notebooks of the caller and public notebooks of others.
+func (s service) GetOne(ctx context.Context, notebookUUID uuid.UUID, userUUID uuid.UUID) (*Notebook, error) {
+	nb, err := s.get(ctx, notebookUUID)
+	if err != nil {
+		return nil, err
+	}
+	if *nb.UserUUID != userUUID && !*nb.Public {
+		return nil, apperror.ErrForbidden
+	}
+	return nb, nil
+}
+
+func (s service) GetNotes(ctx context.Context, notebookUUID uuid.UUID, userUUID uuid.UUID,
+	opts note.ListOptions) (*note.Notes, error) {
+	if _, err := s.GetOne(ctx, notebookUUID, userUUID); err != nil {
+		return nil, err
+	}
+	opts.Filters = append(opts.Filters, rest.FilterOptions{
+		Field: "notebook_id", Operator: rest.OperatorEq, Values: []string{notebookUUID.String()}})
+	notes, err := s.notes.GetMany(ctx, userUUID, opts)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return &note.Notes{Notes: []note.Note{}}, nil
+		}
+		return nil, err
+	}
+	return notes, nil
+}
+
+func (s service) Update(ctx context.Context, dto UpdateNotebookDTO, userUUID uuid.UUID) error {
+	if dto.IsEmpty() {
+		return apperror.ValidationError(apperror.FieldError{
+			Code: "empty_update", Message: "at least one field must be provided"})
+	}
+	errs := s.validator.Struct(&dto)
+	if dto.ParentUUID != nil && *dto.ParentUUID != uuid.Nil {
+		errs = append(errs, s.checkParent(ctx, *dto.ParentUUID, userUUID)...)
+	}
+	if len(errs) > 0 {
+		return apperror.ValidationError(errs...)
+	}
+
+	nb, err := s.owned(ctx, *dto.NotebookUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if dto.ParentUUID != nil {
+		nb.ParentUUID = dto.ParentUUID
+		if *dto.ParentUUID == uuid.Nil {
+			nb.ParentUUID = nil
+		}
+	}
+	if dto.Name != nil {
+		nb.Name = dto.Name
+	}
+	if dto.Public != nil {
+		nb.Public = dto.Public
+	}
+	changed, err := s.storage.UpdateNotebook(ctx, nb)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) || errors.Is(err, ErrCycle) {
+			return err
+		}
+		return fmt.Errorf("failed to update notebook. error: %w", err)
+	}
+
+	visibility := note.EventUnpublished
+	if *nb.Public {
+		visibility = note.EventPublished
+	}
+	var events []note.Event
+	for _, n := range changed {
+		events = append(events, note.NewEvent(note.EventUpdated, n), note.NewEvent(visibility, n))
+	}
+	s.emit(ctx, events...)
+	return nil
+}
+
+func (s service) Delete(ctx context.Context, notebookUUID uuid.UUID, mode string, userUUID uuid.UUID) error {
+	if mode == "" {
+		mode = DeleteMove
+	}
+	if mode != DeleteMove && mode != DeleteCascade {
+		return apperror.ValidationError(apperror.FieldError{Field: "mode", Code: "invalid",
+			Message: fmt.Sprintf("must be %s or %s", DeleteMove, DeleteCascade)})
+	}
+	if _, err := s.owned(ctx, notebookUUID, userUUID); err != nil {
+		return err
+	}
+	affected, err := s.storage.DeleteNotebook(ctx, notebookUUID, mode)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete notebook. error: %w", err)
+	}
+
+	eventType := note.EventUpdated
+	if mode == DeleteCascade {
+		eventType = note.EventDeleted
+	}
+	events := make([]note.Event, 0, len(affected))
+	for _, n := range affected {
+		events = append(events, note.NewEvent(eventType, n))
+	}
+	s.emit(ctx, events...)
+	return nil
+}
+
+func (s service) get(ctx context.Context, notebookUUID uuid.UUID) (*Notebook, error) {
+	nb, err := s.storage.GetNotebook(ctx, notebookUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get notebook. error: %w", err)
+	}
+	return nb, nil
+}
+
+// owned returns the notebook if userUUID may change it, notebooks of others
+// are forbidden.
+func (s service) owned(ctx context.Context, notebookUUID uuid.UUID, userUUID uuid.UUID) (*Notebook, error) {
+	nb, err := s.GetOne(ctx, notebookUUID, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	if *nb.UserUUID != userUUID {
+		return nil, apperror.ErrForbidden
+	}
+	return nb, nil
+}
+
+func (s service) checkParent(ctx context.Context, parentUUID uuid.UUID, userUUID uuid.UUID) []apperror.FieldError {
+	parent, err := s.storage.GetNotebook(ctx, parentUUID)
+	if err != nil && !errors.Is(err, apperror.ErrNotFound) {
+		s.logger.Errorf("failed to get parent notebook %s: %v", parentUUID, err)
+	}
+	if err != nil || *parent.UserUUID != userUUID {
+		return []apperror.FieldError{{Field: "parent_id", Code: "invalid", Message: "notebook not found"}}
+	}
+	return nil
+}
+
+func (s service) emit(ctx context.Context, events ...note.Event) {
+	if s.publisher == nil || len(events) == 0 {
+		return
+	}
+	s.publisher.Publish(ctx, events...)
+}
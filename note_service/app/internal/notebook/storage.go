@@ -0,0 +1,25 @@
+package notebook
+
+import (
+	"context"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+
+	"github.com/google/uuid"
+)
+
+// ErrCycle is returned when a notebook would end up below itself.
+var ErrCycle = apperror.ValidationError(apperror.FieldError{Field: "parent_id", Code: "cycle",
+	Message: "can't move a notebook into itself or one of its notebooks"})
+
+type Storage interface {
+	CreateNotebook(ctx context.Context, notebook *Notebook) error
+	GetNotebook(ctx context.Context, notebookUUID uuid.UUID) (*Notebook, error)
+	GetNotebooks(ctx context.Context, userUUID uuid.UUID) (*Notebooks, error)
+	// UpdateNotebook saves notebook and returns the inheriting notes whose
+	// visibility changed with it.
+	UpdateNotebook(ctx context.Context, notebook *Notebook) ([]note.Note, error)
+	// DeleteNotebook deletes the notebook as mode says and returns the notes
+	// it moved or deleted.
+	DeleteNotebook(ctx context.Context, notebookUUID uuid.UUID, mode string) ([]note.Note, error)
+}
@@ -0,0 +1,32 @@
+package db
+
+import (
+	"context"
+	"note_service/app/internal/outbox"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/postgres"
+	"time"
+)
+
+var _ outbox.Storage = &db{}
+
+type db struct {
+	client *postgres.Client
+	logger logging.Logger
+}
+
+func NewStorage(client *postgres.Client, logger logging.Logger) outbox.Storage {
+	return &db{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (s *db) Relay(ctx context.Context, limit int, lease time.Duration, send func(outbox.Message) error) (int,
+	error) {
+	return s.client.RelayOutbox(ctx, limit, lease, send)
+}
+
+func (s *db) Purge(ctx context.Context, before time.Time) (int64, error) {
+	return s.client.PurgeOutbox(ctx, before)
+}
@@ -0,0 +1,19 @@
+package outbox
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Message is an outbox row. Sequence grows with every insert, so ordering by
+// it keeps the events of one note in the order they were committed.
+type Message struct {
+	Sequence   int64           `json:"sequence"`
+	EventID    uuid.UUID       `json:"event_id"`
+	NoteID     uuid.UUID       `json:"note_id"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+	CreateTime time.Time       `json:"create_time"`
+}
@@ -0,0 +1,96 @@
+package outbox
+
+import (
+	"context"
+	"note_service/app/pkg/logging"
+	"sync"
+	"time"
+)
+
+type RelayConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	// Lease is how long a claimed batch waits for the next message to be
+	// sent before another relay may take it over, it must exceed a send.
+	Lease time.Duration
+	// Retention is how long published messages are kept, zero keeps them.
+	Retention time.Duration
+}
+
+// Relay moves committed outbox messages to a Sink. A message is marked
+// published only after the sink accepted it, so a crash in between sends it
+// again on restart. The relay stops a batch at the first failure, which keeps
+// later events of the same note behind the failed one.
+type Relay struct {
+	storage Storage
+	sink    Sink
+	cfg     RelayConfig
+	logger  logging.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewRelay(storage Storage, sink Sink, cfg RelayConfig, logger logging.Logger) *Relay {
+	return &Relay{storage: storage, sink: sink, cfg: cfg, logger: logger}
+}
+
+func (r *Relay) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		ticker := time.NewTicker(r.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				r.drain(ctx)
+				r.purge(ctx)
+			}
+		}
+	}()
+}
+
+func (r *Relay) Close() error {
+	if r.cancel != nil {
+		r.cancel()
+	}
+	r.wg.Wait()
+	return nil
+}
+
+// drain relays full batches back to back until the outbox is empty or a
+// send fails.
+func (r *Relay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		var sendErr error
+		sent, err := r.storage.Relay(ctx, r.cfg.BatchSize, r.cfg.Lease, func(msg Message) error {
+			sendErr = r.sink.Send(ctx, msg)
+			return sendErr
+		})
+		if err != nil {
+			r.logger.Errorf("failed to relay outbox: %v", err)
+			return
+		}
+		if sendErr != nil {
+			r.logger.Warnf("outbox sink failed after %d messages: %v", sent, sendErr)
+			return
+		}
+		if sent < r.cfg.BatchSize {
+			return
+		}
+	}
+}
+
+func (r *Relay) purge(ctx context.Context) {
+	if r.cfg.Retention <= 0 {
+		return
+	}
+	if _, err := r.storage.Purge(ctx, time.Now().UTC().Add(-r.cfg.Retention)); err != nil {
+		r.logger.Errorf("failed to purge outbox: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Sink receives relayed messages. Send must only return nil once the message
+// is durably handed over, the relay retries everything after a failure, so
+// sinks have to tolerate duplicates.
+type Sink interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// WriterSink writes messages as JSON lines, typically to stdout.
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+func (s *WriterSink) Send(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.w.Write(append(line, '\n'))
+	return err
+}
+
+// FileSink appends JSON lines to a file and syncs after every message.
+type FileSink struct {
+	mu   sync.Mutex
+	file *os.File
+}
+
+func NewFileSink(path string) (*FileSink, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0640)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox file. error: %w", err)
+	}
+	return &FileSink{file: file}, nil
+}
+
+func (s *FileSink) Send(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.file.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+func (s *FileSink) Close() error {
+	return s.file.Close()
+}
+
+// HTTPSink POSTs every message to a URL and expects a 2xx answer. The
+// X-Event-ID header lets the receiver drop duplicates.
+type HTTPSink struct {
+	url    string
+	client *http.Client
+}
+
+func NewHTTPSink(url string, client *http.Client) *HTTPSink {
+	return &HTTPSink{url: url, client: client}
+}
+
+func (s *HTTPSink) Send(ctx context.Context, msg Message) error {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", msg.EventID.String())
+	req.Header.Set("X-Event-Type", msg.Type)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("outbox sink answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Broker is the part of a message broker client the relay needs. Messages
+// are keyed by note id so brokers that partition by key keep per note order.
+type Broker interface {
+	Publish(ctx context.Context, topic string, key, value []byte) error
+}
+
+type BrokerSink struct {
+	broker Broker
+	topic  string
+}
+
+func NewBrokerSink(broker Broker, topic string) *BrokerSink {
+	return &BrokerSink{broker: broker, topic: topic}
+}
+
+func (s *BrokerSink) Send(ctx context.Context, msg Message) error {
+	value, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return s.broker.Publish(ctx, s.topic, []byte(msg.NoteID.String()), value)
+}
@@ -0,0 +1,16 @@
+package outbox
+
+import (
+	"context"
+	"time"
+)
+
+type Storage interface {
+	// Relay claims up to limit unpublished messages for lease, hands them to
+	// send in sequence order and marks each one published once sent, up to
+	// the first error. Only one caller relays at a time, send runs outside of
+	// any transaction.
+	Relay(ctx context.Context, limit int, lease time.Duration, send func(Message) error) (int, error)
+	// Purge removes published messages older than before.
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
@@ -0,0 +1,61 @@
+package db
+
+import (
+	"context"
+	"note_service/app/internal/reminder"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/postgres"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ reminder.Storage = &db{}
+
+type db struct {
+	client *postgres.Client
+	logger logging.Logger
+}
+
+func NewStorage(client *postgres.Client, logger logging.Logger) reminder.Storage {
+	return &db{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (s *db) CreateReminder(ctx context.Context, reminder *reminder.Reminder) error {
+	return s.client.CreateReminder(ctx, reminder)
+}
+
+func (s *db) GetReminder(ctx context.Context, reminderUUID uuid.UUID) (*reminder.Reminder, error) {
+	return s.client.GetReminder(ctx, reminderUUID)
+}
+
+func (s *db) GetReminders(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*reminder.Reminders, error) {
+	return s.client.GetReminders(ctx, noteUUID, userUUID)
+}
+
+func (s *db) GetDueReminders(ctx context.Context, userUUID uuid.UUID, t time.Time) ([]reminder.Reminder, error) {
+	return s.client.GetDueReminders(ctx, userUUID, t)
+}
+
+func (s *db) DeleteReminder(ctx context.Context, reminderUUID uuid.UUID) error {
+	return s.client.DeleteReminder(ctx, reminderUUID)
+}
+
+func (s *db) ClaimReminders(ctx context.Context, limit int, lease time.Duration) ([]reminder.Reminder, error) {
+	return s.client.ClaimReminders(ctx, limit, lease)
+}
+
+func (s *db) CompleteReminder(ctx context.Context, reminderUUID uuid.UUID, fired time.Time, next *time.Time) error {
+	return s.client.CompleteReminder(ctx, reminderUUID, fired, next)
+}
+
+func (s *db) RenewReminder(ctx context.Context, reminderUUID uuid.UUID, held, until time.Time) (bool, error) {
+	return s.client.RenewReminder(ctx, reminderUUID, held, until)
+}
+
+func (s *db) RetryReminder(ctx context.Context, reminderUUID uuid.UUID, retry time.Time) error {
+	return s.client.RetryReminder(ctx, reminderUUID, retry)
+}
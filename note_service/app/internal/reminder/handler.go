@@ -0,0 +1,205 @@
+package reminder
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/handlers"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/openapi"
+	"note_service/app/pkg/ratelimit"
+	"note_service/app/pkg/user"
+	"note_service/app/pkg/validator"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	remindersURL = "/notes/:uuid/reminders"
+	reminderURL  = "/notes/:uuid/reminders/:reminder"
+	upcomingURL  = "/reminders/upcoming"
+
+	// reminders share the rate limits of notes
+	readGroup  = "notes_read"
+	writeGroup = "notes_write"
+
+	defaultUpcoming      = 24 * time.Hour
+	defaultUpcomingLimit = 50
+	maxUpcomingLimit     = 500
+)
+
+type Handler struct {
+	Logger          logging.Logger
+	ReminderService Service
+	UserClient      user_client.UserClient
+	RateLimiter     *ratelimit.Limiter
+	MaxBodyBytes    int64
+}
+
+func (h *Handler) Register(router openapi.Router) {
+	for _, route := range h.Routes() {
+		router.HandlerFunc(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (h *Handler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{ // POST /notes/{uuid}/reminders
+			Method:    http.MethodPost,
+			Path:      remindersURL,
+			Handler:   h.protect(writeGroup, h.CreateReminder),
+			Operation: createReminderOperation,
+		},
+		{ // GET /notes/{uuid}/reminders
+			Method:    http.MethodGet,
+			Path:      remindersURL,
+			Handler:   h.protect(readGroup, h.GetReminders),
+			Operation: getRemindersOperation,
+		},
+		{ // DELETE /notes/{uuid}/reminders/{reminder}
+			Method:    http.MethodDelete,
+			Path:      reminderURL,
+			Handler:   h.protect(writeGroup, h.DeleteReminder),
+			Operation: deleteReminderOperation,
+		},
+		{ // GET /reminders/upcoming
+			Method:    http.MethodGet,
+			Path:      upcomingURL,
+			Handler:   h.protect(readGroup, h.GetUpcoming),
+			Operation: getUpcomingOperation,
+		},
+	}
+}
+
+// protect requires an authenticated caller, reminders are personal.
+func (h *Handler) protect(group string, fn func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return user.Authentication(h.UserClient,
+		h.RateLimiter.Limit(group, user.Authorization(apperror.Middleware(fn))))
+}
+
+func (h *Handler) CreateReminder(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("CREATE REMINDER")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+
+	var dto CreateReminderDTO
+	defer r.Body.Close()
+	if err := validator.DecodeJSON(w, r, &dto, h.MaxBodyBytes); err != nil {
+		return err
+	}
+	dto.NoteUUID = &noteUUID
+	dto.UserUUID = &userUUID
+
+	reminder, err := h.ReminderService.Create(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	reminderBytes, err := json.Marshal(reminder)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/notes/%s/reminders/%s", noteUUID, reminder.ReminderUUID))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(reminderBytes)
+
+	return nil
+}
+
+func (h *Handler) GetReminders(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET REMINDERS")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	reminders, err := h.ReminderService.GetForNote(r.Context(), noteUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	remindersBytes, err := json.Marshal(reminders)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(remindersBytes)
+
+	return nil
+}
+
+func (h *Handler) DeleteReminder(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("DELETE REMINDER")
+	w.Header().Set("Content-Type", "application/json")
+
+	noteUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	reminderUUID, err := handlers.PathUUID(r, "reminder")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	if err := h.ReminderService.Delete(r.Context(), noteUUID, reminderUUID, userUUID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) GetUpcoming(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET UPCOMING REMINDERS")
+	w.Header().Set("Content-Type", "application/json")
+
+	q := r.URL.Query()
+	var details []apperror.FieldError
+	within := defaultUpcoming
+	if v := q.Get("within"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 || d > MaxUpcoming {
+			details = append(details, apperror.FieldError{Field: "within", Code: "invalid",
+				Message: fmt.Sprintf("must be a positive duration up to %s", MaxUpcoming)})
+		}
+		within = d
+	}
+	limit := defaultUpcomingLimit
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxUpcomingLimit {
+			details = append(details, apperror.FieldError{Field: "limit", Code: "invalid",
+				Message: fmt.Sprintf("must be between 1 and %d", maxUpcomingLimit)})
+		}
+		limit = n
+	}
+	if len(details) > 0 {
+		return apperror.ValidationError(details...)
+	}
+
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	occurrences, err := h.ReminderService.Upcoming(r.Context(), userUUID, within, limit)
+	if err != nil {
+		return err
+	}
+	occurrencesBytes, err := json.Marshal(occurrences)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(occurrencesBytes)
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package reminder
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reminder belongs to the user who set it, on a note that user can see.
+// StartTime is the first occurrence, RRule repeats it. NextFireTime is nil
+// once every occurrence has fired.
+type Reminder struct {
+	ReminderUUID *uuid.UUID `json:"id,omitempty"`
+	NoteUUID     *uuid.UUID `json:"note_id,omitempty"`
+	UserUUID     *uuid.UUID `json:"user_id,omitempty"`
+	StartTime    *time.Time `json:"start_time,omitempty"`
+	RRule        *string    `json:"rrule,omitempty"`
+	NextFireTime *time.Time `json:"next_fire_time,omitempty"`
+	FiredCount   *int       `json:"fired_count,omitempty"`
+	LastFireTime *time.Time `json:"last_fire_time,omitempty"`
+	// Attempts counts failed notifications of the next occurrence.
+	Attempts   *int       `json:"-"`
+	CreateTime *time.Time `json:"create_time,omitempty"`
+	// LeaseUntil is the lease ClaimReminders took, RenewReminder needs it to
+	// tell whether the lease is still held.
+	LeaseUntil *time.Time `json:"-"`
+}
+
+type Reminders struct {
+	Reminders []Reminder `json:"reminders"`
+}
+
+// Occurrence is one future firing of a reminder.
+type Occurrence struct {
+	ReminderUUID uuid.UUID `json:"reminder_id"`
+	NoteUUID     uuid.UUID `json:"note_id"`
+	FireTime     time.Time `json:"fire_time"`
+}
+
+type Occurrences struct {
+	Occurrences []Occurrence `json:"occurrences"`
+}
+
+type CreateReminderDTO struct {
+	NoteUUID *uuid.UUID `json:"-" validate:"-"`
+	UserUUID *uuid.UUID `json:"-" validate:"-"`
+	// FireTime is the first occurrence.
+	FireTime *time.Time `json:"fire_time" validate:"required"`
+	// RRule repeats the reminder, e.g. FREQ=WEEKLY;BYDAY=MO,FR;COUNT=10.
+	RRule *string `json:"rrule,omitempty" validate:"notblank,rule=rrule"`
+}
@@ -0,0 +1,115 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"note_service/app/internal/webhook"
+	"note_service/app/pkg/logging"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Notification is sent for every occurrence that fires.
+type Notification struct {
+	ReminderUUID uuid.UUID `json:"reminder_id"`
+	NoteUUID     uuid.UUID `json:"note_id"`
+	UserUUID     uuid.UUID `json:"user_id"`
+	FireTime     time.Time `json:"fire_time"`
+	// Occurrence counts from 1.
+	Occurrence int `json:"occurrence"`
+}
+
+// Notifier delivers fired reminders. An error makes the worker try the same
+// occurrence again later.
+type Notifier interface {
+	Notify(ctx context.Context, n Notification) error
+}
+
+type LogNotifier struct {
+	logger logging.Logger
+}
+
+func NewLogNotifier(logger logging.Logger) *LogNotifier {
+	return &LogNotifier{logger: logger}
+}
+
+func (l *LogNotifier) Notify(ctx context.Context, n Notification) error {
+	l.logger.Infof("reminder %s fired for note %s of user %s at %s", n.ReminderUUID, n.NoteUUID, n.UserUUID,
+		n.FireTime.Format(time.RFC3339))
+	return nil
+}
+
+// WebhookNotifier posts notifications as JSON to one URL, signed like webhook
+// deliveries when a secret is set.
+type WebhookNotifier struct {
+	url    string
+	secret string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url, secret string, client *http.Client) *WebhookNotifier {
+	return &WebhookNotifier{url: url, secret: secret, client: client}
+}
+
+func (wn *WebhookNotifier) Notify(ctx context.Context, n Notification) error {
+	payload, err := json.Marshal(n)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, wn.url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "note_service-reminders")
+	req.Header.Set(webhook.EventHeader, "reminder.fired")
+	if wn.secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(webhook.TimestampHeader, timestamp)
+		req.Header.Set(webhook.SignatureHeader, "sha256="+webhook.Sign(wn.secret, timestamp, payload))
+	}
+
+	resp, err := wn.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("reminder webhook answered %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// StubNotifier keeps notifications in memory, for tests and local runs.
+type StubNotifier struct {
+	mu            sync.Mutex
+	notifications []Notification
+	// Err, when set, is returned instead of recording the notification.
+	Err error
+}
+
+func (s *StubNotifier) Notify(ctx context.Context, n Notification) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.Err != nil {
+		return s.Err
+	}
+	s.notifications = append(s.notifications, n)
+	return nil
+}
+
+// Notifications returns what was notified so far.
+func (s *StubNotifier) Notifications() []Notification {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Notification(nil), s.notifications...)
+}
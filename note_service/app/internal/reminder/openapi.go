@@ -0,0 +1,82 @@
+package reminder
+
+import (
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/openapi"
+)
+
+func (h *Handler) Describe(b *openapi.Builder) {
+	b.Schema(Reminder{})
+	b.Schema(Reminders{})
+	b.Schema(Occurrences{})
+	b.Schema(CreateReminderDTO{})
+	b.Schema(apperror.Problem{})
+	b.AddRoutes(h.Routes())
+}
+
+var (
+	createReminderOperation = openapi.Operation{
+		OperationID: "createReminder",
+		Summary:     "Set a reminder on a note, optionally repeated by an RRULE",
+		Description: "Supported rule parts are FREQ (HOURLY, DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL, " +
+			"COUNT, UNTIL and BYDAY with FREQ=WEEKLY. Occurrences are computed in UTC.",
+		Tags:        []string{"reminders"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("CreateReminderDTO"))},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "Created", Content: openapi.JSON(openapi.Ref("Reminder")),
+				Headers: map[string]openapi.Header{
+					"Location": {Description: "URL of the new reminder", Schema: &openapi.Schema{Type: "string"}},
+				}},
+			"400": openapi.Problem("Malformed request body or note id"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("The note is not accessible to the caller"),
+			"404": openapi.Problem("Note not found"),
+			"422": openapi.Problem("Validation failed"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	getRemindersOperation = openapi.Operation{
+		OperationID: "listReminders",
+		Summary:     "List the caller's reminders on a note",
+		Tags:        []string{"reminders"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Reminders, next to fire first", Content: openapi.JSON(openapi.Ref("Reminders"))},
+			"400": openapi.Problem("Malformed note id"),
+			"401": openapi.Problem("Authentication required"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	deleteReminderOperation = openapi.Operation{
+		OperationID: "deleteReminder",
+		Summary:     "Delete a reminder",
+		Tags:        []string{"reminders"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Deleted"},
+			"400": openapi.Problem("Malformed note or reminder id"),
+			"401": openapi.Problem("Authentication required"),
+			"404": openapi.Problem("Reminder not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	getUpcomingOperation = openapi.Operation{
+		OperationID: "listUpcomingReminders",
+		Summary:     "List the occurrences of the caller's reminders that fire soon",
+		Tags:        []string{"reminders"},
+		Security:    openapi.BearerAuth(),
+		Parameters: []openapi.Parameter{
+			{Name: "within", In: "query", Description: "How far to look ahead as a Go duration, default 24h, at most 744h",
+				Schema: &openapi.Schema{Type: "string"}},
+			{Name: "limit", In: "query", Description: "Maximum number of occurrences, default 50, at most 500",
+				Schema: &openapi.Schema{Type: "integer"}},
+		},
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Occurrences, soonest first", Content: openapi.JSON(openapi.Ref("Occurrences"))},
+			"401": openapi.Problem("Authentication required"),
+			"422": openapi.Problem("Invalid within or limit parameter"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+)
@@ -0,0 +1,200 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/rrule"
+	"note_service/app/pkg/validator"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// MaxPerNote limits the reminders one user sets on one note.
+	MaxPerNote = 20
+	// MaxUpcoming is the furthest GET /reminders/upcoming looks ahead.
+	MaxUpcoming = 31 * 24 * time.Hour
+)
+
+var _ Service = &service{}
+
+type service struct {
+	storage   Storage
+	notes     note.Storage
+	validator *validator.Validator
+	logger    logging.Logger
+}
+
+func NewService(storage Storage, notes note.Storage, validator *validator.Validator,
+	logger logging.Logger) (Service, error) {
+	return &service{
+		storage:   storage,
+		notes:     notes,
+		validator: validator,
+		logger:    logger,
+	}, nil
+}
+
+type Service interface {
+	Create(ctx context.Context, dto CreateReminderDTO) (*Reminder, error)
+	GetForNote(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Reminders, error)
+	Delete(ctx context.Context, noteUUID, reminderUUID uuid.UUID, userUUID uuid.UUID) error
+	// Upcoming lists the occurrences of the caller's reminders in the next
+	// within, soonest first.
+	Upcoming(ctx context.Context, userUUID uuid.UUID, within time.Duration, limit int) (*Occurrences, error)
+}
+
+func (s service) Create(ctx context.Context, dto CreateReminderDTO) (*Reminder, error) {
+	errs := s.validator.Struct(&dto)
+	if len(errs) > 0 {
+		return nil, apperror.ValidationError(errs...)
+	}
+	start := dto.FireTime.UTC().Truncate(time.Second)
+	var recurrence *string
+	if dto.RRule != nil {
+		rule, err := rrule.Parse(*dto.RRule)
+		if err != nil {
+			return nil, apperror.ValidationError(apperror.FieldError{Field: "rrule", Code: "invalid",
+				Message: err.Error()})
+		}
+		canonical := rule.String()
+		recurrence = &canonical
+	}
+	next, err := nextFire(start, recurrence, time.Now().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if next == nil {
+		field := "fire_time"
+		if recurrence != nil {
+			field = "rrule"
+		}
+		return nil, apperror.ValidationError(apperror.FieldError{Field: field, Code: "past",
+			Message: "has no occurrence in the future"})
+	}
+
+	if _, err := s.notes.GetByID(ctx, *dto.NoteUUID, *dto.UserUUID); err != nil {
+		if errors.Is(err, apperror.ErrForbidden) || errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to find note by uuid. error: %w", err)
+	}
+	existing, err := s.storage.GetReminders(ctx, *dto.NoteUUID, *dto.UserUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders. error: %w", err)
+	}
+	if len(existing.Reminders) >= MaxPerNote {
+		return nil, apperror.ValidationError(apperror.FieldError{Code: "limit",
+			Message: fmt.Sprintf("at most %d reminders per note", MaxPerNote)})
+	}
+
+	fired := 0
+	reminder := Reminder{
+		NoteUUID:     dto.NoteUUID,
+		UserUUID:     dto.UserUUID,
+		StartTime:    &start,
+		RRule:        recurrence,
+		NextFireTime: next,
+		FiredCount:   &fired,
+	}
+	if err := s.storage.CreateReminder(ctx, &reminder); err != nil {
+		return nil, fmt.Errorf("failed to create reminder. error: %w", err)
+	}
+	return &reminder, nil
+}
+
+func (s service) GetForNote(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Reminders, error) {
+	reminders, err := s.storage.GetReminders(ctx, noteUUID, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders. error: %w", err)
+	}
+	return reminders, nil
+}
+
+func (s service) Delete(ctx context.Context, noteUUID, reminderUUID uuid.UUID, userUUID uuid.UUID) error {
+	reminder, err := s.storage.GetReminder(ctx, reminderUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to get reminder. error: %w", err)
+	}
+	if *reminder.UserUUID != userUUID || *reminder.NoteUUID != noteUUID {
+		return apperror.ErrNotFound
+	}
+	if err := s.storage.DeleteReminder(ctx, reminderUUID); err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return err
+		}
+		return fmt.Errorf("failed to delete reminder. error: %w", err)
+	}
+	return nil
+}
+
+func (s service) Upcoming(ctx context.Context, userUUID uuid.UUID, within time.Duration,
+	limit int) (*Occurrences, error) {
+	until := time.Now().UTC().Add(within)
+	reminders, err := s.storage.GetDueReminders(ctx, userUUID, until)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reminders. error: %w", err)
+	}
+
+	occurrences := Occurrences{Occurrences: []Occurrence{}}
+	for _, r := range reminders {
+		add := func(t time.Time) {
+			occurrences.Occurrences = append(occurrences.Occurrences,
+				Occurrence{ReminderUUID: *r.ReminderUUID, NoteUUID: *r.NoteUUID, FireTime: t})
+		}
+		if r.RRule == nil {
+			add(*r.NextFireTime)
+			continue
+		}
+		rule, err := rrule.Parse(*r.RRule)
+		if err != nil {
+			s.logger.Errorf("reminder %s has an invalid rule: %v", r.ReminderUUID, err)
+			continue
+		}
+		seen := 0
+		rule.EachFrom(*r.StartTime, *r.NextFireTime, func(t time.Time) bool {
+			if t.After(until) {
+				return false
+			}
+			add(t)
+			seen++
+			return seen < limit
+		})
+	}
+
+	sort.Slice(occurrences.Occurrences, func(i, j int) bool {
+		return occurrences.Occurrences[i].FireTime.Before(occurrences.Occurrences[j].FireTime)
+	})
+	if len(occurrences.Occurrences) > limit {
+		occurrences.Occurrences = occurrences.Occurrences[:limit]
+	}
+	return &occurrences, nil
+}
+
+// nextFire returns the first occurrence after t, nil if there is none.
+func nextFire(start time.Time, recurrence *string, t time.Time) (*time.Time, error) {
+	if recurrence == nil {
+		if start.After(t) {
+			return &start, nil
+		}
+		return nil, nil
+	}
+	rule, err := rrule.Parse(*recurrence)
+	if err != nil {
+		return nil, err
+	}
+	next, ok := rule.After(start, t)
+	if !ok {
+		return nil, nil
+	}
+	return &next, nil
+}
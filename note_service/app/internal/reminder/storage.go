@@ -0,0 +1,33 @@
+package reminder
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Storage interface {
+	CreateReminder(ctx context.Context, reminder *Reminder) error
+	GetReminder(ctx context.Context, reminderUUID uuid.UUID) (*Reminder, error)
+	// GetReminders returns the reminders userUUID set on a note.
+	GetReminders(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*Reminders, error)
+	// GetDueReminders returns the reminders of userUUID that fire next before t.
+	GetDueReminders(ctx context.Context, userUUID uuid.UUID, t time.Time) ([]Reminder, error)
+	DeleteReminder(ctx context.Context, reminderUUID uuid.UUID) error
+
+	// ClaimReminders locks up to limit due reminders and leases them, so
+	// other instances skip them until the lease runs out.
+	ClaimReminders(ctx context.Context, limit int, lease time.Duration) ([]Reminder, error)
+	// RenewReminder moves the lease held until held on to until. It returns
+	// false if the lease was lost, another worker may have claimed the
+	// reminder then.
+	RenewReminder(ctx context.Context, reminderUUID uuid.UUID, held, until time.Time) (bool, error)
+	// CompleteReminder records that the occurrence at fired went out and moves
+	// the reminder on to next, nil ends it. It does nothing if the occurrence
+	// was completed already.
+	CompleteReminder(ctx context.Context, reminderUUID uuid.UUID, fired time.Time, next *time.Time) error
+	// RetryReminder counts a failed notification and keeps the reminder
+	// leased until retry.
+	RetryReminder(ctx context.Context, reminderUUID uuid.UUID, retry time.Time) error
+}
@@ -0,0 +1,123 @@
+package reminder
+
+import (
+	"context"
+	"note_service/app/pkg/logging"
+	"sync"
+	"time"
+)
+
+type WorkerConfig struct {
+	PollInterval time.Duration
+	BatchSize    int
+	Lease        time.Duration
+	MaxAttempts  int
+	RetryDelay   time.Duration
+}
+
+// Worker fires due reminders into a Notifier. Reminders are claimed with
+// SKIP LOCKED and leased, and the lease of each is renewed right before it
+// is notified, so a slow batch can't outlive it and with several instances
+// running each occurrence is notified by exactly one of them. Only a worker
+// dying between notifying and recording the occurrence makes it fire again
+// once the lease runs out.
+// Occurrences missed while no worker ran collapse into a single notification.
+type Worker struct {
+	storage  Storage
+	notifier Notifier
+	cfg      WorkerConfig
+	logger   logging.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorker(storage Storage, notifier Notifier, cfg WorkerConfig, logger logging.Logger) *Worker {
+	return &Worker{storage: storage, notifier: notifier, cfg: cfg, logger: logger}
+}
+
+func (w *Worker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.poll(ctx)
+			}
+		}
+	}()
+}
+
+func (w *Worker) Close() error {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+	return nil
+}
+
+func (w *Worker) poll(ctx context.Context) {
+	reminders, err := w.storage.ClaimReminders(ctx, w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		w.logger.Errorf("failed to claim reminders: %v", err)
+		return
+	}
+	for i := range reminders {
+		w.fire(ctx, &reminders[i])
+	}
+}
+
+func (w *Worker) fire(ctx context.Context, r *Reminder) {
+	fired := *r.NextFireTime
+	until := time.Now().UTC().Add(w.cfg.Lease).Truncate(time.Microsecond)
+	held, err := w.storage.RenewReminder(ctx, *r.ReminderUUID, *r.LeaseUntil, until)
+	if err != nil {
+		w.logger.Errorf("failed to renew lease of reminder %s: %v", r.ReminderUUID, err)
+		return
+	}
+	if !held {
+		w.logger.Warnf("lease of reminder %s ran out before it was notified, skipping it", r.ReminderUUID)
+		return
+	}
+	r.LeaseUntil = &until
+
+	err = w.notifier.Notify(ctx, Notification{
+		ReminderUUID: *r.ReminderUUID,
+		NoteUUID:     *r.NoteUUID,
+		UserUUID:     *r.UserUUID,
+		FireTime:     fired,
+		Occurrence:   *r.FiredCount + 1,
+	})
+	now := time.Now().UTC()
+	if err != nil {
+		attempts := *r.Attempts + 1
+		if attempts < w.cfg.MaxAttempts {
+			w.logger.Warnf("failed to notify reminder %s, attempt %d: %v", r.ReminderUUID, attempts, err)
+			if err := w.storage.RetryReminder(ctx, *r.ReminderUUID, now.Add(w.cfg.RetryDelay)); err != nil {
+				w.logger.Errorf("failed to reschedule reminder %s: %v", r.ReminderUUID, err)
+			}
+			return
+		}
+		w.logger.Errorf("giving up on reminder %s at %s after %d attempts: %v", r.ReminderUUID,
+			fired.Format(time.RFC3339), attempts, err)
+	}
+
+	after := fired
+	if now.After(after) {
+		after = now
+	}
+	next, err := nextFire(*r.StartTime, r.RRule, after)
+	if err != nil {
+		w.logger.Errorf("reminder %s has an invalid rule, ending it: %v", r.ReminderUUID, err)
+	}
+	if err := w.storage.CompleteReminder(ctx, *r.ReminderUUID, fired, next); err != nil {
+		w.logger.Errorf("failed to record reminder %s: %v", r.ReminderUUID, err)
+	}
+}
@@ -0,0 +1,67 @@
+package reminder
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"note_service/app/pkg/logging"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type fakeStorage struct {
+	Storage
+	reminders []Reminder
+	// lost lists the reminders whose lease another worker took over.
+	lost      map[uuid.UUID]bool
+	completed []uuid.UUID
+}
+
+func (s *fakeStorage) ClaimReminders(ctx context.Context, limit int, lease time.Duration) ([]Reminder, error) {
+	return s.reminders, nil
+}
+
+func (s *fakeStorage) RenewReminder(ctx context.Context, reminderUUID uuid.UUID, held, until time.Time) (bool, error) {
+	return !s.lost[reminderUUID], nil
+}
+
+func (s *fakeStorage) CompleteReminder(ctx context.Context, reminderUUID uuid.UUID, fired time.Time, next *time.Time) error {
+	s.completed = append(s.completed, reminderUUID)
+	return nil
+}
+
+type fakeNotifier struct {
+	sent []uuid.UUID
+}
+
+func (n *fakeNotifier) Notify(ctx context.Context, notification Notification) error {
+	n.sent = append(n.sent, notification.ReminderUUID)
+	return nil
+}
+
+func newReminder(t time.Time) Reminder {
+	id, noteUUID, userUUID := uuid.New(), uuid.New(), uuid.New()
+	count, attempts := 0, 0
+	return Reminder{ReminderUUID: &id, NoteUUID: &noteUUID, UserUUID: &userUUID, StartTime: &t,
+		NextFireTime: &t, FiredCount: &count, Attempts: &attempts, LeaseUntil: &t}
+}
+
+func TestWorkerSkipsLostLeases(t *testing.T) {
+	now := time.Now().UTC()
+	kept, lost := newReminder(now), newReminder(now)
+	storage := &fakeStorage{reminders: []Reminder{lost, kept}, lost: map[uuid.UUID]bool{*lost.ReminderUUID: true}}
+	notifier := &fakeNotifier{}
+	w := NewWorker(storage, notifier, WorkerConfig{BatchSize: 10, Lease: time.Minute, MaxAttempts: 3},
+		logging.Logger{Entry: logrus.NewEntry(logrus.New())})
+
+	w.poll(context.Background())
+
+	if len(notifier.sent) != 1 || notifier.sent[0] != *kept.ReminderUUID {
+		t.Errorf("notified %v, want only %s", notifier.sent, kept.ReminderUUID)
+	}
+	if len(storage.completed) != 1 || storage.completed[0] != *kept.ReminderUUID {
+		t.Errorf("completed %v, want only %s", storage.completed, kept.ReminderUUID)
+	}
+}
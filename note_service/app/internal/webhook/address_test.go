@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.100.100.200", false},
+		{"fd00:ec2::254", false},
+		{"fe80::1", false},
+		{"0.0.0.0", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := publicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("publicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	s := service{resolver: net.DefaultResolver}
+	for _, raw := range []string{"http://127.0.0.1/hook", "http://169.254.169.254/latest/meta-data",
+		"https://[::1]:8443/hook", "http://10.0.0.5/"} {
+		if errs := s.checkURL(context.Background(), &raw); len(errs) != 1 || errs[0].Code != "forbidden_address" {
+			t.Errorf("checkURL(%s) = %v, want forbidden_address", raw, errs)
+		}
+	}
+	raw := "http://93.184.216.34/hook"
+	if errs := s.checkURL(context.Background(), &raw); len(errs) != 0 {
+		t.Errorf("checkURL(%s) = %v", raw, errs)
+	}
+}
+
+func TestHTTPClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	_, err := NewHTTPClient(time.Second, false).Get(srv.URL)
+	if !errors.Is(err, ErrForbiddenAddress) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrForbiddenAddress)
+	}
+	resp, err := NewHTTPClient(time.Second, true).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("Get() with allowPrivate error = %v", err)
+	}
+	resp.Body.Close()
+}
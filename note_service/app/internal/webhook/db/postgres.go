@@ -0,0 +1,77 @@
+package db
+
+import (
+	"context"
+	"note_service/app/internal/webhook"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/postgres"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ webhook.Storage = &db{}
+
+type db struct {
+	client *postgres.Client
+	logger logging.Logger
+}
+
+func NewStorage(client *postgres.Client, logger logging.Logger) webhook.Storage {
+	return &db{
+		client: client,
+		logger: logger,
+	}
+}
+
+func (s *db) CreateEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	return s.client.CreateWebhookEndpoint(ctx, endpoint)
+}
+
+func (s *db) GetEndpoint(ctx context.Context, endpointUUID uuid.UUID) (*webhook.Endpoint, error) {
+	return s.client.GetWebhookEndpoint(ctx, endpointUUID)
+}
+
+func (s *db) GetEndpoints(ctx context.Context, userUUID uuid.UUID) (*webhook.Endpoints, error) {
+	return s.client.GetWebhookEndpoints(ctx, userUUID)
+}
+
+func (s *db) GetSubscribedEndpoints(ctx context.Context, userUUID uuid.UUID, eventType string) ([]webhook.Endpoint, error) {
+	return s.client.GetSubscribedWebhookEndpoints(ctx, userUUID, eventType)
+}
+
+func (s *db) UpdateEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	return s.client.UpdateWebhookEndpoint(ctx, endpoint)
+}
+
+func (s *db) DeleteEndpoint(ctx context.Context, endpointUUID uuid.UUID) error {
+	return s.client.DeleteWebhookEndpoint(ctx, endpointUUID)
+}
+
+func (s *db) RecordEndpointResult(ctx context.Context, endpointUUID uuid.UUID, success bool, disableAfter int) error {
+	return s.client.RecordWebhookEndpointResult(ctx, endpointUUID, success, disableAfter)
+}
+
+func (s *db) CreateDeliveries(ctx context.Context, deliveries []webhook.Delivery) error {
+	return s.client.CreateWebhookDeliveries(ctx, deliveries)
+}
+
+func (s *db) GetDelivery(ctx context.Context, deliveryUUID uuid.UUID) (*webhook.Delivery, error) {
+	return s.client.GetWebhookDelivery(ctx, deliveryUUID)
+}
+
+func (s *db) GetDeliveries(ctx context.Context, endpointUUID uuid.UUID, limit int) (*webhook.Deliveries, error) {
+	return s.client.GetWebhookDeliveries(ctx, endpointUUID, limit)
+}
+
+func (s *db) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhook.Delivery, error) {
+	return s.client.ClaimWebhookDeliveries(ctx, limit, lease)
+}
+
+func (s *db) RenewDelivery(ctx context.Context, deliveryUUID, claim uuid.UUID, until time.Time) (bool, error) {
+	return s.client.RenewWebhookDelivery(ctx, deliveryUUID, claim, until)
+}
+
+func (s *db) UpdateDelivery(ctx context.Context, delivery *webhook.Delivery) (bool, error) {
+	return s.client.UpdateWebhookDelivery(ctx, delivery)
+}
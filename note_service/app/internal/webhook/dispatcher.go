@@ -0,0 +1,64 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+)
+
+var _ note.EventPublisher = &Dispatcher{}
+
+// Dispatcher turns note events into pending deliveries, one per subscribed
+// endpoint. The Worker sends them. Deliveries are stored, so those of
+// private notes leave out the text.
+type Dispatcher struct {
+	storage Storage
+	logger  logging.Logger
+}
+
+func NewDispatcher(storage Storage, logger logging.Logger) *Dispatcher {
+	return &Dispatcher{storage: storage, logger: logger}
+}
+
+type payload struct {
+	ID         string    `json:"id"`
+	Type       string    `json:"type"`
+	OccurredAt string    `json:"occurred_at"`
+	Data       note.Note `json:"data"`
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, events ...note.Event) {
+	for _, event := range events {
+		if event.Note.UserUUID == nil {
+			continue
+		}
+		endpoints, err := d.storage.GetSubscribedEndpoints(ctx, *event.Note.UserUUID, event.Type)
+		if err != nil {
+			d.logger.Errorf("failed to find webhook endpoints for %s: %v", event.Type, err)
+			continue
+		}
+		if len(endpoints) == 0 {
+			continue
+		}
+
+		body, err := json.Marshal(payload{
+			ID:         event.ID.String(),
+			Type:       event.Type,
+			OccurredAt: event.OccurredAt.Format("2006-01-02T15:04:05.999999Z07:00"),
+			Data:       event.Note.WithoutPrivateText(),
+		})
+		if err != nil {
+			d.logger.Errorf("failed to encode webhook payload: %v", err)
+			continue
+		}
+
+		deliveries := make([]Delivery, 0, len(endpoints))
+		for _, e := range endpoints {
+			deliveries = append(deliveries, newDelivery(*e.EndpointUUID, event.ID, event.Type, body))
+		}
+		if err := d.storage.CreateDeliveries(ctx, deliveries); err != nil {
+			d.logger.Errorf("failed to queue webhook deliveries for %s: %v", event.Type, err)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+
+	"github.com/google/uuid"
+)
+
+type fakeStorage struct {
+	Storage
+	deliveries []Delivery
+}
+
+func (s *fakeStorage) GetSubscribedEndpoints(ctx context.Context, userUUID uuid.UUID, eventType string) ([]Endpoint, error) {
+	id := uuid.New()
+	return []Endpoint{{EndpointUUID: &id}}, nil
+}
+
+func (s *fakeStorage) CreateDeliveries(ctx context.Context, deliveries []Delivery) error {
+	s.deliveries = append(s.deliveries, deliveries...)
+	return nil
+}
+
+func TestDispatcherLeavesOutPrivateText(t *testing.T) {
+	storage := &fakeStorage{}
+	d := NewDispatcher(storage, logging.Logger{})
+	for _, public := range []bool{false, true} {
+		noteUUID, owner := uuid.New(), uuid.New()
+		text := "text of the note"
+		d.Publish(context.Background(), note.NewEvent(note.EventUpdated,
+			note.Note{NoteUUID: &noteUUID, UserUUID: &owner, Text: &text, Public: &public}))
+	}
+	if len(storage.deliveries) != 2 {
+		t.Fatalf("queued %d deliveries, want 2", len(storage.deliveries))
+	}
+	if bytes.Contains(storage.deliveries[0].Payload, []byte("text of the note")) {
+		t.Errorf("private note payload %s holds its text", storage.deliveries[0].Payload)
+	}
+	if !bytes.Contains(storage.deliveries[1].Payload, []byte("text of the note")) {
+		t.Errorf("public note payload %s lost its text", storage.deliveries[1].Payload)
+	}
+}
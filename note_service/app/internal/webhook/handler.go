@@ -0,0 +1,260 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/internal/handlers"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/openapi"
+	"note_service/app/pkg/ratelimit"
+	"note_service/app/pkg/user"
+	"note_service/app/pkg/validator"
+
+	"github.com/google/uuid"
+)
+
+const (
+	webhooksURL   = "/webhooks"
+	webhookURL    = "/webhooks/:uuid"
+	deliveriesURL = "/webhooks/:uuid/deliveries"
+	replayURL     = "/webhooks/:uuid/deliveries/:delivery/replay"
+
+	webhooksGroup = "webhooks"
+)
+
+type Handler struct {
+	Logger         logging.Logger
+	WebhookService Service
+	UserClient     user_client.UserClient
+	RateLimiter    *ratelimit.Limiter
+	MaxBodyBytes   int64
+}
+
+func (h *Handler) Register(router openapi.Router) {
+	for _, route := range h.Routes() {
+		router.HandlerFunc(route.Method, route.Path, route.Handler)
+	}
+}
+
+func (h *Handler) Routes() []openapi.Route {
+	return []openapi.Route{
+		{ // POST /webhooks
+			Method:    http.MethodPost,
+			Path:      webhooksURL,
+			Handler:   h.protect(h.CreateEndpoint),
+			Operation: createEndpointOperation,
+		},
+		{ // GET /webhooks
+			Method:    http.MethodGet,
+			Path:      webhooksURL,
+			Handler:   h.protect(h.GetEndpoints),
+			Operation: getEndpointsOperation,
+		},
+		{ // GET /webhooks/{uuid}
+			Method:    http.MethodGet,
+			Path:      webhookURL,
+			Handler:   h.protect(h.GetEndpoint),
+			Operation: getEndpointOperation,
+		},
+		{ // PATCH /webhooks/{uuid}
+			Method:    http.MethodPatch,
+			Path:      webhookURL,
+			Handler:   h.protect(h.UpdateEndpoint),
+			Operation: updateEndpointOperation,
+		},
+		{ // DELETE /webhooks/{uuid}
+			Method:    http.MethodDelete,
+			Path:      webhookURL,
+			Handler:   h.protect(h.DeleteEndpoint),
+			Operation: deleteEndpointOperation,
+		},
+		{ // GET /webhooks/{uuid}/deliveries
+			Method:    http.MethodGet,
+			Path:      deliveriesURL,
+			Handler:   h.protect(h.GetDeliveries),
+			Operation: getDeliveriesOperation,
+		},
+		{ // POST /webhooks/{uuid}/deliveries/{delivery}/replay
+			Method:    http.MethodPost,
+			Path:      replayURL,
+			Handler:   h.protect(h.ReplayDelivery),
+			Operation: replayDeliveryOperation,
+		},
+	}
+}
+
+// protect requires an authenticated caller, webhooks are never anonymous.
+func (h *Handler) protect(fn func(http.ResponseWriter, *http.Request) error) http.HandlerFunc {
+	return user.Authentication(h.UserClient,
+		h.RateLimiter.Limit(webhooksGroup, user.Authorization(apperror.Middleware(fn))))
+}
+
+func (h *Handler) CreateEndpoint(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("CREATE WEBHOOK")
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+
+	var dto CreateEndpointDTO
+	defer r.Body.Close()
+	if err := validator.DecodeJSON(w, r, &dto, h.MaxBodyBytes); err != nil {
+		return err
+	}
+	dto.UserUUID = &userUUID
+
+	endpoint, err := h.WebhookService.CreateEndpoint(r.Context(), dto)
+	if err != nil {
+		return err
+	}
+	endpointBytes, err := json.Marshal(endpoint)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("%s/%s", webhooksURL, endpoint.EndpointUUID))
+	w.WriteHeader(http.StatusCreated)
+	w.Write(endpointBytes)
+
+	return nil
+}
+
+func (h *Handler) GetEndpoints(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET WEBHOOKS")
+	w.Header().Set("Content-Type", "application/json")
+
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	endpoints, err := h.WebhookService.GetEndpoints(r.Context(), userUUID)
+	if err != nil {
+		return err
+	}
+	endpointsBytes, err := json.Marshal(endpoints)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(endpointsBytes)
+
+	return nil
+}
+
+func (h *Handler) GetEndpoint(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET WEBHOOK")
+	w.Header().Set("Content-Type", "application/json")
+
+	endpointUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	endpoint, err := h.WebhookService.GetEndpoint(r.Context(), endpointUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	endpointBytes, err := json.Marshal(endpoint)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(endpointBytes)
+
+	return nil
+}
+
+func (h *Handler) UpdateEndpoint(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("PARTIALLY UPDATE WEBHOOK")
+	w.Header().Set("Content-Type", "application/json")
+
+	endpointUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+
+	var dto UpdateEndpointDTO
+	defer r.Body.Close()
+	if err := validator.DecodeJSON(w, r, &dto, h.MaxBodyBytes); err != nil {
+		return err
+	}
+	dto.EndpointUUID = &endpointUUID
+
+	if err := h.WebhookService.UpdateEndpoint(r.Context(), dto, userUUID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) DeleteEndpoint(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("DELETE WEBHOOK")
+	w.Header().Set("Content-Type", "application/json")
+
+	endpointUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	if err := h.WebhookService.DeleteEndpoint(r.Context(), endpointUUID, userUUID); err != nil {
+		return err
+	}
+	w.WriteHeader(http.StatusNoContent)
+
+	return nil
+}
+
+func (h *Handler) GetDeliveries(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("GET WEBHOOK DELIVERIES")
+	w.Header().Set("Content-Type", "application/json")
+
+	endpointUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	deliveries, err := h.WebhookService.GetDeliveries(r.Context(), endpointUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	deliveriesBytes, err := json.Marshal(deliveries)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write(deliveriesBytes)
+
+	return nil
+}
+
+func (h *Handler) ReplayDelivery(w http.ResponseWriter, r *http.Request) error {
+	h.Logger.Info("REPLAY WEBHOOK DELIVERY")
+	w.Header().Set("Content-Type", "application/json")
+
+	endpointUUID, err := handlers.PathUUID(r, "uuid")
+	if err != nil {
+		return err
+	}
+	deliveryUUID, err := handlers.PathUUID(r, "delivery")
+	if err != nil {
+		return err
+	}
+	userUUID := r.Context().Value("userUUID").(uuid.UUID)
+	delivery, err := h.WebhookService.ReplayDelivery(r.Context(), endpointUUID, deliveryUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	deliveryBytes, err := json.Marshal(delivery)
+	if err != nil {
+		return err
+	}
+
+	w.WriteHeader(http.StatusAccepted)
+	w.Write(deliveryBytes)
+
+	return nil
+}
@@ -0,0 +1,74 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+// Endpoint receives the events it is subscribed to. Endpoints owned by a user
+// only receive events of that user's notes, endpoints without an owner are
+// registered by admins and receive events of every note.
+type Endpoint struct {
+	EndpointUUID        *uuid.UUID `json:"id,omitempty"`
+	UserUUID            *uuid.UUID `json:"user_id,omitempty"`
+	URL                 *string    `json:"url,omitempty"`
+	Secret              *string    `json:"secret,omitempty"`
+	Events              []string   `json:"events,omitempty"`
+	Active              *bool      `json:"active,omitempty"`
+	ConsecutiveFailures *int       `json:"consecutive_failures,omitempty"`
+	CreateTime          *time.Time `json:"create_time,omitempty"`
+	DisabledTime        *time.Time `json:"disabled_time,omitempty"`
+}
+
+type Endpoints struct {
+	Endpoints []Endpoint `json:"endpoints"`
+}
+
+type Delivery struct {
+	DeliveryUUID    *uuid.UUID      `json:"id,omitempty"`
+	EndpointUUID    *uuid.UUID      `json:"endpoint_id,omitempty"`
+	EventUUID       *uuid.UUID      `json:"event_id,omitempty"`
+	EventType       *string         `json:"event_type,omitempty"`
+	Payload         json.RawMessage `json:"payload,omitempty"`
+	Status          *string         `json:"status,omitempty"`
+	Attempts        *int            `json:"attempts,omitempty"`
+	NextAttemptTime *time.Time      `json:"next_attempt_time,omitempty"`
+	LastStatusCode  *int            `json:"last_status_code,omitempty"`
+	LastError       *string         `json:"last_error,omitempty"`
+	CreateTime      *time.Time      `json:"create_time,omitempty"`
+	UpdateTime      *time.Time      `json:"update_time,omitempty"`
+	// ClaimUUID is the claim a worker holds on the delivery, set by
+	// ClaimDeliveries.
+	ClaimUUID *uuid.UUID `json:"-"`
+}
+
+type Deliveries struct {
+	Deliveries []Delivery `json:"deliveries"`
+}
+
+type CreateEndpointDTO struct {
+	UserUUID *uuid.UUID `json:"-" validate:"-"`
+	URL      *string    `json:"url" validate:"required,rule=url"`
+	Events   []string   `json:"events"`
+	// Global endpoints receive events of every note, only admins may create them.
+	Global *bool `json:"global,omitempty"`
+}
+
+type UpdateEndpointDTO struct {
+	EndpointUUID *uuid.UUID `json:"-" validate:"-"`
+	URL          *string    `json:"url,omitempty" validate:"rule=url"`
+	Events       []string   `json:"events,omitempty"`
+	Active       *bool      `json:"active,omitempty"`
+}
+
+func (dto UpdateEndpointDTO) IsEmpty() bool {
+	return dto.URL == nil && dto.Events == nil && dto.Active == nil
+}
@@ -0,0 +1,116 @@
+package webhook
+
+import (
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/openapi"
+)
+
+func (h *Handler) Describe(b *openapi.Builder) {
+	b.Schema(Endpoint{})
+	b.Schema(Endpoints{})
+	b.Schema(Delivery{})
+	b.Schema(Deliveries{})
+	b.Schema(CreateEndpointDTO{})
+	b.Schema(UpdateEndpointDTO{})
+	b.Schema(apperror.Problem{})
+	b.AddRoutes(h.Routes())
+}
+
+var (
+	createEndpointOperation = openapi.Operation{
+		OperationID: "createWebhook",
+		Summary:     "Register a webhook endpoint, the signing secret is only returned here",
+		Tags:        []string{"webhooks"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("CreateEndpointDTO"))},
+		Responses: map[string]openapi.Response{
+			"201": {Description: "Created", Content: openapi.JSON(openapi.Ref("Endpoint")),
+				Headers: map[string]openapi.Header{
+					"Location": {Description: "URL of the new endpoint", Schema: &openapi.Schema{Type: "string"}},
+				}},
+			"400": openapi.Problem("Malformed request body"),
+			"401": openapi.Problem("Authentication required"),
+			"403": openapi.Problem("Only admins may register global endpoints"),
+			"422": openapi.Problem("Validation failed"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	getEndpointsOperation = openapi.Operation{
+		OperationID: "listWebhooks",
+		Summary:     "List the caller's webhook endpoints",
+		Tags:        []string{"webhooks"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Endpoints", Content: openapi.JSON(openapi.Ref("Endpoints"))},
+			"401": openapi.Problem("Authentication required"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	getEndpointOperation = openapi.Operation{
+		OperationID: "getWebhook",
+		Summary:     "Get a webhook endpoint",
+		Tags:        []string{"webhooks"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "The endpoint", Content: openapi.JSON(openapi.Ref("Endpoint"))},
+			"400": openapi.Problem("Malformed endpoint id"),
+			"401": openapi.Problem("Authentication required"),
+			"404": openapi.Problem("Endpoint not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	updateEndpointOperation = openapi.Operation{
+		OperationID: "updateWebhook",
+		Summary:     "Change the URL, events or active flag of a webhook endpoint",
+		Tags:        []string{"webhooks"},
+		Security:    openapi.BearerAuth(),
+		RequestBody: &openapi.RequestBody{Required: true, Content: openapi.JSON(openapi.Ref("UpdateEndpointDTO"))},
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Updated"},
+			"400": openapi.Problem("Malformed request body or endpoint id"),
+			"401": openapi.Problem("Authentication required"),
+			"404": openapi.Problem("Endpoint not found"),
+			"422": openapi.Problem("Validation failed"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	deleteEndpointOperation = openapi.Operation{
+		OperationID: "deleteWebhook",
+		Summary:     "Delete a webhook endpoint and its delivery log",
+		Tags:        []string{"webhooks"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"204": {Description: "Deleted"},
+			"400": openapi.Problem("Malformed endpoint id"),
+			"401": openapi.Problem("Authentication required"),
+			"404": openapi.Problem("Endpoint not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	getDeliveriesOperation = openapi.Operation{
+		OperationID: "listWebhookDeliveries",
+		Summary:     "List the most recent deliveries of a webhook endpoint",
+		Tags:        []string{"webhooks"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"200": {Description: "Deliveries, newest first", Content: openapi.JSON(openapi.Ref("Deliveries"))},
+			"400": openapi.Problem("Malformed endpoint id"),
+			"401": openapi.Problem("Authentication required"),
+			"404": openapi.Problem("Endpoint not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+	replayDeliveryOperation = openapi.Operation{
+		OperationID: "replayWebhookDelivery",
+		Summary:     "Queue a new delivery with the payload of an earlier one",
+		Tags:        []string{"webhooks"},
+		Security:    openapi.BearerAuth(),
+		Responses: map[string]openapi.Response{
+			"202": {Description: "Queued", Content: openapi.JSON(openapi.Ref("Delivery"))},
+			"400": openapi.Problem("Malformed endpoint or delivery id"),
+			"401": openapi.Problem("Authentication required"),
+			"404": openapi.Problem("Endpoint or delivery not found"),
+			"429": openapi.Problem("Rate limit exceeded"),
+		},
+	}
+)
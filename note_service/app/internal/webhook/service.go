@@ -0,0 +1,298 @@
+package webhook
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/validator"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var _ Service = &service{}
+
+type service struct {
+	storage   Storage
+	validator *validator.Validator
+	admins    map[uuid.UUID]bool
+	// allowPrivate lets endpoints point at loopback and private addresses,
+	// for development only.
+	allowPrivate bool
+	resolver     *net.Resolver
+	logger       logging.Logger
+}
+
+func NewService(storage Storage, validator *validator.Validator, admins []uuid.UUID, allowPrivate bool,
+	logger logging.Logger) (Service, error) {
+	s := &service{
+		storage:      storage,
+		validator:    validator,
+		admins:       make(map[uuid.UUID]bool, len(admins)),
+		allowPrivate: allowPrivate,
+		resolver:     net.DefaultResolver,
+		logger:       logger,
+	}
+	for _, admin := range admins {
+		s.admins[admin] = true
+	}
+	return s, nil
+}
+
+type Service interface {
+	CreateEndpoint(ctx context.Context, dto CreateEndpointDTO) (*Endpoint, error)
+	GetEndpoints(ctx context.Context, userUUID uuid.UUID) (*Endpoints, error)
+	GetEndpoint(ctx context.Context, endpointUUID uuid.UUID, userUUID uuid.UUID) (*Endpoint, error)
+	UpdateEndpoint(ctx context.Context, dto UpdateEndpointDTO, userUUID uuid.UUID) error
+	DeleteEndpoint(ctx context.Context, endpointUUID uuid.UUID, userUUID uuid.UUID) error
+	GetDeliveries(ctx context.Context, endpointUUID uuid.UUID, userUUID uuid.UUID) (*Deliveries, error)
+	ReplayDelivery(ctx context.Context, endpointUUID, deliveryUUID uuid.UUID, userUUID uuid.UUID) (*Delivery, error)
+}
+
+func (s service) CreateEndpoint(ctx context.Context, dto CreateEndpointDTO) (*Endpoint, error) {
+	errs := s.validator.Struct(&dto)
+	errs = append(errs, s.checkURL(ctx, dto.URL)...)
+	errs = append(errs, checkEvents(dto.Events)...)
+	if len(errs) > 0 {
+		return nil, apperror.ValidationError(errs...)
+	}
+
+	owner := dto.UserUUID
+	if dto.Global != nil && *dto.Global {
+		if !s.admins[*dto.UserUUID] {
+			return nil, apperror.ErrForbidden
+		}
+		owner = nil
+	}
+	events := dto.Events
+	if len(events) == 0 {
+		events = note.EventTypes
+	}
+	secret, err := newSecret()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate webhook secret. error: %w", err)
+	}
+	active := true
+
+	endpoint := Endpoint{
+		UserUUID: owner,
+		URL:      dto.URL,
+		Secret:   &secret,
+		Events:   events,
+		Active:   &active,
+	}
+	if err := s.storage.CreateEndpoint(ctx, &endpoint); err != nil {
+		return nil, fmt.Errorf("failed to create webhook endpoint. error: %w", err)
+	}
+	return &endpoint, nil
+}
+
+func (s service) GetEndpoints(ctx context.Context, userUUID uuid.UUID) (*Endpoints, error) {
+	endpoints, err := s.storage.GetEndpoints(ctx, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook endpoints. error: %w", err)
+	}
+	visible := Endpoints{Endpoints: []Endpoint{}}
+	for _, e := range endpoints.Endpoints {
+		if e.UserUUID != nil || s.admins[userUUID] {
+			e.Secret = nil
+			visible.Endpoints = append(visible.Endpoints, e)
+		}
+	}
+	return &visible, nil
+}
+
+func (s service) GetEndpoint(ctx context.Context, endpointUUID uuid.UUID, userUUID uuid.UUID) (*Endpoint, error) {
+	endpoint, err := s.authorized(ctx, endpointUUID, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	endpoint.Secret = nil
+	return endpoint, nil
+}
+
+func (s service) UpdateEndpoint(ctx context.Context, dto UpdateEndpointDTO, userUUID uuid.UUID) error {
+	if dto.IsEmpty() {
+		return apperror.ValidationError(apperror.FieldError{
+			Code: "empty_update", Message: "at least one field must be provided"})
+	}
+	errs := s.validator.Struct(&dto)
+	if dto.URL != nil {
+		errs = append(errs, s.checkURL(ctx, dto.URL)...)
+	}
+	if dto.Events != nil {
+		errs = append(errs, checkEvents(dto.Events)...)
+	}
+	if len(errs) > 0 {
+		return apperror.ValidationError(errs...)
+	}
+
+	endpoint, err := s.authorized(ctx, *dto.EndpointUUID, userUUID)
+	if err != nil {
+		return err
+	}
+	if dto.URL != nil {
+		endpoint.URL = dto.URL
+	}
+	if len(dto.Events) > 0 {
+		endpoint.Events = dto.Events
+	}
+	if dto.Active != nil {
+		endpoint.Active = dto.Active
+		if *dto.Active {
+			// re-enabling starts with a clean failure history
+			zero := 0
+			endpoint.ConsecutiveFailures = &zero
+			endpoint.DisabledTime = nil
+		}
+	}
+	if err := s.storage.UpdateEndpoint(ctx, endpoint); err != nil {
+		return fmt.Errorf("failed to update webhook endpoint. error: %w", err)
+	}
+	return nil
+}
+
+func (s service) DeleteEndpoint(ctx context.Context, endpointUUID uuid.UUID, userUUID uuid.UUID) error {
+	if _, err := s.authorized(ctx, endpointUUID, userUUID); err != nil {
+		return err
+	}
+	if err := s.storage.DeleteEndpoint(ctx, endpointUUID); err != nil {
+		return fmt.Errorf("failed to delete webhook endpoint. error: %w", err)
+	}
+	return nil
+}
+
+func (s service) GetDeliveries(ctx context.Context, endpointUUID uuid.UUID, userUUID uuid.UUID) (*Deliveries, error) {
+	if _, err := s.authorized(ctx, endpointUUID, userUUID); err != nil {
+		return nil, err
+	}
+	deliveries, err := s.storage.GetDeliveries(ctx, endpointUUID, 100)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get webhook deliveries. error: %w", err)
+	}
+	return deliveries, nil
+}
+
+// ReplayDelivery queues a new delivery with the payload of an earlier one.
+func (s service) ReplayDelivery(ctx context.Context, endpointUUID, deliveryUUID uuid.UUID,
+	userUUID uuid.UUID) (*Delivery, error) {
+	if _, err := s.authorized(ctx, endpointUUID, userUUID); err != nil {
+		return nil, err
+	}
+	original, err := s.storage.GetDelivery(ctx, deliveryUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery. error: %w", err)
+	}
+	if *original.EndpointUUID != endpointUUID {
+		return nil, apperror.ErrNotFound
+	}
+
+	replay := newDelivery(endpointUUID, *original.EventUUID, *original.EventType, original.Payload)
+	if err := s.storage.CreateDeliveries(ctx, []Delivery{replay}); err != nil {
+		return nil, fmt.Errorf("failed to queue webhook delivery. error: %w", err)
+	}
+	return &replay, nil
+}
+
+func (s service) authorized(ctx context.Context, endpointUUID uuid.UUID, userUUID uuid.UUID) (*Endpoint, error) {
+	endpoint, err := s.storage.GetEndpoint(ctx, endpointUUID)
+	if err != nil {
+		if errors.Is(err, apperror.ErrNotFound) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("failed to get webhook endpoint. error: %w", err)
+	}
+	if endpoint.UserUUID == nil {
+		if !s.admins[userUUID] {
+			return nil, apperror.ErrNotFound
+		}
+	} else if *endpoint.UserUUID != userUUID {
+		return nil, apperror.ErrNotFound
+	}
+	return endpoint, nil
+}
+
+func newDelivery(endpointUUID, eventUUID uuid.UUID, eventType string, payload []byte) Delivery {
+	id := uuid.New()
+	status := StatusPending
+	attempts := 0
+	now := time.Now().UTC()
+	return Delivery{
+		DeliveryUUID:    &id,
+		EndpointUUID:    &endpointUUID,
+		EventUUID:       &eventUUID,
+		EventType:       &eventType,
+		Payload:         payload,
+		Status:          &status,
+		Attempts:        &attempts,
+		NextAttemptTime: &now,
+		CreateTime:      &now,
+		UpdateTime:      &now,
+	}
+}
+
+// checkURL also resolves the host, so obviously internal endpoints are
+// refused up front. The worker's dialer checks again on every delivery.
+func (s service) checkURL(ctx context.Context, raw *string) []apperror.FieldError {
+	if raw == nil {
+		return nil
+	}
+	u, err := url.Parse(*raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return []apperror.FieldError{{Field: "url", Code: "invalid", Message: "must be an absolute http or https URL"}}
+	}
+	if s.allowPrivate {
+		return nil
+	}
+	ips := []net.IP{net.ParseIP(u.Hostname())}
+	if ips[0] == nil {
+		addrs, err := s.resolver.LookupIPAddr(ctx, u.Hostname())
+		if err != nil || len(addrs) == 0 {
+			return []apperror.FieldError{{Field: "url", Code: "unresolvable", Message: "host does not resolve"}}
+		}
+		ips = ips[:0]
+		for _, a := range addrs {
+			ips = append(ips, a.IP)
+		}
+	}
+	for _, ip := range ips {
+		if !publicIP(ip) {
+			return []apperror.FieldError{{Field: "url", Code: "forbidden_address",
+				Message: "must not point at a loopback, private or link-local address"}}
+		}
+	}
+	return nil
+}
+
+func checkEvents(events []string) []apperror.FieldError {
+	var errs []apperror.FieldError
+	for _, e := range events {
+		known := false
+		for _, t := range note.EventTypes {
+			known = known || e == t
+		}
+		if !known {
+			errs = append(errs, apperror.FieldError{Field: "events", Code: "invalid",
+				Message: fmt.Sprintf("unknown event type %q", e)})
+		}
+	}
+	return errs
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
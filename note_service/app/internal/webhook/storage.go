@@ -0,0 +1,36 @@
+package webhook
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Storage interface {
+	CreateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	GetEndpoint(ctx context.Context, endpointUUID uuid.UUID) (*Endpoint, error)
+	GetEndpoints(ctx context.Context, userUUID uuid.UUID) (*Endpoints, error)
+	// GetSubscribedEndpoints returns active endpoints owned by userUUID or by
+	// nobody that subscribe to eventType.
+	GetSubscribedEndpoints(ctx context.Context, userUUID uuid.UUID, eventType string) ([]Endpoint, error)
+	UpdateEndpoint(ctx context.Context, endpoint *Endpoint) error
+	DeleteEndpoint(ctx context.Context, endpointUUID uuid.UUID) error
+	// RecordEndpointResult resets the failure counter on success, otherwise
+	// increments it and deactivates the endpoint once it reaches disableAfter.
+	RecordEndpointResult(ctx context.Context, endpointUUID uuid.UUID, success bool, disableAfter int) error
+
+	CreateDeliveries(ctx context.Context, deliveries []Delivery) error
+	GetDelivery(ctx context.Context, deliveryUUID uuid.UUID) (*Delivery, error)
+	GetDeliveries(ctx context.Context, endpointUUID uuid.UUID, limit int) (*Deliveries, error)
+	// ClaimDeliveries locks up to limit due pending deliveries and pushes their
+	// next attempt time out by lease, so other instances skip them. The
+	// deliveries share a fresh ClaimUUID.
+	ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error)
+	// RenewDelivery pushes the next attempt time of a delivery out to until
+	// and reports whether it is still held under claim.
+	RenewDelivery(ctx context.Context, deliveryUUID, claim uuid.UUID, until time.Time) (bool, error)
+	// UpdateDelivery saves an attempt and drops the claim on the delivery.
+	// Nothing is saved and false is returned once another worker claimed it.
+	UpdateDelivery(ctx context.Context, delivery *Delivery) (bool, error)
+}
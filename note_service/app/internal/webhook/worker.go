@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"note_service/app/pkg/logging"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	TimestampHeader = "X-Webhook-Timestamp"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+type WorkerConfig struct {
+	PollInterval         time.Duration
+	BatchSize            int
+	Lease                time.Duration
+	MaxAttempts          int
+	BaseBackoff          time.Duration
+	MaxBackoff           time.Duration
+	DisableAfterFailures int
+}
+
+// Worker sends pending deliveries. Several instances may run at once, claimed
+// deliveries are leased so each attempt is made by one worker.
+type Worker struct {
+	storage Storage
+	client  *http.Client
+	cfg     WorkerConfig
+	logger  logging.Logger
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+func NewWorker(storage Storage, client *http.Client, cfg WorkerConfig, logger logging.Logger) *Worker {
+	return &Worker{storage: storage, client: client, cfg: cfg, logger: logger}
+}
+
+func (w *Worker) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	w.cancel = cancel
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		ticker := time.NewTicker(w.cfg.PollInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				w.poll(ctx)
+			}
+		}
+	}()
+}
+
+func (w *Worker) Close() error {
+	if w.cancel != nil {
+		w.cancel()
+	}
+	w.wg.Wait()
+	return nil
+}
+
+func (w *Worker) poll(ctx context.Context) {
+	deliveries, err := w.storage.ClaimDeliveries(ctx, w.cfg.BatchSize, w.cfg.Lease)
+	if err != nil {
+		w.logger.Errorf("failed to claim webhook deliveries: %v", err)
+		return
+	}
+	for i := range deliveries {
+		w.deliver(ctx, &deliveries[i])
+	}
+}
+
+// deliver makes one attempt at d. The lease of the batch may have run out
+// on earlier sends, so it is renewed first and d is skipped if another
+// worker took it over.
+func (w *Worker) deliver(ctx context.Context, d *Delivery) {
+	until := time.Now().UTC().Add(w.cfg.Lease)
+	held, err := w.storage.RenewDelivery(ctx, *d.DeliveryUUID, *d.ClaimUUID, until)
+	if err != nil {
+		w.logger.Errorf("failed to renew lease of webhook delivery %s: %v", d.DeliveryUUID, err)
+		return
+	}
+	if !held {
+		w.logger.Warnf("claim on webhook delivery %s was lost before it was sent, skipping it", d.DeliveryUUID)
+		return
+	}
+
+	endpoint, err := w.storage.GetEndpoint(ctx, *d.EndpointUUID)
+	if err != nil {
+		w.logger.Errorf("failed to load webhook endpoint %s: %v", d.EndpointUUID, err)
+		return
+	}
+
+	attempts := *d.Attempts + 1
+	d.Attempts = &attempts
+	now := time.Now().UTC()
+	d.UpdateTime = &now
+
+	if endpoint.Active == nil || !*endpoint.Active {
+		status := StatusFailed
+		msg := "endpoint disabled"
+		d.Status, d.LastError = &status, &msg
+		w.save(ctx, d)
+		return
+	}
+
+	code, sendErr := w.send(ctx, endpoint, d)
+	if code != 0 {
+		d.LastStatusCode = &code
+	}
+	success := sendErr == nil
+	if success {
+		status := StatusSucceeded
+		d.Status, d.LastError = &status, nil
+	} else {
+		msg := sendErr.Error()
+		d.LastError = &msg
+		if attempts >= w.cfg.MaxAttempts {
+			status := StatusFailed
+			d.Status = &status
+		} else {
+			next := now.Add(w.backoff(attempts))
+			d.NextAttemptTime = &next
+		}
+	}
+	w.save(ctx, d)
+
+	if err := w.storage.RecordEndpointResult(ctx, *endpoint.EndpointUUID, success, w.cfg.DisableAfterFailures); err != nil {
+		w.logger.Errorf("failed to record webhook endpoint result: %v", err)
+	}
+}
+
+func (w *Worker) send(ctx context.Context, endpoint *Endpoint, d *Delivery) (int, error) {
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, *endpoint.URL, bytes.NewReader(d.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "note_service-webhooks")
+	req.Header.Set(EventHeader, *d.EventType)
+	req.Header.Set(DeliveryHeader, d.DeliveryUUID.String())
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(*endpoint.Secret, timestamp, d.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint answered %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (w *Worker) save(ctx context.Context, d *Delivery) {
+	saved, err := w.storage.UpdateDelivery(ctx, d)
+	if err != nil {
+		w.logger.Errorf("failed to update webhook delivery %s: %v", d.DeliveryUUID, err)
+	} else if !saved {
+		w.logger.Warnf("claim on webhook delivery %s was lost, its attempt is not recorded", d.DeliveryUUID)
+	}
+}
+
+func (w *Worker) backoff(attempts int) time.Duration {
+	d := w.cfg.BaseBackoff << (attempts - 1)
+	if d <= 0 || d > w.cfg.MaxBackoff {
+		return w.cfg.MaxBackoff
+	}
+	return d
+}
+
+// Sign returns the hex HMAC-SHA256 of "timestamp.payload" with the endpoint
+// secret. Receivers recompute it to check the X-Webhook-Signature header.
+func Sign(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"note_service/app/pkg/logging"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+type leaseStorage struct {
+	Storage
+	endpoint Endpoint
+	claimed  []Delivery
+	lost     map[uuid.UUID]bool
+	renewed  map[uuid.UUID]time.Time
+	updated  []Delivery
+}
+
+func (s *leaseStorage) ClaimDeliveries(ctx context.Context, limit int, lease time.Duration) ([]Delivery, error) {
+	return s.claimed, nil
+}
+
+func (s *leaseStorage) RenewDelivery(ctx context.Context, deliveryUUID, claim uuid.UUID, until time.Time) (bool, error) {
+	if s.lost[deliveryUUID] {
+		return false, nil
+	}
+	s.renewed[deliveryUUID] = until
+	return true, nil
+}
+
+func (s *leaseStorage) GetEndpoint(ctx context.Context, endpointUUID uuid.UUID) (*Endpoint, error) {
+	return &s.endpoint, nil
+}
+
+func (s *leaseStorage) UpdateDelivery(ctx context.Context, delivery *Delivery) (bool, error) {
+	s.updated = append(s.updated, *delivery)
+	return true, nil
+}
+
+func (s *leaseStorage) RecordEndpointResult(ctx context.Context, endpointUUID uuid.UUID, success bool,
+	disableAfter int) error {
+	return nil
+}
+
+func newDeliveryBatch(n int, endpointUUID uuid.UUID) []Delivery {
+	claim := uuid.New()
+	deliveries := make([]Delivery, n)
+	for i := range deliveries {
+		id, event := uuid.New(), "note.created"
+		attempts, status := 0, StatusPending
+		deliveries[i] = Delivery{DeliveryUUID: &id, EndpointUUID: &endpointUUID, EventType: &event,
+			Payload: []byte(`{}`), Status: &status, Attempts: &attempts, ClaimUUID: &claim}
+	}
+	return deliveries
+}
+
+func TestWorkerSkipsLostClaims(t *testing.T) {
+	var sent []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sent = append(sent, r.Header.Get(DeliveryHeader))
+	}))
+	defer server.Close()
+
+	endpointUUID, active := uuid.New(), true
+	url, secret := server.URL, "secret"
+	batch := newDeliveryBatch(2, endpointUUID)
+	storage := &leaseStorage{
+		endpoint: Endpoint{EndpointUUID: &endpointUUID, URL: &url, Secret: &secret, Active: &active},
+		claimed:  batch,
+		lost:     map[uuid.UUID]bool{*batch[0].DeliveryUUID: true},
+		renewed:  map[uuid.UUID]time.Time{},
+	}
+	logger := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+	w := NewWorker(storage, server.Client(), WorkerConfig{Lease: time.Minute, MaxAttempts: 3}, logger)
+
+	start := time.Now()
+	w.poll(context.Background())
+
+	if len(sent) != 1 || sent[0] != batch[1].DeliveryUUID.String() {
+		t.Fatalf("sent %v, want only %s", sent, batch[1].DeliveryUUID)
+	}
+	if until := storage.renewed[*batch[1].DeliveryUUID]; until.Before(start.Add(time.Minute)) {
+		t.Errorf("lease renewed until %s, want a full lease from the send", until)
+	}
+	if len(storage.updated) != 1 || *storage.updated[0].DeliveryUUID != *batch[1].DeliveryUUID ||
+		*storage.updated[0].ClaimUUID != *batch[1].ClaimUUID {
+		t.Errorf("updated %d deliveries, want only %s under its claim", len(storage.updated), batch[1].DeliveryUUID)
+	}
+	if *storage.updated[0].Status != StatusSucceeded {
+		t.Errorf("status = %s, want %s", *storage.updated[0].Status, StatusSucceeded)
+	}
+}
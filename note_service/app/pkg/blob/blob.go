@@ -0,0 +1,34 @@
+// Package blob stores opaque content addressed by key.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var ErrNotFound = errors.New("blob not found")
+
+// Store keeps blobs. Writes are staged because callers usually derive the
+// key from the content, e.g. its hash, and only know it after writing.
+type Store interface {
+	Stage(ctx context.Context) (Staged, error)
+	Open(ctx context.Context, key string) (Blob, error)
+	Delete(ctx context.Context, key string) error
+}
+
+// Staged is a blob being written. Commit stores it under key, when a blob
+// with that key exists already the staged copy is dropped. Abort discards it.
+// Exactly one of them must be called.
+type Staged interface {
+	io.Writer
+	Commit(key string) error
+	Abort() error
+}
+
+type Blob interface {
+	io.ReadSeekCloser
+	Size() int64
+	ModTime() time.Time
+}
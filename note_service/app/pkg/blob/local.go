@@ -0,0 +1,124 @@
+package blob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var _ Store = &LocalStore{}
+
+// LocalStore keeps blobs as files below root, fanned out by the first two
+// byte pairs of the key. Staged files live in root/tmp so committing is a
+// rename on the same filesystem.
+type LocalStore struct {
+	root string
+}
+
+func NewLocalStore(root string) (*LocalStore, error) {
+	if err := os.MkdirAll(filepath.Join(root, "tmp"), 0750); err != nil {
+		return nil, fmt.Errorf("failed to create blob directory. error: %w", err)
+	}
+	return &LocalStore{root: root}, nil
+}
+
+func (s *LocalStore) Stage(ctx context.Context) (Staged, error) {
+	f, err := os.CreateTemp(filepath.Join(s.root, "tmp"), "stage-*")
+	if err != nil {
+		return nil, err
+	}
+	return &localStaged{store: s, file: f}, nil
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (Blob, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, ErrNotFound
+		}
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return &localBlob{File: f, info: info}, nil
+}
+
+func (s *LocalStore) Delete(ctx context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+func (s *LocalStore) path(key string) (string, error) {
+	if len(key) < 5 || strings.ContainsAny(key, `/\.`) {
+		return "", fmt.Errorf("invalid blob key %q", key)
+	}
+	return filepath.Join(s.root, key[:2], key[2:4], key), nil
+}
+
+type localStaged struct {
+	store *LocalStore
+	file  *os.File
+}
+
+func (b *localStaged) Write(p []byte) (int, error) {
+	return b.file.Write(p)
+}
+
+func (b *localStaged) Commit(key string) error {
+	path, err := b.store.path(key)
+	if err != nil {
+		b.Abort()
+		return err
+	}
+	if err := b.file.Sync(); err != nil {
+		b.Abort()
+		return err
+	}
+	if err := b.file.Close(); err != nil {
+		os.Remove(b.file.Name())
+		return err
+	}
+	if _, err := os.Stat(path); err == nil {
+		return os.Remove(b.file.Name())
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0750); err != nil {
+		os.Remove(b.file.Name())
+		return err
+	}
+	return os.Rename(b.file.Name(), path)
+}
+
+func (b *localStaged) Abort() error {
+	b.file.Close()
+	return os.Remove(b.file.Name())
+}
+
+type localBlob struct {
+	*os.File
+	info os.FileInfo
+}
+
+func (b *localBlob) Size() int64 {
+	return b.info.Size()
+}
+
+func (b *localBlob) ModTime() time.Time {
+	return b.info.ModTime()
+}
@@ -0,0 +1,190 @@
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Options struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+type CORS struct {
+	allowAll         bool
+	origins          []string
+	wildcards        []wildcard
+	methods          string
+	headers          map[string]bool
+	allowedHeaders   string
+	exposedHeaders   string
+	allowCredentials bool
+	maxAge           string
+}
+
+// wildcard matches origins like https://*.example.com
+type wildcard struct {
+	prefix string
+	suffix string
+}
+
+func (w wildcard) match(origin string) bool {
+	return len(origin) > len(w.prefix)+len(w.suffix) &&
+		strings.HasPrefix(origin, w.prefix) && strings.HasSuffix(origin, w.suffix)
+}
+
+// ErrWildcardCredentials is returned for the "*" origin combined with
+// credentials, which would let every site make requests as the user.
+var ErrWildcardCredentials = errors.New(`cors: origin "*" can't be allowed with credentials`)
+
+func New(opts Options) (*CORS, error) {
+	c := &CORS{
+		headers:          make(map[string]bool),
+		allowCredentials: opts.AllowCredentials,
+	}
+	for _, o := range opts.AllowedOrigins {
+		o = strings.ToLower(strings.TrimSpace(o))
+		switch {
+		case o == "*":
+			if opts.AllowCredentials {
+				return nil, ErrWildcardCredentials
+			}
+			c.allowAll = true
+		case strings.Contains(o, "*"):
+			i := strings.Index(o, "*")
+			c.wildcards = append(c.wildcards, wildcard{prefix: o[:i], suffix: o[i+1:]})
+		default:
+			c.origins = append(c.origins, o)
+		}
+	}
+
+	methods := opts.AllowedMethods
+	if len(methods) == 0 {
+		methods = []string{http.MethodGet, http.MethodPost, http.MethodPatch, http.MethodDelete}
+	}
+	c.methods = strings.ToUpper(strings.Join(methods, ", "))
+
+	for _, h := range opts.AllowedHeaders {
+		c.headers[http.CanonicalHeaderKey(strings.TrimSpace(h))] = true
+	}
+	c.allowedHeaders = strings.Join(opts.AllowedHeaders, ", ")
+	c.exposedHeaders = strings.Join(opts.ExposedHeaders, ", ")
+	if opts.MaxAge > 0 {
+		c.maxAge = strconv.Itoa(int(opts.MaxAge.Seconds()))
+	}
+	return c, nil
+}
+
+// OriginAllowed reports whether origin is one of the allowed origins.
+func (c *CORS) OriginAllowed(origin string) bool {
+	if c.allowAll {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	for _, o := range c.origins {
+		if o == origin {
+			return true
+		}
+	}
+	for _, w := range c.wildcards {
+		if w.match(origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) setOrigin(w http.ResponseWriter, origin string) {
+	h := w.Header()
+	if c.allowAll {
+		h.Set("Access-Control-Allow-Origin", "*")
+	} else {
+		h.Set("Access-Control-Allow-Origin", origin)
+	}
+	if c.allowCredentials {
+		h.Set("Access-Control-Allow-Credentials", "true")
+	}
+}
+
+// Handler adds CORS headers to actual (non-preflight) requests.
+func (c *CORS) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		origin := r.Header.Get("Origin")
+		if r.Method != http.MethodOptions {
+			w.Header().Add("Vary", "Origin")
+		}
+		if origin != "" && r.Method != http.MethodOptions && c.OriginAllowed(origin) {
+			c.setOrigin(w, origin)
+			if c.exposedHeaders != "" {
+				w.Header().Set("Access-Control-Expose-Headers", c.exposedHeaders)
+			}
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Preflight answers OPTIONS requests. It is meant for httprouter's
+// GlobalOPTIONS, which already sets the Allow header for the matched route.
+func (c *CORS) Preflight(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	reqMethod := r.Header.Get("Access-Control-Request-Method")
+	if origin == "" || reqMethod == "" {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	h := w.Header()
+	h.Add("Vary", "Origin")
+	h.Add("Vary", "Access-Control-Request-Method")
+	h.Add("Vary", "Access-Control-Request-Headers")
+	if !c.OriginAllowed(origin) || !c.methodAllowed(reqMethod, h.Get("Allow")) || !c.headersAllowed(r) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	c.setOrigin(w, origin)
+	h.Set("Access-Control-Allow-Methods", c.methods)
+	if reqHeaders := r.Header.Get("Access-Control-Request-Headers"); reqHeaders != "" {
+		h.Set("Access-Control-Allow-Headers", reqHeaders)
+	}
+	if c.maxAge != "" {
+		h.Set("Access-Control-Max-Age", c.maxAge)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (c *CORS) methodAllowed(method, routeAllow string) bool {
+	method = strings.ToUpper(method)
+	if !strings.Contains(", "+c.methods+", ", ", "+method+", ") {
+		return false
+	}
+	if routeAllow == "" {
+		return true
+	}
+	for _, m := range strings.Split(routeAllow, ",") {
+		if strings.TrimSpace(m) == method {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *CORS) headersAllowed(r *http.Request) bool {
+	reqHeaders := r.Header.Get("Access-Control-Request-Headers")
+	if reqHeaders == "" {
+		return true
+	}
+	for _, h := range strings.Split(reqHeaders, ",") {
+		if !c.headers[http.CanonicalHeaderKey(strings.TrimSpace(h))] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,52 @@
+package cors
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestNewRefusesWildcardWithCredentials(t *testing.T) {
+	_, err := New(Options{AllowedOrigins: []string{"*"}, AllowCredentials: true})
+	if !errors.Is(err, ErrWildcardCredentials) {
+		t.Fatalf("New() error = %v, want %v", err, ErrWildcardCredentials)
+	}
+}
+
+func TestHandlerOrigin(t *testing.T) {
+	tests := []struct {
+		name       string
+		opts       Options
+		origin     string
+		wantOrigin string
+		wantCreds  string
+	}{
+		{"wildcard", Options{AllowedOrigins: []string{"*"}}, "https://a.test", "*", ""},
+		{"listed with credentials", Options{AllowedOrigins: []string{"https://a.test"}, AllowCredentials: true},
+			"https://a.test", "https://a.test", "true"},
+		{"subdomain pattern", Options{AllowedOrigins: []string{"https://*.a.test"}}, "https://x.a.test",
+			"https://x.a.test", ""},
+		{"not listed", Options{AllowedOrigins: []string{"https://a.test"}, AllowCredentials: true},
+			"https://evil.test", "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := New(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			h := c.Handler(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {}))
+			r := httptest.NewRequest(http.MethodGet, "/notes", nil)
+			r.Header.Set("Origin", tt.origin)
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			if got := w.Header().Get("Access-Control-Allow-Origin"); got != tt.wantOrigin {
+				t.Errorf("Access-Control-Allow-Origin = %q, want %q", got, tt.wantOrigin)
+			}
+			if got := w.Header().Get("Access-Control-Allow-Credentials"); got != tt.wantCreds {
+				t.Errorf("Access-Control-Allow-Credentials = %q, want %q", got, tt.wantCreds)
+			}
+		})
+	}
+}
@@ -0,0 +1,180 @@
+// Package envelope implements envelope encryption: data is sealed with a
+// data key, and data keys are stored wrapped by a master key that never
+// leaves the process. Replacing the master key only means rewrapping the
+// data keys. Everything is AES-256-GCM with a random nonce in front of the
+// ciphertext.
+package envelope
+
+import (
+	"bufio"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const KeySize = 32
+
+var (
+	ErrUnknownMasterKey = errors.New("data key is wrapped by an unknown master key")
+	ErrDecrypt          = errors.New("ciphertext can't be decrypted")
+)
+
+// Keyring holds the master keys. The first one wraps new data keys, the
+// others are kept to unwrap data keys wrapped before it was rotated in.
+type Keyring struct {
+	current string
+	keys    map[string]cipher.AEAD
+}
+
+// NewKeyring takes base64 encoded master keys, current one first.
+func NewKeyring(encoded []string) (*Keyring, error) {
+	if len(encoded) == 0 {
+		return nil, errors.New("no master key")
+	}
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(encoded))}
+	for i, s := range encoded {
+		raw, err := base64.StdEncoding.DecodeString(strings.TrimSpace(s))
+		if err != nil {
+			return nil, fmt.Errorf("master key %d is not base64: %w", i, err)
+		}
+		if len(raw) != KeySize {
+			return nil, fmt.Errorf("master key %d has %d bytes, want %d", i, len(raw), KeySize)
+		}
+		aead, err := newAEAD(raw)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(raw)
+		id := hex.EncodeToString(sum[:4])
+		if i == 0 {
+			k.current = id
+		}
+		k.keys[id] = aead
+	}
+	return k, nil
+}
+
+// ReadKeyFile reads master keys from a file with one base64 key per line,
+// current one first. Blank lines and lines starting with # are skipped.
+func ReadKeyFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			keys = append(keys, line)
+		}
+	}
+	return keys, scanner.Err()
+}
+
+// MasterKeyID names the current master key.
+func (k *Keyring) MasterKeyID() string {
+	return k.current
+}
+
+// NewDataKey returns a random data key and the key wrapped by the current
+// master key. context is bound to the wrapped key, unwrapping needs it too.
+func (k *Keyring) NewDataKey(context []byte) (*DataKey, []byte, error) {
+	raw := make([]byte, KeySize)
+	if _, err := rand.Read(raw); err != nil {
+		return nil, nil, err
+	}
+	key, err := newDataKey(raw)
+	if err != nil {
+		return nil, nil, err
+	}
+	wrapped, err := seal(k.keys[k.current], raw, context)
+	if err != nil {
+		return nil, nil, err
+	}
+	return key, wrapped, nil
+}
+
+// Unwrap opens a data key wrapped by the master key masterKeyID.
+func (k *Keyring) Unwrap(masterKeyID string, wrapped, context []byte) (*DataKey, error) {
+	raw, err := k.unwrap(masterKeyID, wrapped, context)
+	if err != nil {
+		return nil, err
+	}
+	return newDataKey(raw)
+}
+
+// Rewrap wraps a data key again with the current master key.
+func (k *Keyring) Rewrap(masterKeyID string, wrapped, context []byte) ([]byte, error) {
+	raw, err := k.unwrap(masterKeyID, wrapped, context)
+	if err != nil {
+		return nil, err
+	}
+	return seal(k.keys[k.current], raw, context)
+}
+
+func (k *Keyring) unwrap(masterKeyID string, wrapped, context []byte) ([]byte, error) {
+	aead, ok := k.keys[masterKeyID]
+	if !ok {
+		return nil, ErrUnknownMasterKey
+	}
+	return open(aead, wrapped, context)
+}
+
+// DataKey seals and opens data. additional is authenticated but not
+// encrypted, it ties the ciphertext to where it is stored.
+type DataKey struct {
+	aead cipher.AEAD
+}
+
+func newDataKey(raw []byte) (*DataKey, error) {
+	aead, err := newAEAD(raw)
+	if err != nil {
+		return nil, err
+	}
+	return &DataKey{aead: aead}, nil
+}
+
+func (d *DataKey) Seal(plaintext, additional []byte) ([]byte, error) {
+	return seal(d.aead, plaintext, additional)
+}
+
+func (d *DataKey) Open(ciphertext, additional []byte) ([]byte, error) {
+	return open(d.aead, ciphertext, additional)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func seal(aead cipher.AEAD, plaintext, additional []byte) ([]byte, error) {
+	nonce := make([]byte, aead.NonceSize(), aead.NonceSize()+len(plaintext)+aead.Overhead())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, additional), nil
+}
+
+func open(aead cipher.AEAD, ciphertext, additional []byte) ([]byte, error) {
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, ErrDecrypt
+	}
+	nonce, sealed := ciphertext[:aead.NonceSize()], ciphertext[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, sealed, additional)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,166 @@
+package envelope
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newKey(t *testing.T) string {
+	t.Helper()
+	raw := make([]byte, KeySize)
+	if _, err := rand.Read(raw); err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(raw)
+}
+
+func newKeyring(t *testing.T, keys ...string) *Keyring {
+	t.Helper()
+	k, err := NewKeyring(keys)
+	if err != nil {
+		t.Fatalf("NewKeyring() error = %v", err)
+	}
+	return k
+}
+
+func TestNewKeyringErrors(t *testing.T) {
+	short := base64.StdEncoding.EncodeToString(make([]byte, 16))
+	for name, keys := range map[string][]string{
+		"none":       nil,
+		"not base64": {"not base64!"},
+		"short":      {short},
+		"second bad": {newKey(t), "not base64!"},
+	} {
+		if _, err := NewKeyring(keys); err == nil {
+			t.Errorf("NewKeyring(%s) error = nil", name)
+		}
+	}
+}
+
+func TestDataKeyRoundTrip(t *testing.T) {
+	k := newKeyring(t, newKey(t))
+	context, additional := []byte("user 1"), []byte("note 1")
+	key, wrapped, err := k.NewDataKey(context)
+	if err != nil {
+		t.Fatalf("NewDataKey() error = %v", err)
+	}
+	ciphertext, err := key.Seal([]byte("secret text"), additional)
+	if err != nil {
+		t.Fatalf("Seal() error = %v", err)
+	}
+	if bytes.Contains(ciphertext, []byte("secret text")) {
+		t.Fatal("Seal() left the plaintext in the ciphertext")
+	}
+
+	unwrapped, err := k.Unwrap(k.MasterKeyID(), wrapped, context)
+	if err != nil {
+		t.Fatalf("Unwrap() error = %v", err)
+	}
+	plaintext, err := unwrapped.Open(ciphertext, additional)
+	if err != nil || string(plaintext) != "secret text" {
+		t.Errorf("Open() = %q, %v, want secret text", plaintext, err)
+	}
+}
+
+func TestOpenRefusesTampering(t *testing.T) {
+	k := newKeyring(t, newKey(t))
+	key, _, err := k.NewDataKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := key.Seal([]byte("secret text"), []byte("note 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tampered := append([]byte(nil), ciphertext...)
+	tampered[len(tampered)-1] ^= 1
+
+	tests := []struct {
+		name       string
+		ciphertext []byte
+		additional []byte
+	}{
+		{"wrong additional data", ciphertext, []byte("note 2")},
+		{"tampered ciphertext", tampered, []byte("note 1")},
+		{"truncated", ciphertext[:5], []byte("note 1")},
+	}
+	for _, tt := range tests {
+		if _, err := key.Open(tt.ciphertext, tt.additional); !errors.Is(err, ErrDecrypt) {
+			t.Errorf("Open(%s) error = %v, want ErrDecrypt", tt.name, err)
+		}
+	}
+}
+
+func TestUnwrapRefuses(t *testing.T) {
+	k := newKeyring(t, newKey(t))
+	_, wrapped, err := k.NewDataKey([]byte("user 1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := k.Unwrap("00000000", wrapped, []byte("user 1")); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("Unwrap(unknown master key) error = %v, want ErrUnknownMasterKey", err)
+	}
+	if _, err := k.Unwrap(k.MasterKeyID(), wrapped, []byte("user 2")); !errors.Is(err, ErrDecrypt) {
+		t.Errorf("Unwrap(wrong context) error = %v, want ErrDecrypt", err)
+	}
+	other := newKeyring(t, newKey(t))
+	if _, err := other.Unwrap(k.MasterKeyID(), wrapped, []byte("user 1")); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("Unwrap() on a keyring without the master key error = %v, want ErrUnknownMasterKey", err)
+	}
+}
+
+func TestRewrapUnderNewMasterKey(t *testing.T) {
+	oldKey, newKeyB64 := newKey(t), newKey(t)
+	before := newKeyring(t, oldKey)
+	context := []byte("user 1")
+	key, wrapped, err := before.NewDataKey(context)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ciphertext, err := key.Seal([]byte("secret text"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotated := newKeyring(t, newKeyB64, oldKey)
+	if rotated.MasterKeyID() == before.MasterKeyID() {
+		t.Fatal("MasterKeyID() unchanged after rotation")
+	}
+	rewrapped, err := rotated.Rewrap(before.MasterKeyID(), wrapped, context)
+	if err != nil {
+		t.Fatalf("Rewrap() error = %v", err)
+	}
+
+	// Once the old master key is dropped only the rewrapped key still opens.
+	after := newKeyring(t, newKeyB64)
+	if _, err := after.Unwrap(before.MasterKeyID(), wrapped, context); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("Unwrap(old wrapped key) error = %v, want ErrUnknownMasterKey", err)
+	}
+	unwrapped, err := after.Unwrap(after.MasterKeyID(), rewrapped, context)
+	if err != nil {
+		t.Fatalf("Unwrap(rewrapped key) error = %v", err)
+	}
+	if plaintext, err := unwrapped.Open(ciphertext, nil); err != nil || string(plaintext) != "secret text" {
+		t.Errorf("Open() with the rewrapped key = %q, %v, want secret text", plaintext, err)
+	}
+	if _, err := after.Rewrap(before.MasterKeyID(), wrapped, context); !errors.Is(err, ErrUnknownMasterKey) {
+		t.Errorf("Rewrap(unknown master key) error = %v, want ErrUnknownMasterKey", err)
+	}
+}
+
+func TestReadKeyFile(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "keys")
+	content := "# current first\n  key-1  \n\n# retired\nkey-2\n"
+	if err := os.WriteFile(name, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	keys, err := ReadKeyFile(name)
+	if err != nil || len(keys) != 2 || keys[0] != "key-1" || keys[1] != "key-2" {
+		t.Errorf("ReadKeyFile() = %q, %v, want [key-1 key-2]", keys, err)
+	}
+}
@@ -0,0 +1,221 @@
+// Package noteclient is a typed Go client for the note_service /notes API.
+package noteclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"note_service/app/pkg/rest"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const notesResource = "/notes"
+
+type Client struct {
+	base  rest.BaseClient
+	token string
+	retry RetryPolicy
+}
+
+type Option func(*Client)
+
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) { c.base.HTTPClient = hc }
+}
+
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(c *Client) { c.retry = p }
+}
+
+func WithToken(token string) Option {
+	return func(c *Client) { c.token = token }
+}
+
+func NewClient(baseURL string, opts ...Option) *Client {
+	c := &Client{
+		base: rest.BaseClient{
+			BaseURL: baseURL,
+			HTTPClient: &http.Client{
+				Timeout: 10 * time.Second,
+			},
+		},
+		retry: DefaultRetryPolicy,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// SetToken replaces the bearer token sent with every request.
+func (c *Client) SetToken(token string) {
+	c.token = token
+}
+
+func (c *Client) List(ctx context.Context, opts ListOptions) (*NotesPage, error) {
+	var filters []rest.FilterOptions
+	if opts.Limit > 0 {
+		filters = append(filters, rest.FilterOptions{Field: "limit", Values: []string{strconv.Itoa(opts.Limit)}})
+	}
+	if opts.Offset > 0 {
+		filters = append(filters, rest.FilterOptions{Field: "offset", Values: []string{strconv.Itoa(opts.Offset)}})
+	}
+	if opts.Archived {
+		filters = append(filters, rest.FilterOptions{Field: "archived", Values: []string{"true"}})
+	}
+	if opts.Sort != "" {
+		filters = append(filters, rest.FilterOptions{Field: "sort", Values: []string{opts.Sort}})
+	}
+	filters = append(filters, opts.Filters...)
+
+	resp, err := c.do(ctx, http.MethodGet, notesResource, filters, nil)
+	if err != nil {
+		// note_service answers 404 when nothing is visible
+		if errors.Is(err, ErrNotFound) {
+			return &NotesPage{Notes: []Note{}}, nil
+		}
+		return nil, err
+	}
+	defer resp.Body().Close()
+
+	var page NotesPage
+	if err := json.NewDecoder(resp.Body()).Decode(&page); err != nil {
+		return nil, fmt.Errorf("failed to decode body due to error %w", err)
+	}
+	page.Total = len(page.Notes) + opts.Offset
+	if total, err := strconv.Atoi(resp.Header().Get("X-Total-Count")); err == nil {
+		page.Total = total
+	}
+	return &page, nil
+}
+
+func (c *Client) Get(ctx context.Context, id uuid.UUID) (*Note, error) {
+	resp, err := c.do(ctx, http.MethodGet, path.Join(notesResource, id.String()), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body().Close()
+
+	var n Note
+	if err := json.NewDecoder(resp.Body()).Decode(&n); err != nil {
+		return nil, fmt.Errorf("failed to decode body due to error %w", err)
+	}
+	return &n, nil
+}
+
+// Create returns the id of the new note, taken from the Location header.
+func (c *Client) Create(ctx context.Context, req CreateNoteRequest) (uuid.UUID, error) {
+	resp, err := c.do(ctx, http.MethodPost, notesResource, nil, req)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	resp.Body().Close()
+
+	location, err := resp.Location()
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("failed to read location header. error: %w", err)
+	}
+	id, err := uuid.Parse(path.Base(location.Path))
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("unexpected location %q. error: %w", location, err)
+	}
+	return id, nil
+}
+
+func (c *Client) Update(ctx context.Context, id uuid.UUID, req UpdateNoteRequest) error {
+	resp, err := c.do(ctx, http.MethodPatch, path.Join(notesResource, id.String()), nil, req)
+	if err != nil {
+		return err
+	}
+	return resp.Body().Close()
+}
+
+// Schedule replaces when a note gets published or unpublished, a nil time
+// clears it.
+func (c *Client) Schedule(ctx context.Context, id uuid.UUID, req ScheduleRequest) (*Note, error) {
+	resp, err := c.do(ctx, http.MethodPut, path.Join(notesResource, id.String(), "schedule"), nil, req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body().Close()
+
+	var n Note
+	if err := json.NewDecoder(resp.Body()).Decode(&n); err != nil {
+		return nil, fmt.Errorf("failed to decode body due to error %w", err)
+	}
+	return &n, nil
+}
+
+func (c *Client) Delete(ctx context.Context, id uuid.UUID) error {
+	resp, err := c.do(ctx, http.MethodDelete, path.Join(notesResource, id.String()), nil, nil)
+	if err != nil {
+		return err
+	}
+	return resp.Body().Close()
+}
+
+// do sends the request, retrying according to the client's policy. Non-2xx
+// responses are returned as *Error; the caller closes the body on success.
+func (c *Client) do(ctx context.Context, method, resource string, filters []rest.FilterOptions,
+	body interface{}) (*rest.APIResponse, error) {
+	uri, err := c.base.BuildURL(resource, filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build URL. error: %w", err)
+	}
+
+	var payload []byte
+	if body != nil {
+		if payload, err = json.Marshal(body); err != nil {
+			return nil, fmt.Errorf("failed to encode body. error: %w", err)
+		}
+	}
+
+	attempts := max(c.retry.MaxAttempts, 1)
+	for attempt := 0; ; attempt++ {
+		var reqBody io.Reader
+		if payload != nil {
+			reqBody = bytes.NewReader(payload)
+		}
+		req, err := http.NewRequestWithContext(ctx, method, uri, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create new request due to error: %w", err)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+
+		resp, err := c.base.SendRequest(req)
+		status := 0
+		var header http.Header
+		if err == nil {
+			if resp.IsOk {
+				return resp, nil
+			}
+			status = resp.StatusCode()
+			header = resp.Header()
+		}
+
+		if attempt+1 >= attempts || !c.retry.retryable(method, status) || ctx.Err() != nil {
+			if err != nil {
+				return nil, err
+			}
+			return nil, newError(status, resp.Error)
+		}
+
+		timer := time.NewTimer(c.retry.delay(attempt, header))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
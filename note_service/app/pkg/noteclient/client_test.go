@@ -0,0 +1,180 @@
+package noteclient
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var fastRetry = RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}
+
+func newTestClient(t *testing.T, h http.HandlerFunc, opts ...Option) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	return NewClient(srv.URL, append([]Option{WithToken("secret"), WithRetryPolicy(fastRetry)}, opts...)...)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func TestCreateGetDelete(t *testing.T) {
+	id := uuid.New()
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer secret" {
+			t.Errorf("Authorization = %q", got)
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/notes":
+			var req CreateNoteRequest
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Text != "hello" {
+				t.Errorf("create body = %+v, %v", req, err)
+			}
+			w.Header().Set("Location", "/notes/"+id.String())
+			w.WriteHeader(http.StatusCreated)
+		case r.Method == http.MethodGet && r.URL.Path == "/notes/"+id.String():
+			writeJSON(w, http.StatusOK, Note{ID: id, Text: "hello"})
+		case r.Method == http.MethodDelete && r.URL.Path == "/notes/"+id.String():
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			t.Errorf("unexpected %s %s", r.Method, r.URL)
+			w.WriteHeader(http.StatusTeapot)
+		}
+	})
+	ctx := context.Background()
+
+	got, err := c.Create(ctx, CreateNoteRequest{Text: "hello"})
+	if err != nil || got != id {
+		t.Fatalf("Create() = %s, %v, want %s", got, err, id)
+	}
+	n, err := c.Get(ctx, id)
+	if err != nil || n.Text != "hello" {
+		t.Fatalf("Get() = %+v, %v", n, err)
+	}
+	if err := c.Delete(ctx, id); err != nil {
+		t.Fatalf("Delete() error = %v", err)
+	}
+}
+
+func TestErrorDecoding(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/problem+json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write([]byte(`{"title":"Unprocessable Entity","status":422,"detail":"invalid note",` +
+			`"code":"validation_failed","errors":[{"field":"text","code":"required","message":"is required"}]}`))
+	})
+
+	_, err := c.Create(context.Background(), CreateNoteRequest{})
+	if !errors.Is(err, ErrValidation) {
+		t.Fatalf("Create() error = %v, want %v", err, ErrValidation)
+	}
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Create() error = %T, want *Error", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Message != "invalid note" ||
+		len(apiErr.Errors) != 1 || apiErr.Errors[0].Field != "text" {
+		t.Errorf("Create() error = %+v", apiErr)
+	}
+}
+
+func TestErrorCodeFromStatus(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	if _, err := c.Get(context.Background(), uuid.New()); !errors.Is(err, ErrForbidden) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrForbidden)
+	}
+}
+
+func TestRetry(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		writeJSON(w, http.StatusOK, Note{Text: "hello"})
+	})
+	if _, err := c.Get(context.Background(), uuid.New()); err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Errorf("server called %d times, want 3", got)
+	}
+}
+
+func TestRetrySkipsPost(t *testing.T) {
+	var calls atomic.Int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	if _, err := c.Create(context.Background(), CreateNoteRequest{Text: "hello"}); err == nil {
+		t.Fatal("Create() error = nil")
+	}
+	if got := calls.Load(); got != 1 {
+		t.Errorf("server called %d times, want 1", got)
+	}
+}
+
+func TestListNotFoundIsEmpty(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	page, err := c.List(context.Background(), ListOptions{})
+	if err != nil || len(page.Notes) != 0 {
+		t.Fatalf("List() = %+v, %v", page, err)
+	}
+}
+
+func TestIterate(t *testing.T) {
+	const total = 7
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		offset, _ := strconv.Atoi(r.URL.Query().Get("offset"))
+		var notes []Note
+		for i := offset; i < min(offset+limit, total); i++ {
+			notes = append(notes, Note{Text: strconv.Itoa(i)})
+		}
+		w.Header().Set("X-Total-Count", strconv.Itoa(total))
+		writeJSON(w, http.StatusOK, NotesPage{Notes: notes})
+	})
+
+	it := c.Iterate(context.Background(), 3)
+	var got []string
+	for it.Next() {
+		got = append(got, it.Note().Text)
+	}
+	if err := it.Err(); err != nil {
+		t.Fatalf("Err() = %v", err)
+	}
+	if len(got) != total || got[0] != "0" || got[total-1] != "6" {
+		t.Errorf("iterated %v", got)
+	}
+	if it.Total() != total {
+		t.Errorf("Total() = %d, want %d", it.Total(), total)
+	}
+}
+
+func TestContextCanceled(t *testing.T) {
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}, WithRetryPolicy(RetryPolicy{MaxAttempts: 5, BaseDelay: time.Hour, MaxDelay: time.Hour}))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := c.Get(ctx, uuid.New()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Get() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
@@ -0,0 +1,95 @@
+package noteclient
+
+import (
+	"fmt"
+	"note_service/app/pkg/rest"
+	"strings"
+)
+
+// Error codes mirror the ones note_service puts in problem+json responses.
+const (
+	CodeBadRequest      = "bad_request"
+	CodeValidation      = "validation_failed"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeTooManyRequests = "too_many_requests"
+	CodeUpstream        = "upstream_error"
+	CodeInternal        = "internal_error"
+)
+
+var (
+	ErrBadRequest      = &Error{Code: CodeBadRequest}
+	ErrValidation      = &Error{Code: CodeValidation}
+	ErrUnauthorized    = &Error{Code: CodeUnauthorized}
+	ErrForbidden       = &Error{Code: CodeForbidden}
+	ErrNotFound        = &Error{Code: CodeNotFound}
+	ErrTooManyRequests = &Error{Code: CodeTooManyRequests}
+	ErrUpstream        = &Error{Code: CodeUpstream}
+	ErrInternal        = &Error{Code: CodeInternal}
+)
+
+type FieldError = rest.FieldError
+
+// Error is a non-2xx response of note_service. errors.Is matches it against
+// the Err* values by code, so callers can write errors.Is(err, ErrNotFound).
+type Error struct {
+	StatusCode int
+	Code       string
+	Message    string
+	Errors     []FieldError
+}
+
+func (e *Error) Error() string {
+	msg := fmt.Sprintf("note_service: %d %s: %s", e.StatusCode, e.Code, e.Message)
+	if len(e.Errors) > 0 {
+		fields := make([]string, 0, len(e.Errors))
+		for _, f := range e.Errors {
+			fields = append(fields, f.Field+" "+f.Message)
+		}
+		msg += " (" + strings.Join(fields, "; ") + ")"
+	}
+	return msg
+}
+
+func (e *Error) Is(target error) bool {
+	t, ok := target.(*Error)
+	return ok && t.Code == e.Code && (t.StatusCode == 0 || t.StatusCode == e.StatusCode)
+}
+
+var statusCodes = map[int]string{
+	400: CodeBadRequest,
+	401: CodeUnauthorized,
+	403: CodeForbidden,
+	404: CodeNotFound,
+	413: CodeBadRequest,
+	422: CodeValidation,
+	429: CodeTooManyRequests,
+	502: CodeUpstream,
+}
+
+func newError(status int, apiErr rest.APIError) *Error {
+	e := &Error{
+		StatusCode: status,
+		Code:       apiErr.Code,
+		Message:    apiErr.Detail,
+		Errors:     apiErr.Errors,
+	}
+	if e.Code == "" {
+		e.Code = apiErr.ErrorCode
+	}
+	if e.Code == "" {
+		if code, ok := statusCodes[status]; ok {
+			e.Code = code
+		} else {
+			e.Code = CodeInternal
+		}
+	}
+	if e.Message == "" {
+		e.Message = apiErr.Message
+	}
+	if e.Message == "" {
+		e.Message = apiErr.Title
+	}
+	return e
+}
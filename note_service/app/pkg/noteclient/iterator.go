@@ -0,0 +1,69 @@
+package noteclient
+
+import "context"
+
+// Iterator walks all notes visible to the caller page by page:
+//
+//	it := c.Iterate(ctx, 50)
+//	for it.Next() {
+//		n := it.Note()
+//	}
+//	if err := it.Err(); err != nil { ... }
+type Iterator struct {
+	ctx      context.Context
+	client   *Client
+	pageSize int
+	offset   int
+	total    int
+	page     []Note
+	idx      int
+	done     bool
+	err      error
+}
+
+func (c *Client) Iterate(ctx context.Context, pageSize int) *Iterator {
+	if pageSize <= 0 {
+		pageSize = 50
+	}
+	return &Iterator{ctx: ctx, client: c, pageSize: pageSize, idx: -1}
+}
+
+func (it *Iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	it.idx++
+	if it.idx < len(it.page) {
+		return true
+	}
+	if it.done {
+		return false
+	}
+
+	page, err := it.client.List(it.ctx, ListOptions{Limit: it.pageSize, Offset: it.offset})
+	if err != nil {
+		it.err = err
+		return false
+	}
+	it.page = page.Notes
+	it.idx = 0
+	it.offset += len(page.Notes)
+	it.total = page.Total
+	if len(page.Notes) < it.pageSize || it.offset >= page.Total {
+		it.done = true
+	}
+	return len(it.page) > 0
+}
+
+func (it *Iterator) Note() Note {
+	return it.page[it.idx]
+}
+
+// Total is the number of notes reported by the last fetched page.
+func (it *Iterator) Total() int {
+	return it.total
+}
+
+func (it *Iterator) Err() error {
+	return it.err
+}
@@ -0,0 +1,137 @@
+package noteclient
+
+import (
+	"encoding/json"
+	"time"
+
+	"note_service/app/pkg/rest"
+
+	"github.com/google/uuid"
+)
+
+type Note struct {
+	ID         uuid.UUID `json:"id"`
+	UserID     uuid.UUID `json:"user_id"`
+	CreateTime time.Time `json:"create_time"`
+	Text       string    `json:"text"`
+	Public     bool      `json:"public"`
+	Format     string    `json:"format"`
+	Title      string    `json:"title,omitempty"`
+	Pinned     bool      `json:"pinned"`
+	Archived   bool      `json:"archived"`
+	// NotebookID is the nil UUID for notes at the root.
+	NotebookID    uuid.UUID `json:"notebook_id"`
+	InheritPublic bool      `json:"inherit_public"`
+	// PublishAt and UnpublishAt are pending visibility changes.
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+	ExpiresAt   *time.Time `json:"expires_at,omitempty"`
+	// BurnAfterRead notes are deleted by Get of anyone but their owner.
+	BurnAfterRead bool `json:"burn_after_read"`
+	// Encrypted notes have Ciphertext instead of Text, the client decrypts
+	// it with the key wrapped for it in Recipients.
+	Encrypted  bool        `json:"encrypted"`
+	Ciphertext []byte      `json:"ciphertext,omitempty"`
+	Recipients []Recipient `json:"recipients,omitempty"`
+}
+
+// Recipient is a user who can decrypt an encrypted note, WrappedKey is the
+// key of the note encrypted for that user.
+type Recipient struct {
+	UserID     uuid.UUID `json:"user_id"`
+	Algorithm  string    `json:"algorithm"`
+	KeyID      string    `json:"key_id,omitempty"`
+	WrappedKey []byte    `json:"wrapped_key"`
+}
+
+type CreateNoteRequest struct {
+	// Text is left out of encrypted notes.
+	Text string `json:"text,omitempty"`
+	// Public is left out for notes in a notebook when InheritPublic is set.
+	Public bool `json:"public"`
+	// Format is plain or markdown, the server defaults to plain.
+	Format string `json:"format,omitempty"`
+	Title  string `json:"title,omitempty"`
+	Pinned bool   `json:"pinned,omitempty"`
+	// NotebookID puts the note into a notebook.
+	NotebookID *uuid.UUID `json:"notebook_id,omitempty"`
+	// PublishAt and UnpublishAt schedule a change of Public.
+	PublishAt     *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt   *time.Time `json:"unpublish_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	BurnAfterRead bool       `json:"burn_after_read,omitempty"`
+	// Encrypted notes need Ciphertext and Recipients, the owner included.
+	Encrypted  bool        `json:"encrypted,omitempty"`
+	Ciphertext []byte      `json:"ciphertext,omitempty"`
+	Recipients []Recipient `json:"recipients,omitempty"`
+	// InheritPublic leaves Public to the notebook.
+	InheritPublic bool `json:"-"`
+}
+
+// MarshalJSON leaves out public for notes that inherit it.
+func (r CreateNoteRequest) MarshalJSON() ([]byte, error) {
+	type plain CreateNoteRequest
+	if !r.InheritPublic {
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(struct {
+		plain
+		Public *bool `json:"public,omitempty"`
+	}{plain: plain(r)})
+}
+
+// UpdateNoteRequest only sends the fields that are set.
+type UpdateNoteRequest struct {
+	Text     *string `json:"text,omitempty"`
+	Public   *bool   `json:"public,omitempty"`
+	Format   *string `json:"format,omitempty"`
+	Title    *string `json:"title,omitempty"`
+	Pinned   *bool   `json:"pinned,omitempty"`
+	Archived *bool   `json:"archived,omitempty"`
+	// NotebookID moves the note, the nil UUID moves it to the root.
+	NotebookID    *uuid.UUID `json:"notebook_id,omitempty"`
+	InheritPublic *bool      `json:"inherit_public,omitempty"`
+	// ExpiresAt moves the expiry, it can't be removed.
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	BurnAfterRead *bool      `json:"burn_after_read,omitempty"`
+	// Ciphertext and Recipients replace those of an encrypted note.
+	Ciphertext []byte      `json:"ciphertext,omitempty"`
+	Recipients []Recipient `json:"recipients,omitempty"`
+	// ClearTitle removes the title, Title is ignored then.
+	ClearTitle bool `json:"-"`
+}
+
+// MarshalJSON sends "title": null for ClearTitle.
+func (r UpdateNoteRequest) MarshalJSON() ([]byte, error) {
+	type plain UpdateNoteRequest
+	if !r.ClearTitle {
+		return json.Marshal(plain(r))
+	}
+	return json.Marshal(struct {
+		plain
+		Title *string `json:"title"`
+	}{plain: plain(r)})
+}
+
+type ScheduleRequest struct {
+	PublishAt   *time.Time `json:"publish_at,omitempty"`
+	UnpublishAt *time.Time `json:"unpublish_at,omitempty"`
+}
+
+type ListOptions struct {
+	Limit  int
+	Offset int
+	// Archived includes archived notes.
+	Archived bool
+	// Filters are sent as is, e.g.
+	// {Field: "create_time", Operator: rest.OperatorGt, Values: []string{"2024-01-01"}}.
+	Filters []rest.FilterOptions
+	// Sort is create_time or -create_time.
+	Sort string
+}
+
+type NotesPage struct {
+	Notes []Note `json:"notes"`
+	// Total is the number of notes visible to the caller before paging.
+	Total int `json:"-"`
+}
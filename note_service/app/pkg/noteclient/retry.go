@@ -0,0 +1,56 @@
+package noteclient
+
+import (
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// RetryPolicy decides how often and how long to wait before repeating a
+// request that failed with a network error or a retryable status.
+type RetryPolicy struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+	// RetryNonIdempotent also retries POST requests, which may create
+	// duplicate notes when the first attempt reached the server.
+	RetryNonIdempotent bool
+}
+
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	BaseDelay:   200 * time.Millisecond,
+	MaxDelay:    5 * time.Second,
+}
+
+var NoRetry = RetryPolicy{MaxAttempts: 1}
+
+func (p RetryPolicy) retryable(method string, status int) bool {
+	if method == http.MethodPost && !p.RetryNonIdempotent {
+		return false
+	}
+	switch status {
+	case 0, http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// delay returns the exponential backoff with full jitter for the given
+// attempt, or the server's Retry-After when it is longer.
+func (p RetryPolicy) delay(attempt int, header http.Header) time.Duration {
+	d := p.BaseDelay << attempt
+	if d <= 0 || d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	d = time.Duration(rand.Int63n(int64(d) + 1))
+	if header != nil {
+		if secs, err := strconv.Atoi(header.Get("Retry-After")); err == nil {
+			if ra := time.Duration(secs) * time.Second; ra > d {
+				d = min(ra, p.MaxDelay)
+			}
+		}
+	}
+	return d
+}
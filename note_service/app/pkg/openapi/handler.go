@@ -0,0 +1,83 @@
+package openapi
+
+import (
+	"embed"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+	swaggerfiles "github.com/swaggo/files/v2"
+)
+
+const (
+	SpecURL   = "/openapi.json"
+	DocsURL   = "/docs"
+	initURL   = "/docs/init.js"
+	assetsURL = "/docs/assets/"
+)
+
+// The docs page and Swagger UI are served from the binary, the page works
+// offline and loads no third-party scripts.
+//
+//go:embed ui/index.html ui/init.js
+var ui embed.FS
+
+// docsPolicy only lets the docs page load what it is served with.
+const docsPolicy = "default-src 'self'; img-src 'self' data:; style-src 'self' 'unsafe-inline'"
+
+type Handler struct {
+	spec   []byte
+	assets http.Handler
+}
+
+func NewHandler(doc Document) (*Handler, error) {
+	spec, err := json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &Handler{
+		spec:   spec,
+		assets: http.StripPrefix(assetsURL, http.FileServer(http.FS(swaggerfiles.FS))),
+	}, nil
+}
+
+func (h *Handler) Register(router *httprouter.Router) {
+	router.HandlerFunc(http.MethodGet, SpecURL, h.Spec)
+	router.HandlerFunc(http.MethodGet, DocsURL, h.Docs)
+	router.HandlerFunc(http.MethodGet, initURL, h.Docs)
+	router.HandlerFunc(http.MethodGet, assetsURL+"*filepath", h.Assets)
+}
+
+func (h *Handler) Spec(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.spec)
+}
+
+// Docs serves the docs page and its script.
+func (h *Handler) Docs(w http.ResponseWriter, r *http.Request) {
+	name, contentType := "ui/index.html", "text/html; charset=utf-8"
+	if r.URL.Path == initURL {
+		name, contentType = "ui/init.js", "text/javascript; charset=utf-8"
+	}
+	page, err := ui.ReadFile(name)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	w.WriteHeader(http.StatusOK)
+	w.Write(page)
+}
+
+// Assets serves the embedded Swagger UI files, leaving out source maps.
+func (h *Handler) Assets(w http.ResponseWriter, r *http.Request) {
+	if strings.HasSuffix(r.URL.Path, ".map") || strings.HasSuffix(r.URL.Path, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	h.assets.ServeHTTP(w, r)
+}
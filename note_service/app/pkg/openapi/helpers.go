@@ -0,0 +1,17 @@
+package openapi
+
+import "note_service/app/internal/apperror"
+
+// Ref points at a schema registered with Builder.Schema.
+func Ref(name string) *Schema {
+	return &Schema{Ref: "#/components/schemas/" + name}
+}
+
+// Problem is an error response with an apperror.Problem body, the handler
+// describing it registers that schema.
+func Problem(description string) Response {
+	return Response{
+		Description: description,
+		Content:     map[string]MediaType{apperror.ProblemContentType: {Schema: Ref("Problem")}},
+	}
+}
@@ -0,0 +1,31 @@
+package openapi
+
+import "net/http"
+
+// Router is what handlers register their routes on, *httprouter.Router and
+// Recorder satisfy it.
+type Router interface {
+	HandlerFunc(method, path string, handler http.HandlerFunc)
+}
+
+// Recorder registers routes on a Router and keeps a list of them, so the
+// document can be checked against what is actually registered rather than
+// against what handlers say they register.
+type Recorder struct {
+	router     Router
+	registered []Route
+}
+
+func NewRecorder(router Router) *Recorder {
+	return &Recorder{router: router}
+}
+
+func (r *Recorder) HandlerFunc(method, path string, handler http.HandlerFunc) {
+	r.router.HandlerFunc(method, path, handler)
+	r.registered = append(r.registered, Route{Method: method, Path: path, Handler: handler})
+}
+
+// Registered lists the routes registered so far.
+func (r *Recorder) Registered() []Route {
+	return r.registered
+}
@@ -0,0 +1,169 @@
+package openapi
+
+import (
+	"note_service/app/pkg/validator"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 interface{}        `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Description          string             `json:"description,omitempty"`
+	Enum                 []interface{}      `json:"enum,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	ReadOnly             bool               `json:"readOnly,omitempty"`
+}
+
+var (
+	uuidType = reflect.TypeOf(uuid.UUID{})
+	timeType = reflect.TypeOf(time.Time{})
+)
+
+type schemaRegistry struct {
+	components map[string]*Schema
+	rules      map[string]validator.Rule
+}
+
+func (r *schemaRegistry) of(v interface{}) *Schema {
+	return r.schema(reflect.TypeOf(v))
+}
+
+func (r *schemaRegistry) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t {
+	case uuidType:
+		return &Schema{Type: "string", Format: "uuid"}
+	case timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: r.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: r.schema(t.Elem())}
+	case reflect.Struct:
+		return r.structSchema(t)
+	}
+	return &Schema{}
+}
+
+func (r *schemaRegistry) structSchema(t reflect.Type) *Schema {
+	name := t.Name()
+	ref := &Schema{Ref: "#/components/schemas/" + name}
+	if name != "" {
+		if _, ok := r.components[name]; ok {
+			return ref
+		}
+		// placeholder so recursive types terminate
+		r.components[name] = &Schema{}
+	}
+
+	s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		jsonTag := strings.Split(f.Tag.Get("json"), ",")
+		if jsonTag[0] == "-" {
+			continue
+		}
+		validate := f.Tag.Get("validate")
+		if validate == "-" {
+			// filled in by the server, never read from clients
+			continue
+		}
+		fieldName := jsonTag[0]
+		if fieldName == "" {
+			fieldName = f.Name
+		}
+		fs := r.schema(f.Type)
+		if fs.Ref == "" {
+			r.constrain(fs, validate)
+		}
+		if doc := f.Tag.Get("doc"); doc != "" {
+			fs.Description = doc
+		}
+		s.Properties[fieldName] = fs
+		if hasOption(validate, "required") {
+			s.Required = append(s.Required, fieldName)
+		}
+	}
+	if name == "" {
+		return s
+	}
+	r.components[name] = s
+	return ref
+}
+
+// constrain adds what the validate tag of a string field enforces to fs.
+func (r *schemaRegistry) constrain(fs *Schema, validate string) {
+	if fs.Type != "string" {
+		return
+	}
+	if values, ok := optionValue(validate, "oneof"); ok {
+		for _, v := range strings.Split(values, "|") {
+			fs.Enum = append(fs.Enum, v)
+		}
+	}
+	min := 0
+	if hasOption(validate, "notblank") {
+		min = 1
+	}
+	if name, ok := optionValue(validate, "rule"); ok {
+		rule := r.rules[name]
+		if rule.MinRunes > min {
+			min = rule.MinRunes
+		}
+		if rule.MaxRunes > 0 {
+			max := rule.MaxRunes
+			fs.MaxLength = &max
+		}
+	}
+	if min > 0 {
+		fs.MinLength = &min
+	}
+}
+
+func optionValue(tag, option string) (string, bool) {
+	for _, o := range strings.Split(tag, ",") {
+		if v, ok := strings.CutPrefix(o, option+"="); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
+
+func hasOption(tag, option string) bool {
+	for _, o := range strings.Split(tag, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,213 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"note_service/app/pkg/validator"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+const Version = "3.1.0"
+
+type Document struct {
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Components Components            `json:"components"`
+	Security   []map[string][]string `json:"security,omitempty"`
+}
+
+type Info struct {
+	Title   string `json:"title"`
+	Version string `json:"version"`
+}
+
+type PathItem map[string]*Operation
+
+type Components struct {
+	Schemas         map[string]*Schema        `json:"schemas,omitempty"`
+	SecuritySchemes map[string]SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type SecurityScheme struct {
+	Type   string `json:"type"`
+	Scheme string `json:"scheme,omitempty"`
+}
+
+type Operation struct {
+	OperationID string                `json:"operationId"`
+	Summary     string                `json:"summary,omitempty"`
+	Description string                `json:"description,omitempty"`
+	Tags        []string              `json:"tags,omitempty"`
+	Parameters  []Parameter           `json:"parameters,omitempty"`
+	RequestBody *RequestBody          `json:"requestBody,omitempty"`
+	Responses   map[string]Response   `json:"responses"`
+	Security    []map[string][]string `json:"security,omitempty"`
+}
+
+type Parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type RequestBody struct {
+	Required bool                 `json:"required,omitempty"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+type Response struct {
+	Description string               `json:"description"`
+	Headers     map[string]Header    `json:"headers,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Header struct {
+	Description string  `json:"description,omitempty"`
+	Schema      *Schema `json:"schema"`
+}
+
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Route is a single registration: handlers build their router setup and
+// their part of the document from the same list, so they cannot drift apart.
+type Route struct {
+	Method    string
+	Path      string
+	Handler   http.HandlerFunc
+	Operation Operation
+}
+
+type Builder struct {
+	doc     Document
+	schemas *schemaRegistry
+}
+
+func NewBuilder(title, version string) *Builder {
+	b := &Builder{
+		doc: Document{
+			OpenAPI: Version,
+			Info:    Info{Title: title, Version: version},
+			Paths:   make(map[string]PathItem),
+			Components: Components{
+				Schemas: make(map[string]*Schema),
+				SecuritySchemes: map[string]SecurityScheme{
+					"bearerAuth": {Type: "http", Scheme: "bearer"},
+				},
+			},
+		},
+	}
+	b.schemas = &schemaRegistry{components: b.doc.Components.Schemas}
+	return b
+}
+
+// Rules gives the lengths of fields validated with rule=name, they are set
+// on the schemas described after it.
+func (b *Builder) Rules(rules map[string]validator.Rule) {
+	b.schemas.rules = rules
+}
+
+// Schema returns a schema for the Go value v, registering named struct types
+// as components and referencing them.
+func (b *Builder) Schema(v interface{}) *Schema {
+	return b.schemas.of(v)
+}
+
+var (
+	paramPattern    = regexp.MustCompile(`[:*]([A-Za-z0-9_]+)`)
+	templatePattern = regexp.MustCompile(`\{[A-Za-z0-9_]+\}`)
+)
+
+// Add registers an operation for an httprouter path, converting :name
+// segments into OpenAPI {name} templates with matching path parameters.
+func (b *Builder) Add(route Route) {
+	path := paramPattern.ReplaceAllString(route.Path, "{$1}")
+	op := route.Operation
+	for _, m := range paramPattern.FindAllStringSubmatch(route.Path, -1) {
+		if !hasParameter(op.Parameters, m[1], "path") {
+			op.Parameters = append(op.Parameters, Parameter{
+				Name: m[1], In: "path", Required: true, Schema: &Schema{Type: "string"},
+			})
+		}
+	}
+	item, ok := b.doc.Paths[path]
+	if !ok {
+		item = make(PathItem)
+		b.doc.Paths[path] = item
+	}
+	item[strings.ToLower(route.Method)] = &op
+}
+
+func (b *Builder) AddRoutes(routes []Route) {
+	for _, r := range routes {
+		b.Add(r)
+	}
+}
+
+// Verify checks the document against router: every route in registered,
+// the routes recorded while registering on it, has a documented operation
+// with a unique id, and every documented operation is served by router.
+func (b *Builder) Verify(registered []Route, router *httprouter.Router) error {
+	var missing, unserved []string
+	for _, r := range registered {
+		path := paramPattern.ReplaceAllString(r.Path, "{$1}")
+		op, ok := b.doc.Paths[path][strings.ToLower(r.Method)]
+		if !ok || op.OperationID == "" || len(op.Responses) == 0 {
+			missing = append(missing, r.Method+" "+r.Path)
+		}
+	}
+	seen := make(map[string]bool)
+	for path, item := range b.doc.Paths {
+		for method, op := range item {
+			if seen[op.OperationID] {
+				return fmt.Errorf("duplicate operationId %q", op.OperationID)
+			}
+			seen[op.OperationID] = true
+			// Every parameter gets a sample value, routes like /notes/stream
+			// that a handler serves under /notes/:uuid are found as well.
+			sample := templatePattern.ReplaceAllString(path, "sample")
+			if h, _, _ := router.Lookup(strings.ToUpper(method), sample); h == nil {
+				unserved = append(unserved, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		sort.Strings(missing)
+		return fmt.Errorf("routes missing from openapi document: %s", strings.Join(missing, ", "))
+	}
+	if len(unserved) > 0 {
+		sort.Strings(unserved)
+		return fmt.Errorf("documented operations not registered on the router: %s", strings.Join(unserved, ", "))
+	}
+	return nil
+}
+
+func (b *Builder) Document() Document {
+	return b.doc
+}
+
+func hasParameter(params []Parameter, name, in string) bool {
+	for _, p := range params {
+		if p.Name == name && p.In == in {
+			return true
+		}
+	}
+	return false
+}
+
+// BearerAuth is the security requirement for routes behind user.Authentication.
+func BearerAuth() []map[string][]string {
+	return []map[string][]string{{"bearerAuth": {}}}
+}
+
+func JSON(s *Schema) map[string]MediaType {
+	return map[string]MediaType{"application/json": {Schema: s}}
+}
@@ -0,0 +1,65 @@
+package openapi
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+var getThing = Operation{OperationID: "getThing", Responses: map[string]Response{"200": {Description: "OK"}}}
+
+func noop(http.ResponseWriter, *http.Request) {}
+
+func TestVerify(t *testing.T) {
+	t.Run("matching", func(t *testing.T) {
+		router := httprouter.New()
+		recorder := NewRecorder(router)
+		recorder.HandlerFunc(http.MethodGet, "/things/:id", noop)
+		b := NewBuilder("test", "1")
+		b.Add(Route{Method: http.MethodGet, Path: "/things/:id", Operation: getThing})
+		if err := b.Verify(recorder.Registered(), router); err != nil {
+			t.Fatal(err)
+		}
+	})
+	t.Run("registered but undocumented", func(t *testing.T) {
+		router := httprouter.New()
+		recorder := NewRecorder(router)
+		recorder.HandlerFunc(http.MethodDelete, "/things/:id", noop)
+		b := NewBuilder("test", "1")
+		err := b.Verify(recorder.Registered(), router)
+		if err == nil || !strings.Contains(err.Error(), "DELETE /things/:id") {
+			t.Fatalf("Verify() error = %v, want the undocumented route", err)
+		}
+	})
+	t.Run("documented but unregistered", func(t *testing.T) {
+		router := httprouter.New()
+		b := NewBuilder("test", "1")
+		b.Add(Route{Method: http.MethodGet, Path: "/things/:id", Operation: getThing})
+		err := b.Verify(nil, router)
+		if err == nil || !strings.Contains(err.Error(), "GET /things/{id}") {
+			t.Fatalf("Verify() error = %v, want the unregistered operation", err)
+		}
+	})
+}
+
+func TestDocsServeEmbeddedUI(t *testing.T) {
+	h, err := NewHandler(NewBuilder("test", "1").Document())
+	if err != nil {
+		t.Fatal(err)
+	}
+	router := httprouter.New()
+	h.Register(router)
+	for _, path := range []string{DocsURL, initURL, assetsURL + "swagger-ui-bundle.js", assetsURL + "swagger-ui.css"} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %s = %d, want 200", path, w.Code)
+		}
+		if strings.Contains(w.Body.String(), "unpkg.com") {
+			t.Errorf("GET %s loads from unpkg.com", path)
+		}
+	}
+}
@@ -0,0 +1,381 @@
+// Package ot implements operational transformation for plain text. An Op is
+// written in JSON the way ot.js does it, a list of components where a
+// positive number retains, a string inserts and a negative number deletes:
+//
+//	[3, "abc", -2, 5]
+//
+// Positions and lengths count Unicode code points.
+package ot
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"unicode/utf8"
+)
+
+var (
+	ErrBaseLength = errors.New("operation doesn't fit the text")
+	ErrMismatch   = errors.New("operations don't share a base text")
+	ErrTooLong    = fmt.Errorf("operation is longer than %d code points", MaxLen)
+)
+
+// MaxLen bounds BaseLen and TargetLen of a decoded op, far above any text
+// while keeping their sums from overflowing.
+const MaxLen = 1 << 30
+
+// Component is a single retain, insert or delete, only one of the fields is
+// set.
+type Component struct {
+	Retain int
+	Insert string
+	Delete int
+}
+
+// Op turns a text of BaseLen code points into one of TargetLen. Build it
+// with Retain, Insert and Delete, which keep it normalized.
+type Op struct {
+	Components []Component
+	BaseLen    int
+	TargetLen  int
+}
+
+func (o *Op) Retain(n int) *Op {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLen += n
+	o.TargetLen += n
+	if last := o.last(); last != nil && last.Retain > 0 {
+		last.Retain += n
+		return o
+	}
+	o.Components = append(o.Components, Component{Retain: n})
+	return o
+}
+
+// Insert keeps inserts in front of a delete at the same position, so equal
+// edits have equal components.
+func (o *Op) Insert(s string) *Op {
+	if s == "" {
+		return o
+	}
+	o.TargetLen += utf8.RuneCountInString(s)
+	last := o.last()
+	switch {
+	case last != nil && last.Insert != "":
+		last.Insert += s
+	case last != nil && last.Delete > 0:
+		if n := len(o.Components); n > 1 && o.Components[n-2].Insert != "" {
+			o.Components[n-2].Insert += s
+		} else {
+			o.Components = append(o.Components[:n-1], Component{Insert: s}, *last)
+		}
+	default:
+		o.Components = append(o.Components, Component{Insert: s})
+	}
+	return o
+}
+
+func (o *Op) Delete(n int) *Op {
+	if n <= 0 {
+		return o
+	}
+	o.BaseLen += n
+	if last := o.last(); last != nil && last.Delete > 0 {
+		last.Delete += n
+		return o
+	}
+	o.Components = append(o.Components, Component{Delete: n})
+	return o
+}
+
+func (o *Op) last() *Component {
+	if len(o.Components) == 0 {
+		return nil
+	}
+	return &o.Components[len(o.Components)-1]
+}
+
+// IsNoop reports whether the op leaves any text unchanged.
+func (o Op) IsNoop() bool {
+	return len(o.Components) == 0 || (len(o.Components) == 1 && o.Components[0].Retain > 0)
+}
+
+func (o Op) Apply(text []rune) ([]rune, error) {
+	if len(text) != o.BaseLen {
+		return nil, ErrBaseLength
+	}
+	out := make([]rune, 0, o.TargetLen)
+	pos := 0
+	// BaseLen only matches the components of ops built by Retain and
+	// Delete, Components may have been set by hand.
+	for _, c := range o.Components {
+		switch {
+		case c.Retain > 0:
+			if c.Retain > len(text)-pos {
+				return nil, ErrBaseLength
+			}
+			out = append(out, text[pos:pos+c.Retain]...)
+			pos += c.Retain
+		case c.Insert != "":
+			out = append(out, []rune(c.Insert)...)
+		default:
+			if c.Delete < 0 || c.Delete > len(text)-pos {
+				return nil, ErrBaseLength
+			}
+			pos += c.Delete
+		}
+	}
+	if pos != len(text) {
+		return nil, ErrBaseLength
+	}
+	return out, nil
+}
+
+// TransformIndex moves a position in the base text to where it ends up in
+// the target text. Inserts at the position push it forward.
+func (o Op) TransformIndex(index int) int {
+	newIndex := index
+	for _, c := range o.Components {
+		switch {
+		case c.Retain > 0:
+			index -= c.Retain
+		case c.Insert != "":
+			newIndex += utf8.RuneCountInString(c.Insert)
+		default:
+			if c.Delete < index {
+				newIndex -= c.Delete
+			} else {
+				newIndex -= index
+			}
+			index -= c.Delete
+		}
+		if index < 0 {
+			break
+		}
+	}
+	return newIndex
+}
+
+// Transform takes two ops on the same text and returns a' and b' such that
+// applying a then b' equals applying b then a'. Inserts of a at the same
+// position as inserts of b go first.
+func Transform(a, b Op) (Op, Op, error) {
+	if a.BaseLen != b.BaseLen {
+		return Op{}, Op{}, ErrMismatch
+	}
+	var a1, b1 Op
+	ca, cb := a.Components, b.Components
+	var x, y Component
+	next := func(cs *[]Component, c *Component) bool {
+		if len(*cs) == 0 {
+			return false
+		}
+		*c, *cs = (*cs)[0], (*cs)[1:]
+		return true
+	}
+	hasX, hasY := next(&ca, &x), next(&cb, &y)
+	for hasX || hasY {
+		if hasX && x.Insert != "" {
+			a1.Insert(x.Insert)
+			b1.Retain(utf8.RuneCountInString(x.Insert))
+			hasX = next(&ca, &x)
+			continue
+		}
+		if hasY && y.Insert != "" {
+			a1.Retain(utf8.RuneCountInString(y.Insert))
+			b1.Insert(y.Insert)
+			hasY = next(&cb, &y)
+			continue
+		}
+		if !hasX || !hasY {
+			return Op{}, Op{}, ErrMismatch
+		}
+
+		xn, yn := x.Retain+x.Delete, y.Retain+y.Delete
+		n := xn
+		if yn < n {
+			n = yn
+		}
+		switch {
+		case x.Retain > 0 && y.Retain > 0:
+			a1.Retain(n)
+			b1.Retain(n)
+		case x.Delete > 0 && y.Retain > 0:
+			a1.Delete(n)
+		case x.Retain > 0 && y.Delete > 0:
+			b1.Delete(n)
+		}
+		// Both deleting the same text leaves nothing to do for either.
+		x, hasX = shrink(x, n), true
+		y, hasY = shrink(y, n), true
+		if x.Retain+x.Delete == 0 {
+			hasX = next(&ca, &x)
+		}
+		if y.Retain+y.Delete == 0 {
+			hasY = next(&cb, &y)
+		}
+	}
+	return a1, b1, nil
+}
+
+// Compose returns a single op that has the effect of applying a and then b.
+func Compose(a, b Op) (Op, error) {
+	if a.TargetLen != b.BaseLen {
+		return Op{}, ErrMismatch
+	}
+	var o Op
+	ca, cb := a.Components, b.Components
+	var x, y Component
+	// xs holds what is left of an insert of a.
+	var xs []rune
+	next := func(cs *[]Component, c *Component) bool {
+		if len(*cs) == 0 {
+			return false
+		}
+		*c, *cs = (*cs)[0], (*cs)[1:]
+		return true
+	}
+	nextX := func() bool {
+		ok := next(&ca, &x)
+		xs = []rune(x.Insert)
+		return ok
+	}
+	hasX, hasY := nextX(), next(&cb, &y)
+	for hasX || hasY {
+		if hasX && x.Insert == "" && x.Delete > 0 {
+			o.Delete(x.Delete)
+			hasX = nextX()
+			continue
+		}
+		if hasY && y.Insert != "" {
+			o.Insert(y.Insert)
+			hasY = next(&cb, &y)
+			continue
+		}
+		if !hasX || !hasY {
+			return Op{}, ErrMismatch
+		}
+
+		xn := x.Retain
+		if x.Insert != "" {
+			xn = len(xs)
+		}
+		yn := y.Retain + y.Delete
+		n := xn
+		if yn < n {
+			n = yn
+		}
+		switch {
+		case x.Insert != "" && y.Retain > 0:
+			o.Insert(string(xs[:n]))
+		case x.Retain > 0 && y.Retain > 0:
+			o.Retain(n)
+		case x.Retain > 0 && y.Delete > 0:
+			o.Delete(n)
+		}
+		// An insert of a deleted by b leaves nothing.
+		if x.Insert != "" {
+			xs = xs[n:]
+			if len(xs) == 0 {
+				hasX = nextX()
+			}
+		} else if x = shrink(x, n); x.Retain == 0 {
+			hasX = nextX()
+		}
+		if y = shrink(y, n); y.Retain+y.Delete == 0 {
+			hasY = next(&cb, &y)
+		}
+	}
+	return o, nil
+}
+
+func shrink(c Component, n int) Component {
+	if c.Retain > 0 {
+		c.Retain -= n
+	} else {
+		c.Delete -= n
+	}
+	return c
+}
+
+// Diff returns an op turning from into to, keeping their common prefix and
+// suffix.
+func Diff(from, to string) Op {
+	a, b := []rune(from), []rune(to)
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	var o Op
+	o.Retain(prefix)
+	o.Insert(string(b[prefix : len(b)-suffix]))
+	o.Delete(len(a) - prefix - suffix)
+	o.Retain(suffix)
+	return o
+}
+
+func (o Op) MarshalJSON() ([]byte, error) {
+	out := make([]interface{}, 0, len(o.Components))
+	for _, c := range o.Components {
+		switch {
+		case c.Retain > 0:
+			out = append(out, c.Retain)
+		case c.Insert != "":
+			out = append(out, c.Insert)
+		default:
+			out = append(out, -c.Delete)
+		}
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON refuses ops longer than MaxLen.
+func (o *Op) UnmarshalJSON(data []byte) error {
+	var raw []json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	*o = Op{}
+	for i, r := range raw {
+		if len(r) > 0 && r[0] == '"' {
+			var s string
+			if err := json.Unmarshal(r, &s); err != nil {
+				return err
+			}
+			if s == "" {
+				return fmt.Errorf("component %d is an empty insert", i)
+			}
+			if utf8.RuneCountInString(s) > MaxLen-o.TargetLen {
+				return ErrTooLong
+			}
+			o.Insert(s)
+			continue
+		}
+		var n int
+		if err := json.NewDecoder(bytes.NewReader(r)).Decode(&n); err != nil {
+			return fmt.Errorf("component %d is neither a string nor an integer", i)
+		}
+		switch {
+		case n > 0:
+			if n > MaxLen-o.BaseLen || n > MaxLen-o.TargetLen {
+				return ErrTooLong
+			}
+			o.Retain(n)
+		case n < 0:
+			if n < -(MaxLen - o.BaseLen) {
+				return ErrTooLong
+			}
+			o.Delete(-n)
+		default:
+			return fmt.Errorf("component %d is zero", i)
+		}
+	}
+	return nil
+}
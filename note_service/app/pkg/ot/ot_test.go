@@ -0,0 +1,204 @@
+package ot
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"testing"
+)
+
+func op(t *testing.T, s string) Op {
+	t.Helper()
+	var o Op
+	if err := json.Unmarshal([]byte(s), &o); err != nil {
+		t.Fatalf("Unmarshal(%s) error = %v", s, err)
+	}
+	return o
+}
+
+func apply(t *testing.T, o Op, text string) string {
+	t.Helper()
+	out, err := o.Apply([]rune(text))
+	if err != nil {
+		t.Fatalf("Apply(%q) error = %v", text, err)
+	}
+	return string(out)
+}
+
+func TestApply(t *testing.T) {
+	tests := []struct {
+		op, text, want string
+	}{
+		{`[5]`, "hello", "hello"},
+		{`["¡", 5, "!"]`, "hello", "¡hello!"},
+		{`[1, -3, "ipp", 1]`, "hello", "hippo"},
+		{`[-2, 1]`, "héy", "y"},
+	}
+	for _, tt := range tests {
+		if got := apply(t, op(t, tt.op), tt.text); got != tt.want {
+			t.Errorf("%s.Apply(%q) = %q, want %q", tt.op, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestApplyRejectsWrongLength(t *testing.T) {
+	for _, text := range []string{"hell", "hello!"} {
+		if _, err := op(t, `[1, -3, "ipp", 1]`).Apply([]rune(text)); !errors.Is(err, ErrBaseLength) {
+			t.Errorf("Apply(%q) error = %v, want %v", text, err, ErrBaseLength)
+		}
+	}
+	// Components set by hand don't have to match BaseLen.
+	for _, o := range []Op{
+		{Components: []Component{{Retain: 10}}, BaseLen: 5},
+		{Components: []Component{{Delete: 10}}, BaseLen: 5},
+		{Components: []Component{{Retain: 2}}, BaseLen: 5},
+	} {
+		if _, err := o.Apply([]rune("hello")); !errors.Is(err, ErrBaseLength) {
+			t.Errorf("%+v.Apply() error = %v, want %v", o, err, ErrBaseLength)
+		}
+	}
+}
+
+func TestUnmarshalRejectsMalformed(t *testing.T) {
+	for _, s := range []string{
+		`{}`, `[0]`, `[""]`, `[1.5]`, `[true]`, `[null]`, `[[1]]`,
+		fmt.Sprintf(`[%d]`, MaxLen+1),
+		fmt.Sprintf(`[%d, %d]`, MaxLen, 1),
+		fmt.Sprintf(`[%d]`, -MaxLen-1),
+		fmt.Sprintf(`[%d, %d]`, math.MaxInt64, math.MaxInt64),
+		fmt.Sprintf(`[%d, 1]`, math.MinInt64),
+		`[99999999999999999999999]`,
+	} {
+		var o Op
+		if err := json.Unmarshal([]byte(s), &o); err == nil {
+			t.Errorf("Unmarshal(%s) = %+v, want an error", s, o)
+		}
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	o := op(t, `[2, "ab", -1, 3]`)
+	data, err := json.Marshal(o)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `[2,"ab",-1,3]` {
+		t.Errorf("Marshal() = %s", data)
+	}
+	if o.BaseLen != 6 || o.TargetLen != 7 {
+		t.Errorf("BaseLen, TargetLen = %d, %d, want 6, 7", o.BaseLen, o.TargetLen)
+	}
+}
+
+func TestBuilderNormalizes(t *testing.T) {
+	var o Op
+	o.Retain(1).Retain(2).Delete(1).Insert("a").Insert("b").Delete(2).Retain(0).Insert("")
+	want := []Component{{Retain: 3}, {Insert: "ab"}, {Delete: 3}}
+	if !reflect.DeepEqual(o.Components, want) {
+		t.Errorf("Components = %+v, want %+v", o.Components, want)
+	}
+}
+
+func TestTransform(t *testing.T) {
+	const text = "the quick fox"
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"inserts apart", `[4, "very ", 9]`, `[13, "!"]`, "the very quick fox!"},
+		{"inserts at the same position", `[4, "a", 9]`, `[4, "b", 9]`, "the abquick fox"},
+		{"insert into a deletion", `[6, "X", 7]`, `[4, -6, 3]`, "the Xfox"},
+		{"same deletion", `[4, -6, 3]`, `[4, -6, 3]`, "the fox"},
+		{"overlapping deletions", `[2, -6, 5]`, `[6, -5, 2]`, "thox"},
+		{"replace everything", `[-13, "slow"]`, `["a ", 13]`, "slowa "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, b := op(t, tt.a), op(t, tt.b)
+			a1, b1, err := Transform(a, b)
+			if err != nil {
+				t.Fatalf("Transform() error = %v", err)
+			}
+			ab := apply(t, b1, apply(t, a, text))
+			ba := apply(t, a1, apply(t, b, text))
+			if ab != tt.want || ba != tt.want {
+				t.Errorf("a then b' = %q, b then a' = %q, want %q", ab, ba, tt.want)
+			}
+		})
+	}
+}
+
+func TestTransformRejectsMismatch(t *testing.T) {
+	if _, _, err := Transform(op(t, `[3]`), op(t, `[4]`)); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Transform() error = %v, want %v", err, ErrMismatch)
+	}
+	bad := Op{Components: []Component{{Retain: 2}}, BaseLen: 3}
+	if _, _, err := Transform(bad, op(t, `[3]`)); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Transform(malformed) error = %v, want %v", err, ErrMismatch)
+	}
+}
+
+func TestCompose(t *testing.T) {
+	const text = "hello world"
+	tests := []struct {
+		a, b string
+	}{
+		{`[5, ",", 6]`, `[12, "!"]`},
+		{`[6, "big ", 5]`, `[4, -6, 5]`},
+		{`["abc", 11]`, `[1, -1, 12]`},
+		{`[-6, 5]`, `["bye ", 5]`},
+		{`[5, -6]`, `[-5]`},
+	}
+	for _, tt := range tests {
+		a, b := op(t, tt.a), op(t, tt.b)
+		ab, err := Compose(a, b)
+		if err != nil {
+			t.Fatalf("Compose(%s, %s) error = %v", tt.a, tt.b, err)
+		}
+		if got, want := apply(t, ab, text), apply(t, b, apply(t, a, text)); got != want {
+			t.Errorf("Compose(%s, %s) gives %q, want %q", tt.a, tt.b, got, want)
+		}
+	}
+	if _, err := Compose(op(t, `[3]`), op(t, `[4]`)); !errors.Is(err, ErrMismatch) {
+		t.Errorf("Compose() error = %v, want %v", err, ErrMismatch)
+	}
+}
+
+func TestTransformConverges(t *testing.T) {
+	a, b := op(t, `[2, "xy", -3, 4]`), op(t, `[-1, 5, "z", 3]`)
+	a1, b1, err := Transform(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab1, err := Compose(a, b1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ba1, err := Compose(b, a1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := apply(t, ab1, "abcdefghi"), apply(t, ba1, "abcdefghi"); got != want {
+		t.Errorf("a·b' gives %q, b·a' gives %q", got, want)
+	}
+}
+
+func TestTransformIndex(t *testing.T) {
+	o := op(t, `[2, "xy", -3, 4]`)
+	for index, want := range map[int]int{0: 0, 2: 4, 3: 4, 5: 4, 6: 5, 9: 8} {
+		if got := o.TransformIndex(index); got != want {
+			t.Errorf("TransformIndex(%d) = %d, want %d", index, got, want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, tt := range [][2]string{{"hello", "help"}, {"", "new"}, {"gone", ""}, {"same", "same"}, {"añb", "ab"}} {
+		if got := apply(t, Diff(tt[0], tt[1]), tt[0]); got != tt[1] {
+			t.Errorf("Diff(%q, %q) applied = %q", tt[0], tt[1], got)
+		}
+	}
+}
@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	e "note_service/app/internal/apperror"
+	"note_service/app/internal/attachment"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+const attachmentColumns = `id, note_id, user_id, filename, content_type, size, sha256, create_time`
+
+func scanAttachment(row rowScanner) (*attachment.Attachment, error) {
+	var a attachment.Attachment
+	if err := row.Scan(&a.AttachmentUUID, &a.NoteUUID, &a.UserUUID, &a.Filename, &a.ContentType, &a.Size,
+		&a.SHA256, &a.CreateTime); err != nil {
+		return nil, err
+	}
+	return &a, nil
+}
+
+// CreateAttachment inserts the row only while the owner's total stays within
+// quota. The owner's existing rows are locked first so concurrent uploads of
+// one user are checked one after another. commit stores the blob while its
+// hash is locked, so a concurrent delete can't remove it before the row
+// referring to it exists.
+func (c *Client) CreateAttachment(ctx context.Context, a *attachment.Attachment, quota int64,
+	commit func() error) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if quota > 0 {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext($1::text))`, a.UserUUID); err != nil {
+			return fmt.Errorf("error locking attachment quota: %w", err)
+		}
+		var used int64
+		usageQuery := `SELECT COALESCE(SUM(size), 0) FROM attachments WHERE user_id = $1`
+		if err := tx.QueryRowContext(ctx, usageQuery, a.UserUUID).Scan(&used); err != nil {
+			return fmt.Errorf("error getting attachment usage: %w", err)
+		}
+		if used+*a.Size > quota {
+			return attachment.ErrQuotaExceeded
+		}
+	}
+
+	if err := lockBlobs(ctx, tx, []string{*a.SHA256}); err != nil {
+		return err
+	}
+	if err := commit(); err != nil {
+		return err
+	}
+	query := `INSERT INTO attachments (` + attachmentColumns + `) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	if _, err := tx.ExecContext(ctx, query, a.AttachmentUUID, a.NoteUUID, a.UserUUID, a.Filename, a.ContentType,
+		a.Size, a.SHA256, a.CreateTime); err != nil {
+		return fmt.Errorf("error creating attachment: %w", err)
+	}
+	return tx.Commit()
+}
+
+func (c *Client) GetAttachment(ctx context.Context, attachmentUUID uuid.UUID) (*attachment.Attachment, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE id = $1`
+	a, err := scanAttachment(c.db.QueryRowContext(ctx, query, attachmentUUID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, e.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting attachment: %w", err)
+	}
+	return a, nil
+}
+
+func (c *Client) GetNoteAttachments(ctx context.Context, noteUUID uuid.UUID) (*attachment.Attachments, error) {
+	query := `SELECT ` + attachmentColumns + ` FROM attachments WHERE note_id = $1 ORDER BY create_time`
+	rows, err := c.Query(ctx, query, noteUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting attachments: %w", err)
+	}
+	defer rows.Close()
+	attachments := attachment.Attachments{Attachments: []attachment.Attachment{}}
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error getting attachments: %w", err)
+		}
+		attachments.Attachments = append(attachments.Attachments, *a)
+	}
+	return &attachments, rows.Err()
+}
+
+func (c *Client) DeleteAttachment(ctx context.Context, attachmentUUID uuid.UUID, drop func(sha256 string)) error {
+	_, err := c.deleteAttachments(ctx, `id = $1`, attachmentUUID, drop)
+	return err
+}
+
+func (c *Client) DeleteNoteAttachments(ctx context.Context, noteUUID uuid.UUID, drop func(sha256 string)) (int64,
+	error) {
+	return c.deleteAttachments(ctx, `note_id = $1`, noteUUID, drop)
+}
+
+// SweepAttachments deletes the attachments of notes that no longer exist,
+// left behind when a note was deleted without its event being handled.
+func (c *Client) SweepAttachments(ctx context.Context, drop func(sha256 string)) (int64, error) {
+	return c.deleteAttachments(ctx, `NOT EXISTS (SELECT 1 FROM notes n WHERE n.id = attachments.note_id)`, nil,
+		drop)
+}
+
+// ReleaseAttachmentBlob calls drop when no attachment refers to the blob.
+func (c *Client) ReleaseAttachmentBlob(ctx context.Context, sha256 string, drop func(sha256 string)) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+	if err := dropUnreferenced(ctx, tx, []string{sha256}, drop); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// deleteAttachments deletes the rows matching where and calls drop for the
+// blobs nothing refers to anymore. Hashes are locked after the delete and
+// checked again, an upload committing the same blob either finishes first
+// and keeps it, or waits and stores it anew.
+func (c *Client) deleteAttachments(ctx context.Context, where string, arg interface{},
+	drop func(sha256 string)) (int64, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM attachments WHERE ` + where + ` RETURNING sha256`
+	var args []interface{}
+	if arg != nil {
+		args = append(args, arg)
+	}
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting attachments: %w", err)
+	}
+	var deleted int64
+	seen := make(map[string]bool)
+	var sums []string
+	for rows.Next() {
+		var sum string
+		if err := rows.Scan(&sum); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error deleting attachments: %w", err)
+		}
+		deleted++
+		if !seen[sum] {
+			seen[sum] = true
+			sums = append(sums, sum)
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error deleting attachments: %w", err)
+	}
+	if err := dropUnreferenced(ctx, tx, sums, drop); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error deleting attachments: %w", err)
+	}
+	return deleted, nil
+}
+
+// dropUnreferenced locks sums and calls drop for each one no attachment
+// refers to. Blobs go before the transaction commits, while they are still
+// locked; a failed commit then leaves rows without a blob, which read as
+// not found, rather than a blob deleted under a new row.
+func dropUnreferenced(ctx context.Context, tx *sql.Tx, sums []string, drop func(sha256 string)) error {
+	if err := lockBlobs(ctx, tx, sums); err != nil {
+		return err
+	}
+	query := `SELECT EXISTS (SELECT 1 FROM attachments WHERE sha256 = $1)`
+	for _, sum := range sums {
+		var referenced bool
+		if err := tx.QueryRowContext(ctx, query, sum).Scan(&referenced); err != nil {
+			return fmt.Errorf("error checking attachment blob: %w", err)
+		}
+		if !referenced {
+			drop(sum)
+		}
+	}
+	return nil
+}
+
+// lockBlobs takes the transaction's locks of the blob hashes, sorted so two
+// transactions never wait on each other.
+func lockBlobs(ctx context.Context, tx *sql.Tx, sums []string) error {
+	sums = append([]string(nil), sums...)
+	sort.Strings(sums)
+	for _, sum := range sums {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock(hashtext('blob:' || $1))`, sum); err != nil {
+			return fmt.Errorf("error locking attachment blob: %w", err)
+		}
+	}
+	return nil
+}
+
+func (c *Client) AttachmentUsage(ctx context.Context, userUUID uuid.UUID) (int64, error) {
+	var used int64
+	query := `SELECT COALESCE(SUM(size), 0) FROM attachments WHERE user_id = $1`
+	if err := c.db.QueryRowContext(ctx, query, userUUID).Scan(&used); err != nil {
+		return 0, fmt.Errorf("error getting attachment usage: %w", err)
+	}
+	return used, nil
+}
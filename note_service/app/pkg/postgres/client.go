@@ -17,6 +17,7 @@ import (
 type Client struct {
 	logger logging.Logger
 	db     *sql.DB
+	keys   *textKeys
 }
 
 func NewClient(ctx context.Context, host, port, username, password, database string, logger logging.Logger) (*Client, error) {
@@ -37,6 +38,62 @@ func NewClient(ctx context.Context, host, port, username, password, database str
 		db:     db}, nil
 }
 
+const noteColumns = `id, user_id, create_time, text, text_ciphertext, text_key_version, public, format, title,
+	pinned, archived, notebook_id, inherit_public, publish_at, unpublish_at, expires_at, burn_after_read, encrypted,
+	e2e_ciphertext, e2e_recipients`
+
+// notExpired leaves out notes past their expires_at that the reaper hasn't
+// deleted yet.
+const notExpired = `(expires_at IS NULL OR expires_at > now())`
+
+// visiblePublic is true for public notes unless their unpublish_at passed,
+// reads hide them before the Scheduler applies it. Note.VisibleTo is the
+// same rule for a note already read.
+const visiblePublic = `(public AND (unpublish_at IS NULL OR unpublish_at > now()))`
+
+// recipientOf is true for notes encrypted for the user in placeholder param.
+func recipientOf(param string) string {
+	return `(e2e_recipients @> jsonb_build_array(jsonb_build_object('user_id', ` + param + `::uuid)))`
+}
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanNote reads a row selected with noteColumns, decrypting the text.
+func (c *Client) scanNote(ctx context.Context, row rowScanner, n *note.Note) error {
+	var s sealedText
+	if err := row.Scan(noteFields(n, &s)...); err != nil {
+		return err
+	}
+	return c.openText(ctx, n, s)
+}
+
+// noteFields are the scan destinations for noteColumns, the text ends up in
+// s while it is encrypted.
+func noteFields(n *note.Note, s *sealedText) []interface{} {
+	return []interface{}{&n.NoteUUID, &n.UserUUID, &n.CreateTime, &n.Text, &s.ciphertext, &s.keyVersion,
+		&n.Public, &n.Format, &n.Title, &n.Pinned, &n.Archived, &n.NotebookUUID, &n.InheritPublic, &n.PublishAt,
+		&n.UnpublishAt, &n.ExpiresAt, &n.BurnAfterRead, &n.Encrypted, &n.Ciphertext, recipientsColumn{n}}
+}
+
+var errUnknownNotebook = e.ValidationError(e.FieldError{Field: "notebook_id", Code: "invalid",
+	Message: "notebook not found"})
+
+// notebookPublic reads the visibility of a notebook owned by userUUID and
+// keeps it from changing until tx ends.
+func notebookPublic(ctx context.Context, tx *sql.Tx, notebookUUID, userUUID uuid.UUID) (bool, error) {
+	var public bool
+	query := `SELECT public FROM notebooks WHERE id = $1 AND user_id = $2 FOR SHARE`
+	if err := tx.QueryRowContext(ctx, query, notebookUUID, userUUID).Scan(&public); err != nil {
+		if err == sql.ErrNoRows {
+			return false, errUnknownNotebook
+		}
+		return false, fmt.Errorf("error getting notebook: %w", err)
+	}
+	return public, nil
+}
+
 func (c *Client) Close() error {
 	return c.db.Close()
 }
@@ -62,38 +119,126 @@ func (c *Client) CreateNote(ctx context.Context, note *note.Note) error {
 	currentTime := time.Now()
 	note.NoteUUID = &ID
 	note.CreateTime = &currentTime
-	query := `INSERT INTO notes (id, user_id, text, public, create_time)
-               VALUES ($1, $2, $3, $4, $5)`
-	_, err := c.Exec(ctx, query, note.NoteUUID, note.UserUUID, note.Text, note.Public, note.CreateTime)
+
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	inherit := false
+	if note.NotebookUUID != nil {
+		public, err := notebookPublic(ctx, tx, *note.NotebookUUID, *note.UserUUID)
+		if err != nil {
+			return err
+		}
+		if note.Public == nil {
+			inherit = true
+			note.Public = &public
+		}
+	}
+	note.InheritPublic = &inherit
+
+	text, sealed, err := c.sealText(ctx, ID, *note.UserUUID, *note.Public, note.Text)
+	if err != nil {
+		return err
+	}
+	recipients, err := recipientsValue(note.Recipients)
+	if err != nil {
+		return err
+	}
+	query := `INSERT INTO notes (id, user_id, text, text_ciphertext, text_key_version, public, format, title,
+		pinned, archived, notebook_id, inherit_public, publish_at, unpublish_at, expires_at, burn_after_read,
+		encrypted, e2e_ciphertext, e2e_recipients, create_time)
+               VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20)`
+	_, err = tx.ExecContext(ctx, query, note.NoteUUID, note.UserUUID, text, nullBytes(sealed.ciphertext),
+		sealed.keyVersion, note.Public, note.Format, note.Title, note.Pinned, note.Archived, note.NotebookUUID,
+		note.InheritPublic, note.PublishAt, note.UnpublishAt, note.ExpiresAt, note.BurnAfterRead, note.Encrypted,
+		nullBytes(note.Ciphertext), recipients, note.CreateTime)
 	if err != nil {
 		return fmt.Errorf("error creating note: %w", err)
 	}
+	// Links of encrypted notes can't be read, they stay unlinked.
+	if note.Text != nil {
+		if err := saveLinks(ctx, tx, *note.NoteUUID, *note.Text); err != nil {
+			return err
+		}
+	}
+	events := []string{eventCreated}
+	if note.Public != nil && *note.Public {
+		events = append(events, eventPublished)
+	}
+	if note.PublishAt != nil || note.UnpublishAt != nil {
+		events = append(events, eventScheduled)
+	}
+	if err := insertOutbox(ctx, tx, *note, events...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error creating note: %w", err)
+	}
 	return nil
 }
 
-func (c *Client) GetNotes(ctx context.Context, userUUID uuid.UUID) (*note.Notes, error) {
+func (c *Client) GetNotes(ctx context.Context, userUUID uuid.UUID, opts note.ListOptions) (*note.Notes, error) {
 	var notes note.Notes
-	query := fmt.Sprintf(`
-		SELECT id, user_id, create_time, text, public
+	args := []interface{}{userUUID, opts.IncludeArchived}
+	where, textLike, err := noteFilters(opts.Filters, &args)
+	if err != nil {
+		return nil, err
+	}
+	order, err := noteOrder(opts.Sort)
+	if err != nil {
+		return nil, err
+	}
+	from := `
 		FROM notes
-		WHERE (public = true OR (user_id = '%s' AND public = false))
-	`, userUUID)
-	rows, err := c.Query(ctx, query)
+		WHERE (user_id = $1 OR ` + visiblePublic + ` OR ` + recipientOf("$1") + `)
+			AND ($2 OR NOT archived) AND (user_id = $1 OR NOT burn_after_read)
+			AND ` + notExpired + where
+	// Encrypted text is matched after decrypting it, the page can only be
+	// cut once that is done.
+	pageInQuery := opts.Limit > 0 && (len(textLike) == 0 || c.keys == nil)
+	query := `SELECT ` + noteColumns + from + `
+		ORDER BY ` + order
+	if pageInQuery {
+		if err := c.db.QueryRowContext(ctx, `SELECT count(*)`+from, args...).Scan(&notes.Total); err != nil {
+			return nil, fmt.Errorf("error counting notes: %w", err)
+		}
+		query += fmt.Sprintf(`
+		LIMIT $%d OFFSET $%d`, len(args)+1, len(args)+2)
+		args = append(args, opts.Limit, opts.Offset)
+	}
+	rows, err := c.Query(ctx, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("error getting notes: %w", err)
 	}
 	defer rows.Close()
 	for rows.Next() {
 		var note_ note.Note
-		if err := rows.Scan(&note_.NoteUUID, &note_.UserUUID, &note_.CreateTime, &note_.Text, &note_.Public); err != nil {
-			if err == sql.ErrNoRows {
-				return nil, nil // Notes not found
-			}
+		var sealed sealedText
+		if err := rows.Scan(noteFields(&note_, &sealed)...); err != nil {
 			return nil, fmt.Errorf("error getting note by ID: %w", err)
 		}
+		if err := c.openText(ctx, &note_, sealed); err != nil {
+			return nil, err
+		}
+		// The database can't search encrypted text, it is matched here.
+		if sealed.keyVersion.Valid && !containsFold(*note_.Text, textLike) {
+			continue
+		}
 		notes.Notes = append(notes.Notes, note_)
 	}
-	if len(notes.Notes) == 0 {
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting notes: %w", err)
+	}
+	if !pageInQuery {
+		notes.Total = len(notes.Notes)
+		if opts.Limit > 0 {
+			notes.Notes = notes.Notes[min(opts.Offset, len(notes.Notes)):min(opts.Offset+opts.Limit, len(notes.Notes))]
+		}
+	}
+	if notes.Total == 0 {
 		return &notes, e.ErrNotFound
 	}
 	return &notes, nil
@@ -102,16 +247,16 @@ func (c *Client) GetNotes(ctx context.Context, userUUID uuid.UUID) (*note.Notes,
 func (c *Client) GetNoteByID(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*note.Note, error) {
 	var note note.Note
 	query := `
-		SELECT id, user_id, create_time, text, public FROM notes WHERE id = $1 
+		SELECT ` + noteColumns + ` FROM notes WHERE id = $1 AND ` + notExpired + `
 	`
 	row := c.db.QueryRowContext(ctx, query, noteUUID)
-	if err := row.Scan(&note.NoteUUID, &note.UserUUID, &note.CreateTime, &note.Text, &note.Public); err != nil {
+	if err := c.scanNote(ctx, row, &note); err != nil {
 		if err == sql.ErrNoRows {
 			return nil, e.ErrNotFound // Note not found
 		}
 		return nil, fmt.Errorf("error getting note by ID: %w", err)
 	}
-	if *note.UserUUID != userUUID && !*note.Public {
+	if !note.VisibleTo(userUUID, time.Now()) {
 		return &note, e.ErrForbidden
 	}
 	return &note, nil
@@ -122,44 +267,143 @@ func (c *Client) UpdateNote(ctx context.Context, note *note.Note, userUUID uuid.
 	if err != nil {
 		return err
 	}
-	updateQuery := "UPDATE notes SET"
 
-	if note.Text != nil {
-		updateQuery += fmt.Sprintf(" text = '%s',", *note.Text)
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
 	}
+	defer tx.Rollback()
 
-	if note.Public != nil {
-		updateQuery += fmt.Sprintf(" public = '%t',", *note.Public)
+	var owner uuid.UUID
+	var wasPublic, inherit bool
+	var notebookUUID *uuid.UUID
+	var publishAt, unpublishAt *time.Time
+	lockQuery := `SELECT user_id, public, notebook_id, inherit_public, publish_at, unpublish_at
+		FROM notes WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, lockQuery, note.NoteUUID).Scan(&owner, &wasPublic, &notebookUUID,
+		&inherit, &publishAt, &unpublishAt); err != nil {
+		if err == sql.ErrNoRows {
+			return e.ErrNotFound
+		}
+		return fmt.Errorf("error updating note: %w", err)
+	}
+	// Public notes and recipients may read a note, only its owner changes it.
+	if owner != userUUID {
+		return e.ErrForbidden
 	}
 
-	// Remove trailing comma
-	updateQuery = updateQuery[:len(updateQuery)-1]
+	// An explicit public overrides the notebook, inheriting only means
+	// something inside a notebook.
+	if note.NotebookUUID != nil {
+		notebookUUID = note.NotebookUUID
+		if *notebookUUID == uuid.Nil {
+			notebookUUID = nil
+		}
+	}
+	public := wasPublic
+	if note.Public != nil {
+		public, inherit = *note.Public, false
+	} else if note.InheritPublic != nil {
+		inherit = *note.InheritPublic
+	}
+	if notebookUUID != nil {
+		inherited, err := notebookPublic(ctx, tx, *notebookUUID, owner)
+		if err != nil {
+			return err
+		}
+		if inherit {
+			public = inherited
+		}
+	} else {
+		inherit = false
+	}
 
-	// Add WHERE clause for noteID
-	updateQuery += fmt.Sprintf(" WHERE id = '%s'", *note.NoteUUID)
+	// Setting public by hand drops a schedule that would contradict it.
+	clearSchedule := note.Public != nil && scheduleConflicts(public, publishAt, unpublishAt)
 
-	stmt, err := c.db.Prepare(updateQuery)
+	// The text is stored separately, it may have to be encrypted.
+	updateQuery := `UPDATE notes SET public = $2,
+		format = COALESCE($3, format), title = CASE WHEN $13 THEN $4 ELSE title END, pinned = COALESCE($5, pinned),
+		archived = COALESCE($6, archived), notebook_id = $7, inherit_public = $8,
+		expires_at = COALESCE($9, expires_at), burn_after_read = COALESCE($10, burn_after_read),
+		e2e_ciphertext = COALESCE($11, e2e_ciphertext), e2e_recipients = COALESCE($12, e2e_recipients),
+		publish_at = CASE WHEN $14 THEN NULL ELSE publish_at END,
+		unpublish_at = CASE WHEN $14 THEN NULL ELSE unpublish_at END
+		WHERE id = $1
+		RETURNING ` + noteColumns
+	recipients, err := recipientsValue(note.Recipients)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
-
-	_, err = stmt.Exec()
-	if err != nil {
+	text := note.Text
+	textChanged := text != nil
+	row := tx.QueryRowContext(ctx, updateQuery, note.NoteUUID, public, note.Format, note.Title,
+		note.Pinned, note.Archived, notebookUUID, inherit, note.ExpiresAt, note.BurnAfterRead,
+		nullBytes(note.Ciphertext), recipients, note.TitleSet || note.Title != nil, clearSchedule)
+	if err := c.scanNote(ctx, row, note); err != nil {
 		return fmt.Errorf("error updating note: %w", err)
 	}
+	if textChanged {
+		note.Text = text
+	}
+	if textChanged || wasPublic != public {
+		if err := c.storeText(ctx, tx, *note); err != nil {
+			return err
+		}
+	}
+	if textChanged {
+		if err := saveLinks(ctx, tx, *note.NoteUUID, *note.Text); err != nil {
+			return err
+		}
+	}
 
+	events := []string{eventUpdated}
+	switch {
+	case !wasPublic && *note.Public:
+		events = append(events, eventPublished)
+	case wasPublic && !*note.Public:
+		events = append(events, eventUnpublished)
+	}
+	if clearSchedule {
+		events = append(events, eventScheduled)
+	}
+	if err := insertOutbox(ctx, tx, *note, events...); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error updating note: %w", err)
+	}
 	return nil
 }
 
 func (c *Client) DeleteNote(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) error {
-	_, err := c.GetNoteByID(ctx, noteUUID, userUUID)
+	prev, err := c.GetNoteByID(ctx, noteUUID, userUUID)
 	if err != nil {
 		return err
 	}
-	query := `DELETE FROM notes WHERE id = $1`
-	_, err = c.Exec(ctx, query, noteUUID)
+	if *prev.UserUUID != userUUID {
+		return e.ErrForbidden
+	}
+
+	tx, err := c.db.BeginTx(ctx, nil)
 	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var deleted note.Note
+	query := `DELETE FROM notes WHERE id = $1 AND user_id = $2 RETURNING ` + noteColumns
+	row := tx.QueryRowContext(ctx, query, noteUUID, userUUID)
+	if err := c.scanNote(ctx, row, &deleted); err != nil {
+		if err == sql.ErrNoRows {
+			return e.ErrNotFound
+		}
+		return fmt.Errorf("error deleting note: %w", err)
+	}
+	if err := insertOutbox(ctx, tx, deleted, eventDeleted); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("error deleting note: %w", err)
 	}
 	return nil
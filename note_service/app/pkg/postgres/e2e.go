@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"encoding/json"
+	"fmt"
+	"note_service/app/internal/note"
+)
+
+// Notes encrypted by the client keep their ciphertext in e2e_ciphertext and
+// the keys wrapped for each recipient in e2e_recipients. The server stores
+// both as they come, they never go through textKeys.
+
+// recipientsColumn scans e2e_recipients into the recipients of n.
+type recipientsColumn struct {
+	n *note.Note
+}
+
+func (c recipientsColumn) Scan(src interface{}) error {
+	c.n.Recipients = nil
+	if src == nil {
+		return nil
+	}
+	b, ok := src.([]byte)
+	if !ok {
+		return fmt.Errorf("error scanning recipients: unexpected %T", src)
+	}
+	return json.Unmarshal(b, &c.n.Recipients)
+}
+
+// recipientsValue is the e2e_recipients to store for recipients, NULL when
+// there are none to store.
+func recipientsValue(recipients []note.Recipient) (interface{}, error) {
+	if recipients == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(recipients)
+	if err != nil {
+		return nil, fmt.Errorf("error encoding recipients: %w", err)
+	}
+	return b, nil
+}
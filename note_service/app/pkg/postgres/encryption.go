@@ -0,0 +1,391 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"note_service/app/internal/note"
+	"note_service/app/pkg/envelope"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// retiredKeyGrace is how long a data key outlives its successor, a write
+// that picked it just before the rotation still commits readable text.
+const retiredKeyGrace = time.Hour
+
+var errNoKeyring = errors.New("note text is encrypted but no master key is configured")
+
+// textKeys encrypts the text of private notes with a data key per user. The
+// data keys live in note_keys wrapped by the master key, unwrapped ones are
+// cached.
+type textKeys struct {
+	keyring *envelope.Keyring
+
+	mu    sync.Mutex
+	cache map[dataKeyRef]*envelope.DataKey
+}
+
+type dataKeyRef struct {
+	userUUID uuid.UUID
+	version  int
+}
+
+// context binds a wrapped data key to its row in note_keys.
+func (r dataKeyRef) context() []byte {
+	return binary.BigEndian.AppendUint32(r.userUUID[:], uint32(r.version))
+}
+
+// sealedText is the text of a note as stored while encrypted: text is NULL
+// and text_ciphertext holds it, sealed with version text_key_version of the
+// owner's data key and bound to the note id.
+type sealedText struct {
+	ciphertext []byte
+	keyVersion sql.NullInt64
+}
+
+// nullBytes keeps a missing ciphertext NULL, lib/pq stores a nil slice as
+// an empty bytea.
+func nullBytes(b []byte) interface{} {
+	if b == nil {
+		return nil
+	}
+	return b
+}
+
+// EnableEncryption makes the client encrypt the text of private notes at
+// rest. Public notes stay in plaintext so the database can still search
+// them. Texts written before are encrypted by RotateNoteKeys. It must be
+// called before the client is used.
+func (c *Client) EnableEncryption(keyring *envelope.Keyring) {
+	c.keys = &textKeys{keyring: keyring, cache: make(map[dataKeyRef]*envelope.DataKey)}
+}
+
+// openText decrypts the text of n if it was stored encrypted.
+func (c *Client) openText(ctx context.Context, n *note.Note, s sealedText) error {
+	if !s.keyVersion.Valid {
+		return nil
+	}
+	if c.keys == nil {
+		return errNoKeyring
+	}
+	key, err := c.dataKey(ctx, dataKeyRef{userUUID: *n.UserUUID, version: int(s.keyVersion.Int64)})
+	if err != nil {
+		return err
+	}
+	text, err := key.Open(s.ciphertext, n.NoteUUID[:])
+	if err != nil {
+		return fmt.Errorf("error decrypting note %s: %w", n.NoteUUID, err)
+	}
+	plaintext := string(text)
+	n.Text = &plaintext
+	return nil
+}
+
+// sealText returns the text to store for text and, when the note is
+// private, its encrypted form instead.
+func (c *Client) sealText(ctx context.Context, noteUUID, owner uuid.UUID, public bool,
+	text *string) (*string, sealedText, error) {
+	if c.keys == nil || public || text == nil {
+		return text, sealedText{}, nil
+	}
+	version, key, err := c.currentDataKey(ctx, owner)
+	if err != nil {
+		return nil, sealedText{}, err
+	}
+	ciphertext, err := key.Seal([]byte(*text), noteUUID[:])
+	if err != nil {
+		return nil, sealedText{}, fmt.Errorf("error encrypting note %s: %w", noteUUID, err)
+	}
+	keyVersion := sql.NullInt64{Int64: int64(version), Valid: true}
+	return nil, sealedText{ciphertext: ciphertext, keyVersion: keyVersion}, nil
+}
+
+// storeText writes the text of n the way its visibility asks for, after
+// the visibility changed or the text is stored under an old data key. Notes
+// encrypted by the client have no text to store.
+func (c *Client) storeText(ctx context.Context, tx *sql.Tx, n note.Note) error {
+	if n.IsEncrypted() {
+		return nil
+	}
+	text, sealed, err := c.sealText(ctx, *n.NoteUUID, *n.UserUUID, *n.Public, n.Text)
+	if err != nil {
+		return err
+	}
+	query := `UPDATE notes SET text = $2, text_ciphertext = $3, text_key_version = $4 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, query, n.NoteUUID, text, nullBytes(sealed.ciphertext),
+		sealed.keyVersion); err != nil {
+		return fmt.Errorf("error storing note text: %w", err)
+	}
+	return nil
+}
+
+// currentDataKey returns the newest data key of userUUID, creating the
+// first one. Keys are created outside of any transaction, so a key is
+// always committed before text sealed with it.
+func (c *Client) currentDataKey(ctx context.Context, userUUID uuid.UUID) (int, *envelope.DataKey, error) {
+	query := `SELECT version, wrapped_key, master_key_id FROM note_keys
+		WHERE user_id = $1 ORDER BY version DESC LIMIT 1`
+	var ref dataKeyRef
+	var wrapped []byte
+	var masterKeyID string
+	err := c.db.QueryRowContext(ctx, query, userUUID).Scan(&ref.version, &wrapped, &masterKeyID)
+	if err == sql.ErrNoRows {
+		// Another instance may create it at the same time, read back
+		// whichever key won.
+		if _, err := c.createDataKey(ctx, dataKeyRef{userUUID: userUUID, version: 1}); err != nil {
+			return 0, nil, err
+		}
+		err = c.db.QueryRowContext(ctx, query, userUUID).Scan(&ref.version, &wrapped, &masterKeyID)
+	}
+	if err != nil {
+		return 0, nil, fmt.Errorf("error getting data key: %w", err)
+	}
+	ref.userUUID = userUUID
+	key, err := c.unwrapDataKey(ref, wrapped, masterKeyID)
+	return ref.version, key, err
+}
+
+// dataKey returns the data key text was sealed with.
+func (c *Client) dataKey(ctx context.Context, ref dataKeyRef) (*envelope.DataKey, error) {
+	c.keys.mu.Lock()
+	key, ok := c.keys.cache[ref]
+	c.keys.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+	var wrapped []byte
+	var masterKeyID string
+	query := `SELECT wrapped_key, master_key_id FROM note_keys WHERE user_id = $1 AND version = $2`
+	if err := c.db.QueryRowContext(ctx, query, ref.userUUID, ref.version).Scan(&wrapped,
+		&masterKeyID); err != nil {
+		return nil, fmt.Errorf("error getting data key %d of user %s: %w", ref.version, ref.userUUID, err)
+	}
+	return c.unwrapDataKey(ref, wrapped, masterKeyID)
+}
+
+func (c *Client) unwrapDataKey(ref dataKeyRef, wrapped []byte, masterKeyID string) (*envelope.DataKey, error) {
+	c.keys.mu.Lock()
+	defer c.keys.mu.Unlock()
+	if key, ok := c.keys.cache[ref]; ok {
+		return key, nil
+	}
+	key, err := c.keys.keyring.Unwrap(masterKeyID, wrapped, ref.context())
+	if err != nil {
+		return nil, fmt.Errorf("error unwrapping data key %d of user %s: %w", ref.version, ref.userUUID, err)
+	}
+	c.keys.cache[ref] = key
+	return key, nil
+}
+
+// createDataKey stores a new data key unless ref already exists and
+// reports whether it did.
+func (c *Client) createDataKey(ctx context.Context, ref dataKeyRef) (bool, error) {
+	_, wrapped, err := c.keys.keyring.NewDataKey(ref.context())
+	if err != nil {
+		return false, fmt.Errorf("error creating data key: %w", err)
+	}
+	query := `INSERT INTO note_keys (user_id, version, wrapped_key, master_key_id) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`
+	result, err := c.db.ExecContext(ctx, query, ref.userUUID, ref.version, wrapped, c.keys.keyring.MasterKeyID())
+	if err != nil {
+		return false, fmt.Errorf("error creating data key: %w", err)
+	}
+	created, err := result.RowsAffected()
+	return created > 0, err
+}
+
+// RotateNoteKeys does a batch of each rotation step and returns how many
+// keys and notes it changed, 0 once everything is up to date:
+//
+//   - data keys wrapped by an old master key are wrapped by the current one
+//   - users whose newest data key was created before now-maxAge get a new
+//     one, a maxAge of 0 keeps data keys forever
+//   - notes are encrypted with the newest data key of their owner when
+//     private and decrypted when public
+//   - data keys no note uses anymore are deleted
+func (c *Client) RotateNoteKeys(ctx context.Context, now time.Time, maxAge time.Duration,
+	limit int) (int, error) {
+	if c.keys == nil {
+		return 0, errNoKeyring
+	}
+	total := 0
+	steps := []func() (int, error){
+		func() (int, error) { return c.rewrapDataKeys(ctx, limit) },
+		func() (int, error) {
+			if maxAge <= 0 {
+				return 0, nil
+			}
+			return c.renewDataKeys(ctx, now.Add(-maxAge), limit)
+		},
+		func() (int, error) { return c.resealNotes(ctx, limit) },
+		func() (int, error) { return c.deleteRetiredDataKeys(ctx, now.Add(-retiredKeyGrace)) },
+	}
+	for _, step := range steps {
+		n, err := step()
+		total += n
+		if err != nil {
+			return total, err
+		}
+	}
+	return total, nil
+}
+
+func (c *Client) rewrapDataKeys(ctx context.Context, limit int) (int, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT user_id, version, wrapped_key, master_key_id FROM note_keys
+		WHERE master_key_id <> $1 LIMIT $2 FOR UPDATE SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, query, c.keys.keyring.MasterKeyID(), limit)
+	if err != nil {
+		return 0, fmt.Errorf("error getting data keys: %w", err)
+	}
+	type stale struct {
+		ref         dataKeyRef
+		wrapped     []byte
+		masterKeyID string
+	}
+	var keys []stale
+	for rows.Next() {
+		var k stale
+		if err := rows.Scan(&k.ref.userUUID, &k.ref.version, &k.wrapped, &k.masterKeyID); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error getting data keys: %w", err)
+		}
+		keys = append(keys, k)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error getting data keys: %w", err)
+	}
+
+	updateQuery := `UPDATE note_keys SET wrapped_key = $3, master_key_id = $4 WHERE user_id = $1 AND version = $2`
+	for _, k := range keys {
+		wrapped, err := c.keys.keyring.Rewrap(k.masterKeyID, k.wrapped, k.ref.context())
+		if err != nil {
+			return 0, fmt.Errorf("error rewrapping data key %d of user %s: %w", k.ref.version, k.ref.userUUID,
+				err)
+		}
+		if _, err := tx.ExecContext(ctx, updateQuery, k.ref.userUUID, k.ref.version, wrapped,
+			c.keys.keyring.MasterKeyID()); err != nil {
+			return 0, fmt.Errorf("error rewrapping data keys: %w", err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error rewrapping data keys: %w", err)
+	}
+	return len(keys), nil
+}
+
+func (c *Client) renewDataKeys(ctx context.Context, createdBefore time.Time, limit int) (int, error) {
+	query := `SELECT user_id, max(version) FROM note_keys
+		GROUP BY user_id HAVING max(create_time) < $1 LIMIT $2`
+	rows, err := c.Query(ctx, query, createdBefore, limit)
+	if err != nil {
+		return 0, fmt.Errorf("error getting data keys: %w", err)
+	}
+	var refs []dataKeyRef
+	for rows.Next() {
+		var ref dataKeyRef
+		if err := rows.Scan(&ref.userUUID, &ref.version); err != nil {
+			rows.Close()
+			return 0, fmt.Errorf("error getting data keys: %w", err)
+		}
+		refs = append(refs, ref)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("error getting data keys: %w", err)
+	}
+
+	renewed := 0
+	for _, ref := range refs {
+		ref.version++
+		created, err := c.createDataKey(ctx, ref)
+		if err != nil {
+			return renewed, err
+		}
+		if created {
+			renewed++
+		}
+	}
+	return renewed, nil
+}
+
+// resealNotes stores the text of notes whose storage doesn't match their
+// visibility, or that use an old data key, the right way.
+func (c *Client) resealNotes(ctx context.Context, limit int) (int, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + noteColumns + ` FROM notes WHERE id IN (
+			SELECT n.id FROM notes n
+			WHERE (NOT n.encrypted AND n.public = (n.text_key_version IS NOT NULL))
+				OR n.text_key_version < (SELECT max(k.version) FROM note_keys k WHERE k.user_id = n.user_id)
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED)`
+	notes, err := c.queryNotes(ctx, tx, query, limit)
+	if err != nil {
+		return 0, fmt.Errorf("error getting notes to re-encrypt: %w", err)
+	}
+	for _, n := range notes {
+		if err := c.storeText(ctx, tx, n); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("error re-encrypting notes: %w", err)
+	}
+	return len(notes), nil
+}
+
+// deleteRetiredDataKeys deletes data keys that were replaced before
+// replacedBefore and are no longer used. The newest key of a user is kept.
+func (c *Client) deleteRetiredDataKeys(ctx context.Context, replacedBefore time.Time) (int, error) {
+	query := `DELETE FROM note_keys k
+		WHERE EXISTS (SELECT 1 FROM note_keys newer
+				WHERE newer.user_id = k.user_id AND newer.version > k.version AND newer.create_time < $1)
+			AND NOT EXISTS (SELECT 1 FROM notes n WHERE n.user_id = k.user_id AND n.text_key_version = k.version)
+		RETURNING user_id, version`
+	rows, err := c.Query(ctx, query, replacedBefore)
+	if err != nil {
+		return 0, fmt.Errorf("error deleting data keys: %w", err)
+	}
+	defer rows.Close()
+	deleted := 0
+	c.keys.mu.Lock()
+	defer c.keys.mu.Unlock()
+	for rows.Next() {
+		var ref dataKeyRef
+		if err := rows.Scan(&ref.userUUID, &ref.version); err != nil {
+			return deleted, fmt.Errorf("error deleting data keys: %w", err)
+		}
+		delete(c.keys.cache, ref)
+		deleted++
+	}
+	return deleted, rows.Err()
+}
+
+// containsFold reports whether text contains every one of substrings,
+// ignoring case like ILIKE does.
+func containsFold(text string, substrings []string) bool {
+	text = strings.ToLower(text)
+	for _, s := range substrings {
+		if !strings.Contains(text, strings.ToLower(s)) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	e "note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// BurnNote deletes a burn-after-read note in a single statement, so only one
+// of concurrent readers gets it back.
+func (c *Client) BurnNote(ctx context.Context, noteUUID uuid.UUID, readerUUID uuid.UUID) (*note.Note, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var burned note.Note
+	query := `DELETE FROM notes WHERE id = $1 AND burn_after_read AND (` + visiblePublic + ` OR ` +
+		recipientOf("$2") + `) AND ` + notExpired + `
+		RETURNING ` + noteColumns
+	if err := c.scanNote(ctx, tx.QueryRowContext(ctx, query, noteUUID, readerUUID), &burned); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, e.ErrNotFound
+		}
+		return nil, fmt.Errorf("error burning note: %w", err)
+	}
+	if err := redactNote(ctx, tx, *burned.NoteUUID); err != nil {
+		return nil, err
+	}
+	if err := insertOutbox(ctx, tx, burned.Redacted(), eventDeleted); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error burning note: %w", err)
+	}
+	return &burned, nil
+}
+
+// redactedFields are the keys of note.Redacted in an event payload.
+const redactedFields = `'{text,title,ciphertext,recipients}'::text[]`
+
+// redactNote strips the content of a burned or expired note from the events
+// still kept about it, in the outbox and in webhook deliveries, so it doesn't
+// outlive the note.
+func redactNote(ctx context.Context, tx *sql.Tx, noteUUID uuid.UUID) error {
+	outboxQuery := `UPDATE note_outbox SET payload = jsonb_set(payload, '{note}', (payload->'note') - ` +
+		redactedFields + `) WHERE note_id = $1`
+	if _, err := tx.ExecContext(ctx, outboxQuery, noteUUID); err != nil {
+		return fmt.Errorf("error redacting outbox events: %w", err)
+	}
+	deliveriesQuery := `UPDATE webhook_deliveries SET payload = jsonb_set(payload, '{data}', (payload->'data') - ` +
+		redactedFields + `) WHERE payload->'data'->>'id' = $1`
+	if _, err := tx.ExecContext(ctx, deliveriesQuery, noteUUID.String()); err != nil {
+		return fmt.Errorf("error redacting webhook deliveries: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) DeleteExpiredNotes(ctx context.Context, now time.Time, limit int) ([]note.Note, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `DELETE FROM notes WHERE id IN (
+			SELECT id FROM notes WHERE expires_at <= $1 ORDER BY expires_at LIMIT $2 FOR UPDATE SKIP LOCKED)
+		RETURNING ` + noteColumns
+	expired, err := c.queryNotes(ctx, tx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error deleting expired notes: %w", err)
+	}
+	for _, n := range expired {
+		if err := redactNote(ctx, tx, *n.NoteUUID); err != nil {
+			return nil, err
+		}
+		if err := insertOutbox(ctx, tx, n.Redacted(), eventDeleted); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error deleting expired notes: %w", err)
+	}
+	return expired, nil
+}
@@ -0,0 +1,94 @@
+package postgres
+
+import (
+	"fmt"
+	"strings"
+
+	"note_service/app/pkg/rest"
+)
+
+// noteFilterColumns is the whitelist of filterable note fields, only these
+// column names ever end up in a query. Values always go in as arguments.
+var noteFilterColumns = map[string]string{
+	"user_id":     "user_id",
+	"public":      "public",
+	"create_time": "create_time",
+	"text":        "text",
+	"notebook_id": "notebook_id",
+}
+
+var noteSortColumns = map[string]string{
+	"create_time": "create_time",
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// noteFilters compiles filters into AND conditions, appending their values
+// to args so placeholders continue after the arguments already there.
+// Encrypted text passes text filters, textLike lists what it has to
+// contain once decrypted.
+func noteFilters(filters []rest.FilterOptions, args *[]interface{}) (where string, textLike []string, err error) {
+	var b strings.Builder
+	arg := func(v interface{}) string {
+		*args = append(*args, v)
+		return fmt.Sprintf("$%d", len(*args))
+	}
+	for _, f := range filters {
+		column, ok := noteFilterColumns[f.Field]
+		if !ok || len(f.Values) == 0 {
+			return "", nil, fmt.Errorf("can't filter notes by %q", f.Field)
+		}
+		var cond string
+		switch f.Operator {
+		case rest.OperatorEq:
+			cond = column + " = " + arg(f.Values[0])
+		case rest.OperatorNeq:
+			cond = column + " <> " + arg(f.Values[0])
+		case rest.OperatorGt:
+			cond = column + " > " + arg(f.Values[0])
+		case rest.OperatorLt:
+			cond = column + " < " + arg(f.Values[0])
+		case rest.OperatorBetween:
+			if len(f.Values) != 2 {
+				return "", nil, fmt.Errorf("between on %q takes two values", f.Field)
+			}
+			cond = column + " BETWEEN " + arg(f.Values[0]) + " AND " + arg(f.Values[1])
+		case rest.OperatorIn:
+			placeholders := make([]string, len(f.Values))
+			for i, v := range f.Values {
+				placeholders[i] = arg(v)
+			}
+			cond = column + " IN (" + strings.Join(placeholders, ", ") + ")"
+		case rest.OperatorLike:
+			cond = column + " ILIKE " + arg("%"+likeEscaper.Replace(f.Values[0])+"%")
+			if column == "text" {
+				cond = "(text_key_version IS NOT NULL OR " + cond + ")"
+				textLike = append(textLike, f.Values[0])
+			}
+		default:
+			return "", nil, fmt.Errorf("unknown filter operator %q", f.Operator)
+		}
+		b.WriteString("\n\t\t\tAND " + cond)
+	}
+	return b.String(), textLike, nil
+}
+
+// noteOrder keeps pinned notes first and newest first unless sort says
+// otherwise, id breaks ties so paging is stable.
+func noteOrder(sort []rest.SortOptions) (string, error) {
+	order := []string{"pinned DESC"}
+	for _, so := range sort {
+		column, ok := noteSortColumns[so.Field]
+		if !ok {
+			return "", fmt.Errorf("can't sort notes by %q", so.Field)
+		}
+		if so.Desc {
+			column += " DESC"
+		}
+		order = append(order, column)
+	}
+	if len(sort) == 0 {
+		order = append(order, "create_time DESC")
+	}
+	return strings.Join(append(order, "id"), ", "), nil
+}
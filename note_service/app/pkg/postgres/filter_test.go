@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"note_service/app/pkg/rest"
+)
+
+func TestNoteFilters(t *testing.T) {
+	args := []interface{}{"user", true}
+	where, textLike, err := noteFilters([]rest.FilterOptions{
+		{Field: "public", Operator: rest.OperatorEq, Values: []string{"true"}},
+		{Field: "user_id", Operator: rest.OperatorIn, Values: []string{"a", "b"}},
+		{Field: "create_time", Operator: rest.OperatorBetween, Values: []string{"t1", "t2"}},
+		{Field: "notebook_id", Operator: rest.OperatorNeq, Values: []string{"n"}},
+		{Field: "text", Operator: rest.OperatorLike, Values: []string{`50%_\off`}},
+	}, &args)
+	if err != nil {
+		t.Fatalf("noteFilters() error = %v", err)
+	}
+	want := []string{
+		"AND public = $3",
+		"AND user_id IN ($4, $5)",
+		"AND create_time BETWEEN $6 AND $7",
+		"AND notebook_id <> $8",
+		"AND (text_key_version IS NOT NULL OR text ILIKE $9)",
+	}
+	if got := strings.Split(where, "\n\t\t\t")[1:]; !reflect.DeepEqual(got, want) {
+		t.Errorf("where = %q, want %q", got, want)
+	}
+	wantArgs := []interface{}{"user", true, "true", "a", "b", "t1", "t2", "n", `%50\%\_\\off%`}
+	if !reflect.DeepEqual(args, wantArgs) {
+		t.Errorf("args = %q, want %q", args, wantArgs)
+	}
+	if !reflect.DeepEqual(textLike, []string{`50%_\off`}) {
+		t.Errorf("textLike = %q", textLike)
+	}
+}
+
+func TestNoteFiltersRejects(t *testing.T) {
+	tests := []rest.FilterOptions{
+		{Field: "id; DROP TABLE notes", Operator: rest.OperatorEq, Values: []string{"x"}},
+		{Field: "public", Operator: "gte:", Values: []string{"true"}},
+		{Field: "public", Operator: rest.OperatorEq},
+		{Field: "create_time", Operator: rest.OperatorBetween, Values: []string{"t1"}},
+	}
+	for _, f := range tests {
+		var args []interface{}
+		if where, _, err := noteFilters([]rest.FilterOptions{f}, &args); err == nil {
+			t.Errorf("noteFilters(%+v) = %q, want an error", f, where)
+		}
+	}
+}
+
+func TestNoteOrder(t *testing.T) {
+	tests := []struct {
+		sort []rest.SortOptions
+		want string
+	}{
+		{nil, "pinned DESC, create_time DESC, id"},
+		{[]rest.SortOptions{{Field: "create_time"}}, "pinned DESC, create_time, id"},
+		{[]rest.SortOptions{{Field: "create_time", Desc: true}}, "pinned DESC, create_time DESC, id"},
+	}
+	for _, tt := range tests {
+		if got, err := noteOrder(tt.sort); err != nil || got != tt.want {
+			t.Errorf("noteOrder(%+v) = %q, %v, want %q", tt.sort, got, err, tt.want)
+		}
+	}
+	if _, err := noteOrder([]rest.SortOptions{{Field: "text"}}); err == nil {
+		t.Error("noteOrder(text) succeeded, want an error")
+	}
+}
@@ -0,0 +1,144 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"note_service/app/internal/note"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// saveLinks replaces the links of source with the references in text.
+// Targets get no foreign key, links to deleted notes stay behind as broken.
+func saveLinks(ctx context.Context, tx *sql.Tx, source uuid.UUID, text string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM note_links WHERE source_id = $1`, source); err != nil {
+		return fmt.Errorf("error saving note links: %w", err)
+	}
+	targets := note.LinkTargets(source, text)
+	if len(targets) == 0 {
+		return nil
+	}
+	ids := make([]string, len(targets))
+	for i, t := range targets {
+		ids[i] = t.String()
+	}
+	query := `INSERT INTO note_links (source_id, target_id) SELECT $1, unnest($2::uuid[])`
+	if _, err := tx.ExecContext(ctx, query, source, pq.Array(ids)); err != nil {
+		return fmt.Errorf("error saving note links: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) GetNoteLinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*note.Links, error) {
+	query := `SELECT l.target_id, ` + noteColumns + `
+		FROM note_links l LEFT JOIN notes ON notes.id = l.target_id
+		WHERE l.source_id = $1 AND (notes.id IS NULL OR ((` + visiblePublic + ` AND NOT notes.burn_after_read)
+			OR notes.user_id = $2) AND ` + notExpired + `)
+		ORDER BY l.target_id`
+	rows, err := c.Query(ctx, query, noteUUID, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting note links: %w", err)
+	}
+	defer rows.Close()
+	links := note.Links{Links: []note.Link{}}
+	for rows.Next() {
+		var link note.Link
+		var target note.Note
+		var sealed sealedText
+		if err := rows.Scan(append([]interface{}{&link.NoteUUID}, noteFields(&target, &sealed)...)...); err != nil {
+			return nil, fmt.Errorf("error getting note links: %w", err)
+		}
+		if err := c.openText(ctx, &target, sealed); err != nil {
+			return nil, err
+		}
+		if target.NoteUUID == nil {
+			link.Broken = true
+		} else {
+			link.Note = &target
+		}
+		links.Links = append(links.Links, link)
+	}
+	return &links, rows.Err()
+}
+
+func (c *Client) GetNoteBacklinks(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*note.Notes, error) {
+	query := `SELECT ` + noteColumns + `
+		FROM notes JOIN note_links l ON l.source_id = notes.id
+		WHERE l.target_id = $1 AND ((` + visiblePublic + ` AND NOT notes.burn_after_read) OR notes.user_id = $2)
+			AND ` + notExpired + `
+		ORDER BY notes.create_time DESC`
+	rows, err := c.Query(ctx, query, noteUUID, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting note backlinks: %w", err)
+	}
+	defer rows.Close()
+	notes := note.Notes{Notes: []note.Note{}}
+	for rows.Next() {
+		var n note.Note
+		if err := c.scanNote(ctx, rows, &n); err != nil {
+			return nil, fmt.Errorf("error getting note backlinks: %w", err)
+		}
+		notes.Notes = append(notes.Notes, n)
+	}
+	return &notes, rows.Err()
+}
+
+// GetNoteGraph returns every note of userUUID as a node together with the
+// links going out of them. Targets owned by others become external nodes
+// when userUUID may see them and are left out otherwise.
+func (c *Client) GetNoteGraph(ctx context.Context, userUUID uuid.UUID) (*note.Graph, error) {
+	graph := note.Graph{Nodes: []note.GraphNode{}, Edges: []note.GraphEdge{}}
+
+	nodesQuery := `SELECT id, title, public, archived FROM notes WHERE user_id = $1 AND ` + notExpired + `
+		ORDER BY create_time`
+	rows, err := c.Query(ctx, nodesQuery, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting note graph: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var node note.GraphNode
+		if err := rows.Scan(&node.NoteUUID, &node.Title, &node.Public, &node.Archived); err != nil {
+			return nil, fmt.Errorf("error getting note graph: %w", err)
+		}
+		graph.Nodes = append(graph.Nodes, node)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting note graph: %w", err)
+	}
+	rows.Close()
+
+	edgesQuery := `SELECT l.source_id, l.target_id, t.id IS NULL, t.user_id <> $1, t.title, t.public, t.archived
+		FROM note_links l
+		JOIN notes s ON s.id = l.source_id
+		LEFT JOIN notes t ON t.id = l.target_id
+		WHERE s.user_id = $1 AND (s.expires_at IS NULL OR s.expires_at > now())
+			AND (t.id IS NULL OR ((t.public AND (t.unpublish_at IS NULL OR t.unpublish_at > now())
+				AND NOT t.burn_after_read) OR t.user_id = $1)
+				AND (t.expires_at IS NULL OR t.expires_at > now()))
+		ORDER BY l.source_id, l.target_id`
+	rows, err = c.Query(ctx, edgesQuery, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting note graph: %w", err)
+	}
+	defer rows.Close()
+	external := map[uuid.UUID]bool{}
+	for rows.Next() {
+		var edge note.GraphEdge
+		var foreign, public, archived sql.NullBool
+		var title *string
+		if err := rows.Scan(&edge.Source, &edge.Target, &edge.Broken, &foreign, &title, &public,
+			&archived); err != nil {
+			return nil, fmt.Errorf("error getting note graph: %w", err)
+		}
+		graph.Edges = append(graph.Edges, edge)
+		if foreign.Bool && !external[edge.Target] {
+			external[edge.Target] = true
+			graph.Nodes = append(graph.Nodes, note.GraphNode{NoteUUID: edge.Target, Title: title,
+				Public: public.Bool, Archived: archived.Bool, External: true})
+		}
+	}
+	return &graph, rows.Err()
+}
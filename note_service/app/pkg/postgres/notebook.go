@@ -0,0 +1,233 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	e "note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"note_service/app/internal/notebook"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const notebookColumns = `id, user_id, parent_id, name, public, create_time`
+
+// notebookTree selects the id of notebook $1 and of every notebook below it.
+const notebookTree = `WITH RECURSIVE tree AS (
+		SELECT id FROM notebooks WHERE id = $1
+		UNION ALL
+		SELECT n.id FROM notebooks n JOIN tree t ON n.parent_id = t.id
+	)`
+
+func scanNotebook(row rowScanner) (*notebook.Notebook, error) {
+	var nb notebook.Notebook
+	if err := row.Scan(&nb.NotebookUUID, &nb.UserUUID, &nb.ParentUUID, &nb.Name, &nb.Public,
+		&nb.CreateTime); err != nil {
+		return nil, err
+	}
+	return &nb, nil
+}
+
+func (c *Client) CreateNotebook(ctx context.Context, nb *notebook.Notebook) error {
+	ID := uuid.New()
+	currentTime := time.Now()
+	nb.NotebookUUID = &ID
+	nb.CreateTime = &currentTime
+	query := `INSERT INTO notebooks (id, user_id, parent_id, name, public, create_time)
+               VALUES ($1, $2, $3, $4, $5, $6)`
+	_, err := c.Exec(ctx, query, nb.NotebookUUID, nb.UserUUID, nb.ParentUUID, nb.Name, nb.Public, nb.CreateTime)
+	if err != nil {
+		return fmt.Errorf("error creating notebook: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) GetNotebook(ctx context.Context, notebookUUID uuid.UUID) (*notebook.Notebook, error) {
+	query := `SELECT ` + notebookColumns + ` FROM notebooks WHERE id = $1`
+	nb, err := scanNotebook(c.db.QueryRowContext(ctx, query, notebookUUID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, e.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting notebook: %w", err)
+	}
+	return nb, nil
+}
+
+func (c *Client) GetNotebooks(ctx context.Context, userUUID uuid.UUID) (*notebook.Notebooks, error) {
+	query := `SELECT ` + notebookColumns + ` FROM notebooks WHERE user_id = $1 ORDER BY name, create_time`
+	rows, err := c.Query(ctx, query, userUUID)
+	if err != nil {
+		return nil, fmt.Errorf("error getting notebooks: %w", err)
+	}
+	defer rows.Close()
+	notebooks := notebook.Notebooks{Notebooks: []notebook.Notebook{}}
+	for rows.Next() {
+		nb, err := scanNotebook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error getting notebooks: %w", err)
+		}
+		notebooks.Notebooks = append(notebooks.Notebooks, *nb)
+	}
+	return &notebooks, rows.Err()
+}
+
+// UpdateNotebook refuses parents below the notebook itself, then passes a
+// changed visibility on to every notebook below it and to the notes in all
+// of them that inherit it. The notebook row stays
+// locked meanwhile, so notes created or moved into it concurrently wait and
+// see the new visibility.
+func (c *Client) UpdateNotebook(ctx context.Context, nb *notebook.Notebook) ([]note.Note, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var wasPublic bool
+	lockQuery := `SELECT public FROM notebooks WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, lockQuery, nb.NotebookUUID).Scan(&wasPublic); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, e.ErrNotFound
+		}
+		return nil, fmt.Errorf("error updating notebook: %w", err)
+	}
+
+	if nb.ParentUUID != nil {
+		var cycle bool
+		cycleQuery := `WITH RECURSIVE ancestors AS (
+				SELECT id, parent_id FROM notebooks WHERE id = $2
+				UNION ALL
+				SELECT n.id, n.parent_id FROM notebooks n JOIN ancestors a ON n.id = a.parent_id
+			)
+			SELECT EXISTS (SELECT 1 FROM ancestors WHERE id = $1)`
+		if err := tx.QueryRowContext(ctx, cycleQuery, nb.NotebookUUID, nb.ParentUUID).Scan(&cycle); err != nil {
+			return nil, fmt.Errorf("error updating notebook: %w", err)
+		}
+		if cycle {
+			return nil, notebook.ErrCycle
+		}
+	}
+
+	updateQuery := `UPDATE notebooks SET parent_id = $2, name = $3, public = $4 WHERE id = $1`
+	if _, err := tx.ExecContext(ctx, updateQuery, nb.NotebookUUID, nb.ParentUUID, nb.Name, nb.Public); err != nil {
+		return nil, fmt.Errorf("error updating notebook: %w", err)
+	}
+
+	var changed []note.Note
+	if wasPublic != *nb.Public {
+		treeQuery := notebookTree + `
+			UPDATE notebooks SET public = $2 WHERE id IN (SELECT id FROM tree) AND public <> $2`
+		if _, err := tx.ExecContext(ctx, treeQuery, nb.NotebookUUID, nb.Public); err != nil {
+			return nil, fmt.Errorf("error updating notebook: %w", err)
+		}
+		notesQuery := notebookTree + `
+			UPDATE notes SET public = $2
+			WHERE notebook_id IN (SELECT id FROM tree) AND inherit_public AND public <> $2
+			RETURNING ` + noteColumns
+		changed, err = c.queryNotes(ctx, tx, notesQuery, nb.NotebookUUID, nb.Public)
+		if err != nil {
+			return nil, fmt.Errorf("error updating notebook notes: %w", err)
+		}
+		visibility := eventUnpublished
+		if *nb.Public {
+			visibility = eventPublished
+		}
+		for _, n := range changed {
+			if err := c.storeText(ctx, tx, n); err != nil {
+				return nil, err
+			}
+			if err := insertOutbox(ctx, tx, n, eventUpdated, visibility); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error updating notebook: %w", err)
+	}
+	return changed, nil
+}
+
+// DeleteNotebook deletes the notebook as mode says. Notes moved to the root
+// keep the visibility they had and stop inheriting.
+func (c *Client) DeleteNotebook(ctx context.Context, notebookUUID uuid.UUID, mode string) ([]note.Note, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked uuid.UUID
+	lockQuery := `SELECT id FROM notebooks WHERE id = $1 FOR UPDATE`
+	if err := tx.QueryRowContext(ctx, lockQuery, notebookUUID).Scan(&locked); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, e.ErrNotFound
+		}
+		return nil, fmt.Errorf("error deleting notebook: %w", err)
+	}
+
+	var affected []note.Note
+	var event string
+	switch mode {
+	case notebook.DeleteCascade:
+		notesQuery := notebookTree + `
+			DELETE FROM notes WHERE notebook_id IN (SELECT id FROM tree)
+			RETURNING ` + noteColumns
+		if affected, err = c.queryNotes(ctx, tx, notesQuery, notebookUUID); err != nil {
+			return nil, fmt.Errorf("error deleting notebook notes: %w", err)
+		}
+		notebooksQuery := notebookTree + `
+			DELETE FROM notebooks WHERE id IN (SELECT id FROM tree)`
+		if _, err := tx.ExecContext(ctx, notebooksQuery, notebookUUID); err != nil {
+			return nil, fmt.Errorf("error deleting notebook: %w", err)
+		}
+		event = eventDeleted
+	case notebook.DeleteMove:
+		notesQuery := `UPDATE notes SET notebook_id = NULL, inherit_public = false
+			WHERE notebook_id = $1
+			RETURNING ` + noteColumns
+		if affected, err = c.queryNotes(ctx, tx, notesQuery, notebookUUID); err != nil {
+			return nil, fmt.Errorf("error moving notebook notes: %w", err)
+		}
+		childrenQuery := `UPDATE notebooks SET parent_id = NULL WHERE parent_id = $1`
+		if _, err := tx.ExecContext(ctx, childrenQuery, notebookUUID); err != nil {
+			return nil, fmt.Errorf("error moving notebooks: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, `DELETE FROM notebooks WHERE id = $1`, notebookUUID); err != nil {
+			return nil, fmt.Errorf("error deleting notebook: %w", err)
+		}
+		event = eventUpdated
+	default:
+		return nil, fmt.Errorf("unknown notebook delete mode %q", mode)
+	}
+
+	for _, n := range affected {
+		if err := insertOutbox(ctx, tx, n, event); err != nil {
+			return nil, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error deleting notebook: %w", err)
+	}
+	return affected, nil
+}
+
+func (c *Client) queryNotes(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) ([]note.Note, error) {
+	rows, err := tx.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var notes []note.Note
+	for rows.Next() {
+		var n note.Note
+		if err := c.scanNote(ctx, rows, &n); err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
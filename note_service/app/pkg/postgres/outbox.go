@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"note_service/app/internal/note"
+	"note_service/app/internal/outbox"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Note methods name their parameter note, these keep the event types
+// reachable inside them.
+const (
+	eventCreated     = note.EventCreated
+	eventUpdated     = note.EventUpdated
+	eventDeleted     = note.EventDeleted
+	eventPublished   = note.EventPublished
+	eventUnpublished = note.EventUnpublished
+	eventScheduled   = note.EventScheduled
+)
+
+// outboxLock is the advisory lock key held while claiming a batch.
+const outboxLock = 0x6e6f7465
+
+// insertOutbox records events for n inside the transaction that changed it.
+// Private notes are recorded without their text.
+func insertOutbox(ctx context.Context, tx *sql.Tx, n note.Note, eventTypes ...string) error {
+	n = n.WithoutPrivateText()
+	query := `INSERT INTO note_outbox (event_id, note_id, event_type, payload, create_time)
+               VALUES ($1, $2, $3, $4, $5)`
+	for _, eventType := range eventTypes {
+		event := note.NewEvent(eventType, n)
+		payload, err := json.Marshal(event)
+		if err != nil {
+			return fmt.Errorf("error encoding outbox event: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, query, event.ID, n.NoteUUID, event.Type, payload, event.OccurredAt); err != nil {
+			return fmt.Errorf("error writing outbox event: %w", err)
+		}
+	}
+	return nil
+}
+
+// RelayOutbox claims a batch, then sends it with no transaction or lock
+// held. Each sent message is marked published on its own and renews the
+// claim of the rest, so lease only has to cover a single send.
+func (c *Client) RelayOutbox(ctx context.Context, limit int, lease time.Duration,
+	send func(outbox.Message) error) (int, error) {
+	claim := uuid.New()
+	messages, err := c.claimOutbox(ctx, claim, limit, lease)
+	if err != nil || len(messages) == 0 {
+		return 0, err
+	}
+	defer c.releaseOutbox(context.WithoutCancel(ctx), claim)
+
+	markQuery := `UPDATE note_outbox SET published_time = $3, claim_id = NULL, claimed_until = NULL
+		WHERE sequence = $2 AND claim_id = $1`
+	renewQuery := `UPDATE note_outbox SET claimed_until = $2 WHERE claim_id = $1`
+	sent := 0
+	for _, msg := range messages {
+		if err := send(msg); err != nil {
+			break
+		}
+		result, err := c.Exec(ctx, markQuery, claim, msg.Sequence, time.Now().UTC())
+		if err != nil {
+			return sent, fmt.Errorf("error marking outbox published: %w", err)
+		}
+		if n, err := result.RowsAffected(); err != nil || n == 0 {
+			return sent, fmt.Errorf("error marking outbox published: claim on %d expired", msg.Sequence)
+		}
+		sent++
+		if _, err := c.Exec(ctx, renewQuery, claim, time.Now().UTC().Add(lease)); err != nil {
+			return sent, fmt.Errorf("error renewing outbox claim: %w", err)
+		}
+	}
+	return sent, nil
+}
+
+// claimOutbox claims up to limit unpublished messages in sequence order.
+// Nothing is claimed while another relay's claim is live, a single relay at
+// a time is what keeps events of one note in commit order.
+func (c *Client) claimOutbox(ctx context.Context, claim uuid.UUID, limit int,
+	lease time.Duration) ([]outbox.Message, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, outboxLock).Scan(&locked); err != nil {
+		return nil, fmt.Errorf("error locking outbox: %w", err)
+	}
+	if !locked {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+	var busy bool
+	busyQuery := `SELECT EXISTS (SELECT 1 FROM note_outbox WHERE published_time IS NULL AND claimed_until > $1)`
+	if err := tx.QueryRowContext(ctx, busyQuery, now).Scan(&busy); err != nil {
+		return nil, fmt.Errorf("error claiming outbox: %w", err)
+	}
+	if busy {
+		return nil, nil
+	}
+
+	query := `UPDATE note_outbox o SET claim_id = $1, claimed_until = $2
+		FROM (SELECT sequence FROM note_outbox
+			WHERE published_time IS NULL
+			ORDER BY sequence
+			LIMIT $3) batch
+		WHERE o.sequence = batch.sequence
+		RETURNING o.sequence, o.event_id, o.note_id, o.event_type, o.payload, o.create_time`
+	rows, err := tx.QueryContext(ctx, query, claim, now.Add(lease), limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming outbox: %w", err)
+	}
+	var messages []outbox.Message
+	for rows.Next() {
+		var msg outbox.Message
+		var payload []byte
+		if err := rows.Scan(&msg.Sequence, &msg.EventID, &msg.NoteID, &msg.Type, &payload, &msg.CreateTime); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error claiming outbox: %w", err)
+		}
+		msg.Payload = payload
+		messages = append(messages, msg)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error claiming outbox: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error claiming outbox: %w", err)
+	}
+	// RETURNING has no order
+	sort.Slice(messages, func(i, j int) bool { return messages[i].Sequence < messages[j].Sequence })
+	return messages, nil
+}
+
+// releaseOutbox hands the unsent rest of a claim back, they are sent first
+// by the next relay.
+func (c *Client) releaseOutbox(ctx context.Context, claim uuid.UUID) {
+	query := `UPDATE note_outbox SET claim_id = NULL, claimed_until = NULL WHERE claim_id = $1`
+	if _, err := c.Exec(ctx, query, claim); err != nil {
+		c.logger.Errorf("error releasing outbox claim: %v", err)
+	}
+}
+
+func (c *Client) PurgeOutbox(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM note_outbox WHERE published_time IS NOT NULL AND published_time < $1`
+	result, err := c.Exec(ctx, query, before)
+	if err != nil {
+		return 0, fmt.Errorf("error purging outbox: %w", err)
+	}
+	return result.RowsAffected()
+}
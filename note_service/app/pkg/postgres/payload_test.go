@@ -0,0 +1,129 @@
+package postgres_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"note_service/app/internal/note"
+	notedb "note_service/app/internal/note/db"
+	"note_service/app/internal/note/storagetest"
+	"note_service/app/internal/webhook"
+	webhookdb "note_service/app/internal/webhook/db"
+	"note_service/app/pkg/logging"
+	"note_service/app/pkg/postgres"
+	"note_service/app/pkg/postgres/postgrestest"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// payloads is set up to record note events both in the outbox and as
+// webhook deliveries.
+type payloads struct {
+	client     *postgres.Client
+	notes      note.Storage
+	dispatcher *webhook.Dispatcher
+	owner      uuid.UUID
+	endpoint   uuid.UUID
+}
+
+func newPayloads(t *testing.T) *payloads {
+	t.Helper()
+	client := postgrestest.NewClient(t)
+	logger := logging.Logger{Entry: logrus.NewEntry(logrus.New())}
+	owner := postgrestest.NewUser(t, client)
+	url, secret, active := "https://hooks.example.com/notes", "secret", true
+	endpoint := webhook.Endpoint{UserUUID: &owner, URL: &url, Secret: &secret, Active: &active,
+		Events: []string{note.EventCreated, note.EventUpdated, note.EventDeleted}}
+	if err := client.CreateWebhookEndpoint(context.Background(), &endpoint); err != nil {
+		t.Fatal(err)
+	}
+	return &payloads{client: client, notes: notedb.NewStorage(client, logger),
+		dispatcher: webhook.NewDispatcher(webhookdb.NewStorage(client, logger), logger), owner: owner,
+		endpoint: *endpoint.EndpointUUID}
+}
+
+// create stores a note and queues the webhook deliveries of its creation.
+func (p *payloads) create(t *testing.T, text string, public bool, opts ...func(*note.Note)) note.Note {
+	t.Helper()
+	n := storagetest.Create(t, p.notes, p.owner, text, public, opts...)
+	t.Cleanup(func() {
+		p.client.Exec(context.Background(), `DELETE FROM note_outbox WHERE note_id = $1`, n.NoteUUID)
+	})
+	p.dispatcher.Publish(context.Background(), note.NewEvent(note.EventCreated, n))
+	return n
+}
+
+// assertNotStored reads back every outbox event of the note and every
+// webhook delivery of the endpoint.
+func (p *payloads) assertNotStored(t *testing.T, noteUUID uuid.UUID, text string) {
+	t.Helper()
+	ctx := context.Background()
+	check := func(table, query string, arg interface{}) {
+		rows, err := p.client.Query(ctx, query, arg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rows.Close()
+		found := 0
+		for rows.Next() {
+			var payload []byte
+			if err := rows.Scan(&payload); err != nil {
+				t.Fatal(err)
+			}
+			found++
+			if bytes.Contains(payload, []byte(text)) {
+				t.Errorf("%s payload %s holds the note text", table, payload)
+			}
+		}
+		if found == 0 {
+			t.Errorf("no %s payloads to check", table)
+		}
+	}
+	check("note_outbox", `SELECT payload FROM note_outbox WHERE note_id = $1`, noteUUID)
+	check("webhook_deliveries", `SELECT payload FROM webhook_deliveries WHERE endpoint_id = $1`, p.endpoint)
+}
+
+func TestBurnRedactsPayloads(t *testing.T) {
+	p := newPayloads(t)
+	burn := true
+	n := p.create(t, "read me once", true, func(n *note.Note) { n.BurnAfterRead = &burn })
+
+	reader := postgrestest.NewUser(t, p.client)
+	burned, err := p.client.BurnNote(context.Background(), *n.NoteUUID, reader)
+	if err != nil {
+		t.Fatalf("BurnNote() error = %v", err)
+	}
+	if *burned.Text != "read me once" {
+		t.Errorf("burned text = %q, want the reader to get it", *burned.Text)
+	}
+	p.dispatcher.Publish(context.Background(), note.NewEvent(note.EventDeleted, burned.Redacted()))
+	p.assertNotStored(t, *n.NoteUUID, "read me once")
+}
+
+func TestReapRedactsPayloads(t *testing.T) {
+	p := newPayloads(t)
+	expiresAt := time.Now().Add(-time.Minute).UTC()
+	n := p.create(t, "short lived", true, func(n *note.Note) { n.ExpiresAt = &expiresAt })
+
+	if _, err := p.client.DeleteExpiredNotes(context.Background(), time.Now(), 1000); err != nil {
+		t.Fatalf("DeleteExpiredNotes() error = %v", err)
+	}
+	p.assertNotStored(t, *n.NoteUUID, "short lived")
+}
+
+func TestPrivateTextNotStored(t *testing.T) {
+	p := newPayloads(t)
+	n := p.create(t, "private thoughts", false)
+
+	text := "more private thoughts"
+	updated := note.Note{NoteUUID: n.NoteUUID, Text: &text}
+	if err := p.notes.Update(context.Background(), &updated, p.owner); err != nil {
+		t.Fatalf("Update() error = %v", err)
+	}
+	p.dispatcher.Publish(context.Background(), note.NewEvent(note.EventUpdated, updated))
+
+	p.assertNotStored(t, *n.NoteUUID, "private thoughts")
+}
@@ -0,0 +1,183 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	e "note_service/app/internal/apperror"
+	"note_service/app/internal/reminder"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const reminderColumns = `id, note_id, user_id, start_time, rrule, next_fire_time, fired_count, last_fire_time,
+	attempts, create_time`
+
+func scanReminder(row rowScanner) (*reminder.Reminder, error) {
+	var r reminder.Reminder
+	if err := row.Scan(&r.ReminderUUID, &r.NoteUUID, &r.UserUUID, &r.StartTime, &r.RRule, &r.NextFireTime,
+		&r.FiredCount, &r.LastFireTime, &r.Attempts, &r.CreateTime); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func (c *Client) CreateReminder(ctx context.Context, r *reminder.Reminder) error {
+	ID := uuid.New()
+	currentTime := time.Now()
+	attempts := 0
+	r.ReminderUUID = &ID
+	r.CreateTime = &currentTime
+	r.Attempts = &attempts
+	query := `INSERT INTO reminders (id, note_id, user_id, start_time, rrule, next_fire_time, fired_count, attempts,
+		create_time)
+               VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	_, err := c.Exec(ctx, query, r.ReminderUUID, r.NoteUUID, r.UserUUID, r.StartTime, r.RRule, r.NextFireTime,
+		r.FiredCount, r.Attempts, r.CreateTime)
+	if err != nil {
+		return fmt.Errorf("error creating reminder: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) GetReminder(ctx context.Context, reminderUUID uuid.UUID) (*reminder.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders WHERE id = $1`
+	r, err := scanReminder(c.db.QueryRowContext(ctx, query, reminderUUID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, e.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting reminder: %w", err)
+	}
+	return r, nil
+}
+
+func (c *Client) GetReminders(ctx context.Context, noteUUID uuid.UUID, userUUID uuid.UUID) (*reminder.Reminders, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders
+		WHERE note_id = $1 AND user_id = $2
+		ORDER BY next_fire_time NULLS LAST, create_time`
+	reminders, err := c.queryReminders(ctx, query, noteUUID, userUUID)
+	if err != nil {
+		return nil, err
+	}
+	return &reminder.Reminders{Reminders: reminders}, nil
+}
+
+func (c *Client) GetDueReminders(ctx context.Context, userUUID uuid.UUID, t time.Time) ([]reminder.Reminder, error) {
+	query := `SELECT ` + reminderColumns + ` FROM reminders
+		WHERE user_id = $1 AND next_fire_time <= $2
+		ORDER BY next_fire_time`
+	return c.queryReminders(ctx, query, userUUID, t)
+}
+
+func (c *Client) queryReminders(ctx context.Context, query string, args ...interface{}) ([]reminder.Reminder, error) {
+	rows, err := c.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting reminders: %w", err)
+	}
+	defer rows.Close()
+	reminders := []reminder.Reminder{}
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error getting reminders: %w", err)
+		}
+		reminders = append(reminders, *r)
+	}
+	return reminders, rows.Err()
+}
+
+func (c *Client) DeleteReminder(ctx context.Context, reminderUUID uuid.UUID) error {
+	result, err := c.Exec(ctx, `DELETE FROM reminders WHERE id = $1`, reminderUUID)
+	if err != nil {
+		return fmt.Errorf("error deleting reminder: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return e.ErrNotFound
+	}
+	return nil
+}
+
+// ClaimReminders locks due reminders with SKIP LOCKED so concurrent workers
+// never claim the same rows, then leases them through lease_until. The due
+// time itself stays, CompleteReminder checks it to record each occurrence
+// only once.
+func (c *Client) ClaimReminders(ctx context.Context, limit int, lease time.Duration) ([]reminder.Reminder, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + reminderColumns + ` FROM reminders
+		WHERE next_fire_time <= now() AND (lease_until IS NULL OR lease_until <= now())
+		ORDER BY next_fire_time
+		LIMIT $1
+		FOR UPDATE SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, query, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming reminders: %w", err)
+	}
+	var reminders []reminder.Reminder
+	var ids []string
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error claiming reminders: %w", err)
+		}
+		reminders = append(reminders, *r)
+		ids = append(ids, r.ReminderUUID.String())
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// Postgres keeps microseconds, the lease has to compare equal later.
+	until := time.Now().UTC().Add(lease).Truncate(time.Microsecond)
+	leaseQuery := `UPDATE reminders SET lease_until = $2 WHERE id = ANY($1::uuid[])`
+	if _, err := tx.ExecContext(ctx, leaseQuery, pq.Array(ids), until); err != nil {
+		return nil, fmt.Errorf("error leasing reminders: %w", err)
+	}
+	for i := range reminders {
+		reminders[i].LeaseUntil = &until
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error claiming reminders: %w", err)
+	}
+	return reminders, nil
+}
+
+// RenewReminder only extends a lease that is still the one taken at held and
+// hasn't run out yet.
+func (c *Client) RenewReminder(ctx context.Context, reminderUUID uuid.UUID, held, until time.Time) (bool, error) {
+	query := `UPDATE reminders SET lease_until = $3 WHERE id = $1 AND lease_until = $2 AND lease_until > now()`
+	result, err := c.Exec(ctx, query, reminderUUID, held, until.Truncate(time.Microsecond))
+	if err != nil {
+		return false, fmt.Errorf("error renewing reminder lease: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func (c *Client) CompleteReminder(ctx context.Context, reminderUUID uuid.UUID, fired time.Time, next *time.Time) error {
+	query := `UPDATE reminders
+		SET next_fire_time = $3, fired_count = fired_count + 1, last_fire_time = $2, attempts = 0,
+			lease_until = NULL
+		WHERE id = $1 AND next_fire_time = $2`
+	if _, err := c.Exec(ctx, query, reminderUUID, fired, next); err != nil {
+		return fmt.Errorf("error completing reminder: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) RetryReminder(ctx context.Context, reminderUUID uuid.UUID, retry time.Time) error {
+	query := `UPDATE reminders SET attempts = attempts + 1, lease_until = $2 WHERE id = $1`
+	if _, err := c.Exec(ctx, query, reminderUUID, retry); err != nil {
+		return fmt.Errorf("error rescheduling reminder: %w", err)
+	}
+	return nil
+}
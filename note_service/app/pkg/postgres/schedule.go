@@ -0,0 +1,121 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	e "note_service/app/internal/apperror"
+	"note_service/app/internal/note"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func (c *Client) ScheduleNote(ctx context.Context, n *note.Note, userUUID uuid.UUID) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `UPDATE notes SET publish_at = $2, unpublish_at = $3
+		WHERE id = $1 AND user_id = $4
+		RETURNING ` + noteColumns
+	row := tx.QueryRowContext(ctx, query, n.NoteUUID, n.PublishAt, n.UnpublishAt, userUUID)
+	if err := c.scanNote(ctx, row, n); err != nil {
+		if err == sql.ErrNoRows {
+			return e.ErrNotFound
+		}
+		return fmt.Errorf("error scheduling note: %w", err)
+	}
+	if err := insertOutbox(ctx, tx, *n, eventScheduled); err != nil {
+		return err
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("error scheduling note: %w", err)
+	}
+	return nil
+}
+
+// scheduleConflicts is note.ScheduleConflicts for callers whose note
+// variable hides the package.
+func scheduleConflicts(public bool, publishAt, unpublishAt *time.Time) bool {
+	return note.ScheduleConflicts(public, publishAt, unpublishAt, time.Now())
+}
+
+// ApplyNoteSchedules locks due notes with SKIP LOCKED, so concurrent
+// schedulers split the work, and flips them in one transaction with their
+// outbox events. A note flipped by its schedule stops inheriting from its
+// notebook, like an explicit public would.
+func (c *Client) ApplyNoteSchedules(ctx context.Context, now time.Time, limit int) ([]note.Transition, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	type due struct {
+		id                     uuid.UUID
+		public                 bool
+		publishAt, unpublishAt *time.Time
+	}
+	query := `SELECT id, public, publish_at, unpublish_at
+		FROM notes
+		WHERE publish_at <= $1 OR unpublish_at <= $1
+		ORDER BY LEAST(publish_at, unpublish_at)
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, query, now, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting scheduled notes: %w", err)
+	}
+	var notes []due
+	for rows.Next() {
+		var d due
+		if err := rows.Scan(&d.id, &d.public, &d.publishAt, &d.unpublishAt); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error getting scheduled notes: %w", err)
+		}
+		notes = append(notes, d)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error getting scheduled notes: %w", err)
+	}
+
+	// SET expressions see the old row, so inherit_public only survives when
+	// public stays the same.
+	updateQuery := `UPDATE notes SET public = $2, inherit_public = inherit_public AND public = $2,
+		publish_at = CASE WHEN publish_at <= $3 THEN NULL ELSE publish_at END,
+		unpublish_at = CASE WHEN unpublish_at <= $3 THEN NULL ELSE unpublish_at END
+		WHERE id = $1
+		RETURNING ` + noteColumns
+	transitions := make([]note.Transition, 0, len(notes))
+	for _, d := range notes {
+		public := note.DuePublic(d.public, d.publishAt, d.unpublishAt, now)
+		t := note.Transition{WasPublic: d.public}
+		if err := c.scanNote(ctx, tx.QueryRowContext(ctx, updateQuery, d.id, public, now), &t.Note); err != nil {
+			return nil, fmt.Errorf("error applying note schedule: %w", err)
+		}
+		if public != d.public {
+			if err := c.storeText(ctx, tx, t.Note); err != nil {
+				return nil, err
+			}
+		}
+		events := []string{eventUpdated}
+		switch {
+		case !d.public && public:
+			events = append(events, eventPublished)
+		case d.public && !public:
+			events = append(events, eventUnpublished)
+		}
+		if err := insertOutbox(ctx, tx, t.Note, events...); err != nil {
+			return nil, err
+		}
+		transitions = append(transitions, t)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error applying note schedules: %w", err)
+	}
+	return transitions, nil
+}
@@ -0,0 +1,277 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	e "note_service/app/internal/apperror"
+	"note_service/app/internal/webhook"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+const endpointColumns = `id, user_id, url, secret, events, active, consecutive_failures, create_time, disabled_time`
+
+const deliveryColumns = `id, endpoint_id, event_id, event_type, payload, status, attempts, next_attempt_time,
+	last_status_code, last_error, create_time, update_time`
+
+func scanEndpoint(row rowScanner) (*webhook.Endpoint, error) {
+	var endpoint webhook.Endpoint
+	var events pq.StringArray
+	if err := row.Scan(&endpoint.EndpointUUID, &endpoint.UserUUID, &endpoint.URL, &endpoint.Secret, &events,
+		&endpoint.Active, &endpoint.ConsecutiveFailures, &endpoint.CreateTime, &endpoint.DisabledTime); err != nil {
+		return nil, err
+	}
+	endpoint.Events = events
+	return &endpoint, nil
+}
+
+func scanDelivery(row rowScanner) (*webhook.Delivery, error) {
+	var delivery webhook.Delivery
+	var payload []byte
+	if err := row.Scan(&delivery.DeliveryUUID, &delivery.EndpointUUID, &delivery.EventUUID, &delivery.EventType,
+		&payload, &delivery.Status, &delivery.Attempts, &delivery.NextAttemptTime, &delivery.LastStatusCode,
+		&delivery.LastError, &delivery.CreateTime, &delivery.UpdateTime); err != nil {
+		return nil, err
+	}
+	delivery.Payload = payload
+	return &delivery, nil
+}
+
+func (c *Client) CreateWebhookEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	ID := uuid.New()
+	currentTime := time.Now()
+	failures := 0
+	endpoint.EndpointUUID = &ID
+	endpoint.CreateTime = &currentTime
+	endpoint.ConsecutiveFailures = &failures
+	query := `INSERT INTO webhook_endpoints (id, user_id, url, secret, events, active, consecutive_failures, create_time)
+               VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
+	_, err := c.Exec(ctx, query, endpoint.EndpointUUID, endpoint.UserUUID, endpoint.URL, endpoint.Secret,
+		pq.Array(endpoint.Events), endpoint.Active, endpoint.ConsecutiveFailures, endpoint.CreateTime)
+	if err != nil {
+		return fmt.Errorf("error creating webhook endpoint: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) GetWebhookEndpoint(ctx context.Context, endpointUUID uuid.UUID) (*webhook.Endpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints WHERE id = $1`
+	endpoint, err := scanEndpoint(c.db.QueryRowContext(ctx, query, endpointUUID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, e.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting webhook endpoint: %w", err)
+	}
+	return endpoint, nil
+}
+
+// GetWebhookEndpoints returns the endpoints owned by userUUID followed by the
+// global ones.
+func (c *Client) GetWebhookEndpoints(ctx context.Context, userUUID uuid.UUID) (*webhook.Endpoints, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints
+		WHERE user_id = $1 OR user_id IS NULL
+		ORDER BY user_id NULLS LAST, create_time`
+	return c.queryWebhookEndpoints(ctx, query, userUUID)
+}
+
+func (c *Client) GetSubscribedWebhookEndpoints(ctx context.Context, userUUID uuid.UUID, eventType string) ([]webhook.Endpoint, error) {
+	query := `SELECT ` + endpointColumns + ` FROM webhook_endpoints
+		WHERE (user_id = $1 OR user_id IS NULL) AND active AND $2 = ANY(events)`
+	endpoints, err := c.queryWebhookEndpoints(ctx, query, userUUID, eventType)
+	if err != nil {
+		return nil, err
+	}
+	return endpoints.Endpoints, nil
+}
+
+func (c *Client) queryWebhookEndpoints(ctx context.Context, query string, args ...interface{}) (*webhook.Endpoints, error) {
+	rows, err := c.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook endpoints: %w", err)
+	}
+	defer rows.Close()
+	endpoints := webhook.Endpoints{Endpoints: []webhook.Endpoint{}}
+	for rows.Next() {
+		endpoint, err := scanEndpoint(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error getting webhook endpoints: %w", err)
+		}
+		endpoints.Endpoints = append(endpoints.Endpoints, *endpoint)
+	}
+	return &endpoints, rows.Err()
+}
+
+func (c *Client) UpdateWebhookEndpoint(ctx context.Context, endpoint *webhook.Endpoint) error {
+	query := `UPDATE webhook_endpoints
+		SET url = $2, events = $3, active = $4, consecutive_failures = $5, disabled_time = $6
+		WHERE id = $1`
+	result, err := c.Exec(ctx, query, endpoint.EndpointUUID, endpoint.URL, pq.Array(endpoint.Events),
+		endpoint.Active, endpoint.ConsecutiveFailures, endpoint.DisabledTime)
+	if err != nil {
+		return fmt.Errorf("error updating webhook endpoint: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return e.ErrNotFound
+	}
+	return nil
+}
+
+func (c *Client) DeleteWebhookEndpoint(ctx context.Context, endpointUUID uuid.UUID) error {
+	query := `DELETE FROM webhook_endpoints WHERE id = $1`
+	result, err := c.Exec(ctx, query, endpointUUID)
+	if err != nil {
+		return fmt.Errorf("error deleting webhook endpoint: %w", err)
+	}
+	if n, _ := result.RowsAffected(); n == 0 {
+		return e.ErrNotFound
+	}
+	return nil
+}
+
+func (c *Client) RecordWebhookEndpointResult(ctx context.Context, endpointUUID uuid.UUID, success bool, disableAfter int) error {
+	var query string
+	args := []interface{}{endpointUUID}
+	if success {
+		query = `UPDATE webhook_endpoints SET consecutive_failures = 0 WHERE id = $1`
+	} else {
+		query = `UPDATE webhook_endpoints
+			SET consecutive_failures = consecutive_failures + 1,
+				active = CASE WHEN $2 > 0 AND consecutive_failures + 1 >= $2 THEN false ELSE active END,
+				disabled_time = CASE WHEN $2 > 0 AND consecutive_failures + 1 >= $2 AND active THEN now() ELSE disabled_time END
+			WHERE id = $1`
+		args = append(args, disableAfter)
+	}
+	if _, err := c.Exec(ctx, query, args...); err != nil {
+		return fmt.Errorf("error recording webhook endpoint result: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) CreateWebhookDeliveries(ctx context.Context, deliveries []webhook.Delivery) error {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `INSERT INTO webhook_deliveries (` + deliveryColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)`
+	for _, d := range deliveries {
+		if _, err := tx.ExecContext(ctx, query, d.DeliveryUUID, d.EndpointUUID, d.EventUUID, d.EventType,
+			[]byte(d.Payload), d.Status, d.Attempts, d.NextAttemptTime, d.LastStatusCode, d.LastError,
+			d.CreateTime, d.UpdateTime); err != nil {
+			return fmt.Errorf("error creating webhook delivery: %w", err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (c *Client) GetWebhookDelivery(ctx context.Context, deliveryUUID uuid.UUID) (*webhook.Delivery, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries WHERE id = $1`
+	delivery, err := scanDelivery(c.db.QueryRowContext(ctx, query, deliveryUUID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, e.ErrNotFound
+		}
+		return nil, fmt.Errorf("error getting webhook delivery: %w", err)
+	}
+	return delivery, nil
+}
+
+func (c *Client) GetWebhookDeliveries(ctx context.Context, endpointUUID uuid.UUID, limit int) (*webhook.Deliveries, error) {
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+		WHERE endpoint_id = $1 ORDER BY create_time DESC LIMIT $2`
+	rows, err := c.Query(ctx, query, endpointUUID, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error getting webhook deliveries: %w", err)
+	}
+	defer rows.Close()
+	deliveries := webhook.Deliveries{Deliveries: []webhook.Delivery{}}
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			return nil, fmt.Errorf("error getting webhook deliveries: %w", err)
+		}
+		deliveries.Deliveries = append(deliveries.Deliveries, *delivery)
+	}
+	return &deliveries, rows.Err()
+}
+
+// ClaimWebhookDeliveries locks due pending deliveries with SKIP LOCKED so
+// concurrent workers never claim the same rows, then leases them by moving
+// next_attempt_time forward. A worker that dies mid-delivery leaves the row
+// to be picked up again once the lease runs out.
+func (c *Client) ClaimWebhookDeliveries(ctx context.Context, limit int, lease time.Duration) ([]webhook.Delivery, error) {
+	tx, err := c.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("error starting transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `SELECT ` + deliveryColumns + ` FROM webhook_deliveries
+		WHERE status = $1 AND next_attempt_time <= now()
+		ORDER BY next_attempt_time
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED`
+	rows, err := tx.QueryContext(ctx, query, webhook.StatusPending, limit)
+	if err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	claim := uuid.New()
+	var deliveries []webhook.Delivery
+	var ids []string
+	for rows.Next() {
+		delivery, err := scanDelivery(rows)
+		if err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+		}
+		delivery.ClaimUUID = &claim
+		deliveries = append(deliveries, *delivery)
+		ids = append(ids, delivery.DeliveryUUID.String())
+	}
+	rows.Close()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	leaseQuery := `UPDATE webhook_deliveries SET next_attempt_time = $2, claim_id = $3 WHERE id = ANY($1::uuid[])`
+	if _, err := tx.ExecContext(ctx, leaseQuery, pq.Array(ids), time.Now().UTC().Add(lease), claim); err != nil {
+		return nil, fmt.Errorf("error leasing webhook deliveries: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("error claiming webhook deliveries: %w", err)
+	}
+	return deliveries, nil
+}
+
+// RenewWebhookDelivery extends the lease of a delivery as long as no other
+// worker claimed it since, claim_id changes with every claim.
+func (c *Client) RenewWebhookDelivery(ctx context.Context, deliveryUUID, claim uuid.UUID,
+	until time.Time) (bool, error) {
+	query := `UPDATE webhook_deliveries SET next_attempt_time = $3 WHERE id = $1 AND claim_id = $2`
+	result, err := c.Exec(ctx, query, deliveryUUID, claim, until)
+	if err != nil {
+		return false, fmt.Errorf("error renewing webhook delivery lease: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
+
+func (c *Client) UpdateWebhookDelivery(ctx context.Context, delivery *webhook.Delivery) (bool, error) {
+	query := `UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, next_attempt_time = $4, last_status_code = $5, last_error = $6, update_time = $7,
+			claim_id = NULL
+		WHERE id = $1 AND claim_id = $8`
+	result, err := c.Exec(ctx, query, delivery.DeliveryUUID, delivery.Status, delivery.Attempts,
+		delivery.NextAttemptTime, delivery.LastStatusCode, delivery.LastError, delivery.UpdateTime, delivery.ClaimUUID)
+	if err != nil {
+		return false, fmt.Errorf("error updating webhook delivery: %w", err)
+	}
+	n, _ := result.RowsAffected()
+	return n > 0, nil
+}
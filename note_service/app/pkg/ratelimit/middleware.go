@@ -0,0 +1,102 @@
+package ratelimit
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"note_service/app/internal/apperror"
+	"note_service/app/pkg/logging"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Group struct {
+	Anonymous     Rule
+	Authenticated Rule
+}
+
+type Limiter struct {
+	store             Store
+	groups            map[string]Group
+	trustForwardedFor bool
+	logger            logging.Logger
+}
+
+func NewLimiter(store Store, groups map[string]Group, trustForwardedFor bool, logger logging.Logger) *Limiter {
+	return &Limiter{
+		store:             store,
+		groups:            groups,
+		trustForwardedFor: trustForwardedFor,
+		logger:            logger,
+	}
+}
+
+// Limit applies the rules of the named route group. It has to run after
+// user.Authentication: callers with a user UUID in the context are limited
+// per user, everyone else per client IP.
+func (l *Limiter) Limit(group string, h http.HandlerFunc) http.HandlerFunc {
+	if l == nil {
+		return h
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		g, ok := l.groups[group]
+		if !ok {
+			h(w, r)
+			return
+		}
+
+		rule := g.Anonymous
+		key := group + ":ip:" + l.clientIP(r)
+		if userUUID, ok := r.Context().Value("userUUID").(uuid.UUID); ok && userUUID != uuid.Nil {
+			rule = g.Authenticated
+			key = group + ":user:" + userUUID.String()
+		}
+		if rule.Requests <= 0 || rule.Period <= 0 {
+			h(w, r)
+			return
+		}
+
+		res, err := l.store.Take(r.Context(), key, rule)
+		if err != nil {
+			l.logger.Errorf("rate limit store failed, letting request through: %v", err)
+			h(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+		if !res.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(res.RetryAfter)))
+			apperror.Write(w, r, apperror.TooManyRequestsError("rate limit exceeded for "+group))
+			return
+		}
+		h(w, r)
+	}
+}
+
+// clientIP keys anonymous callers. Behind a trusted proxy that is the last
+// X-Forwarded-For entry, the one the proxy appended, everything before it
+// comes from the client and can be made up.
+func (l *Limiter) clientIP(r *http.Request) string {
+	if l.trustForwardedFor {
+		if values := r.Header.Values("X-Forwarded-For"); len(values) > 0 {
+			hops := strings.Split(values[len(values)-1], ",")
+			if ip := net.ParseIP(strings.TrimSpace(hops[len(hops)-1])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,104 @@
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"note_service/app/pkg/logging"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// TestMain sets up the global logger apperror.Write reports refused
+// requests to, its files go to a temporary directory.
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "ratelimit")
+	if err != nil {
+		panic(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		panic(err)
+	}
+	logging.Init()
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func TestClientIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		trust   bool
+		headers []string
+		want    string
+	}{
+		{name: "remote address", want: "192.0.2.1"},
+		{name: "untrusted header", headers: []string{"203.0.113.9"}, want: "192.0.2.1"},
+		{name: "single hop", trust: true, headers: []string{"203.0.113.9"}, want: "203.0.113.9"},
+		{name: "spoofed hops", trust: true, headers: []string{"10.0.0.1, 198.51.100.7, 203.0.113.9"},
+			want: "203.0.113.9"},
+		{name: "repeated header", trust: true, headers: []string{"10.0.0.1", "203.0.113.9"}, want: "203.0.113.9"},
+		{name: "ipv6", trust: true, headers: []string{"2001:db8::1"}, want: "2001:db8::1"},
+		{name: "garbage", trust: true, headers: []string{"203.0.113.9, unknown"}, want: "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &Limiter{trustForwardedFor: tt.trust}
+			r := httptest.NewRequest("GET", "/notes", nil)
+			r.RemoteAddr = "192.0.2.1:5555"
+			for _, h := range tt.headers {
+				r.Header.Add("X-Forwarded-For", h)
+			}
+			if got := l.clientIP(r); got != tt.want {
+				t.Errorf("clientIP() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimit(t *testing.T) {
+	store := NewMemoryStore(0)
+	defer store.Close()
+	rule := Rule{Requests: 1, Period: time.Hour}
+	l := NewLimiter(store, map[string]Group{"read": {Anonymous: rule, Authenticated: rule}}, true,
+		logging.Logger{Entry: logrus.NewEntry(logrus.New())})
+	ok := func(w http.ResponseWriter, r *http.Request) {}
+
+	do := func(group, forwardedFor string, userUUID uuid.UUID) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/notes", nil)
+		r.Header.Set("X-Forwarded-For", forwardedFor)
+		r = r.WithContext(context.WithValue(r.Context(), "userUUID", userUUID))
+		w := httptest.NewRecorder()
+		l.Limit(group, ok)(w, r)
+		return w
+	}
+
+	if w := do("read", "1.1.1.1, 203.0.113.9", uuid.Nil); w.Code != http.StatusOK ||
+		w.Header().Get("RateLimit-Limit") != "1" || w.Header().Get("RateLimit-Remaining") != "0" {
+		t.Fatalf("first request = %d %v, want 200 with limit headers", w.Code, w.Header())
+	}
+	w := do("read", "2.2.2.2, 203.0.113.9", uuid.Nil)
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("request with a new spoofed hop = %d, want 429 with Retry-After", w.Code)
+	}
+	if w := do("read", "203.0.113.10", uuid.Nil); w.Code != http.StatusOK {
+		t.Errorf("request from another client = %d, want 200", w.Code)
+	}
+	if w := do("read", "203.0.113.9", uuid.New()); w.Code != http.StatusOK {
+		t.Errorf("authenticated request = %d, want 200 from its own bucket", w.Code)
+	}
+	if w := do("write", "203.0.113.9", uuid.Nil); w.Code != http.StatusOK {
+		t.Errorf("request to an unlimited group = %d, want 200", w.Code)
+	}
+	var none *Limiter
+	w = httptest.NewRecorder()
+	none.Limit("read", ok)(w, httptest.NewRequest("GET", "/notes", nil))
+	if w.Code != http.StatusOK {
+		t.Errorf("request without a limiter = %d, want 200", w.Code)
+	}
+}
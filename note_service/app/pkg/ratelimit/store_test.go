@@ -0,0 +1,55 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreTake(t *testing.T) {
+	s := NewMemoryStore(0)
+	defer s.Close()
+	ctx := context.Background()
+	rule := Rule{Requests: 1, Period: 50 * time.Millisecond, Burst: 2}
+
+	for i, want := range []bool{true, true, false} {
+		res, err := s.Take(ctx, "k", rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if res.Allowed != want || res.Limit != 2 {
+			t.Fatalf("Take() #%d = %+v, want allowed %v of 2", i, res, want)
+		}
+		if !want && (res.RetryAfter <= 0 || res.RetryAfter > rule.Period) {
+			t.Errorf("Take() RetryAfter = %s, want up to %s", res.RetryAfter, rule.Period)
+		}
+	}
+	if res, _ := s.Take(ctx, "other", rule); !res.Allowed {
+		t.Error("Take() on another key was refused")
+	}
+
+	time.Sleep(rule.Period + 10*time.Millisecond)
+	if res, _ := s.Take(ctx, "k", rule); !res.Allowed {
+		t.Error("Take() after a refill period was refused")
+	}
+}
+
+func TestMemoryStoreCleanup(t *testing.T) {
+	s := NewMemoryStore(5 * time.Millisecond)
+	defer s.Close()
+	s.Take(context.Background(), "k", Rule{Requests: 1000, Period: time.Second})
+
+	deadline := time.Now().Add(time.Second)
+	for {
+		s.mu.Lock()
+		n := len(s.buckets)
+		s.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("idle full bucket was not cleaned up")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
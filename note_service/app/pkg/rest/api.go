@@ -31,10 +31,27 @@ func (ar *APIResponse) Location() (*url.URL, error) {
 	return ar.response.Location()
 }
 
+func (ar *APIResponse) Header() http.Header {
+	return ar.response.Header
+}
+
 type APIError struct {
 	Message          string `json:"message,omitempty"`
 	ErrorCode        string `json:"error_code,omitempty"`
 	DeveloperMessage string `json:"developer_message,omitempty"`
+
+	// RFC 7807 problem details members
+	Title  string       `json:"title,omitempty"`
+	Status int          `json:"status,omitempty"`
+	Detail string       `json:"detail,omitempty"`
+	Code   string       `json:"code,omitempty"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 func (aep *APIError) ToString() string {
@@ -23,7 +23,9 @@ func (c *BaseClient) SendRequest(req *http.Request) (*APIResponse, error) {
 	}
 
 	req.Header.Set("Accept", "application/json; charset=utf-8")
-	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	if req.Header.Get("Content-Type") == "" {
+		req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	}
 
 	response, err := c.HTTPClient.Do(req)
 	if err != nil {
@@ -59,7 +61,7 @@ func (c *BaseClient) BuildURL(resource string, filters []FilterOptions) (string,
 	if len(filters) > 0 {
 		q := parsedURL.Query()
 		for _, fo := range filters {
-			q.Set(fo.Field, fo.ToStringWF())
+			q.Add(fo.Field, fo.ToStringWF())
 		}
 		parsedURL.RawQuery = q.Encode()
 	}
@@ -0,0 +1,192 @@
+package rest
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Operators as they appear in a query value, ToStringWF of a parsed filter
+// gives back the original value.
+const (
+	OperatorEq      = "eq:"
+	OperatorNeq     = "neq:"
+	OperatorIn      = "in:"
+	OperatorGt      = "gt:"
+	OperatorLt      = "lt:"
+	OperatorBetween = "between:"
+	OperatorLike    = "like:"
+)
+
+const maxInValues = 100
+
+// Value types a filter field may have.
+const (
+	TypeString = "string"
+	TypeUUID   = "uuid"
+	TypeBool   = "bool"
+	TypeTime   = "time"
+)
+
+type FilterField struct {
+	Type      string
+	Operators []string
+}
+
+// FilterSchema lists the query parameters that are filters.
+type FilterSchema map[string]FilterField
+
+type FilterError struct {
+	Field   string
+	Message string
+}
+
+func (e FilterError) Error() string {
+	return fmt.Sprintf("%s %s", e.Field, e.Message)
+}
+
+// FilterErrors holds every problem found in a query.
+type FilterErrors []FilterError
+
+func (e FilterErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, fe := range e {
+		msgs = append(msgs, fe.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// ParseFilters reads the parameters of schema from q, e.g. public=true,
+// user_id=in:a,b or create_time=between:2024-01-01,2024-02-01. A value
+// without an operator means eq. Values are checked against the field type,
+// times are RFC 3339 or plain dates. Fields are returned sorted by name.
+func ParseFilters(q url.Values, schema FilterSchema) ([]FilterOptions, error) {
+	names := make([]string, 0, len(schema))
+	for name := range schema {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var filters []FilterOptions
+	var errs FilterErrors
+	for _, name := range names {
+		for _, raw := range q[name] {
+			fo, err := parseFilter(name, raw, schema[name])
+			if err != nil {
+				errs = append(errs, *err)
+				continue
+			}
+			filters = append(filters, fo)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return filters, nil
+}
+
+func parseFilter(name, raw string, field FilterField) (FilterOptions, *FilterError) {
+	fo := FilterOptions{Field: name, Operator: OperatorEq}
+	value := raw
+	if i := strings.Index(raw, ":"); i > 0 {
+		if op := raw[:i+1]; knownOperator(op) {
+			fo.Operator, value = op, raw[i+1:]
+		}
+	}
+	if !allowed(fo.Operator, field.Operators) {
+		return fo, &FilterError{Field: name, Message: fmt.Sprintf("does not support %s", strings.TrimSuffix(fo.Operator, ":"))}
+	}
+
+	if fo.Operator == OperatorLike {
+		fo.Values = []string{value}
+	} else {
+		fo.Values = strings.Split(value, ",")
+	}
+	switch fo.Operator {
+	case OperatorIn:
+		if len(fo.Values) > maxInValues {
+			return fo, &FilterError{Field: name, Message: fmt.Sprintf("takes at most %d values", maxInValues)}
+		}
+	case OperatorBetween:
+		if len(fo.Values) != 2 {
+			return fo, &FilterError{Field: name, Message: "between takes two values"}
+		}
+	default:
+		if len(fo.Values) != 1 {
+			return fo, &FilterError{Field: name, Message: "takes one value"}
+		}
+	}
+
+	for i, v := range fo.Values {
+		normalized, ok := normalize(field.Type, v)
+		if !ok {
+			return fo, &FilterError{Field: name, Message: fmt.Sprintf("must be a %s", field.Type)}
+		}
+		fo.Values[i] = normalized
+	}
+	return fo, nil
+}
+
+func normalize(typ, v string) (string, bool) {
+	switch typ {
+	case TypeUUID:
+		id, err := uuid.Parse(v)
+		return id.String(), err == nil
+	case TypeBool:
+		b, err := strconv.ParseBool(v)
+		return strconv.FormatBool(b), err == nil
+	case TypeTime:
+		if t, err := time.Parse(time.RFC3339, v); err == nil {
+			return t.UTC().Format(time.RFC3339Nano), true
+		}
+		if t, err := time.Parse(time.DateOnly, v); err == nil {
+			return t.Format(time.RFC3339Nano), true
+		}
+		return "", false
+	}
+	return v, true
+}
+
+func knownOperator(op string) bool {
+	switch op {
+	case OperatorEq, OperatorNeq, OperatorIn, OperatorGt, OperatorLt, OperatorBetween, OperatorLike:
+		return true
+	}
+	return false
+}
+
+func allowed(op string, ops []string) bool {
+	for _, o := range ops {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+type SortOptions struct {
+	Field string
+	Desc  bool
+}
+
+// ParseSort reads a comma separated sort parameter like -create_time,title,
+// a leading minus sorts descending.
+func ParseSort(raw string, fields []string) ([]SortOptions, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	var sorts []SortOptions
+	for _, part := range strings.Split(raw, ",") {
+		so := SortOptions{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+		if !allowed(so.Field, fields) {
+			return nil, FilterError{Field: "sort", Message: fmt.Sprintf("can't sort by %q", so.Field)}
+		}
+		sorts = append(sorts, so)
+	}
+	return sorts, nil
+}
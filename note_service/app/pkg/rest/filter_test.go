@@ -0,0 +1,121 @@
+package rest
+
+import (
+	"errors"
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+var testSchema = FilterSchema{
+	"user_id":     {Type: TypeUUID, Operators: []string{OperatorEq, OperatorIn}},
+	"public":      {Type: TypeBool, Operators: []string{OperatorEq, OperatorNeq}},
+	"create_time": {Type: TypeTime, Operators: []string{OperatorGt, OperatorBetween}},
+	"text":        {Type: TypeString, Operators: []string{OperatorLike}},
+}
+
+func TestParseFilters(t *testing.T) {
+	const id = "3f2504e0-4f89-11d3-9a0c-0305e82c3301"
+	q := url.Values{
+		"public":      {"true", "neq:0"},
+		"user_id":     {"in:" + id + "," + id},
+		"create_time": {"between:2024-01-01,2024-02-01T10:00:00+02:00"},
+		"text":        {"like:a,b:c"},
+		"other":       {"ignored"},
+	}
+	got, err := ParseFilters(q, testSchema)
+	if err != nil {
+		t.Fatalf("ParseFilters() error = %v", err)
+	}
+	want := []FilterOptions{
+		{Field: "create_time", Operator: OperatorBetween, Values: []string{"2024-01-01T00:00:00Z", "2024-02-01T08:00:00Z"}},
+		{Field: "public", Operator: OperatorEq, Values: []string{"true"}},
+		{Field: "public", Operator: OperatorNeq, Values: []string{"false"}},
+		{Field: "text", Operator: OperatorLike, Values: []string{"a,b:c"}},
+		{Field: "user_id", Operator: OperatorIn, Values: []string{id, id}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseFilters() = %+v, want %+v", got, want)
+	}
+}
+
+func TestParseFiltersErrors(t *testing.T) {
+	many := "in:"
+	for i := 0; i <= maxInValues; i++ {
+		if i > 0 {
+			many += ","
+		}
+		many += "3f2504e0-4f89-11d3-9a0c-0305e82c3301"
+	}
+	tests := []struct {
+		name string
+		q    url.Values
+		want FilterErrors
+	}{
+		{"operator", url.Values{"public": {"gt:true"}},
+			FilterErrors{{Field: "public", Message: "does not support gt"}}},
+		{"type", url.Values{"user_id": {"eq:nope"}, "public": {"maybe"}},
+			FilterErrors{{Field: "public", Message: "must be a bool"}, {Field: "user_id", Message: "must be a uuid"}}},
+		{"time", url.Values{"create_time": {"gt:yesterday"}},
+			FilterErrors{{Field: "create_time", Message: "must be a time"}}},
+		{"between", url.Values{"create_time": {"between:2024-01-01"}},
+			FilterErrors{{Field: "create_time", Message: "between takes two values"}}},
+		{"single", url.Values{"public": {"true,false"}},
+			FilterErrors{{Field: "public", Message: "takes one value"}}},
+		{"in limit", url.Values{"user_id": {many}},
+			FilterErrors{{Field: "user_id", Message: "takes at most 100 values"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseFilters(tt.q, testSchema)
+			var errs FilterErrors
+			if !errors.As(err, &errs) || !reflect.DeepEqual(errs, tt.want) {
+				t.Errorf("ParseFilters() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	got, err := ParseSort("-create_time,title", []string{"create_time", "title"})
+	if err != nil {
+		t.Fatalf("ParseSort() error = %v", err)
+	}
+	want := []SortOptions{{Field: "create_time", Desc: true}, {Field: "title"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseSort() = %+v, want %+v", got, want)
+	}
+	if got, err := ParseSort("", nil); got != nil || err != nil {
+		t.Errorf("ParseSort(\"\") = %v, %v", got, err)
+	}
+	var fe FilterError
+	if _, err := ParseSort("text", []string{"create_time"}); !errors.As(err, &fe) || fe.Field != "sort" {
+		t.Errorf("ParseSort(text) error = %v, want a sort FilterError", err)
+	}
+}
+
+func TestBuildURLRepeatsFields(t *testing.T) {
+	c := BaseClient{BaseURL: "http://notes.test/api/"}
+	got, err := c.BuildURL("notes", []FilterOptions{
+		{Field: "create_time", Operator: OperatorGt, Values: []string{"2024-01-01"}},
+		{Field: "create_time", Operator: OperatorLt, Values: []string{"2024-02-01"}},
+		{Field: "user_id", Operator: OperatorIn, Values: []string{"a", "b"}},
+	})
+	if err != nil {
+		t.Fatalf("BuildURL() error = %v", err)
+	}
+	u, err := url.Parse(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.Path != "/api/notes" {
+		t.Errorf("path = %q, want /api/notes", u.Path)
+	}
+	q := u.Query()
+	if want := []string{"gt:2024-01-01", "lt:2024-02-01"}; !reflect.DeepEqual(q["create_time"], want) {
+		t.Errorf("create_time = %v, want %v", q["create_time"], want)
+	}
+	if q.Get("user_id") != "in:a,b" {
+		t.Errorf("user_id = %q, want in:a,b", q.Get("user_id"))
+	}
+}
@@ -0,0 +1,286 @@
+// Package rrule implements the subset of RFC 5545 recurrence rules that
+// reminders need: FREQ (HOURLY, DAILY, WEEKLY, MONTHLY, YEARLY), INTERVAL,
+// COUNT, UNTIL and BYDAY together with FREQ=WEEKLY, e.g.
+//
+//	FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH;COUNT=10
+//
+// Occurrences are computed in the location of the start time.
+package rrule
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	Hourly  = "HOURLY"
+	Daily   = "DAILY"
+	Weekly  = "WEEKLY"
+	Monthly = "MONTHLY"
+	Yearly  = "YEARLY"
+)
+
+// maxSkipped stops rules that never produce another occurrence, such as
+// February 29 every 100 years, after that many periods in a row without one.
+const maxSkipped = 1000
+
+var weekdays = map[string]time.Weekday{
+	"MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday,
+	"FR": time.Friday, "SA": time.Saturday, "SU": time.Sunday,
+}
+
+type Rule struct {
+	Freq     string
+	Interval int
+	// Count limits the number of occurrences, Until the last one. At most
+	// one of them is set.
+	Count int
+	Until time.Time
+	ByDay []time.Weekday
+}
+
+// Parse reads a rule with or without the RRULE: prefix.
+func Parse(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return r, errors.New("empty rule")
+	}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		key, value, ok := strings.Cut(part, "=")
+		key = strings.ToUpper(key)
+		if !ok || value == "" {
+			return r, fmt.Errorf("malformed part %q", part)
+		}
+		if seen[key] {
+			return r, fmt.Errorf("%s given twice", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			r.Freq = strings.ToUpper(value)
+			switch r.Freq {
+			case Hourly, Daily, Weekly, Monthly, Yearly:
+			default:
+				return r, fmt.Errorf("unsupported FREQ %q", value)
+			}
+		case "INTERVAL", "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return r, fmt.Errorf("%s must be a positive integer", key)
+			}
+			if key == "INTERVAL" {
+				r.Interval = n
+			} else {
+				r.Count = n
+			}
+		case "UNTIL":
+			until, err := parseUntil(value)
+			if err != nil {
+				return r, err
+			}
+			r.Until = until
+		case "BYDAY":
+			for _, d := range strings.Split(strings.ToUpper(value), ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					return r, fmt.Errorf("unsupported BYDAY value %q", d)
+				}
+				r.ByDay = append(r.ByDay, wd)
+			}
+		default:
+			return r, fmt.Errorf("unsupported part %s", key)
+		}
+	}
+	switch {
+	case r.Freq == "":
+		return r, errors.New("FREQ is required")
+	case r.Count > 0 && !r.Until.IsZero():
+		return r, errors.New("COUNT and UNTIL can't be combined")
+	case len(r.ByDay) > 0 && r.Freq != Weekly:
+		return r, errors.New("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	sort.Slice(r.ByDay, func(i, j int) bool { return fromMonday(r.ByDay[i]) < fromMonday(r.ByDay[j]) })
+	// A weekday listed twice is still a single occurrence a week.
+	days := r.ByDay[:0]
+	for i, d := range r.ByDay {
+		if i == 0 || d != r.ByDay[i-1] {
+			days = append(days, d)
+		}
+	}
+	r.ByDay = days
+	return r, nil
+}
+
+func parseUntil(value string) (time.Time, error) {
+	for _, layout := range []string{"20060102T150405Z", "20060102"} {
+		if t, err := time.Parse(layout, value); err == nil {
+			if layout == "20060102" {
+				// a date includes the whole day
+				t = t.Add(24*time.Hour - time.Nanosecond)
+			}
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("UNTIL %q must look like 20060102T150405Z or 20060102", value)
+}
+
+// String formats the rule in the canonical order.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + r.Freq}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = strings.ToUpper(d.String()[:2])
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Each calls fn with the occurrences of the rule starting at start, in order,
+// until fn returns false or the rule ends. start is the first occurrence
+// unless BYDAY leaves out its weekday.
+func (r Rule) Each(start time.Time, fn func(time.Time) bool) {
+	r.each(start, 0, 0, fn)
+}
+
+// EachFrom is Each leaving out the occurrences before from. Rules with a
+// fixed length period are expanded from the period holding from instead of
+// from start.
+func (r Rule) EachFrom(start, from time.Time, fn func(time.Time) bool) {
+	period, n := r.skipTo(start, from)
+	r.each(start, period, n, func(t time.Time) bool {
+		return t.Before(from) || fn(t)
+	})
+}
+
+// After returns the first occurrence later than t.
+func (r Rule) After(start, t time.Time) (time.Time, bool) {
+	var next time.Time
+	r.EachFrom(start, t, func(o time.Time) bool {
+		if o.After(t) {
+			next = o
+			return false
+		}
+		return true
+	})
+	return next, !next.IsZero()
+}
+
+// each expands the rule from the given period on, n is the number of
+// occurrences in the periods before it.
+func (r Rule) each(start time.Time, period, n int, fn func(time.Time) bool) {
+	if r.Count > 0 && n >= r.Count {
+		return
+	}
+	emit := func(t time.Time) bool {
+		n++
+		return fn(t) && (r.Count == 0 || n < r.Count)
+	}
+	for skipped := 0; skipped < maxSkipped; period++ {
+		k := period * r.Interval
+		if r.Freq == Weekly && len(r.ByDay) > 0 {
+			monday := start.AddDate(0, 0, -fromMonday(start.Weekday())+7*k)
+			for _, d := range r.ByDay {
+				t := monday.AddDate(0, 0, fromMonday(d))
+				if t.Before(start) {
+					continue
+				}
+				if r.ended(t) || !emit(t) {
+					return
+				}
+			}
+			continue
+		}
+		t, ok := r.step(start, k)
+		if r.ended(t) {
+			return
+		}
+		if !ok {
+			skipped++
+			continue
+		}
+		skipped = 0
+		if !emit(t) {
+			return
+		}
+	}
+}
+
+func (r Rule) ended(t time.Time) bool {
+	return !r.Until.IsZero() && t.After(r.Until)
+}
+
+// skipTo returns a period starting no later than t and the number of
+// occurrences before it. Monthly and yearly periods vary in length and skip
+// missing days, those rules are expanded from start, they have few periods.
+func (r Rule) skipTo(start, t time.Time) (period, n int) {
+	var length time.Duration
+	switch r.Freq {
+	case Hourly:
+		length = time.Hour
+	case Daily:
+		length = 24 * time.Hour
+	case Weekly:
+		length = 7 * 24 * time.Hour
+	default:
+		return 0, 0
+	}
+	// Days are an hour shorter or longer around DST changes, starting a
+	// period early makes up for it.
+	period = int(t.Sub(start)/(length*time.Duration(r.Interval))) - 1
+	if period <= 0 {
+		return 0, 0
+	}
+	if r.Freq != Weekly || len(r.ByDay) == 0 {
+		return period, period
+	}
+	// All of BYDAY falls into every week but the first, which starts on
+	// the weekday of start.
+	first := 0
+	for _, d := range r.ByDay {
+		if fromMonday(d) >= fromMonday(start.Weekday()) {
+			first++
+		}
+	}
+	return period, first + (period-1)*len(r.ByDay)
+}
+
+// step moves start k units of the frequency ahead. Months and years without
+// the start's day, like February 30, have no occurrence.
+func (r Rule) step(start time.Time, k int) (time.Time, bool) {
+	switch r.Freq {
+	case Hourly:
+		return start.Add(time.Duration(k) * time.Hour), true
+	case Daily:
+		return start.AddDate(0, 0, k), true
+	case Weekly:
+		return start.AddDate(0, 0, 7*k), true
+	case Monthly:
+		t := start.AddDate(0, k, 0)
+		return t, t.Day() == start.Day()
+	default:
+		t := start.AddDate(k, 0, 0)
+		return t, t.Day() == start.Day() && t.Month() == start.Month()
+	}
+}
+
+func fromMonday(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
@@ -0,0 +1,190 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseErrors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"RRULE:",
+		"COUNT=2",
+		"FREQ",
+		"FREQ=",
+		"FREQ=SECONDLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=-1",
+		"FREQ=DAILY;COUNT=x",
+		"FREQ=DAILY;UNTIL=2026",
+		"FREQ=DAILY;COUNT=2;UNTIL=20260101",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;BYMONTH=1",
+	} {
+		if r, err := Parse(s); err == nil {
+			t.Errorf("Parse(%q) = %s, want an error", s, r)
+		}
+	}
+}
+
+func TestParseString(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"FREQ=DAILY", "FREQ=DAILY"},
+		{"RRULE:freq=weekly;byday=th,mo;interval=2", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,TH"},
+		{"FREQ=WEEKLY;BYDAY=MO,MO,FR,MO", "FREQ=WEEKLY;BYDAY=MO,FR"},
+		{"FREQ=MONTHLY;INTERVAL=1;COUNT=3", "FREQ=MONTHLY;COUNT=3"},
+		{"FREQ=YEARLY;UNTIL=20300101T120000Z", "FREQ=YEARLY;UNTIL=20300101T120000Z"},
+		{"FREQ=DAILY;UNTIL=20300101", "FREQ=DAILY;UNTIL=20300101T235959Z"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q) error = %v", tt.in, err)
+			continue
+		}
+		if got := r.String(); got != tt.want {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02 15:04", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// expand returns up to max occurrences of rule from start.
+func expand(t *testing.T, rule, start string, max int) []time.Time {
+	t.Helper()
+	r, err := Parse(rule)
+	if err != nil {
+		t.Fatalf("Parse(%q) error = %v", rule, err)
+	}
+	var got []time.Time
+	r.Each(date(start), func(o time.Time) bool {
+		got = append(got, o)
+		return len(got) < max
+	})
+	return got
+}
+
+func TestEach(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  string
+		start string
+		want  []string
+	}{
+		{"count", "FREQ=DAILY;COUNT=3", "2026-01-01 09:00",
+			[]string{"2026-01-01 09:00", "2026-01-02 09:00", "2026-01-03 09:00"}},
+		{"until date includes the day", "FREQ=DAILY;UNTIL=20260103", "2026-01-01 23:00",
+			[]string{"2026-01-01 23:00", "2026-01-02 23:00", "2026-01-03 23:00"}},
+		{"until time", "FREQ=HOURLY;INTERVAL=5;UNTIL=20260101T100000Z", "2026-01-01 00:00",
+			[]string{"2026-01-01 00:00", "2026-01-01 05:00", "2026-01-01 10:00"}},
+		{"byday", "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4", "2026-01-07 08:00",
+			[]string{"2026-01-07 08:00", "2026-01-12 08:00", "2026-01-14 08:00", "2026-01-19 08:00"}},
+		{"repeated byday", "FREQ=WEEKLY;BYDAY=MO,MO;COUNT=3", "2026-01-05 08:00",
+			[]string{"2026-01-05 08:00", "2026-01-12 08:00", "2026-01-19 08:00"}},
+		{"byday every other week", "FREQ=WEEKLY;INTERVAL=2;BYDAY=SU;COUNT=2", "2026-01-05 08:00",
+			[]string{"2026-01-11 08:00", "2026-01-25 08:00"}},
+		{"skips february 30", "FREQ=MONTHLY;COUNT=3", "2026-01-30 12:00",
+			[]string{"2026-01-30 12:00", "2026-03-30 12:00", "2026-04-30 12:00"}},
+		{"skips short months", "FREQ=MONTHLY;COUNT=4", "2026-01-31 12:00",
+			[]string{"2026-01-31 12:00", "2026-03-31 12:00", "2026-05-31 12:00", "2026-07-31 12:00"}},
+		{"skips february 29", "FREQ=YEARLY;COUNT=2", "2024-02-29 12:00",
+			[]string{"2024-02-29 12:00", "2028-02-29 12:00"}},
+		{"until ends skipping rules", "FREQ=MONTHLY;UNTIL=20260301", "2026-01-31 12:00",
+			[]string{"2026-01-31 12:00"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := expand(t, tt.rule, tt.start, 10)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Each() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(date(tt.want[i])) {
+					t.Errorf("Each() occurrence %d = %s, want %s", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// TestAfter checks After, which skips ahead instead of expanding from start,
+// against expanding from start.
+func TestAfter(t *testing.T) {
+	rules := []string{
+		"FREQ=HOURLY",
+		"FREQ=HOURLY;INTERVAL=7;COUNT=500",
+		"FREQ=DAILY;INTERVAL=3",
+		"FREQ=DAILY;COUNT=40",
+		"FREQ=WEEKLY;INTERVAL=2;UNTIL=20270101",
+		"FREQ=WEEKLY;BYDAY=MO,FR;COUNT=25",
+		"FREQ=WEEKLY;INTERVAL=3;BYDAY=TU,SA",
+		"FREQ=MONTHLY;COUNT=10",
+		"FREQ=YEARLY",
+	}
+	start := date("2026-01-31 09:30")
+	for _, rule := range rules {
+		r, err := Parse(rule)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", rule, err)
+		}
+		var all []time.Time
+		r.Each(start, func(o time.Time) bool {
+			all = append(all, o)
+			return o.Before(start.AddDate(1, 0, 0))
+		})
+		for at := start.Add(-time.Hour); at.Before(start.AddDate(1, 0, 0)); at = at.Add(17 * time.Hour) {
+			var want time.Time
+			for _, o := range all {
+				if o.After(at) {
+					want = o
+					break
+				}
+			}
+			got, ok := r.After(start, at)
+			if ok != !want.IsZero() || !got.Equal(want) {
+				t.Fatalf("%s: After(%s) = %s, %v, want %s", rule, at, got, ok, want)
+			}
+		}
+	}
+}
+
+func TestAfterFarAhead(t *testing.T) {
+	r, err := Parse("FREQ=HOURLY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := date("2000-01-01 00:00")
+	got, ok := r.After(start, date("2030-06-01 12:30"))
+	if !ok || !got.Equal(date("2030-06-01 13:00")) {
+		t.Errorf("After() 30 years on = %s, %v, want 2030-06-01 13:00", got, ok)
+	}
+}
+
+func TestAfterAcrossDST(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone data: %v", err)
+	}
+	r, err := Parse("FREQ=DAILY")
+	if err != nil {
+		t.Fatal(err)
+	}
+	start := time.Date(2026, 1, 1, 0, 30, 0, 0, loc)
+	// Clocks go forward on March 8, occurrences stay at 00:30 local time.
+	got, ok := r.After(start, time.Date(2026, 3, 20, 0, 0, 0, 0, loc))
+	if want := time.Date(2026, 3, 20, 0, 30, 0, 0, loc); !ok || !got.Equal(want) {
+		t.Errorf("After() = %s, %v, want %s", got, ok, want)
+	}
+}
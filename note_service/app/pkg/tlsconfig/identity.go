@@ -0,0 +1,65 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/x509"
+	"net/http"
+
+	"github.com/google/uuid"
+)
+
+type identityKey struct{}
+
+// Identity describes a client certificate that passed verification against
+// the configured CA bundle.
+type Identity struct {
+	CommonName   string
+	Organization []string
+	DNSNames     []string
+	URIs         []string
+	SerialNumber string
+	// UserUUID is the user the certificate acts as, uuid.Nil unless its URI
+	// or common name is mapped to one.
+	UserUUID uuid.UUID
+}
+
+func IdentityFromContext(ctx context.Context) (Identity, bool) {
+	id, ok := ctx.Value(identityKey{}).(Identity)
+	return id, ok
+}
+
+// Middleware stores the verified client certificate identity, if any, in the
+// request context so handlers can authenticate service-to-service callers.
+// users maps certificate URIs and common names to the users they act as.
+func Middleware(h http.Handler, users map[string]uuid.UUID) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			h.ServeHTTP(w, r)
+			return
+		}
+		id := newIdentity(r.TLS.VerifiedChains[0][0], users)
+		ctx := context.WithValue(r.Context(), identityKey{}, id)
+		h.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func newIdentity(leaf *x509.Certificate, users map[string]uuid.UUID) Identity {
+	id := Identity{
+		CommonName:   leaf.Subject.CommonName,
+		Organization: leaf.Subject.Organization,
+		DNSNames:     leaf.DNSNames,
+		SerialNumber: leaf.SerialNumber.String(),
+	}
+	for _, u := range leaf.URIs {
+		id.URIs = append(id.URIs, u.String())
+	}
+	// URIs such as SPIFFE ids name a workload more precisely than a common
+	// name, they are looked up first.
+	for _, name := range append(id.URIs, id.CommonName) {
+		if userUUID, ok := users[name]; ok && name != "" {
+			id.UserUUID = userUUID
+			break
+		}
+	}
+	return id
+}
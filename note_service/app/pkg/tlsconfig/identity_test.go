@@ -0,0 +1,61 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestMiddleware(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://example.org/billing")
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}, URIs: []*url.URL{spiffe},
+		SerialNumber: big.NewInt(7)}
+	byURI, byName := uuid.New(), uuid.New()
+
+	tests := []struct {
+		name     string
+		state    *tls.ConnectionState
+		users    map[string]uuid.UUID
+		wantOK   bool
+		wantUser uuid.UUID
+	}{
+		{name: "plain http"},
+		{name: "unverified", state: &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf}}},
+		{name: "unmapped", state: verified(leaf), wantOK: true},
+		{name: "common name", state: verified(leaf), users: map[string]uuid.UUID{"billing": byName},
+			wantOK: true, wantUser: byName},
+		{name: "uri first", state: verified(leaf),
+			users: map[string]uuid.UUID{"billing": byName, spiffe.String(): byURI}, wantOK: true, wantUser: byURI},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var id Identity
+			var ok bool
+			h := Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, ok = IdentityFromContext(r.Context())
+			}), tt.users)
+			r := httptest.NewRequest("GET", "/notes", nil)
+			r.TLS = tt.state
+			h.ServeHTTP(httptest.NewRecorder(), r)
+
+			if ok != tt.wantOK || id.UserUUID != tt.wantUser {
+				t.Fatalf("IdentityFromContext() = %v, %v, want user %s, %v", id, ok, tt.wantUser, tt.wantOK)
+			}
+			if ok && (id.CommonName != "billing" || id.SerialNumber != "7" || len(id.URIs) != 1) {
+				t.Errorf("IdentityFromContext() = %+v, want the leaf certificate", id)
+			}
+		})
+	}
+}
+
+func verified(leaf *x509.Certificate) *tls.ConnectionState {
+	return &tls.ConnectionState{PeerCertificates: []*x509.Certificate{leaf},
+		VerifiedChains: [][]*x509.Certificate{{leaf}}}
+}
@@ -0,0 +1,140 @@
+package tlsconfig
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"note_service/app/pkg/logging"
+
+	"github.com/sirupsen/logrus"
+)
+
+// writeCert writes a self-signed certificate for commonName and its key to
+// dir, dated modTime so the reloader sees the change.
+func writeCert(t *testing.T, dir, commonName string, modTime time.Time) (certFile, keyFile string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(time.Now().UnixNano()),
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile, keyFile = filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key")
+	writeFile(t, certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), modTime)
+	writeFile(t, keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), modTime)
+	return certFile, keyFile
+}
+
+func writeFile(t *testing.T, name string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(name, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func commonName(t *testing.T, r *Reloader) string {
+	t.Helper()
+	cert, err := r.GetCertificate(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return leaf.Subject.CommonName
+}
+
+func newTestReloader(t *testing.T, certFile, keyFile, caFile string) (*Reloader, error) {
+	r, err := NewReloader(certFile, keyFile, caFile, 10*time.Millisecond,
+		logging.Logger{Entry: logrus.NewEntry(logrus.New())})
+	if r != nil {
+		t.Cleanup(func() { r.Close() })
+	}
+	return r, err
+}
+
+func TestReloaderPicksUpChangedFiles(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile := writeCert(t, dir, "first", start)
+	r, err := newTestReloader(t, certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	if got := commonName(t, r); got != "first" {
+		t.Fatalf("certificate = %q, want first", got)
+	}
+
+	writeCert(t, dir, "second", start.Add(time.Minute))
+	deadline := time.Now().Add(2 * time.Second)
+	for commonName(t, r) != "second" {
+		if time.Now().After(deadline) {
+			t.Fatal("certificate not reloaded after the files changed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestReloaderKeepsCertificateOnBrokenFiles(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Now().Add(-time.Hour)
+	certFile, keyFile := writeCert(t, dir, "first", start)
+	r, err := newTestReloader(t, certFile, keyFile, "")
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+
+	writeFile(t, keyFile, []byte("not a key"), start.Add(time.Minute))
+	time.Sleep(100 * time.Millisecond)
+	if got := commonName(t, r); got != "first" {
+		t.Errorf("certificate = %q after a broken reload, want first", got)
+	}
+}
+
+func TestReloaderClientCAs(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server", time.Now())
+	r, err := newTestReloader(t, certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	if r.ClientCAs() == nil {
+		t.Error("ClientCAs() = nil, want the CA bundle")
+	}
+
+	empty := filepath.Join(dir, "empty.pem")
+	writeFile(t, empty, []byte("no certificates here"), time.Now())
+	if _, err := newTestReloader(t, certFile, keyFile, empty); err == nil {
+		t.Error("NewReloader() with an empty CA bundle error = nil")
+	}
+	if _, err := newTestReloader(t, certFile, filepath.Join(dir, "missing.key"), ""); err == nil {
+		t.Error("NewReloader() with a missing key error = nil")
+	}
+}
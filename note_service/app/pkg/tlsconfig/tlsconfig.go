@@ -0,0 +1,149 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+type Options struct {
+	MinVersion   string
+	CipherSuites []string
+	ClientCAFile string
+	ClientAuth   string
+}
+
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var clientAuthTypes = map[string]tls.ClientAuthType{
+	"none":               tls.NoClientCert,
+	"request":            tls.RequestClientCert,
+	"require":            tls.RequireAnyClientCert,
+	"verify_if_given":    tls.VerifyClientCertIfGiven,
+	"require_and_verify": tls.RequireAndVerifyClientCert,
+}
+
+// New builds a server tls.Config whose certificate and client CA bundle are
+// served from the reloader, so rotated files are picked up without a restart.
+func New(opts Options, reloader *Reloader) (*tls.Config, error) {
+	minVersion, err := parseVersion(opts.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	cipherSuites, err := parseCipherSuites(opts.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+	clientAuth, err := parseClientAuth(opts.ClientAuth, opts.ClientCAFile != "")
+	if err != nil {
+		return nil, err
+	}
+
+	// h2 has to be offered over ALPN, gRPC only speaks HTTP/2.
+	base := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuth,
+		GetCertificate: reloader.GetCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+	if opts.ClientCAFile == "" {
+		return base, nil
+	}
+
+	base.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		cfg := base.Clone()
+		cfg.GetConfigForClient = nil
+		cfg.ClientCAs = reloader.ClientCAs()
+		return cfg, nil
+	}
+	return base, nil
+}
+
+// WithNextProtos returns a copy of cfg offering protos over ALPN, on the
+// configs its GetConfigForClient returns as well.
+func WithNextProtos(cfg *tls.Config, protos ...string) *tls.Config {
+	c := cfg.Clone()
+	c.NextProtos = protos
+	if get := cfg.GetConfigForClient; get != nil {
+		c.GetConfigForClient = func(hello *tls.ClientHelloInfo) (*tls.Config, error) {
+			forClient, err := get(hello)
+			if err != nil || forClient == nil {
+				return forClient, err
+			}
+			// New returns a fresh clone for every handshake.
+			forClient.NextProtos = protos
+			return forClient, nil
+		}
+	}
+	return c
+}
+
+// ParseUsers parses the client certificate names to user ids mapping of the
+// config.
+func ParseUsers(names map[string]string) (map[string]uuid.UUID, error) {
+	users := make(map[string]uuid.UUID, len(names))
+	for name, id := range names {
+		userUUID, err := uuid.Parse(id)
+		if err != nil {
+			return nil, fmt.Errorf("invalid user id %q for client certificate %q", id, name)
+		}
+		users[name] = userUUID
+	}
+	return users, nil
+}
+
+func parseVersion(v string) (uint16, error) {
+	if v == "" {
+		return tls.VersionTLS12, nil
+	}
+	version, ok := versions[v]
+	if !ok {
+		return 0, fmt.Errorf("unknown tls version %q", v)
+	}
+	return version, nil
+}
+
+func parseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	known := make(map[string]uint16)
+	for _, suite := range tls.CipherSuites() {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+func parseClientAuth(v string, hasCA bool) (tls.ClientAuthType, error) {
+	if v == "" {
+		if hasCA {
+			return tls.RequireAndVerifyClientCert, nil
+		}
+		return tls.NoClientCert, nil
+	}
+	clientAuth, ok := clientAuthTypes[v]
+	if !ok {
+		return 0, fmt.Errorf("unknown client auth type %q", v)
+	}
+	if clientAuth >= tls.VerifyClientCertIfGiven && !hasCA {
+		return 0, fmt.Errorf("client auth %q requires a client CA file", v)
+	}
+	return clientAuth, nil
+}
@@ -0,0 +1,78 @@
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestNew(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeCert(t, dir, "server", time.Now())
+	r, err := newTestReloader(t, certFile, keyFile, certFile)
+	if err != nil {
+		t.Fatalf("NewReloader() error = %v", err)
+	}
+	suite := tls.CipherSuites()[0]
+
+	tests := []struct {
+		name           string
+		opts           Options
+		wantErr        bool
+		wantMinVersion uint16
+		wantClientAuth tls.ClientAuthType
+		wantSuites     []uint16
+	}{
+		{name: "defaults", wantMinVersion: tls.VersionTLS12, wantClientAuth: tls.NoClientCert},
+		{name: "CA verifies by default", opts: Options{MinVersion: "1.3", ClientCAFile: certFile},
+			wantMinVersion: tls.VersionTLS13, wantClientAuth: tls.RequireAndVerifyClientCert},
+		{name: "client auth", opts: Options{ClientCAFile: certFile, ClientAuth: "verify_if_given"},
+			wantMinVersion: tls.VersionTLS12, wantClientAuth: tls.VerifyClientCertIfGiven},
+		{name: "cipher suites", opts: Options{CipherSuites: []string{" " + suite.Name}},
+			wantMinVersion: tls.VersionTLS12, wantSuites: []uint16{suite.ID}},
+		{name: "unknown version", opts: Options{MinVersion: "1.4"}, wantErr: true},
+		{name: "insecure cipher suite", opts: Options{CipherSuites: []string{"TLS_RSA_WITH_RC4_128_SHA"}},
+			wantErr: true},
+		{name: "unknown client auth", opts: Options{ClientCAFile: certFile, ClientAuth: "always"}, wantErr: true},
+		{name: "verify without CA", opts: Options{ClientAuth: "require_and_verify"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg, err := New(tt.opts, r)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("New() error = nil, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if cfg.MinVersion != tt.wantMinVersion || cfg.ClientAuth != tt.wantClientAuth {
+				t.Errorf("New() = MinVersion %x, ClientAuth %v, want %x, %v", cfg.MinVersion, cfg.ClientAuth,
+					tt.wantMinVersion, tt.wantClientAuth)
+			}
+			if len(cfg.CipherSuites) != len(tt.wantSuites) ||
+				(len(tt.wantSuites) > 0 && cfg.CipherSuites[0] != tt.wantSuites[0]) {
+				t.Errorf("New() CipherSuites = %v, want %v", cfg.CipherSuites, tt.wantSuites)
+			}
+			if (cfg.GetConfigForClient != nil) != (tt.opts.ClientCAFile != "") {
+				t.Errorf("New() GetConfigForClient set = %v, want %v", cfg.GetConfigForClient != nil,
+					tt.opts.ClientCAFile != "")
+			}
+		})
+	}
+}
+
+func TestParseUsers(t *testing.T) {
+	id := uuid.New()
+	users, err := ParseUsers(map[string]string{"billing": id.String()})
+	if err != nil || users["billing"] != id {
+		t.Errorf("ParseUsers() = %v, %v, want billing mapped to %s", users, err, id)
+	}
+	if _, err := ParseUsers(map[string]string{"billing": "not-a-uuid"}); err == nil {
+		t.Error("ParseUsers() with an invalid id error = nil")
+	}
+}
@@ -0,0 +1,55 @@
+package user
+
+import (
+	"context"
+	"note_service/app/internal/client/user_client"
+	"note_service/app/pkg/logging"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// UnaryAuthentication is the gRPC counterpart of Authentication: it resolves
+// the bearer token from the "authorization" metadata entry and stores the
+// user UUID in the context, uuid.Nil for anonymous callers.
+func UnaryAuthentication(c user_client.UserClient) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(authenticate(ctx, c), req)
+	}
+}
+
+func StreamAuthentication(c user_client.UserClient) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &authenticatedStream{ServerStream: ss, ctx: authenticate(ss.Context(), c)})
+	}
+}
+
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
+
+func authenticate(ctx context.Context, c user_client.UserClient) context.Context {
+	logger := logging.GetLogger()
+	userUUID := uuid.Nil
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 || !strings.HasPrefix(values[0], "Bearer ") {
+		return context.WithValue(ctx, "userUUID", userUUID)
+	}
+	token := user_client.Token{AccessToken: strings.TrimPrefix(values[0], "Bearer "), TokenType: "Bearer"}
+	user, err := c.GetUserByToken(ctx, token)
+	if err != nil {
+		logger.Errorf("failed to authenticate grpc call: %v", err)
+	} else {
+		userUUID = user.UUID
+	}
+	return context.WithValue(ctx, "userUUID", userUUID)
+}
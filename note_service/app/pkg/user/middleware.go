@@ -1,8 +1,10 @@
 package user
 
 import (
+	"note_service/app/internal/apperror"
 	"note_service/app/internal/client/user_client"
 	"note_service/app/pkg/logging"
+	"note_service/app/pkg/tlsconfig"
 
 	"context"
 	"net/http"
@@ -15,8 +17,15 @@ type UserClaims struct {
 	userUUID uuid.UUID
 }
 
+// Authentication resolves the bearer token to a user. Without a token, a
+// verified client certificate mapped to a user authenticates as that user.
 func Authentication(c user_client.UserClient, h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		if id, ok := tlsconfig.IdentityFromContext(r.Context()); ok && id.UserUUID != uuid.Nil &&
+			r.Header.Get("Authorization") == "" {
+			h(w, r.WithContext(context.WithValue(r.Context(), "userUUID", id.UserUUID)))
+			return
+		}
 		logger := logging.GetLogger()
 		token := user_client.Token{}
 		authHeader := strings.Split(r.Header.Get("Authorization"), "Bearer ")
@@ -42,8 +51,8 @@ func Authentication(c user_client.UserClient, h http.HandlerFunc) http.HandlerFu
 func Authorization(h http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		userUUID := r.Context().Value("userUUID").(uuid.UUID)
-		if userUUID.String() == "00000000-0000-0000-0000-000000000000" {
-			w.WriteHeader(http.StatusForbidden)
+		if userUUID == uuid.Nil {
+			apperror.Write(w, r, apperror.ErrUnauthorized)
 			return
 		}
 		ctx := context.WithValue(r.Context(), "userUUID", userUUID)
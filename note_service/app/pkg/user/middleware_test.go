@@ -0,0 +1,62 @@
+package user
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"note_service/app/internal/client/user_client"
+	"note_service/app/pkg/tlsconfig"
+
+	"github.com/google/uuid"
+)
+
+type fakeUserClient struct {
+	user_client.UserClient
+	tokens map[string]uuid.UUID
+}
+
+func (c fakeUserClient) GetUserByToken(ctx context.Context, token user_client.Token) (user_client.User, error) {
+	return user_client.User{UUID: c.tokens[token.AccessToken]}, nil
+}
+
+func TestAuthenticationWithClientCertificate(t *testing.T) {
+	service, tokenUser := uuid.New(), uuid.New()
+	client := fakeUserClient{tokens: map[string]uuid.UUID{"token": tokenUser}}
+	leaf := &x509.Certificate{Subject: pkix.Name{CommonName: "billing"}, SerialNumber: big.NewInt(1)}
+
+	tests := []struct {
+		name   string
+		cert   bool
+		header string
+		want   uuid.UUID
+	}{
+		{name: "certificate", cert: true, want: service},
+		{name: "token wins over certificate", cert: true, header: "Bearer token", want: tokenUser},
+		{name: "token", header: "Bearer token", want: tokenUser},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got uuid.UUID
+			h := tlsconfig.Middleware(Authentication(client, func(w http.ResponseWriter, r *http.Request) {
+				got = r.Context().Value("userUUID").(uuid.UUID)
+			}), map[string]uuid.UUID{"billing": service})
+			r := httptest.NewRequest("GET", "/notes", nil)
+			if tt.cert {
+				r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{leaf}}}
+			}
+			if tt.header != "" {
+				r.Header.Set("Authorization", tt.header)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			if got != tt.want {
+				t.Errorf("userUUID = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,138 @@
+package validator
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"note_service/app/internal/apperror"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+)
+
+// DecodeJSON decodes a single JSON object from the request body into dst,
+// rejecting unknown fields and bodies larger than maxBytes. Decoding problems
+// are reported as field errors where the field is known, all at once.
+func DecodeJSON(w http.ResponseWriter, r *http.Request, dst interface{}, maxBytes int64) error {
+	body := io.Reader(r.Body)
+	if maxBytes > 0 {
+		body = http.MaxBytesReader(w, r.Body, maxBytes)
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return decodeError(err)
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	var value json.RawMessage
+	if err := dec.Decode(&value); err != nil {
+		return decodeError(err)
+	}
+	if dec.More() {
+		return apperror.BadRequestError("request body must contain a single JSON object")
+	}
+	if errs := fieldErrors(value, dst); len(errs) > 0 {
+		return apperror.ValidationError(errs...)
+	}
+	// encoding/json replaces invalid UTF-8 rather than failing, so it is
+	// looked for before decoding. fieldErrors names the field it is in.
+	if !utf8.Valid(raw) {
+		return apperror.BadRequestError("request body must be valid UTF-8")
+	}
+
+	dec = json.NewDecoder(bytes.NewReader(value))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+	return nil
+}
+
+// fieldErrors decodes every member of the object one by one into a fresh
+// value of the type of dst, so each unknown or mistyped field is reported
+// instead of only the first one. Bodies that aren't objects are left to the
+// caller.
+func fieldErrors(object json.RawMessage, dst interface{}) []apperror.FieldError {
+	t := reflect.TypeOf(dst)
+	if t.Kind() != reflect.Ptr {
+		return nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(object))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return nil
+	}
+	var errs []apperror.FieldError
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return errs
+		}
+		key := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return errs
+		}
+		if !utf8.Valid(value) {
+			errs = append(errs, apperror.FieldError{Field: key, Code: "invalid_utf8", Message: "must be valid UTF-8"})
+			continue
+		}
+		member, err := json.Marshal(map[string]json.RawMessage{key: value})
+		if err != nil {
+			continue
+		}
+		one := json.NewDecoder(bytes.NewReader(member))
+		one.DisallowUnknownFields()
+		if err := one.Decode(reflect.New(t.Elem()).Interface()); err != nil {
+			var appErr *apperror.AppError
+			if errors.As(decodeError(err), &appErr) {
+				errs = append(errs, appErr.Details...)
+			}
+		}
+	}
+	return errs
+}
+
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var maxBytesErr *http.MaxBytesError
+	switch {
+	case errors.Is(err, io.EOF):
+		return apperror.BadRequestError("request body must not be empty")
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return apperror.BadRequestError("request body contains malformed JSON")
+	case errors.As(err, &syntaxErr):
+		return apperror.BadRequestError(fmt.Sprintf("request body contains malformed JSON at position %d", syntaxErr.Offset))
+	case errors.As(err, &maxBytesErr):
+		return apperror.NewAppError(http.StatusRequestEntityTooLarge, apperror.CodeBadRequest,
+			fmt.Sprintf("request body must not be larger than %d bytes", maxBytesErr.Limit), "")
+	case errors.As(err, &typeErr):
+		return apperror.ValidationError(apperror.FieldError{
+			Field:   typeErr.Field,
+			Code:    "invalid_type",
+			Message: fmt.Sprintf("must be of type %s", jsonType(typeErr.Type.String())),
+		})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return apperror.ValidationError(apperror.FieldError{Field: field, Code: "unknown_field", Message: "is not allowed"})
+	}
+	return apperror.BadRequestError("invalid data")
+}
+
+func jsonType(goType string) string {
+	goType = strings.TrimLeft(goType, "*")
+	switch {
+	case goType == "string" || goType == "uuid.UUID" || goType == "time.Time":
+		return "string"
+	case goType == "bool":
+		return "boolean"
+	case strings.HasPrefix(goType, "int") || strings.HasPrefix(goType, "float") || strings.HasPrefix(goType, "uint"):
+		return "number"
+	case strings.HasPrefix(goType, "[]"):
+		return "array"
+	}
+	return "object"
+}
@@ -0,0 +1,84 @@
+package validator
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"note_service/app/internal/apperror"
+	"strings"
+	"testing"
+)
+
+type decodeDTO struct {
+	Text   *string  `json:"text"`
+	Public *bool    `json:"public"`
+	Tags   []string `json:"tags"`
+}
+
+func decode(body string, maxBytes int64) (decodeDTO, error) {
+	var dto decodeDTO
+	r := httptest.NewRequest("POST", "/notes", strings.NewReader(body))
+	err := DecodeJSON(httptest.NewRecorder(), r, &dto, maxBytes)
+	return dto, err
+}
+
+func TestDecodeJSON(t *testing.T) {
+	dto, err := decode(`{"text": "a", "public": true, "tags": ["x"]}`, 0)
+	if err != nil {
+		t.Fatalf("DecodeJSON() error = %v", err)
+	}
+	if *dto.Text != "a" || !*dto.Public || len(dto.Tags) != 1 {
+		t.Errorf("DecodeJSON() = %+v", dto)
+	}
+}
+
+func TestDecodeJSONErrors(t *testing.T) {
+	type fieldError struct{ field, code string }
+	tests := []struct {
+		name       string
+		body       string
+		maxBytes   int64
+		wantStatus int
+		wantFields []fieldError
+	}{
+		{name: "empty", body: "  ", wantStatus: http.StatusBadRequest},
+		{name: "malformed", body: `{"text": `, wantStatus: http.StatusBadRequest},
+		{name: "syntax", body: `{"text" "a"}`, wantStatus: http.StatusBadRequest},
+		{name: "two objects", body: `{} {}`, wantStatus: http.StatusBadRequest},
+		{name: "too large", body: `{"text": "aaaaaaaaaa"}`, maxBytes: 8, wantStatus: http.StatusRequestEntityTooLarge},
+		{name: "not an object", body: `["a"]`, wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []fieldError{{"", "invalid_type"}}},
+		{name: "all violations", body: `{"bogus": 1, "text": 1, "public": "yes", "tags": [1], "other": {}}`,
+			wantStatus: http.StatusUnprocessableEntity, wantFields: []fieldError{
+				{"bogus", "unknown_field"}, {"text", "invalid_type"}, {"public", "invalid_type"},
+				{"tags.0", "invalid_type"}, {"other", "unknown_field"}}},
+		{name: "invalid utf-8 value", body: "{\"text\": \"a\xffb\", \"public\": true}",
+			wantStatus: http.StatusUnprocessableEntity, wantFields: []fieldError{{"text", "invalid_utf8"}}},
+		{name: "invalid utf-8 key", body: "{\"te\xffxt\": \"a\"}", wantStatus: http.StatusUnprocessableEntity,
+			wantFields: []fieldError{{"te�xt", "unknown_field"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decode(tt.body, tt.maxBytes)
+			var appErr *apperror.AppError
+			if !errors.As(err, &appErr) {
+				t.Fatalf("DecodeJSON() error = %v, want an AppError", err)
+			}
+			if appErr.Status != tt.wantStatus {
+				t.Errorf("DecodeJSON() status = %d, want %d", appErr.Status, tt.wantStatus)
+			}
+			var got []fieldError
+			for _, d := range appErr.Details {
+				got = append(got, fieldError{d.Field, d.Code})
+			}
+			if len(got) != len(tt.wantFields) {
+				t.Fatalf("DecodeJSON() field errors = %v, want %v", got, tt.wantFields)
+			}
+			for i := range got {
+				if got[i] != tt.wantFields[i] {
+					t.Errorf("DecodeJSON() field error %d = %v, want %v", i, got[i], tt.wantFields[i])
+				}
+			}
+		})
+	}
+}
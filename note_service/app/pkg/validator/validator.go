@@ -0,0 +1,148 @@
+package validator
+
+import (
+	"fmt"
+	"note_service/app/internal/apperror"
+	"reflect"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Rule holds the configurable limits a string field can refer to by name,
+// e.g. `validate:"required,rule=text"`.
+type Rule struct {
+	MinRunes int
+	MaxRunes int
+	MaxBytes int
+}
+
+type Validator struct {
+	rules map[string]Rule
+}
+
+func New(rules map[string]Rule) *Validator {
+	return &Validator{rules: rules}
+}
+
+// Struct normalizes string fields of the struct pointed to by s to NFC and
+// checks them against their `validate` tags. Supported options:
+//
+//	required  the pointer must not be nil
+//	notblank  the string must contain something besides whitespace
+//	rule=name apply the named Rule
+//	oneof=a|b the string must be one of the listed values
+//
+// All violations are returned at once.
+func (v *Validator) Struct(s interface{}) []apperror.FieldError {
+	rv := reflect.ValueOf(s)
+	if rv.Kind() != reflect.Ptr || rv.Elem().Kind() != reflect.Struct {
+		panic(fmt.Sprintf("validator: expected pointer to struct, got %T", s))
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+
+	var errs []apperror.FieldError
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag := sf.Tag.Get("validate")
+		if tag == "" || tag == "-" {
+			continue
+		}
+		name := fieldName(sf)
+		fv := rv.Field(i)
+
+		if fv.Kind() == reflect.Ptr && fv.IsNil() {
+			if hasOption(tag, "required") {
+				errs = append(errs, apperror.FieldError{Field: name, Code: "required", Message: "is required"})
+			}
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			fv = fv.Elem()
+		}
+		if fv.Kind() != reflect.String {
+			continue
+		}
+
+		str := fv.String()
+		if !utf8.ValidString(str) {
+			errs = append(errs, apperror.FieldError{Field: name, Code: "invalid_utf8", Message: "must be valid UTF-8"})
+			continue
+		}
+		str = norm.NFC.String(str)
+		fv.SetString(str)
+
+		if hasOption(tag, "notblank") && strings.TrimSpace(str) == "" {
+			errs = append(errs, apperror.FieldError{Field: name, Code: "blank", Message: "must not be blank"})
+			continue
+		}
+		if values, ok := optionValue(tag, "oneof"); ok && !oneOf(str, values) {
+			errs = append(errs, apperror.FieldError{Field: name, Code: "invalid",
+				Message: "must be one of " + strings.ReplaceAll(values, "|", ", ")})
+			continue
+		}
+		if ruleName, ok := optionValue(tag, "rule"); ok {
+			rule, ok := v.rules[ruleName]
+			if !ok {
+				panic(fmt.Sprintf("validator: unknown rule %q on field %s", ruleName, sf.Name))
+			}
+			errs = append(errs, checkRule(name, str, rule)...)
+		}
+	}
+	return errs
+}
+
+func checkRule(name, str string, rule Rule) []apperror.FieldError {
+	var errs []apperror.FieldError
+	runes := utf8.RuneCountInString(str)
+	if rule.MinRunes > 0 && runes < rule.MinRunes {
+		errs = append(errs, apperror.FieldError{Field: name, Code: "too_short",
+			Message: fmt.Sprintf("must be at least %d characters", rule.MinRunes)})
+	}
+	if rule.MaxRunes > 0 && runes > rule.MaxRunes {
+		errs = append(errs, apperror.FieldError{Field: name, Code: "too_long",
+			Message: fmt.Sprintf("must be at most %d characters", rule.MaxRunes)})
+	}
+	if rule.MaxBytes > 0 && len(str) > rule.MaxBytes {
+		errs = append(errs, apperror.FieldError{Field: name, Code: "too_large",
+			Message: fmt.Sprintf("must be at most %d bytes", rule.MaxBytes)})
+	}
+	return errs
+}
+
+func oneOf(str, values string) bool {
+	for _, v := range strings.Split(values, "|") {
+		if str == v {
+			return true
+		}
+	}
+	return false
+}
+
+func fieldName(sf reflect.StructField) string {
+	name := strings.Split(sf.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+	return name
+}
+
+func hasOption(tag, option string) bool {
+	for _, o := range strings.Split(tag, ",") {
+		if o == option {
+			return true
+		}
+	}
+	return false
+}
+
+func optionValue(tag, option string) (string, bool) {
+	for _, o := range strings.Split(tag, ",") {
+		if v, ok := strings.CutPrefix(o, option+"="); ok {
+			return v, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,89 @@
+package validator
+
+import (
+	"strings"
+	"testing"
+)
+
+type structDTO struct {
+	Title  *string `json:"title" validate:"required,notblank,rule=title"`
+	Text   *string `json:"text" validate:"rule=text"`
+	Format *string `json:"format" validate:"oneof=plain|markdown"`
+	Note   string  `validate:"notblank"`
+}
+
+var rules = map[string]Rule{
+	"title": {MinRunes: 2, MaxRunes: 5},
+	"text":  {MaxBytes: 4},
+}
+
+func str(s string) *string { return &s }
+
+func TestStruct(t *testing.T) {
+	type fieldError struct{ field, code string }
+	tests := []struct {
+		name string
+		dto  structDTO
+		want []fieldError
+	}{
+		{name: "valid", dto: structDTO{Title: str("abc"), Text: str("abcd"), Format: str("plain"), Note: "x"}},
+		{name: "missing", dto: structDTO{Note: "x"}, want: []fieldError{{"title", "required"}}},
+		{name: "blank", dto: structDTO{Title: str("   "), Note: "\t"},
+			want: []fieldError{{"title", "blank"}, {"Note", "blank"}}},
+		{name: "rule limits", dto: structDTO{Title: str("a"), Text: str("äöü"), Note: "x"},
+			want: []fieldError{{"title", "too_short"}, {"text", "too_large"}}},
+		{name: "too long", dto: structDTO{Title: str("abcdef"), Note: "x"}, want: []fieldError{{"title", "too_long"}}},
+		{name: "oneof", dto: structDTO{Title: str("abc"), Format: str("html"), Note: "x"},
+			want: []fieldError{{"format", "invalid"}}},
+		{name: "invalid utf-8", dto: structDTO{Title: str("a\xffb"), Note: "x"},
+			want: []fieldError{{"title", "invalid_utf8"}}},
+		{name: "all at once", dto: structDTO{Text: str("abcde"), Format: str("html")},
+			want: []fieldError{{"title", "required"}, {"text", "too_large"}, {"format", "invalid"}, {"Note", "blank"}}},
+	}
+	v := New(rules)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []fieldError
+			for _, e := range v.Struct(&tt.dto) {
+				got = append(got, fieldError{e.Field, e.Code})
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Struct() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Struct() error %d = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestStructNormalizesNFC(t *testing.T) {
+	// "e" followed by a combining acute accent is two runes, NFC makes it one.
+	dto := structDTO{Title: str("cafe\u0301"), Note: "x"}
+	if errs := New(rules).Struct(&dto); len(errs) != 0 {
+		t.Fatalf("Struct() = %v, want no errors", errs)
+	}
+	if *dto.Title != "caf\u00e9" {
+		t.Errorf("Struct() title = %q, want NFC %q", *dto.Title, "caf\u00e9")
+	}
+}
+
+func TestStructPanics(t *testing.T) {
+	for name, s := range map[string]interface{}{
+		"not a pointer": structDTO{},
+		"unknown rule": &struct {
+			S string `validate:"rule=nope"`
+		}{},
+	} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.HasPrefix(r.(string), "validator:") {
+					t.Errorf("Struct(%s) panic = %v, want a validator panic", name, r)
+				}
+			}()
+			New(rules).Struct(s)
+		}()
+	}
+}
@@ -1,8 +1,10 @@
-from fastapi import Depends, Request, HTTPException, status, APIRouter
+import uuid
+
+from fastapi import Depends, Query, Request, HTTPException, status, APIRouter
 from fastapi_users import exceptions
 from fastapi_users.router.common import ErrorCode, ErrorModel
 
-from app.schemas.schemas import UserCreate, UserRead, UsernameUpdate, PasswordUpdate
+from app.schemas.schemas import UserCreate, UserRead, UserPublic, UsernameUpdate, PasswordUpdate
 from app.schemas.responses import password_change_responses, username_change_responses
 from app.models.models import User
 from app.utils.users import UserManager, current_active_user, get_user_manager
@@ -53,6 +55,31 @@ async def get_user(
     return UserRead.model_validate(user)
 
 
+@router.get("/users",
+            response_model=list[UserPublic],
+            tags=["user"]
+)
+async def get_users(
+    ids: str = Query(description="Comma separated user ids, at most 100"),
+    user: User = Depends(current_active_user),
+    user_manager: UserManager = Depends(get_user_manager)
+) -> list[UserPublic]:
+    try:
+        user_ids = [uuid.UUID(i) for i in ids.split(",") if i]
+    except ValueError:
+        raise HTTPException(
+            status_code=status.HTTP_400_BAD_REQUEST,
+            detail="ids must be comma separated UUIDs",
+        )
+    if len(user_ids) > 100:
+        raise HTTPException(
+            status_code=status.HTTP_400_BAD_REQUEST,
+            detail="at most 100 ids can be requested at once",
+        )
+    users = await user_manager.user_db.get_by_ids(user_ids)
+    return [UserPublic.model_validate(u) for u in users]
+
+
 @router.patch(
     "/me", 
     response_model=UserRead,
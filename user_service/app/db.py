@@ -1,4 +1,5 @@
 import logging
+import uuid
 from typing import AsyncGenerator, Optional
 
 from fastapi import Depends
@@ -20,6 +21,11 @@ class UserDatabase(SQLAlchemyUserDatabase):
         )
         return await self._get_user(statement)
 
+    async def get_by_ids(self, ids: list[uuid.UUID]) -> list[User]:
+        statement = select(self.user_table).where(self.user_table.id.in_(ids))
+        results = await self.session.execute(statement)
+        return list(results.unique().scalars().all())
+
 
 async_engine = create_async_engine(
     settings.DB_URI,
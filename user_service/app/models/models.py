@@ -1,8 +1,8 @@
 from datetime import datetime
 import uuid
 
-from sqlalchemy.dialects.postgresql import UUID
-from sqlalchemy import String, ForeignKey, func, event, Boolean
+from sqlalchemy.dialects.postgresql import JSONB, UUID
+from sqlalchemy import String, ForeignKey, func, event, Boolean, DateTime, Integer, LargeBinary
 from sqlalchemy.orm import mapped_column, Mapped
 
 from .base import Base
@@ -26,6 +26,22 @@ class User(Base):
     is_verified: Mapped[bool] = mapped_column(Boolean, default=False, nullable=False)
     
 
+class Notebook(Base):
+    __tablename__ = "notebooks"
+
+    id: Mapped[uuid.UUID] = mapped_column(
+        UUID(as_uuid=True), nullable=False,
+        unique=True, primary_key=True, default=uuid.uuid4
+    )
+    user_id: Mapped[uuid.UUID] = mapped_column(ForeignKey("users.id"), nullable=False, index=True)
+    parent_id: Mapped[uuid.UUID | None] = mapped_column(
+        ForeignKey("notebooks.id", ondelete="SET NULL"), nullable=True, index=True
+    )
+    name: Mapped[str] = mapped_column(String(200), nullable=False)
+    public: Mapped[bool] = mapped_column(Boolean, nullable=False, server_default="false")
+    create_time: Mapped[datetime] = mapped_column(insert_default=func.now())
+
+
 class Note(Base):
     __tablename__ = "notes"
 
@@ -35,8 +51,27 @@ class Note(Base):
     )
     user_id: Mapped[uuid.UUID] = mapped_column(ForeignKey("users.id"), nullable=False)
     create_time: Mapped[datetime] = mapped_column(insert_default=func.now())
-    text: Mapped[str] = mapped_column(String(128), nullable=False)
+    # text is NULL while the text is encrypted into text_ciphertext, and for
+    # notes encrypted by the client, which have e2e_ciphertext instead
+    text: Mapped[str | None] = mapped_column(String(128), nullable=True)
+    text_ciphertext: Mapped[bytes | None] = mapped_column(LargeBinary, nullable=True)
+    text_key_version: Mapped[int | None] = mapped_column(Integer, nullable=True)
     public: Mapped[bool] = mapped_column(nullable=False)
+    format: Mapped[str] = mapped_column(String(16), nullable=False, server_default="plain")
+    title: Mapped[str | None] = mapped_column(String(200), nullable=True)
+    pinned: Mapped[bool] = mapped_column(Boolean, nullable=False, server_default="false")
+    archived: Mapped[bool] = mapped_column(Boolean, nullable=False, server_default="false")
+    notebook_id: Mapped[uuid.UUID | None] = mapped_column(
+        ForeignKey("notebooks.id", ondelete="SET NULL"), nullable=True, index=True
+    )
+    inherit_public: Mapped[bool] = mapped_column(Boolean, nullable=False, server_default="false")
+    publish_at: Mapped[datetime | None] = mapped_column(DateTime(timezone=True), nullable=True)
+    unpublish_at: Mapped[datetime | None] = mapped_column(DateTime(timezone=True), nullable=True)
+    expires_at: Mapped[datetime | None] = mapped_column(DateTime(timezone=True), nullable=True)
+    burn_after_read: Mapped[bool] = mapped_column(Boolean, nullable=False, server_default="false")
+    encrypted: Mapped[bool] = mapped_column(Boolean, nullable=False, server_default="false")
+    e2e_ciphertext: Mapped[bytes | None] = mapped_column(LargeBinary, nullable=True)
+    e2e_recipients: Mapped[list | None] = mapped_column(JSONB, nullable=True)
 
     
 @event.listens_for(User.hashed_password, "set", active_history=True)
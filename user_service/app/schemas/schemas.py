@@ -43,6 +43,13 @@ class UserRead(BaseModel):
     model_config = ConfigDict(from_attributes=True)
 
 
+class UserPublic(BaseModel):
+    id: uuid.UUID
+    username: str
+
+    model_config = ConfigDict(from_attributes=True)
+
+
 class User(UserRead):
     hashed_password: str
 
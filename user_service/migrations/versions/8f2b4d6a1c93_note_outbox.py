@@ -0,0 +1,40 @@
+"""note outbox
+
+Revision ID: 8f2b4d6a1c93
+Revises: 3c1e7a9d2f40
+Create Date: 2026-10-19 11:03:27.118450
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+from sqlalchemy.dialects import postgresql
+
+
+# revision identifiers, used by Alembic.
+revision: str = '8f2b4d6a1c93'
+down_revision: Union[str, None] = '3c1e7a9d2f40'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    op.create_table('note_outbox',
+    sa.Column('sequence', sa.BigInteger(), sa.Identity(always=False), nullable=False),
+    sa.Column('event_id', sa.UUID(), nullable=False),
+    sa.Column('note_id', sa.UUID(), nullable=False),
+    sa.Column('event_type', sa.String(length=64), nullable=False),
+    sa.Column('payload', postgresql.JSONB(), nullable=False),
+    sa.Column('create_time', sa.DateTime(), nullable=False),
+    sa.Column('published_time', sa.DateTime(), nullable=True),
+    sa.PrimaryKeyConstraint('sequence'),
+    sa.UniqueConstraint('event_id')
+    )
+    op.create_index('ix_note_outbox_unpublished', 'note_outbox', ['sequence'], unique=False,
+                    postgresql_where=sa.text('published_time IS NULL'))
+
+
+def downgrade() -> None:
+    op.drop_index('ix_note_outbox_unpublished', table_name='note_outbox')
+    op.drop_table('note_outbox')
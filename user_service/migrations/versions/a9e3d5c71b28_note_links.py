@@ -0,0 +1,42 @@
+"""note links
+
+Revision ID: a9e3d5c71b28
+Revises: f1c6b3d82a47
+Create Date: 2026-10-19 15:47:03.558104
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'a9e3d5c71b28'
+down_revision: Union[str, None] = 'f1c6b3d82a47'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    # target_id has no foreign key: links to deleted notes are kept and
+    # reported as broken.
+    op.create_table('note_links',
+    sa.Column('source_id', sa.UUID(), nullable=False),
+    sa.Column('target_id', sa.UUID(), nullable=False),
+    sa.ForeignKeyConstraint(['source_id'], ['notes.id'], ondelete='CASCADE'),
+    sa.PrimaryKeyConstraint('source_id', 'target_id')
+    )
+    op.create_index(op.f('ix_note_links_target_id'), 'note_links', ['target_id'], unique=False)
+    # links of existing notes
+    op.execute(r"""
+        INSERT INTO note_links (source_id, target_id)
+        SELECT DISTINCT id, lower(m[1])::uuid
+        FROM notes, regexp_matches(text, '\[\[([0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12})\]\]', 'g') AS m
+        WHERE lower(m[1])::uuid <> id
+    """)
+
+
+def downgrade() -> None:
+    op.drop_index(op.f('ix_note_links_target_id'), table_name='note_links')
+    op.drop_table('note_links')
@@ -0,0 +1,49 @@
+"""note e2e encryption
+
+Revision ID: b3e8f14a6d72
+Revises: f7a2c5e81d39
+Create Date: 2026-10-19 23:12:48.207614
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+from sqlalchemy.dialects import postgresql
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'b3e8f14a6d72'
+down_revision: Union[str, None] = 'f7a2c5e81d39'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    op.add_column('notes', sa.Column('encrypted', sa.Boolean(), server_default='false', nullable=False))
+    op.add_column('notes', sa.Column('e2e_ciphertext', sa.LargeBinary(), nullable=True))
+    op.add_column('notes', sa.Column('e2e_recipients', postgresql.JSONB(), nullable=True))
+    op.drop_constraint('ck_notes_text_stored', 'notes', type_='check')
+    op.create_check_constraint('ck_notes_text_stored', 'notes',
+                               'CASE WHEN encrypted THEN text IS NULL AND text_ciphertext IS NULL '
+                               'AND e2e_ciphertext IS NOT NULL AND e2e_recipients IS NOT NULL '
+                               'ELSE text IS NOT NULL OR (text_ciphertext IS NOT NULL '
+                               'AND text_key_version IS NOT NULL) END')
+    # Text of notes encrypted by the client is never sealed by the server.
+    op.drop_index('ix_notes_unsealed', table_name='notes')
+    op.create_index('ix_notes_unsealed', 'notes', ['id'], unique=False,
+                    postgresql_where=sa.text('NOT encrypted AND public = (text_key_version IS NOT NULL)'))
+
+
+def downgrade() -> None:
+    # Fails while any note is encrypted by the client, there is no text to
+    # fall back to.
+    op.drop_index('ix_notes_unsealed', table_name='notes')
+    op.create_index('ix_notes_unsealed', 'notes', ['id'], unique=False,
+                    postgresql_where=sa.text('public = (text_key_version IS NOT NULL)'))
+    op.drop_constraint('ck_notes_text_stored', 'notes', type_='check')
+    op.create_check_constraint('ck_notes_text_stored', 'notes',
+                               'text IS NOT NULL OR (text_ciphertext IS NOT NULL AND text_key_version IS NOT NULL)')
+    op.drop_column('notes', 'e2e_recipients')
+    op.drop_column('notes', 'e2e_ciphertext')
+    op.drop_column('notes', 'encrypted')
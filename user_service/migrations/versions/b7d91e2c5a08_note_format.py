@@ -0,0 +1,28 @@
+"""note format
+
+Revision ID: b7d91e2c5a08
+Revises: 8f2b4d6a1c93
+Create Date: 2026-10-19 12:20:05.774931
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'b7d91e2c5a08'
+down_revision: Union[str, None] = '8f2b4d6a1c93'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    op.add_column('notes', sa.Column('format', sa.String(length=16), server_default='plain', nullable=False))
+    op.create_check_constraint('ck_notes_format', 'notes', "format IN ('plain', 'markdown')")
+
+
+def downgrade() -> None:
+    op.drop_constraint('ck_notes_format', 'notes', type_='check')
+    op.drop_column('notes', 'format')
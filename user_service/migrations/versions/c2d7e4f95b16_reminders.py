@@ -0,0 +1,46 @@
+"""reminders
+
+Revision ID: c2d7e4f95b16
+Revises: a9e3d5c71b28
+Create Date: 2026-10-19 16:32:51.904417
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'c2d7e4f95b16'
+down_revision: Union[str, None] = 'a9e3d5c71b28'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    op.create_table('reminders',
+    sa.Column('id', sa.UUID(), nullable=False),
+    sa.Column('note_id', sa.UUID(), nullable=False),
+    sa.Column('user_id', sa.UUID(), nullable=False),
+    sa.Column('start_time', sa.DateTime(timezone=True), nullable=False),
+    sa.Column('rrule', sa.String(length=512), nullable=True),
+    sa.Column('next_fire_time', sa.DateTime(timezone=True), nullable=True),
+    sa.Column('fired_count', sa.Integer(), server_default='0', nullable=False),
+    sa.Column('last_fire_time', sa.DateTime(timezone=True), nullable=True),
+    sa.Column('attempts', sa.Integer(), server_default='0', nullable=False),
+    sa.Column('lease_until', sa.DateTime(timezone=True), nullable=True),
+    sa.Column('create_time', sa.DateTime(), nullable=False),
+    sa.ForeignKeyConstraint(['note_id'], ['notes.id'], ondelete='CASCADE'),
+    sa.ForeignKeyConstraint(['user_id'], ['users.id'], ),
+    sa.PrimaryKeyConstraint('id')
+    )
+    op.create_index(op.f('ix_reminders_note_id'), 'reminders', ['note_id', 'user_id'], unique=False)
+    op.create_index('ix_reminders_due', 'reminders', ['next_fire_time'], unique=False,
+                    postgresql_where=sa.text('next_fire_time IS NOT NULL'))
+
+
+def downgrade() -> None:
+    op.drop_index('ix_reminders_due', table_name='reminders')
+    op.drop_index(op.f('ix_reminders_note_id'), table_name='reminders')
+    op.drop_table('reminders')
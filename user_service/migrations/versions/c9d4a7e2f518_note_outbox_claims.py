@@ -0,0 +1,30 @@
+"""note outbox claims
+
+Revision ID: c9d4a7e2f518
+Revises: b3e8f14a6d72
+Create Date: 2026-10-20 09:41:05.392817
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'c9d4a7e2f518'
+down_revision: Union[str, None] = 'b3e8f14a6d72'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    # A relay claims a batch and sends it outside of any transaction, the
+    # claim expires when the relay dies before it is done.
+    op.add_column('note_outbox', sa.Column('claim_id', sa.UUID(), nullable=True))
+    op.add_column('note_outbox', sa.Column('claimed_until', sa.DateTime(), nullable=True))
+
+
+def downgrade() -> None:
+    op.drop_column('note_outbox', 'claimed_until')
+    op.drop_column('note_outbox', 'claim_id')
@@ -0,0 +1,45 @@
+"""attachments
+
+Revision ID: d4a8c3f17e25
+Revises: b7d91e2c5a08
+Create Date: 2026-10-19 13:41:52.306117
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'd4a8c3f17e25'
+down_revision: Union[str, None] = 'b7d91e2c5a08'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    # note_id has no foreign key: the note service removes attachments and
+    # their blobs itself once a note is deleted.
+    op.create_table('attachments',
+    sa.Column('id', sa.UUID(), nullable=False),
+    sa.Column('note_id', sa.UUID(), nullable=False),
+    sa.Column('user_id', sa.UUID(), nullable=False),
+    sa.Column('filename', sa.String(length=255), nullable=False),
+    sa.Column('content_type', sa.String(length=255), nullable=False),
+    sa.Column('size', sa.BigInteger(), nullable=False),
+    sa.Column('sha256', sa.String(length=64), nullable=False),
+    sa.Column('create_time', sa.DateTime(), nullable=False),
+    sa.ForeignKeyConstraint(['user_id'], ['users.id'], ),
+    sa.PrimaryKeyConstraint('id')
+    )
+    op.create_index(op.f('ix_attachments_note_id'), 'attachments', ['note_id'], unique=False)
+    op.create_index(op.f('ix_attachments_user_id'), 'attachments', ['user_id'], unique=False)
+    op.create_index(op.f('ix_attachments_sha256'), 'attachments', ['sha256'], unique=False)
+
+
+def downgrade() -> None:
+    op.drop_index(op.f('ix_attachments_sha256'), table_name='attachments')
+    op.drop_index(op.f('ix_attachments_user_id'), table_name='attachments')
+    op.drop_index(op.f('ix_attachments_note_id'), table_name='attachments')
+    op.drop_table('attachments')
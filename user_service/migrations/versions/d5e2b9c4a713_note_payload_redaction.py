@@ -0,0 +1,30 @@
+"""note payload redaction
+
+Revision ID: d5e2b9c4a713
+Revises: c9d4a7e2f518
+Create Date: 2026-10-21 10:12:44.718302
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'd5e2b9c4a713'
+down_revision: Union[str, None] = 'c9d4a7e2f518'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    # Burning or reaping a note redacts the events kept about it.
+    op.create_index('ix_note_outbox_note_id', 'note_outbox', ['note_id'], unique=False)
+    op.create_index('ix_webhook_deliveries_note_id', 'webhook_deliveries',
+                    [sa.text("(payload->'data'->>'id')")], unique=False)
+
+
+def downgrade() -> None:
+    op.drop_index('ix_webhook_deliveries_note_id', table_name='webhook_deliveries')
+    op.drop_index('ix_note_outbox_note_id', table_name='note_outbox')
@@ -0,0 +1,36 @@
+"""note schedule
+
+Revision ID: d8f3a6b20e49
+Revises: c2d7e4f95b16
+Create Date: 2026-10-19 18:05:12.418273
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'd8f3a6b20e49'
+down_revision: Union[str, None] = 'c2d7e4f95b16'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    # The note service flips public once these pass and clears them again,
+    # the partial indexes keep its polling cheap.
+    op.add_column('notes', sa.Column('publish_at', sa.DateTime(timezone=True), nullable=True))
+    op.add_column('notes', sa.Column('unpublish_at', sa.DateTime(timezone=True), nullable=True))
+    op.create_index('ix_notes_publish_at', 'notes', ['publish_at'], unique=False,
+                    postgresql_where=sa.text('publish_at IS NOT NULL'))
+    op.create_index('ix_notes_unpublish_at', 'notes', ['unpublish_at'], unique=False,
+                    postgresql_where=sa.text('unpublish_at IS NOT NULL'))
+
+
+def downgrade() -> None:
+    op.drop_index('ix_notes_unpublish_at', table_name='notes')
+    op.drop_index('ix_notes_publish_at', table_name='notes')
+    op.drop_column('notes', 'unpublish_at')
+    op.drop_column('notes', 'publish_at')
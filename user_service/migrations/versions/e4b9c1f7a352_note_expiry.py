@@ -0,0 +1,31 @@
+"""note expiry
+
+Revision ID: e4b9c1f7a352
+Revises: d8f3a6b20e49
+Create Date: 2026-10-19 19:12:40.275164
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'e4b9c1f7a352'
+down_revision: Union[str, None] = 'd8f3a6b20e49'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    op.add_column('notes', sa.Column('expires_at', sa.DateTime(timezone=True), nullable=True))
+    op.add_column('notes', sa.Column('burn_after_read', sa.Boolean(), server_default=sa.false(), nullable=False))
+    op.create_index('ix_notes_expires_at', 'notes', ['expires_at'], unique=False,
+                    postgresql_where=sa.text('expires_at IS NOT NULL'))
+
+
+def downgrade() -> None:
+    op.drop_index('ix_notes_expires_at', table_name='notes')
+    op.drop_column('notes', 'burn_after_read')
+    op.drop_column('notes', 'expires_at')
@@ -0,0 +1,32 @@
+"""note title, pinned and archived
+
+Revision ID: e5f0a2b9c614
+Revises: d4a8c3f17e25
+Create Date: 2026-10-19 14:32:18.640092
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'e5f0a2b9c614'
+down_revision: Union[str, None] = 'd4a8c3f17e25'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    op.add_column('notes', sa.Column('title', sa.String(length=200), nullable=True))
+    op.add_column('notes', sa.Column('pinned', sa.Boolean(), server_default=sa.false(), nullable=False))
+    op.add_column('notes', sa.Column('archived', sa.Boolean(), server_default=sa.false(), nullable=False))
+    op.create_index('ix_notes_listing', 'notes', ['archived', 'pinned', 'create_time'], unique=False)
+
+
+def downgrade() -> None:
+    op.drop_index('ix_notes_listing', table_name='notes')
+    op.drop_column('notes', 'archived')
+    op.drop_column('notes', 'pinned')
+    op.drop_column('notes', 'title')
@@ -0,0 +1,28 @@
+"""note recipients index
+
+Revision ID: e8a3c6f1d294
+Revises: d5e2b9c4a713
+Create Date: 2026-10-19 14:03:27.551940
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'e8a3c6f1d294'
+down_revision: Union[str, None] = 'd5e2b9c4a713'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    # Recipients of an encrypted note may read it, reads look them up with @>.
+    op.create_index('ix_notes_e2e_recipients', 'notes', ['e2e_recipients'], unique=False,
+                    postgresql_using='gin', postgresql_ops={'e2e_recipients': 'jsonb_path_ops'})
+
+
+def downgrade() -> None:
+    op.drop_index('ix_notes_e2e_recipients', table_name='notes')
@@ -0,0 +1,50 @@
+"""notebooks
+
+Revision ID: f1c6b3d82a47
+Revises: e5f0a2b9c614
+Create Date: 2026-10-19 15:08:44.217530
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'f1c6b3d82a47'
+down_revision: Union[str, None] = 'e5f0a2b9c614'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    op.create_table('notebooks',
+    sa.Column('id', sa.UUID(), nullable=False),
+    sa.Column('user_id', sa.UUID(), nullable=False),
+    sa.Column('parent_id', sa.UUID(), nullable=True),
+    sa.Column('name', sa.String(length=200), nullable=False),
+    sa.Column('public', sa.Boolean(), server_default=sa.false(), nullable=False),
+    sa.Column('create_time', sa.DateTime(), nullable=False),
+    sa.ForeignKeyConstraint(['user_id'], ['users.id'], ),
+    sa.ForeignKeyConstraint(['parent_id'], ['notebooks.id'], ondelete='SET NULL'),
+    sa.PrimaryKeyConstraint('id')
+    )
+    op.create_index(op.f('ix_notebooks_user_id'), 'notebooks', ['user_id'], unique=False)
+    op.create_index(op.f('ix_notebooks_parent_id'), 'notebooks', ['parent_id'], unique=False)
+    # public stays the effective visibility of a note, inherit_public marks
+    # notes that follow their notebook and is kept in sync by the note service.
+    op.add_column('notes', sa.Column('notebook_id', sa.UUID(), nullable=True))
+    op.add_column('notes', sa.Column('inherit_public', sa.Boolean(), server_default=sa.false(), nullable=False))
+    op.create_foreign_key('fk_notes_notebook_id', 'notes', 'notebooks', ['notebook_id'], ['id'], ondelete='SET NULL')
+    op.create_index(op.f('ix_notes_notebook_id'), 'notes', ['notebook_id'], unique=False)
+
+
+def downgrade() -> None:
+    op.drop_index(op.f('ix_notes_notebook_id'), table_name='notes')
+    op.drop_constraint('fk_notes_notebook_id', 'notes', type_='foreignkey')
+    op.drop_column('notes', 'inherit_public')
+    op.drop_column('notes', 'notebook_id')
+    op.drop_index(op.f('ix_notebooks_parent_id'), table_name='notebooks')
+    op.drop_index(op.f('ix_notebooks_user_id'), table_name='notebooks')
+    op.drop_table('notebooks')
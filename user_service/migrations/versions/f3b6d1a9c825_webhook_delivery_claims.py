@@ -0,0 +1,28 @@
+"""webhook delivery claims
+
+Revision ID: f3b6d1a9c825
+Revises: e8a3c6f1d294
+Create Date: 2026-10-21 16:20:31.204518
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'f3b6d1a9c825'
+down_revision: Union[str, None] = 'e8a3c6f1d294'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    # A worker only records an attempt while the delivery is still under its
+    # claim, a lease that ran out may have been claimed again elsewhere.
+    op.add_column('webhook_deliveries', sa.Column('claim_id', sa.UUID(), nullable=True))
+
+
+def downgrade() -> None:
+    op.drop_column('webhook_deliveries', 'claim_id')
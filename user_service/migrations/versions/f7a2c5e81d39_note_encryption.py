@@ -0,0 +1,53 @@
+"""note encryption
+
+Revision ID: f7a2c5e81d39
+Revises: e4b9c1f7a352
+Create Date: 2026-10-19 21:04:17.518306
+
+"""
+from typing import Sequence, Union
+
+from alembic import op
+import sqlalchemy as sa
+
+
+# revision identifiers, used by Alembic.
+revision: str = 'f7a2c5e81d39'
+down_revision: Union[str, None] = 'e4b9c1f7a352'
+branch_labels: Union[str, Sequence[str], None] = None
+depends_on: Union[str, Sequence[str], None] = None
+
+
+def upgrade() -> None:
+    op.create_table('note_keys',
+    sa.Column('user_id', sa.UUID(), nullable=False),
+    sa.Column('version', sa.Integer(), nullable=False),
+    sa.Column('wrapped_key', sa.LargeBinary(), nullable=False),
+    sa.Column('master_key_id', sa.String(length=16), nullable=False),
+    sa.Column('create_time', sa.DateTime(timezone=True), server_default=sa.func.now(), nullable=False),
+    sa.ForeignKeyConstraint(['user_id'], ['users.id'], ondelete='CASCADE'),
+    sa.PrimaryKeyConstraint('user_id', 'version')
+    )
+    op.alter_column('notes', 'text', existing_type=sa.String(length=128), nullable=True)
+    op.add_column('notes', sa.Column('text_ciphertext', sa.LargeBinary(), nullable=True))
+    op.add_column('notes', sa.Column('text_key_version', sa.Integer(), nullable=True))
+    op.create_check_constraint('ck_notes_text_stored', 'notes',
+                               'text IS NOT NULL OR (text_ciphertext IS NOT NULL AND text_key_version IS NOT NULL)')
+    # Notes whose text is stored the wrong way for their visibility, which
+    # the key rotation job encrypts or decrypts.
+    op.create_index('ix_notes_unsealed', 'notes', ['id'], unique=False,
+                    postgresql_where=sa.text('public = (text_key_version IS NOT NULL)'))
+    op.create_index('ix_notes_text_key_version', 'notes', ['user_id', 'text_key_version'], unique=False,
+                    postgresql_where=sa.text('text_key_version IS NOT NULL'))
+
+
+def downgrade() -> None:
+    # Fails while any note text is still encrypted, the database alone
+    # can't decrypt it.
+    op.alter_column('notes', 'text', existing_type=sa.String(length=128), nullable=False)
+    op.drop_index('ix_notes_text_key_version', table_name='notes')
+    op.drop_index('ix_notes_unsealed', table_name='notes')
+    op.drop_constraint('ck_notes_text_stored', 'notes', type_='check')
+    op.drop_column('notes', 'text_key_version')
+    op.drop_column('notes', 'text_ciphertext')
+    op.drop_table('note_keys')